@@ -0,0 +1,18 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+// SkipArchiveKey, as an annotation on a deleted DebugSession, releases it without archiving its final
+// spec and status, e.g. while storage is unavailable and a namespace must be torn down. The Event
+// recording the failed upload is kept either way. Only trusted approvers can set it.
+const SkipArchiveKey = "debug.ajou.oxan0n.me/skip-archive"
+
+// Extension is the file extension, without the dot, of transcripts archived in this format.
+func (f ArchiveFormat) Extension() string {
+	if f == ArchiveFormatBundle {
+		return "zip"
+	}
+	return "log"
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import "time"
+
+// AttachmentHeartbeatInterval is how often the debug proxy refreshes the heartbeat of each open
+// connection in status.attachments.
+const AttachmentHeartbeatInterval = 30 * time.Second
+
+// AttachmentStaleAfter is how long an open connection may go without a heartbeat before it is
+// presumed lost with its proxy. It allows for two missed heartbeats.
+const AttachmentStaleAfter = 3 * AttachmentHeartbeatInterval
+
+// LastHeartbeat is when the proxy last confirmed the connection open. Entries written before
+// heartbeats were recorded count from their attach.
+func (a *Attachment) LastHeartbeat() time.Time {
+	if a.HeartbeatTime != nil {
+		return a.HeartbeatTime.Time
+	}
+	return a.AttachTime.Time
+}
+
+// StaleAt is when an open connection is presumed lost unless its proxy refreshes the heartbeat first.
+func (a *Attachment) StaleAt() time.Time {
+	return a.LastHeartbeat().Add(AttachmentStaleAfter)
+}
+
+// OpenAttachments counts the connections in status.attachments that have not detached.
+func OpenAttachments(status *DebugSessionStatus) int32 {
+	var open int32
+	for _, attachment := range status.Attachments {
+		if attachment.DetachTime == nil {
+			open++
+		}
+	}
+	return open
+}
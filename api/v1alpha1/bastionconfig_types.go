@@ -0,0 +1,135 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BastionAccess describes how a client reaches the debug proxy through a bastion host.
+type BastionAccess struct {
+	// Host is the SSH destination of the bastion, e.g. "user@bastion.example.com".
+	// +kubebuilder:validation:Optional
+	Host string `json:"host,omitempty"`
+
+	// Port is the SSH port of the bastion. The ssh default is used when unset.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port,omitempty"`
+
+	// LocalPort is the port the instructions forward on the client's machine. Defaults to 8080.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	LocalPort int32 `json:"localPort,omitempty"`
+
+	// Teleport sends clients through Teleport application access instead of an SSH tunnel to a
+	// NodePort. Each session is registered as a Teleport app.
+	// +kubebuilder:validation:Optional
+	Teleport *TeleportAccess `json:"teleport,omitempty"`
+
+	// SSM sends clients through an AWS Systems Manager port-forwarding session to the proxy's node
+	// instead of an SSH tunnel through a bastion, for EKS clusters without one.
+	// +kubebuilder:validation:Optional
+	SSM *SSMAccess `json:"ssm,omitempty"`
+
+	// Overlay sends clients straight to the proxy's address on a Tailscale tailnet or WireGuard
+	// network, with no tunnel or NodePort in between.
+	// +kubebuilder:validation:Optional
+	Overlay *OverlayAccess `json:"overlay,omitempty"`
+}
+
+// OverlayAccess describes where the debug proxy listens on an overlay network, e.g. the Tailscale
+// sidecar the chart adds with debugProxy.tailscale.enabled.
+type OverlayAccess struct {
+	// URL is the proxy's base URL on the overlay, e.g. "https://kubedebugsess-proxy.tail1234.ts.net"
+	// or "http://10.100.0.2:8080".
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?://[^/]+$`
+	URL string `json:"url"`
+}
+
+// SSMAccess describes the AWS Systems Manager port forwarding clients use. The proxy's node must run
+// the SSM agent, which is the default on EKS-optimized AMIs.
+type SSMAccess struct {
+	// Region of the node's instance. It is taken from the node's zone when unset.
+	// +kubebuilder:validation:Optional
+	Region string `json:"region,omitempty"`
+}
+
+// TeleportAccess describes the Teleport cluster sessions are published through. The operator creates
+// a Service per session for the Teleport discovery service to register as an app, so the discovery
+// service must watch the operator's namespace.
+type TeleportAccess struct {
+	// Proxy is the address of the Teleport proxy, passed to tsh login --proxy.
+	// +kubebuilder:validation:Required
+	Proxy string `json:"proxy"`
+
+	// Cluster is the Teleport cluster the app is registered in, when it is not the proxy's own.
+	// +kubebuilder:validation:Optional
+	Cluster string `json:"cluster,omitempty"`
+
+	// Labels are added to each session's app, for Teleport roles to grant access by.
+	// +kubebuilder:validation:Optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// BastionEnvironment overrides the access path for the sessions targeting some namespaces.
+type BastionEnvironment struct {
+	// Name identifies the environment, e.g. "production".
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespaces selects the target namespaces the environment applies to.
+	// +kubebuilder:validation:Optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects the target namespaces by label.
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Fields left unset are taken from the spec's defaults. An environment that sets host, teleport,
+	// ssm or overlay also replaces the way clients connect.
+	BastionAccess `json:",inline"`
+}
+
+// BastionConfigSpec describes the access paths handed out in session connection instructions.
+// +kubebuilder:validation:XValidation:rule="has(self.host) || has(self.teleport) || has(self.ssm) || has(self.overlay) || (has(self.environments) && self.environments.all(e, has(e.host) || has(e.teleport) || has(e.ssm) || has(e.overlay)))",message="host, teleport, ssm or overlay is required unless every environment sets one"
+type BastionConfigSpec struct {
+	// Defaults apply to every target namespace that no environment matches.
+	BastionAccess `json:",inline"`
+
+	// Environments override the defaults per target namespace. The first matching one is used.
+	// +kubebuilder:validation:Optional
+	Environments []BastionEnvironment `json:"environments,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Host",type="string",JSONPath=".spec.host"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// BastionConfig describes the bastion hosts clients tunnel through to reach the debug proxy. It
+// replaces the BASTION_HOST environment variable, and changes take effect without restarting the
+// controller.
+type BastionConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BastionConfigSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// BastionConfigList contains a list of BastionConfig
+type BastionConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BastionConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BastionConfig{}, &BastionConfigList{})
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TeamLabel and CostCenterLabel attribute a DebugSession's usage for chargeback. They are read from
+// the session's labels, or else from its target namespace's, and carried onto the proxy metrics, the
+// archived objects' tags and the DebugSessionRecord.
+const (
+	TeamLabel       = "ajou.oxan0n.me/team"
+	CostCenterLabel = "ajou.oxan0n.me/cost-center"
+)
+
+// CostAttribution is who a session's usage is charged to.
+type CostAttribution struct {
+	// +kubebuilder:validation:Optional
+	Team string `json:"team,omitempty"`
+	// +kubebuilder:validation:Optional
+	CostCenter string `json:"costCenter,omitempty"`
+}
+
+// CostAttributionOf resolves the attribution of a session targeting namespace. Each label on the
+// session overrides the namespace's. It returns nil when neither carries any.
+func CostAttributionOf(session *DebugSession, namespace *corev1.Namespace) *CostAttribution {
+	lookup := func(key string) string {
+		if v := session.Labels[key]; v != "" {
+			return v
+		}
+		if namespace != nil {
+			return namespace.Labels[key]
+		}
+		return ""
+	}
+	attribution := &CostAttribution{Team: lookup(TeamLabel), CostCenter: lookup(CostCenterLabel)}
+	if attribution.Team == "" && attribution.CostCenter == "" {
+		return nil
+	}
+	return attribution
+}
+
+// Labels returns the attribution as TeamLabel and CostCenterLabel labels.
+func (a *CostAttribution) Labels() map[string]string {
+	labels := map[string]string{}
+	if a == nil {
+		return labels
+	}
+	if a.Team != "" {
+		labels[TeamLabel] = a.Team
+	}
+	if a.CostCenter != "" {
+		labels[CostCenterLabel] = a.CostCenter
+	}
+	return labels
+}
@@ -5,6 +5,8 @@ Copyright 2025.
 package v1alpha1
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -23,6 +25,298 @@ const (
 	Failed      SessionPhase = "Failed"
 )
 
+// Condition types reported in DebugSessionStatus.Conditions.
+const (
+	// ConditionTargetValidated is True once the target namespace, pod and container were found and usable.
+	ConditionTargetValidated = "TargetValidated"
+	// ConditionInjected is True once the debugger ephemeral container was added to the target pod.
+	ConditionInjected = "Injected"
+	// ConditionReady is True while the debugger container is running and accepting attach connections.
+	ConditionReady = "Ready"
+	// ConditionAttached is True while a client is connected through the debug proxy.
+	ConditionAttached = "Attached"
+	// ConditionExpired is True once the session outlived its TTL.
+	ConditionExpired = "Expired"
+	// ConditionArchived is True once the debugger transcript was uploaded to log storage.
+	ConditionArchived = "Archived"
+	// ConditionNotified reports whether the most recent webhook notification was delivered.
+	ConditionNotified = "Notified"
+	// ConditionDiagnosed is True once failure diagnostics were collected into status.diagnostics.
+	ConditionDiagnosed = "Diagnosed"
+	// ConditionRecorded is True once the session's DebugSessionRecord was written.
+	ConditionRecorded = "Recorded"
+	// ConditionEgressRestricted is True while a NetworkPolicy restricts the target pod's egress, and False
+	// with reason NotEnforceable if other NetworkPolicies already allow it egress.
+	ConditionEgressRestricted = "EgressRestricted"
+	// ConditionPodRecreated is True once the target pod was evicted under CleanupPolicy RecreatePod.
+	ConditionPodRecreated = "PodRecreated"
+	// ConditionRequesterAuthorized is True once the requester was found to hold exec rights on the target pod.
+	ConditionRequesterAuthorized = "RequesterAuthorized"
+	// ConditionCaptured is True once the artifacts of a spec.capture action were uploaded to log storage.
+	ConditionCaptured = "Captured"
+	// ConditionApproved is True once a session that needs approval was approved.
+	ConditionApproved = "Approved"
+	// ConditionAppRegistered is True while the session is registered as a Teleport app.
+	ConditionAppRegistered = "AppRegistered"
+	// ConditionCredentialsIssued is True while Vault credentials issued to the debugger are unrevoked.
+	ConditionCredentialsIssued = "CredentialsIssued"
+	// ConditionTokenIssued is True while the ServiceAccount token issued to the debugger is valid.
+	ConditionTokenIssued = "TokenIssued"
+	// ConditionTargetReplaced is False while a session with spec.followWorkload waits for a replacement
+	// of its lost target pod, and True once it moved to one.
+	ConditionTargetReplaced = "TargetReplaced"
+	// ConditionTicketValidated is True once spec.ticketRef was found open in the configured ticket system.
+	ConditionTicketValidated = "TicketValidated"
+	// ConditionAuthFailuresExceeded is True once status.failedAuthAttempts reached the threshold of
+	// spec.authFailurePolicy.
+	ConditionAuthFailuresExceeded = "AuthFailuresExceeded"
+	// ConditionLocked is True while the debug proxy refuses every connection to the session because
+	// of failed authentication attempts, until it is unlocked with the debug.ajou.oxan0n.me/unlock
+	// annotation.
+	ConditionLocked = "Locked"
+)
+
+// ArchiveFormat selects what is uploaded to log storage when a session ends.
+// +kubebuilder:validation:Enum=Log;Bundle
+type ArchiveFormat string
+
+const (
+	// ArchiveFormatLog uploads the cleaned debugger transcript as a plain text file.
+	ArchiveFormatLog ArchiveFormat = "Log"
+	// ArchiveFormatBundle uploads a zip containing the transcript, the session, the target pod,
+	// its recent Events and the node it runs on.
+	ArchiveFormatBundle ArchiveFormat = "Bundle"
+)
+
+// ConnectionMode selects how clients reach the debug proxy.
+// +kubebuilder:validation:Enum=External;InCluster
+type ConnectionMode string
+
+const (
+	// ConnectionModeExternal hands out instructions to reach the proxy from outside the cluster,
+	// through the BastionConfig's SSH tunnel, Teleport, SSM or overlay network.
+	ConnectionModeExternal ConnectionMode = "External"
+	// ConnectionModeInCluster hands out the proxy's Service DNS name only, for clients that run in the
+	// cluster such as CI jobs and web UI backends. No NodePort or bastion is involved. A node-local
+	// proxy rejects connections that reach it on another node than the target's, so this needs the
+	// proxy to run as a Deployment.
+	ConnectionModeInCluster ConnectionMode = "InCluster"
+)
+
+// ArchivePolicy decides whether a failed transcript upload blocks termination.
+// +kubebuilder:validation:Enum=Required;BestEffort
+type ArchivePolicy string
+
+const (
+	// ArchivePolicyRequired fails the session when the transcript cannot be uploaded.
+	ArchivePolicyRequired ArchivePolicy = "Required"
+	// ArchivePolicyBestEffort completes the session anyway and retries the upload in the background.
+	ArchivePolicyBestEffort ArchivePolicy = "BestEffort"
+)
+
+// EgressPolicy restricts where the debugger can connect to while the session runs.
+// +kubebuilder:validation:Enum=Unrestricted;ClusterInternal;DNSOnly
+type EgressPolicy string
+
+const (
+	// EgressUnrestricted leaves the target pod's network policies alone.
+	EgressUnrestricted EgressPolicy = "Unrestricted"
+	// EgressClusterInternal allows DNS and connections to pods in the cluster only.
+	EgressClusterInternal EgressPolicy = "ClusterInternal"
+	// EgressDNSOnly allows DNS lookups only.
+	EgressDNSOnly EgressPolicy = "DNSOnly"
+)
+
+// ReusePolicy decides whether a session may share the debugger of another session instead of
+// injecting its own.
+// +kubebuilder:validation:Enum=Never;IfCompatible
+type ReusePolicy string
+
+const (
+	// ReusePolicyNever always injects a new debugger container.
+	ReusePolicyNever ReusePolicy = "Never"
+	// ReusePolicyIfCompatible joins an Active session on the same target pod and container running the
+	// same debugger image, with its own token, as a read-only observer. A new debugger is injected only
+	// when there is no such session.
+	ReusePolicyIfCompatible ReusePolicy = "IfCompatible"
+)
+
+// CleanupPolicy decides what happens to the debugger container once the session ends.
+// +kubebuilder:validation:Enum=StopDebugger;RecreatePod;Retain
+type CleanupPolicy string
+
+const (
+	// CleanupPolicyStopDebugger kills the debugger's processes. The stopped container stays in the pod
+	// spec, since ephemeral containers cannot be removed.
+	CleanupPolicyStopDebugger CleanupPolicy = "StopDebugger"
+	// CleanupPolicyRecreatePod also evicts the target pod once the transcript is archived, so that its
+	// ReplicaSet replaces it with a pod free of the debugger. The eviction respects PodDisruptionBudgets
+	// and is skipped for pods not managed by a ReplicaSet.
+	CleanupPolicyRecreatePod CleanupPolicy = "RecreatePod"
+	// CleanupPolicyRetain leaves the debugger running after the session ends, e.g. for a long-running
+	// capture. The attach token is still revoked and the transcript archived up to that point.
+	CleanupPolicyRetain CleanupPolicy = "Retain"
+)
+
+// Toolset names a curated debugger preset.
+// +kubebuilder:validation:Enum=network;database;jvm;golang;python
+type Toolset string
+
+const (
+	// ToolsetNetwork bundles network troubleshooting tools such as tcpdump, dig and curl.
+	ToolsetNetwork Toolset = "network"
+	// ToolsetDatabase bundles database clients.
+	ToolsetDatabase Toolset = "database"
+	// ToolsetJVM bundles a JDK with jcmd, jstack and jmap.
+	ToolsetJVM Toolset = "jvm"
+	// ToolsetGolang bundles the Go toolchain.
+	ToolsetGolang Toolset = "golang"
+	// ToolsetPython bundles a Python interpreter.
+	ToolsetPython Toolset = "python"
+)
+
+// CaptureType selects a non-interactive capture action.
+// +kubebuilder:validation:Enum=Tcpdump;Strace;Perf;ThreadDump;HeapDump;Pprof;Bpftrace
+type CaptureType string
+
+const (
+	// CaptureTcpdump records the target pod's traffic into a pcap file.
+	CaptureTcpdump CaptureType = "Tcpdump"
+	// CaptureStrace traces the system calls of the target container's main process.
+	CaptureStrace CaptureType = "Strace"
+	// CapturePerf samples the stacks of the target container's main process into a perf.data file. No
+	// maintained image ships perf, so spec.debuggerImage must name one that does.
+	CapturePerf CaptureType = "Perf"
+	// CaptureThreadDump prints the threads of the target container's JVM with jcmd.
+	CaptureThreadDump CaptureType = "ThreadDump"
+	// CaptureHeapDump dumps the heap of the target container's JVM into an hprof file. The JVM writes
+	// the dump to its own /tmp first, which must be writable and large enough.
+	CaptureHeapDump CaptureType = "HeapDump"
+	// CapturePprof fetches profiles from a Go net/http/pprof endpoint of the target pod over its
+	// loopback interface, so the endpoint need not be exposed outside the pod.
+	CapturePprof CaptureType = "Pprof"
+	// CaptureBpftrace runs a bpftrace program. It needs privileges that reach beyond the target pod, so
+	// it is only allowed in namespaces labeled debug.ajou.oxan0n.me/allow-ebpf=true and waits for a
+	// debug.ajou.oxan0n.me/approved-by annotation from someone other than the requester.
+	CaptureBpftrace CaptureType = "Bpftrace"
+)
+
+// PprofProfile names a profile served under /debug/pprof/.
+// +kubebuilder:validation:Enum=cpu;heap;goroutine
+type PprofProfile string
+
+const (
+	// PprofCPU samples the CPU for the capture's duration.
+	PprofCPU PprofProfile = "cpu"
+	// PprofHeap snapshots the live heap.
+	PprofHeap PprofProfile = "heap"
+	// PprofGoroutine snapshots the stacks of all goroutines.
+	PprofGoroutine PprofProfile = "goroutine"
+)
+
+// Capture runs a bounded, non-interactive action in place of the debugger shell. What it produces is
+// uploaded to log storage as the session's artifacts when the session ends.
+// +kubebuilder:validation:XValidation:rule="self.type != 'Bpftrace' || has(self.script)",message="a Bpftrace capture requires a script"
+type Capture struct {
+	// Type of the capture action.
+	// +kubebuilder:validation:Required
+	Type CaptureType `json:"type"`
+
+	// Filter narrows what is captured: a tcpdump filter expression such as "tcp port 8080" for Tcpdump,
+	// or an strace -e expression such as "trace=network" for Strace. Other types ignore it.
+	// +kubebuilder:validation:Optional
+	Filter string `json:"filter,omitempty"`
+
+	// Interface to capture on.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=any
+	Interface string `json:"interface,omitempty"`
+
+	// Port is the target's pprof HTTP port for a Pprof capture.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=6060
+	Port int32 `json:"port,omitempty"`
+
+	// Profiles are the profiles a Pprof capture fetches, one artifact each.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default={cpu,heap,goroutine}
+	// +kubebuilder:validation:MaxItems=3
+	// +listType=set
+	Profiles []PprofProfile `json:"profiles,omitempty"`
+
+	// Script is the bpftrace program of a Bpftrace capture. $1 is the PID of the target container's main
+	// process.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=16384
+	Script string `json:"script,omitempty"`
+
+	// DurationSeconds bounds how long the capture runs. Thread and heap dumps take a single snapshot;
+	// a Pprof capture samples the CPU for this long.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=3600
+	// +kubebuilder:default=60
+	DurationSeconds int32 `json:"durationSeconds,omitempty"`
+
+	// MaxPackets stops a Tcpdump capture early once this many packets were recorded. Keep captures well below
+	// the kubelet's container log size limit, which truncates larger ones.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxPackets *int32 `json:"maxPackets,omitempty"`
+}
+
+// Runtime names the language runtime of a target process.
+// +kubebuilder:validation:Enum=Auto;Go;Python;JVM
+type Runtime string
+
+const (
+	// RuntimeAuto detects the runtime from the target process's executable.
+	RuntimeAuto Runtime = "Auto"
+	// RuntimeGo attaches dlv in headless mode.
+	RuntimeGo Runtime = "Go"
+	// RuntimePython dumps the stacks with py-spy and attaches debugpy.
+	RuntimePython Runtime = "Python"
+	// RuntimeJVM starts the JMX management agent through jattach.
+	RuntimeJVM Runtime = "JVM"
+)
+
+// RuntimeAttach attaches a runtime debugger to the target container's main process. The debugger
+// image must provide dlv, py-spy and debugpy, or jattach, as the runtime requires.
+type RuntimeAttach struct {
+	// Runtime of the target process.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Auto
+	Runtime Runtime `json:"runtime,omitempty"`
+
+	// Port the runtime debugger listens on, on the pod's loopback interface. The debug proxy forwards
+	// it to the session's token holder.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=40000
+	Port int32 `json:"port,omitempty"`
+}
+
+// Requester identifies the user who created a DebugSession, as authenticated by the API server.
+type Requester struct {
+	// Username is the name of the user.
+	Username string `json:"username"`
+
+	// UID uniquely identifies the user across time.
+	// +kubebuilder:validation:Optional
+	UID string `json:"uid,omitempty"`
+
+	// Groups are the groups the user belongs to.
+	// +kubebuilder:validation:Optional
+	Groups []string `json:"groups,omitempty"`
+
+	// Extra holds additional information provided by the authenticator.
+	// +kubebuilder:validation:Optional
+	Extra map[string][]string `json:"extra,omitempty"`
+}
+
 // DebugSecurityContext defines security-related options for the ephemeral debug container.
 type DebugSecurityContext struct {
 	// +kubebuilder:default=true
@@ -52,35 +346,364 @@ type DebugSecurityContext struct {
 }
 
 // DebugSessionSpec defines the desired state of a DebugSession, as specified by the user.
+// +kubebuilder:validation:XValidation:rule="has(self.debuggerImage) || has(self.toolset) || has(self.capture)",message="debuggerImage, toolset or capture is required"
+// +kubebuilder:validation:XValidation:rule="!has(self.capture) || self.capture.type != 'Perf' || has(self.debuggerImage)",message="a Perf capture requires a debuggerImage that provides perf"
+// +kubebuilder:validation:XValidation:rule="!has(self.attachDeadlineSeconds) || !has(self.ttl) || self.attachDeadlineSeconds <= self.ttl",message="attachDeadlineSeconds cannot exceed ttl"
+// +kubebuilder:validation:XValidation:rule="!has(self.detachGracePeriodSeconds) || !has(self.ttl) || self.detachGracePeriodSeconds < self.ttl",message="detachGracePeriodSeconds must be less than ttl"
+// +kubebuilder:validation:XValidation:rule="!has(self.capture) || self.capture.type != 'Bpftrace' || !has(self.debuggerImage)",message="a Bpftrace capture runs its pinned image; debuggerImage cannot be set"
+// +kubebuilder:validation:XValidation:rule="has(self.capture) == has(oldSelf.capture)",message="capture is immutable"
+// +kubebuilder:validation:XValidation:rule="has(self.debugSecurity) == has(oldSelf.debugSecurity)",message="debugSecurity is immutable"
 type DebugSessionSpec struct {
-	// TargetPodName is the name of the Pod to which the debug container will be attached.
+	// There is no pod selector yet. One added later must be mutually exclusive with targetPodName,
+	// with a spec-level rule such as has(self.targetPodName) != has(self.targetSelector).
+
+	// TargetPodName is the name of the Pod to which the debug container will be attached. It cannot be
+	// changed once the session is created; spec.followWorkload records a replacement pod in the status.
 	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="targetPodName is immutable"
 	TargetPodName string `json:"targetPodName"`
 
 	// TargetContainerName is the name of a specific container within the target Pod to debug.
 	// +kubebuilder:validation:Optional
 	TargetContainerName string `json:"targetContainerName,omitempty"`
 
-	// TargetNamespace is the namespace where the target Pod is located.
+	// TargetNamespace is the namespace where the target Pod is located. It cannot be changed once the
+	// session is created.
 	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="targetNamespace is immutable"
 	TargetNamespace string `json:"targetNamespace,omitempty"`
 
-	// DebuggerImage is the container image to use for the debugging session.
-	// +kubebuilder:validation:Required
-	DebuggerImage string `json:"debuggerImage"`
+	// DebuggerImage is the container image to use for the debugging session. It may be left out when a
+	// Toolset is chosen, and overrides the toolset's image otherwise. It cannot be changed once the
+	// session is created, and must be a well-formed image reference.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=512
+	// +kubebuilder:validation:XValidation:rule="self.matches('^[a-z0-9]+([._-]+[a-z0-9]+)*(:[0-9]+)?(/[a-z0-9]+([._-]+[a-z0-9]+)*)*(:[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?(@sha256:[a-f0-9]{64})?$')",message="debuggerImage must be an image reference such as registry.example.com/tools/debug:1.0"
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="debuggerImage is immutable"
+	DebuggerImage string `json:"debuggerImage,omitempty"`
+
+	// Toolset selects a curated debugger preset: a maintained image together with the capabilities and
+	// setup commands its tools need.
+	// +kubebuilder:validation:Optional
+	Toolset Toolset `json:"toolset,omitempty"`
+
+	// SetupCommands are run in order by the debugger's shell before it turns interactive, e.g. to install
+	// tools, export variables or change directory, which carry over into the interactive shell. Each
+	// command is echoed with its output into the transcript; a failing command does not stop the rest.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=20
+	// +kubebuilder:validation:items:MinLength=1
+	SetupCommands []string `json:"setupCommands,omitempty"`
+
+	// ToolboxImage, if set, is injected as a second ephemeral container next to the debugger, so that
+	// DebuggerImage can stay a small shell while heavier tooling is pulled in parallel. The toolbox only
+	// idles; its binaries are reachable from the shell through /proc/<pid>/root, since the target pod
+	// shares its process namespace.
+	// +kubebuilder:validation:Optional
+	ToolboxImage string `json:"toolboxImage,omitempty"`
+
+	// Capture, if set, runs a non-interactive capture action instead of an interactive shell and ends the
+	// session when it is done. It comes with its own image and capabilities; debuggerImage or toolset
+	// override the image and debugSecurity the capabilities. A Bpftrace capture always runs its pinned
+	// image. It cannot be changed once the session is created, so that an approval covers what runs.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="capture is immutable"
+	Capture *Capture `json:"capture,omitempty"`
+
+	// RuntimeAttach, if set, attaches a runtime debugger or profiler to the target container's main
+	// process before the shell turns interactive, and lets the debug proxy forward its port. It grants
+	// the debugger SYS_PTRACE.
+	// +kubebuilder:validation:Optional
+	RuntimeAttach *RuntimeAttach `json:"runtimeAttach,omitempty"`
+
+	// VaultCredentials are short-lived credentials read from HashiCorp Vault for this session and
+	// exported to the debugger as environment variables. Their leases are revoked when the session ends.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=8
+	// +listType=map
+	// +listMapKey=envPrefix
+	VaultCredentials []VaultCredential `json:"vaultCredentials,omitempty"`
+
+	// ServiceAccountToken, if set, hands the debugger a short-lived token of the target pod's
+	// ServiceAccount in KUBE_TOKEN, bound to the session so that it stops working when the session ends.
+	// API calls made with it are attributable to the session in the audit log.
+	// +kubebuilder:validation:Optional
+	ServiceAccountToken *ServiceAccountToken `json:"serviceAccountToken,omitempty"`
+
+	// SecretRefs exports Secrets of the target namespace into the debugger as environment variables.
+	// Each must be allowed by the namespace's debug.ajou.oxan0n.me/allowed-secrets annotation.
+	// Ephemeral containers cannot add volumes, so the values are exported rather than mounted.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=16
+	SecretRefs []EnvSourceRef `json:"secretRefs,omitempty"`
+
+	// ConfigMapRefs exports ConfigMaps of the target namespace into the debugger as environment
+	// variables. Each must be allowed by the namespace's debug.ajou.oxan0n.me/allowed-configmaps
+	// annotation.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=16
+	ConfigMapRefs []EnvSourceRef `json:"configMapRefs,omitempty"`
 
-	// TTL is the maximum seconds for debugging sessions.
+	// TTL is the maximum seconds for debugging sessions, from one minute to one day.
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:default=300
+	// +kubebuilder:validation:XValidation:rule="self >= 60 && self <= 86400",message="ttl must be between 60 and 86400 seconds"
 	TTL int32 `json:"ttl,omitempty"`
 
-	// MaxRetryCount is the maximum number of times to retry a session setup for recoverable errors.
+	// MaxRetryCount is the maximum number of times to retry a session setup for recoverable errors,
+	// at most 10.
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:default=3
+	// +kubebuilder:validation:XValidation:rule="self >= 0 && self <= 10",message="maxRetryCount must be between 0 and 10"
 	MaxRetryCount int32 `json:"maxRetryCount,omitempty"`
 
+	// DebugSecurity overrides the debugger's security context. It is checked against the Pod Security
+	// Standards level enforced on the target namespace before injection: missing restrictions the level
+	// requires are added, and forbidden settings fail the session. A privileged debugger, or capabilities
+	// beyond NET_ADMIN, NET_RAW and SYS_PTRACE, need approval, and privileges that allow eBPF need the
+	// debug.ajou.oxan0n.me/allow-ebpf namespace label. It cannot be changed once the session is created.
 	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="debugSecurity is immutable"
 	DebugSecurity *DebugSecurityContext `json:"debugSecurity,omitempty"`
+
+	// TTLAfterFailed is the number of seconds a Failed session is kept before the controller deletes it.
+	// Failed sessions are kept indefinitely when unset, so that they can be retried with the
+	// debug.ajou.oxan0n.me/retry annotation.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	TTLAfterFailed *int32 `json:"ttlAfterFailed,omitempty"`
+
+	// AttachDeadlineSeconds, if set, terminates a Ready session that no client attached to within this
+	// many seconds, revoking its token, instead of leaving it exposed for its whole TTL.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	AttachDeadlineSeconds *int32 `json:"attachDeadlineSeconds,omitempty"`
+
+	// DetachGracePeriodSeconds, if set, terminates the session once no client has been attached for
+	// this many seconds after the last one detached, instead of waiting out the TTL.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	DetachGracePeriodSeconds *int32 `json:"detachGracePeriodSeconds,omitempty"`
+
+	// TerminateOnDetach terminates the session as soon as the last attached client disconnects, as if
+	// DetachGracePeriodSeconds were 0.
+	// +kubebuilder:validation:Optional
+	TerminateOnDetach bool `json:"terminateOnDetach,omitempty"`
+
+	// RotateTokenOnAttach replaces status.oneTimeToken every time a client attaches, so that a token
+	// captured from an earlier connection cannot be replayed later in the session. This applies to
+	// attaches and port-forwards. The new token is written to status.oneTimeToken, not to a Secret,
+	// so clients re-read it from the session's status before each connection.
+	// +kubebuilder:validation:Optional
+	RotateTokenOnAttach bool `json:"rotateTokenOnAttach,omitempty"`
+
+	// AuthFailurePolicy raises an alert, and optionally locks the session, once the debug proxy
+	// counted too many attach attempts with an invalid token.
+	// +kubebuilder:validation:Optional
+	AuthFailurePolicy *AuthFailurePolicy `json:"authFailurePolicy,omitempty"`
+
+	// ArchiveFormat selects whether the transcript alone or a full diagnostic bundle is archived.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Log
+	ArchiveFormat ArchiveFormat `json:"archiveFormat,omitempty"`
+
+	// ArchivePolicy decides whether termination waits for a successful transcript upload, and whether
+	// deleting the session waits until its final spec and status are archived next to the transcript.
+	// A trusted approver can annotate a deleted session with debug.ajou.oxan0n.me/skip-archive to release
+	// it without the archive.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="archivePolicy is immutable"
+	ArchivePolicy ArchivePolicy `json:"archivePolicy,omitempty"`
+
+	// ConnectionMode selects whether the connection instructions reach the debug proxy from outside
+	// the cluster or through its Service from inside it. Both endpoints are recorded in status.endpoints.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=External
+	ConnectionMode ConnectionMode `json:"connectionMode,omitempty"`
+
+	// EgressPolicy, unless Unrestricted, creates a NetworkPolicy restricting egress for the session's
+	// duration and deletes it at termination. Ephemeral containers share the pod's network, so the
+	// restriction applies to the whole target pod, including the workload itself. NetworkPolicies are
+	// additive: if another policy already allows the pod egress, the restriction cannot take effect, and
+	// the EgressRestricted condition is set to False with reason NotEnforceable.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Unrestricted
+	EgressPolicy EgressPolicy `json:"egressPolicy,omitempty"`
+
+	// ReusePolicy decides whether the session may observe a compatible Active session's debugger
+	// instead of injecting another ephemeral container, which could never be removed.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Never
+	ReusePolicy ReusePolicy `json:"reusePolicy,omitempty"`
+
+	// Priority orders sessions queued for a pod at its debugger limit: higher values are admitted first,
+	// and sessions of equal priority in creation order.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=0
+	Priority int32 `json:"priority,omitempty"`
+
+	// CleanupPolicy decides whether the debugger is stopped at session end, removed along with the
+	// target pod, or left running.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=StopDebugger
+	CleanupPolicy CleanupPolicy `json:"cleanupPolicy,omitempty"`
+
+	// RequestedBy is the user who created the session. The admission webhook fills it in from the
+	// create request, overwriting anything the user set, and it cannot be changed afterwards.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="requestedBy is immutable"
+	RequestedBy *Requester `json:"requestedBy,omitempty"`
+
+	// FollowWorkload, when the target pod belongs to a Deployment and is replaced during the session,
+	// e.g. by a rolling restart, injects the debugger into a running replacement instead of failing the
+	// session. Attached clients are disconnected and reconnect, with the same token, to the pod named
+	// in status.targetPodName; the shell starts over there.
+	// +kubebuilder:validation:Optional
+	FollowWorkload bool `json:"followWorkload,omitempty"`
+
+	// TicketRef is the change or incident ticket the session is done for: a Jira, GitHub or ServiceNow
+	// URL, or an ID such as OPS-123, org/repo#42 or INC0012345. Namespaces labelled
+	// debug.ajou.oxan0n.me/require-ticket=true require it. When the controller is configured with a
+	// ticket system, the ticket must exist and be open. It is carried into the audit records and the
+	// tags of archived transcripts and recordings.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=512
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="ticketRef is immutable"
+	TicketRef string `json:"ticketRef,omitempty"`
+}
+
+// FailureClass groups session failures by cause, so that automation can branch on it.
+// +kubebuilder:validation:Enum=AccessDenied;TargetInvalid;TargetProtected;TargetPodLost;DebuggerLimitReached;PolicyViolation;InjectionFailed;RetriesExhausted;ImagePullFailed;ContainerFailed;ArchiveFailed;TicketRejected;Unknown
+type FailureClass string
+
+const (
+	// FailureAccessDenied means the requester may not debug the target pod, or an approver denied the session.
+	FailureAccessDenied FailureClass = "AccessDenied"
+	// FailureTargetInvalid means the target namespace, pod or container does not exist or cannot run a debugger.
+	FailureTargetInvalid FailureClass = "TargetInvalid"
+	// FailureTargetProtected means the target opted out of debugging.
+	FailureTargetProtected FailureClass = "TargetProtected"
+	// FailureTargetPodLost means the target pod went away during the session.
+	FailureTargetPodLost FailureClass = "TargetPodLost"
+	// FailureDebuggerLimitReached means the target pod already ran its maximum number of debuggers.
+	FailureDebuggerLimitReached FailureClass = "DebuggerLimitReached"
+	// FailurePolicyViolation means the debugger would have broken the target's security policy.
+	FailurePolicyViolation FailureClass = "PolicyViolation"
+	// FailureInjectionFailed means the debugger container could not be added to the target pod.
+	FailureInjectionFailed FailureClass = "InjectionFailed"
+	// FailureRetriesExhausted means the debugger container did not recover within spec.maxRetryCount retries.
+	FailureRetriesExhausted FailureClass = "RetriesExhausted"
+	// FailureImagePullFailed means the debugger image could not be pulled.
+	FailureImagePullFailed FailureClass = "ImagePullFailed"
+	// FailureContainerFailed means the debugger container exited with an error.
+	FailureContainerFailed FailureClass = "ContainerFailed"
+	// FailureArchiveFailed means the transcript could not be archived under ArchivePolicy Required.
+	FailureArchiveFailed FailureClass = "ArchiveFailed"
+	// FailureTicketRejected means spec.ticketRef was not found open in the ticket system.
+	FailureTicketRejected FailureClass = "TicketRejected"
+	// FailureUnknown is any other failure.
+	FailureUnknown FailureClass = "Unknown"
+)
+
+// RetryAttempt is one retry of a session whose debugger container ran into a recoverable error.
+type RetryAttempt struct {
+	// Time is when the retry was scheduled.
+	Time metav1.Time `json:"time"`
+
+	// Reason is what the debugger container reported.
+	// +kubebuilder:validation:Optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// FailureDiagnostics captures what the controller observed about a session when it failed.
+type FailureDiagnostics struct {
+	// CollectedAt is when the diagnostics were gathered.
+	CollectedAt metav1.Time `json:"collectedAt"`
+
+	// Class is the cause of the failure.
+	// +kubebuilder:validation:Optional
+	Class FailureClass `json:"class,omitempty"`
+
+	// Reason is the message the session failed with.
+	// +kubebuilder:validation:Optional
+	Reason string `json:"reason,omitempty"`
+
+	// LastContainerState is the last known state of the debugger container.
+	// +kubebuilder:validation:Optional
+	LastContainerState *corev1.ContainerState `json:"lastContainerState,omitempty"`
+
+	// ContainerReason is the reason of the debugger container's last waiting or terminated state.
+	// +kubebuilder:validation:Optional
+	ContainerReason string `json:"containerReason,omitempty"`
+
+	// ExitCode is the debugger container's exit code, if it terminated.
+	// +kubebuilder:validation:Optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// RecentPodEvents are the most recent Events recorded on the target pod.
+	// +kubebuilder:validation:Optional
+	RecentPodEvents []string `json:"recentPodEvents,omitempty"`
+
+	// RetryCount is the number of setup retries made before the session failed.
+	// +kubebuilder:validation:Optional
+	RetryCount int `json:"retryCount,omitempty"`
+
+	// RetryHistory lists the most recent of those retries, oldest first.
+	// +kubebuilder:validation:Optional
+	RetryHistory []RetryAttempt `json:"retryHistory,omitempty"`
+}
+
+// Attachment is the debug proxy's audit entry for one client connection.
+type Attachment struct {
+	// ID identifies the connection, so that its detach can be matched up with it.
+	ID string `json:"id"`
+
+	// User is who attached: the holder of the session's one-time token, i.e. the session's requester.
+	// +kubebuilder:validation:Optional
+	User string `json:"user,omitempty"`
+
+	// SourceIP is the client's address as seen by the proxy.
+	// +kubebuilder:validation:Optional
+	SourceIP string `json:"sourceIP,omitempty"`
+
+	// OverlayUser is the client's Tailscale login, when it connected over a tailnet through the
+	// proxy's Tailscale sidecar.
+	// +kubebuilder:validation:Optional
+	OverlayUser string `json:"overlayUser,omitempty"`
+
+	// UserAgent is the client's User-Agent.
+	// +kubebuilder:validation:Optional
+	UserAgent string `json:"userAgent,omitempty"`
+
+	// Protocol is how the client attached: WebSocket or gRPC.
+	// +kubebuilder:validation:Optional
+	Protocol string `json:"protocol,omitempty"`
+
+	// Proxy is the debug proxy replica serving the connection.
+	// +kubebuilder:validation:Optional
+	Proxy string `json:"proxy,omitempty"`
+
+	// AttachTime is when the proxy accepted the connection.
+	AttachTime metav1.Time `json:"attachTime"`
+
+	// HeartbeatTime is when the proxy last confirmed the connection open. The proxy refreshes it every
+	// 30 seconds; the controller closes an open entry whose heartbeat has gone stale, e.g. because its
+	// proxy was restarted or rolled out mid-connection.
+	// +kubebuilder:validation:Optional
+	HeartbeatTime *metav1.Time `json:"heartbeatTime,omitempty"`
+
+	// DetachTime is when the connection closed; unset while the client is still attached.
+	// +kubebuilder:validation:Optional
+	DetachTime *metav1.Time `json:"detachTime,omitempty"`
+
+	// BytesIn and BytesOut count the terminal input the client sent and the output it received over
+	// the connection. They are set on detach.
+	// +kubebuilder:validation:Optional
+	BytesIn int64 `json:"bytesIn,omitempty"`
+	// +kubebuilder:validation:Optional
+	BytesOut int64 `json:"bytesOut,omitempty"`
 }
 
 // DebugSessionStatus defines the observed state of a DebugSession, as reported by the controller.
@@ -93,18 +716,131 @@ type DebugSessionStatus struct {
 	// +kubebuilder:validation:Optional
 	Message string `json:"message,omitempty"`
 
+	// ObservedGeneration is the most recent spec generation the controller has acted upon.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	// StartTime is the timestamp when the controller successfully initiated the debug session.
 	// +kubebuilder:validation:Optional
 	StartTime *metav1.Time `json:"startTime,omitempty"`
 
+	// ExpiryTime is the timestamp after which the controller terminates the session (StartTime + TTL).
+	// +kubebuilder:validation:Optional
+	ExpiryTime *metav1.Time `json:"expiryTime,omitempty"`
+
+	// ReadyTime is when the debugger first became ready for attach.
+	// +kubebuilder:validation:Optional
+	ReadyTime *metav1.Time `json:"readyTime,omitempty"`
+
+	// FirstAttachTime is the timestamp of the first client connection through the debug proxy.
+	// +kubebuilder:validation:Optional
+	FirstAttachTime *metav1.Time `json:"firstAttachTime,omitempty"`
+
+	// LastAttachTime is the timestamp of the most recent client connection through the debug proxy.
+	// +kubebuilder:validation:Optional
+	LastAttachTime *metav1.Time `json:"lastAttachTime,omitempty"`
+
+	// AttachedClients lists the distinct client addresses that attached through the debug proxy.
+	// +kubebuilder:validation:Optional
+	AttachedClients []string `json:"attachedClients,omitempty"`
+
+	// ConnectedClients is the number of clients currently attached through the debug proxy: the open
+	// entries in Attachments.
+	// +kubebuilder:validation:Optional
+	ConnectedClients int32 `json:"connectedClients,omitempty"`
+
+	// AttachCount is the total number of attach connections the debug proxy has accepted.
+	// +kubebuilder:validation:Optional
+	AttachCount int32 `json:"attachCount,omitempty"`
+
+	// LastActivityTime is the last time terminal input or output passed through the debug proxy. The
+	// proxy updates it at most every 30 seconds.
+	// +kubebuilder:validation:Optional
+	LastActivityTime *metav1.Time `json:"lastActivityTime,omitempty"`
+
+	// LastDetachTime is when the last attached client detached, leaving none.
+	// +kubebuilder:validation:Optional
+	LastDetachTime *metav1.Time `json:"lastDetachTime,omitempty"`
+
+	// BytesIn and BytesOut total the terminal input sent by clients and the output sent to them over
+	// all closed connections, for spotting exfiltration-sized transfers.
+	// +kubebuilder:validation:Optional
+	BytesIn int64 `json:"bytesIn,omitempty"`
+	// +kubebuilder:validation:Optional
+	BytesOut int64 `json:"bytesOut,omitempty"`
+
+	// Attachments records each connection through the debug proxy, oldest first. Only the most
+	// recent connections are kept, and open connections are never dropped.
+	// +kubebuilder:validation:Optional
+	Attachments []Attachment `json:"attachments,omitempty"`
+
 	// TerminationTime is the timestamp when the session was completed or failed.
 	// +kubebuilder:validation:Optional
 	TerminationTime *metav1.Time `json:"terminationTime,omitempty"`
 
+	// ReusedFrom is the namespace/name of the session whose debugger this session observes, when it
+	// was admitted under ReusePolicy IfCompatible. Observers attach read-only.
+	// +kubebuilder:validation:Optional
+	ReusedFrom string `json:"reusedFrom,omitempty"`
+
+	// TargetPodName is the pod the session debugs after spec.followWorkload moved it off
+	// spec.targetPodName.
+	// +kubebuilder:validation:Optional
+	TargetPodName string `json:"targetPodName,omitempty"`
+
+	// TargetDeployment is the Deployment the target pod belongs to, recorded for spec.followWorkload so
+	// that a replacement can be found even once the pod is gone.
+	// +kubebuilder:validation:Optional
+	TargetDeployment string `json:"targetDeployment,omitempty"`
+
+	// PodReplacements lists the most recent moves to a replacement pod, oldest first.
+	// +kubebuilder:validation:Optional
+	PodReplacements []PodReplacement `json:"podReplacements,omitempty"`
+
+	// CostAttribution is who the session's usage is charged to, resolved from the TeamLabel and
+	// CostCenterLabel labels of the session or its target namespace when the session is admitted.
+	// +kubebuilder:validation:Optional
+	CostAttribution *CostAttribution `json:"costAttribution,omitempty"`
+
+	// QueuePosition is the session's place in line while it waits in Pending for a debugger slot on
+	// its target pod, 1 being next. It is cleared once the session is admitted.
+	// +kubebuilder:validation:Optional
+	QueuePosition int32 `json:"queuePosition,omitempty"`
+
 	// DebuggingContainerName is the actual, unique name of the ephemeral container created by the controller.
 	// +kubebuilder:validation:Optional
 	DebuggingContainerName string `json:"debuggingContainerName,omitempty"`
 
+	// DebuggerImage is the image the debugger container runs.
+	// +kubebuilder:validation:Optional
+	DebuggerImage string `json:"debuggerImage,omitempty"`
+
+	// ToolboxContainerName is the name of the toolbox ephemeral container, if spec.toolboxImage is set.
+	// +kubebuilder:validation:Optional
+	ToolboxContainerName string `json:"toolboxContainerName,omitempty"`
+
+	// GrantedCapabilities are the Linux capabilities the debugger container was given beyond its
+	// defaults, after any Pod Security Standards adjustment.
+	// +kubebuilder:validation:Optional
+	GrantedCapabilities []corev1.Capability `json:"grantedCapabilities,omitempty"`
+
+	// TokenExpiryTime is when the ServiceAccount token issued for spec.serviceAccountToken expires.
+	// +kubebuilder:validation:Optional
+	TokenExpiryTime *metav1.Time `json:"tokenExpiryTime,omitempty"`
+
+	// VaultLeases are the leases of the spec.vaultCredentials issued to the debugger.
+	// +kubebuilder:validation:Optional
+	VaultLeases []VaultLease `json:"vaultLeases,omitempty"`
+
+	// Endpoints are the attach URLs of the session on the debug proxy, without the token.
+	// +kubebuilder:validation:Optional
+	Endpoints *SessionEndpoints `json:"endpoints,omitempty"`
+
+	// SSMTunnel is the AWS Systems Manager port forwarding that reaches the debug proxy, when the
+	// BastionConfig selects SSM. kubectl debugsess tunnel starts it.
+	// +kubebuilder:validation:Optional
+	SSMTunnel *SSMTunnel `json:"ssmTunnel,omitempty"`
+
 	// ReadyForAttach indicates if the debug container is running and ready for connection.
 	// +kubebuilder:validation:Optional
 	ReadyForAttach bool `json:"readyForAttach,omitempty"`
@@ -114,10 +850,58 @@ type DebugSessionStatus struct {
 	// +kubebuilder:validation:Optional
 	OneTimeToken string `json:"oneTimeToken,omitempty"`
 
+	// FailedAuthAttempts counts the attach and port-forward attempts the debug proxy rejected for an
+	// invalid or expired token since the session started or was last unlocked.
+	// +kubebuilder:validation:Optional
+	FailedAuthAttempts int32 `json:"failedAuthAttempts,omitempty"`
+
+	// LastFailedAuthTime is when the debug proxy last rejected an invalid token.
+	// +kubebuilder:validation:Optional
+	LastFailedAuthTime *metav1.Time `json:"lastFailedAuthTime,omitempty"`
+
+	// LogKey is the storage key of the archived debugger transcript.
+	// +kubebuilder:validation:Optional
+	LogKey string `json:"logKey,omitempty"`
+
+	// LogURL is a time-limited download link for the archived transcript, if the storage backend
+	// can issue one.
+	// +kubebuilder:validation:Optional
+	LogURL string `json:"logURL,omitempty"`
+
+	// LogURLExpiryTime is the timestamp after which LogURL stops working.
+	// +kubebuilder:validation:Optional
+	LogURLExpiryTime *metav1.Time `json:"logURLExpiryTime,omitempty"`
+
+	// Recordings lists the storage keys of the asciicast recordings the debug proxy captured live,
+	// one per attach connection.
+	// +kubebuilder:validation:Optional
+	Recordings []string `json:"recordings,omitempty"`
+
+	// Artifacts lists the storage keys of the files the spec.capture action produced.
+	// +kubebuilder:validation:Optional
+	Artifacts []string `json:"artifacts,omitempty"`
+
+	// ArchiveAttempts counts failed transcript uploads of a BestEffort session.
+	// +kubebuilder:validation:Optional
+	ArchiveAttempts int32 `json:"archiveAttempts,omitempty"`
+
+	// Restarts counts the times the session was started over after failing, through the
+	// debug.ajou.oxan0n.me/retry annotation.
+	// +kubebuilder:validation:Optional
+	Restarts int32 `json:"restarts,omitempty"`
+
 	// RetryCount tracks the number of retries for recoverable errors.
 	// +kubebuilder:validation:Optional
 	RetryCount int `json:"retryCount,omitempty"`
 
+	// RetryHistory lists the most recent retries, oldest first.
+	// +kubebuilder:validation:Optional
+	RetryHistory []RetryAttempt `json:"retryHistory,omitempty"`
+
+	// Diagnostics is filled in once the session has failed.
+	// +kubebuilder:validation:Optional
+	Diagnostics *FailureDiagnostics `json:"diagnostics,omitempty"`
+
 	// Conditions provides detailed observations of the resource's current state.
 	// +listType=map
 	// +listMapKey=type
@@ -125,11 +909,131 @@ type DebugSessionStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// VaultCredential is a dynamic secret read from Vault for a session.
+type VaultCredential struct {
+	// Path is the Vault path read, e.g. "database/creds/readonly" or "aws/creds/deploy".
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// EnvPrefix names the variables the secret is exported as: each key of its data becomes
+	// <EnvPrefix>_<KEY>, e.g. DB_USERNAME and DB_PASSWORD.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[A-Z][A-Z0-9_]*$`
+	EnvPrefix string `json:"envPrefix"`
+}
+
+// ServiceAccountToken requests a bound ServiceAccount token for the debugger.
+type ServiceAccountToken struct {
+	// Audiences the token is valid for. The API server's own audience is used when empty.
+	// +kubebuilder:validation:Optional
+	Audiences []string `json:"audiences,omitempty"`
+
+	// ExpirationSeconds is the token's lifetime. It is capped at the session's TTL.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=600
+	// +kubebuilder:validation:Maximum=86400
+	// +kubebuilder:default=3600
+	ExpirationSeconds int64 `json:"expirationSeconds,omitempty"`
+}
+
+// EnvSourceRef names a Secret or ConfigMap whose keys are exported into the debugger.
+type EnvSourceRef struct {
+	// Name of the Secret or ConfigMap in the target namespace.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Prefix is prepended to each key to form the variable name.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// VaultLease is a Vault lease issued for a session.
+type VaultLease struct {
+	// Path is the Vault path the lease was issued for.
+	Path string `json:"path"`
+
+	// LeaseID identifies the lease in Vault's audit log.
+	LeaseID string `json:"leaseID"`
+
+	// ExpiryTime is when Vault expires the lease on its own.
+	// +kubebuilder:validation:Optional
+	ExpiryTime *metav1.Time `json:"expiryTime,omitempty"`
+
+	// RevokeTime is when the controller revoked the lease.
+	// +kubebuilder:validation:Optional
+	RevokeTime *metav1.Time `json:"revokeTime,omitempty"`
+}
+
+// AuthFailurePolicy decides what happens when a session sees repeated invalid-token attempts.
+type AuthFailurePolicy struct {
+	// Threshold is the number of failed attempts that raises a Warning Event and an
+	// AuthFailureThresholdExceeded notification.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=5
+	// +kubebuilder:validation:Minimum=1
+	Threshold int32 `json:"threshold,omitempty"`
+
+	// Lock makes the debug proxy refuse every connection to the session, including ones with a valid
+	// token, once the threshold is reached. A locked session is unlocked with the
+	// debug.ajou.oxan0n.me/unlock annotation, which also issues a new token.
+	// +kubebuilder:validation:Optional
+	Lock bool `json:"lock,omitempty"`
+}
+
+// SessionEndpoints are the attach URLs of a session. Clients send the session token in the
+// Authorization header to either.
+type SessionEndpoints struct {
+	// Internal is the attach URL on the proxy's ClusterIP Service, for clients in the cluster.
+	// +kubebuilder:validation:Optional
+	Internal string `json:"internal,omitempty"`
+
+	// External is the attach URL on the proxy's overlay address or NodePort, as reached from outside
+	// the cluster. It is empty for sessions with connectionMode InCluster and behind Teleport, which
+	// hands out its own address.
+	// +kubebuilder:validation:Optional
+	External string `json:"external,omitempty"`
+}
+
+// SSMTunnel is an AWS-StartPortForwardingSessionToRemoteHost session to the debug proxy.
+type SSMTunnel struct {
+	// Target is the EC2 instance ID of the proxy's node.
+	Target string `json:"target"`
+
+	// Region of the instance.
+	Region string `json:"region"`
+
+	// Host and Port are the proxy's address as seen from the instance.
+	Host string `json:"host"`
+	Port int32  `json:"port"`
+
+	// LocalPort is the port forwarded on the client's machine.
+	LocalPort int32 `json:"localPort"`
+}
+
+// StartSessionCommand returns the aws CLI command that opens the tunnel.
+func (t *SSMTunnel) StartSessionCommand() []string {
+	return []string{"aws", "ssm", "start-session",
+		"--region", t.Region,
+		"--target", t.Target,
+		"--document-name", "AWS-StartPortForwardingSessionToRemoteHost",
+		"--parameters", fmt.Sprintf("host=%s,portNumber=%d,localPortNumber=%d", t.Host, t.Port, t.LocalPort),
+	}
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="TargetPod",type=string,JSONPath=`.spec.targetPodName`
 // +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.readyForAttach`
+// +kubebuilder:printcolumn:name="Queue",type=integer,JSONPath=`.status.queuePosition`,priority=1
+// +kubebuilder:printcolumn:name="Clients",type=integer,JSONPath=`.status.connectedClients`
+// +kubebuilder:printcolumn:name="LastActivity",type="date",JSONPath=".status.lastActivityTime"
+// +kubebuilder:printcolumn:name="Attaches",type=integer,JSONPath=`.status.attachCount`,priority=1
+// +kubebuilder:printcolumn:name="BytesOut",type=integer,JSONPath=`.status.bytesOut`,priority=1
+// +kubebuilder:printcolumn:name="Expires",type="date",JSONPath=".status.expiryTime",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // DebugSession is the Schema for the debugsessions API
 type DebugSession struct {
@@ -0,0 +1,140 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RequestedByAnnotation names the person or system that asked for a DebugSession. It is copied into
+// the session's DebugSessionRecord when spec.requestedBy is not set.
+const RequestedByAnnotation = "ajou.oxan0n.me/requested-by"
+
+// SessionNamespaceLabel is set on every DebugSessionRecord to the namespace of its session, so records
+// can be selected by namespace.
+const SessionNamespaceLabel = "ajou.oxan0n.me/session-namespace"
+
+// SessionReference identifies the DebugSession a record was written for.
+type SessionReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+}
+
+// DebugSessionRecordSpec is the audit trail of a finished DebugSession.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="DebugSessionRecord is immutable"
+type DebugSessionRecordSpec struct {
+	// Session is the DebugSession this record describes. The session itself may since have been deleted.
+	Session SessionReference `json:"session"`
+
+	// RequestedBy is who asked for the session: the username in its spec.requestedBy, or else the
+	// requested-by annotation.
+	// +kubebuilder:validation:Optional
+	RequestedBy string `json:"requestedBy,omitempty"`
+
+	// ApprovedBy and DeniedBy are who approved or denied a session that needed approval.
+	// +kubebuilder:validation:Optional
+	ApprovedBy string `json:"approvedBy,omitempty"`
+	// +kubebuilder:validation:Optional
+	DeniedBy string `json:"deniedBy,omitempty"`
+
+	// CostAttribution is who the session's usage is charged to. The record also carries it as
+	// TeamLabel and CostCenterLabel labels.
+	// +kubebuilder:validation:Optional
+	CostAttribution *CostAttribution `json:"costAttribution,omitempty"`
+
+	// TicketRef is the ticket the session was done for, from its spec.ticketRef.
+	// +kubebuilder:validation:Optional
+	TicketRef string `json:"ticketRef,omitempty"`
+
+	// AttachedClients lists the clients that attached through the debug proxy.
+	// +kubebuilder:validation:Optional
+	AttachedClients []string `json:"attachedClients,omitempty"`
+
+	// Attachments are the session's attach audit entries.
+	// +kubebuilder:validation:Optional
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// TargetNamespace, TargetPodName and TargetContainerName identify the debugged workload.
+	TargetNamespace string `json:"targetNamespace"`
+	TargetPodName   string `json:"targetPodName"`
+	// +kubebuilder:validation:Optional
+	TargetContainerName string `json:"targetContainerName,omitempty"`
+
+	// DebuggerImage is the image the debugger container ran.
+	DebuggerImage string `json:"debuggerImage"`
+
+	// GrantedCapabilities are the Linux capabilities the debugger container was given.
+	// +kubebuilder:validation:Optional
+	GrantedCapabilities []corev1.Capability `json:"grantedCapabilities,omitempty"`
+
+	// Outcome is the phase the session finished in.
+	Outcome SessionPhase `json:"outcome"`
+
+	// Message is the session's final status message.
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+
+	// StartTime and TerminationTime bound the live part of the session.
+	// +kubebuilder:validation:Optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +kubebuilder:validation:Optional
+	TerminationTime *metav1.Time `json:"terminationTime,omitempty"`
+
+	// DurationSeconds is TerminationTime - StartTime.
+	// +kubebuilder:validation:Optional
+	DurationSeconds int64 `json:"durationSeconds,omitempty"`
+
+	// TranscriptKey is the storage key of the archived transcript.
+	// +kubebuilder:validation:Optional
+	TranscriptKey string `json:"transcriptKey,omitempty"`
+
+	// Recordings are the storage keys of the live attach recordings.
+	// +kubebuilder:validation:Optional
+	Recordings []string `json:"recordings,omitempty"`
+
+	// Artifacts are the storage keys of the files the session's capture action produced.
+	// +kubebuilder:validation:Optional
+	Artifacts []string `json:"artifacts,omitempty"`
+
+	// VaultLeases are the Vault leases of the credentials issued to the debugger.
+	// +kubebuilder:validation:Optional
+	VaultLeases []VaultLease `json:"vaultLeases,omitempty"`
+
+	// Conditions are the session's conditions when it finished, including policy decisions such as
+	// target validation and injection.
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Namespace",type="string",JSONPath=".spec.session.namespace"
+// +kubebuilder:printcolumn:name="Session",type="string",JSONPath=".spec.session.name"
+// +kubebuilder:printcolumn:name="Outcome",type="string",JSONPath=".spec.outcome"
+// +kubebuilder:printcolumn:name="Requested By",type="string",JSONPath=".spec.requestedBy"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// DebugSessionRecord is an immutable audit record written when a DebugSession finishes. It outlives
+// the DebugSession it describes.
+type DebugSessionRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DebugSessionRecordSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// DebugSessionRecordList contains a list of DebugSessionRecord
+type DebugSessionRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DebugSessionRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DebugSessionRecord{}, &DebugSessionRecordList{})
+}
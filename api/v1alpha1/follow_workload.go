@@ -0,0 +1,35 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodReplacement records a move of a session with spec.followWorkload to a replacement of its target pod.
+type PodReplacement struct {
+	// From is the pod that went away.
+	From string `json:"from"`
+
+	// To is the replacement the debugger was injected into; empty while one is awaited.
+	// +kubebuilder:validation:Optional
+	To string `json:"to,omitempty"`
+
+	// Time is when the loss of From was noticed.
+	Time metav1.Time `json:"time"`
+
+	// TranscriptKey is where the transcript of the debugger in the lost pod was salvaged to, if it could be.
+	// +kubebuilder:validation:Optional
+	TranscriptKey string `json:"transcriptKey,omitempty"`
+}
+
+// CurrentTargetPod is the name of the pod the session debugs: spec.targetPodName, or the replacement
+// recorded in status.targetPodName once spec.followWorkload moved the session.
+func CurrentTargetPod(session *DebugSession) string {
+	if session.Status.TargetPodName != "" {
+		return session.Status.TargetPodName
+	}
+	return session.Spec.TargetPodName
+}
@@ -0,0 +1,18 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import "k8s.io/apimachinery/pkg/api/meta"
+
+// UnlockKey, as an annotation on a DebugSession locked after too many failed authentication attempts,
+// makes the controller unlock it: the failure count is reset and a new token is issued. The controller
+// removes the annotation; on a session that is not locked it does nothing else.
+const UnlockKey = "debug.ajou.oxan0n.me/unlock"
+
+// IsLocked reports whether the session was locked under spec.authFailurePolicy. The debug proxy turns
+// away every connection to a locked session, with or without a valid token.
+func IsLocked(session *DebugSession) bool {
+	return meta.IsStatusConditionTrue(session.Status.Conditions, ConditionLocked)
+}
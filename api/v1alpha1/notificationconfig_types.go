@@ -0,0 +1,93 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretKeyReference selects a key of a Secret in a given namespace.
+type SecretKeyReference struct {
+	// Name of the Secret.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace of the Secret.
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+
+	// Key within the Secret's data.
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+}
+
+// NotificationDestination is a single webhook that session notifications are routed to.
+type NotificationDestination struct {
+	// Name identifies the destination in logs and conditions.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// URL of the webhook. Chat webhook URLs embed credentials, so prefer URLSecretRef.
+	// +kubebuilder:validation:Optional
+	URL string `json:"url,omitempty"`
+
+	// URLSecretRef reads the webhook URL from a Secret.
+	// +kubebuilder:validation:Optional
+	URLSecretRef *SecretKeyReference `json:"urlSecretRef,omitempty"`
+
+	// Format forces the payload format; it is detected from the URL when empty.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=generic;slack;discord;teams;googlechat
+	Format string `json:"format,omitempty"`
+
+	// SigningSecretRef is the HMAC key used to sign requests to this destination.
+	// +kubebuilder:validation:Optional
+	SigningSecretRef *SecretKeyReference `json:"signingSecretRef,omitempty"`
+
+	// Events limits the destination to the listed events. All events are delivered when empty.
+	// +kubebuilder:validation:Optional
+	Events []string `json:"events,omitempty"`
+
+	// Namespaces routes only sessions targeting these namespaces to the destination.
+	// +kubebuilder:validation:Optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector routes only sessions whose target namespace matches the selector.
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// NotificationConfigSpec defines where DebugSession notifications are delivered.
+type NotificationConfigSpec struct {
+	// Destinations lists the webhooks notifications are routed to.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Destinations []NotificationDestination `json:"destinations"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// NotificationConfig routes DebugSession notifications to webhooks. Changes take effect without
+// restarting the controller.
+type NotificationConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NotificationConfigSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// NotificationConfigList contains a list of NotificationConfig
+type NotificationConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotificationConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NotificationConfig{}, &NotificationConfigList{})
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DenyDebugKey opts sensitive workloads out of debugging. Set to "true" as an annotation on a pod, or
+// as a label on a namespace, it makes DebugSessions targeting them be rejected.
+const DenyDebugKey = "debug.ajou.oxan0n.me/deny"
+
+// ProtectedReason returns why debugging the target is denied, or "" if it is not. Either argument may
+// be nil.
+func ProtectedReason(namespace *corev1.Namespace, pod *corev1.Pod) string {
+	if namespace != nil && namespace.Labels[DenyDebugKey] == "true" {
+		return fmt.Sprintf("namespace '%s' is protected from debugging by the %s label", namespace.Name, DenyDebugKey)
+	}
+	if pod != nil && pod.Annotations[DenyDebugKey] == "true" {
+		return fmt.Sprintf("pod '%s' is protected from debugging by the %s annotation", pod.Name, DenyDebugKey)
+	}
+	return ""
+}
+
+// MaxDebuggersPerPodKey, as an annotation on a namespace, overrides the controller's
+// --max-debuggers-per-pod for the pods in it. "0" lifts the limit.
+const MaxDebuggersPerPodKey = "debug.ajou.oxan0n.me/max-debuggers-per-pod"
+
+// AllowEBPFKey, set to "true" as a label on a namespace, allows Bpftrace captures in it. eBPF tracing
+// sees the whole node, so it is off unless a namespace opts in.
+const AllowEBPFKey = "debug.ajou.oxan0n.me/allow-ebpf"
+
+// ApprovedByKey, as an annotation on a DebugSession, names the user who approved a session that needs
+// approval, such as a Bpftrace capture or a privileged debugger. The admission webhook only accepts the approver's own username,
+// and never the requester's.
+const ApprovedByKey = "debug.ajou.oxan0n.me/approved-by"
+
+// DeniedByKey, as an annotation on a DebugSession that needs approval, names the user who denied it;
+// the session then fails instead of waiting for approval. The admission webhook only accepts the
+// denier's own username.
+const DeniedByKey = "debug.ajou.oxan0n.me/denied-by"
+
+// RequiresApproval reports whether the session waits in Pending for an ApprovedByKey annotation: for a
+// Bpftrace capture, or for a debugger spec.debugSecurity makes more privileged than any toolset's.
+func RequiresApproval(session *DebugSession) bool {
+	return isBpftrace(session) || elevatedSecurity(session.Spec.DebugSecurity)
+}
+
+// EBPFDeniedReason returns why an eBPF session may not target the namespace, or "" if it may. Sessions
+// whose spec.debugSecurity grants what eBPF needs count as eBPF sessions. The namespace may be nil.
+func EBPFDeniedReason(session *DebugSession, namespace *corev1.Namespace) string {
+	if !isBpftrace(session) && !grantsEBPF(session.Spec.DebugSecurity) {
+		return ""
+	}
+	if namespace == nil || namespace.Labels[AllowEBPFKey] != "true" {
+		name := session.Spec.TargetNamespace
+		if namespace != nil {
+			name = namespace.Name
+		}
+		return fmt.Sprintf("namespace '%s' does not allow eBPF tracing; label it %s=true", name, AllowEBPFKey)
+	}
+	return ""
+}
+
+// toolsetCapabilities are the capabilities the curated toolsets grant. spec.debugSecurity may add these
+// without approval; any other, or a privileged debugger, needs it.
+var toolsetCapabilities = []corev1.Capability{"NET_ADMIN", "NET_RAW", "SYS_PTRACE"}
+
+// ebpfCapabilities are the capabilities that let a debugger load eBPF programs.
+var ebpfCapabilities = []corev1.Capability{"ALL", "BPF", "PERFMON", "SYS_ADMIN"}
+
+func isBpftrace(session *DebugSession) bool {
+	return session.Spec.Capture != nil && session.Spec.Capture.Type == CaptureBpftrace
+}
+
+// elevatedSecurity reports whether sec runs the debugger privileged or adds a capability beyond
+// toolsetCapabilities.
+func elevatedSecurity(sec *DebugSecurityContext) bool {
+	if sec == nil {
+		return false
+	}
+	if sec.Privileged != nil && *sec.Privileged {
+		return true
+	}
+	return sec.Capabilities != nil && slices.ContainsFunc(sec.Capabilities.Add, func(c corev1.Capability) bool {
+		return !slices.Contains(toolsetCapabilities, normalizeCapability(c))
+	})
+}
+
+// grantsEBPF reports whether sec runs the debugger privileged or adds one of ebpfCapabilities.
+func grantsEBPF(sec *DebugSecurityContext) bool {
+	if sec == nil {
+		return false
+	}
+	if sec.Privileged != nil && *sec.Privileged {
+		return true
+	}
+	return sec.Capabilities != nil && slices.ContainsFunc(sec.Capabilities.Add, func(c corev1.Capability) bool {
+		return slices.Contains(ebpfCapabilities, normalizeCapability(c))
+	})
+}
+
+// normalizeCapability strips the optional CAP_ prefix, which the container runtime accepts too.
+func normalizeCapability(c corev1.Capability) corev1.Capability {
+	return corev1.Capability(strings.TrimPrefix(strings.ToUpper(string(c)), "CAP_"))
+}
+
+// RequireTicketKey, set to "true" as a label on a namespace, requires sessions targeting it to name
+// the ticket they are done for in spec.ticketRef.
+const RequireTicketKey = "debug.ajou.oxan0n.me/require-ticket"
+
+// TicketDeniedReason returns why the session may not target the namespace without a ticket, or "" if
+// it may. The namespace may be nil.
+func TicketDeniedReason(session *DebugSession, namespace *corev1.Namespace) string {
+	if session.Spec.TicketRef != "" || namespace == nil || namespace.Labels[RequireTicketKey] != "true" {
+		return ""
+	}
+	return fmt.Sprintf("namespace '%s' requires a ticket: set spec.ticketRef", namespace.Name)
+}
+
+// AllowedSecretsKey and AllowedConfigMapsKey, as annotations on a namespace, list the Secrets and
+// ConfigMaps of it that sessions may export into the debugger through spec.secretRefs and
+// spec.configMapRefs, as comma-separated names or globs such as "db-*". None are allowed otherwise.
+const (
+	AllowedSecretsKey    = "debug.ajou.oxan0n.me/allowed-secrets"
+	AllowedConfigMapsKey = "debug.ajou.oxan0n.me/allowed-configmaps"
+)
+
+// ConfigRefDeniedReason returns why a Secret or ConfigMap the session refers to may not be exported
+// from the namespace, or "" if all of them may. The namespace may be nil.
+func ConfigRefDeniedReason(session *DebugSession, namespace *corev1.Namespace) string {
+	var annotations map[string]string
+	name := session.Spec.TargetNamespace
+	if namespace != nil {
+		annotations, name = namespace.Annotations, namespace.Name
+	}
+	for _, ref := range session.Spec.SecretRefs {
+		if !allowedByList(annotations[AllowedSecretsKey], ref.Name) {
+			return fmt.Sprintf("namespace '%s' does not allow exporting Secret '%s'; list it in the %s annotation",
+				name, ref.Name, AllowedSecretsKey)
+		}
+	}
+	for _, ref := range session.Spec.ConfigMapRefs {
+		if !allowedByList(annotations[AllowedConfigMapsKey], ref.Name) {
+			return fmt.Sprintf("namespace '%s' does not allow exporting ConfigMap '%s'; list it in the %s annotation",
+				name, ref.Name, AllowedConfigMapsKey)
+		}
+	}
+	return ""
+}
+
+// allowedByList reports whether name matches one of the comma-separated globs of list.
+func allowedByList(list, name string) bool {
+	for _, pattern := range strings.Split(list, ",") {
+		if matched, _ := path.Match(strings.TrimSpace(pattern), name); matched {
+			return true
+		}
+	}
+	return false
+}
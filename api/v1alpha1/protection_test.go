@@ -0,0 +1,98 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestRequiresApproval(t *testing.T) {
+	tests := []struct {
+		name string
+		spec DebugSessionSpec
+		want bool
+	}{
+		{name: "plain shell", spec: DebugSessionSpec{DebuggerImage: "busybox"}},
+		{name: "tcpdump capture", spec: DebugSessionSpec{Capture: &Capture{Type: CaptureTcpdump}}},
+		{name: "bpftrace capture", spec: DebugSessionSpec{Capture: &Capture{Type: CaptureBpftrace}}, want: true},
+		{name: "privileged", spec: DebugSessionSpec{DebugSecurity: &DebugSecurityContext{Privileged: ptr.To(true)}}, want: true},
+		{name: "explicitly unprivileged", spec: DebugSessionSpec{DebugSecurity: &DebugSecurityContext{Privileged: ptr.To(false)}}},
+		{
+			name: "toolset capabilities",
+			spec: DebugSessionSpec{DebugSecurity: &DebugSecurityContext{
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_RAW", "cap_sys_ptrace"}},
+			}},
+		},
+		{
+			name: "other capability",
+			spec: DebugSessionSpec{DebugSecurity: &DebugSecurityContext{
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_RAW", "SYS_ADMIN"}},
+			}},
+			want: true,
+		},
+		{
+			name: "dropped capabilities only",
+			spec: DebugSessionSpec{DebugSecurity: &DebugSecurityContext{
+				Capabilities: &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RequiresApproval(&DebugSession{Spec: tt.spec}); got != tt.want {
+				t.Errorf("RequiresApproval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEBPFDeniedReason(t *testing.T) {
+	allowed := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps", Labels: map[string]string{AllowEBPFKey: "true"}}}
+	plain := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	tests := []struct {
+		name      string
+		spec      DebugSessionSpec
+		namespace *corev1.Namespace
+		denied    bool
+	}{
+		{name: "plain shell", spec: DebugSessionSpec{DebuggerImage: "busybox"}, namespace: plain},
+		{name: "bpftrace in plain namespace", spec: DebugSessionSpec{Capture: &Capture{Type: CaptureBpftrace}}, namespace: plain, denied: true},
+		{name: "bpftrace without namespace", spec: DebugSessionSpec{Capture: &Capture{Type: CaptureBpftrace}}, denied: true},
+		{name: "bpftrace in allowed namespace", spec: DebugSessionSpec{Capture: &Capture{Type: CaptureBpftrace}}, namespace: allowed},
+		{
+			name:      "privileged in plain namespace",
+			spec:      DebugSessionSpec{DebugSecurity: &DebugSecurityContext{Privileged: ptr.To(true)}},
+			namespace: plain,
+			denied:    true,
+		},
+		{
+			name: "BPF capability in plain namespace",
+			spec: DebugSessionSpec{DebugSecurity: &DebugSecurityContext{
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"CAP_BPF"}},
+			}},
+			namespace: plain,
+			denied:    true,
+		},
+		{
+			name: "NET_ADMIN in plain namespace",
+			spec: DebugSessionSpec{DebugSecurity: &DebugSecurityContext{
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN"}},
+			}},
+			namespace: plain,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := EBPFDeniedReason(&DebugSession{Spec: tt.spec}, tt.namespace)
+			if (reason != "") != tt.denied {
+				t.Errorf("EBPFDeniedReason() = %q, want denied %v", reason, tt.denied)
+			}
+		})
+	}
+}
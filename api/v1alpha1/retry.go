@@ -0,0 +1,28 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RetryKey, as an annotation on a Failed DebugSession, makes the controller start the session over
+// from Pending with a fresh debugger container and token. The controller removes the annotation.
+const RetryKey = "debug.ajou.oxan0n.me/retry"
+
+// DebuggerContainerName is the name of the debugger container the session injects. Ephemeral
+// containers cannot be removed, so every retry from scratch gets a new name.
+func DebuggerContainerName(session *DebugSession) string {
+	if session.Status.Restarts > 0 {
+		return fmt.Sprintf("debugger-%s-r%d", session.UID, session.Status.Restarts)
+	}
+	return fmt.Sprintf("debugger-%s", session.UID)
+}
+
+// ToolboxContainerName is the name of the toolbox container injected next to the debugger.
+func ToolboxContainerName(session *DebugSession) string {
+	return "toolbox-" + strings.TrimPrefix(DebuggerContainerName(session), "debugger-")
+}
@@ -0,0 +1,14 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+// SessionUIDLabel and DebuggerContainerLabel are maintained on every DebugSession by the controller,
+// so that the debug proxy can find the session of an attach request with a label selector instead of
+// listing every session. DebuggerContainerLabel is the debugger container the session attaches to,
+// which observers share with the session whose debugger they reuse.
+const (
+	SessionUIDLabel        = "ajou.oxan0n.me/session-uid"
+	DebuggerContainerLabel = "ajou.oxan0n.me/debugger-container"
+)
@@ -21,16 +21,283 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Attachment) DeepCopyInto(out *Attachment) {
+	*out = *in
+	in.AttachTime.DeepCopyInto(&out.AttachTime)
+	if in.HeartbeatTime != nil {
+		in, out := &in.HeartbeatTime, &out.HeartbeatTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DetachTime != nil {
+		in, out := &in.DetachTime, &out.DetachTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Attachment.
+func (in *Attachment) DeepCopy() *Attachment {
+	if in == nil {
+		return nil
+	}
+	out := new(Attachment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthFailurePolicy) DeepCopyInto(out *AuthFailurePolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthFailurePolicy.
+func (in *AuthFailurePolicy) DeepCopy() *AuthFailurePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthFailurePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BastionAccess) DeepCopyInto(out *BastionAccess) {
+	*out = *in
+	if in.Teleport != nil {
+		in, out := &in.Teleport, &out.Teleport
+		*out = new(TeleportAccess)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SSM != nil {
+		in, out := &in.SSM, &out.SSM
+		*out = new(SSMAccess)
+		**out = **in
+	}
+	if in.Overlay != nil {
+		in, out := &in.Overlay, &out.Overlay
+		*out = new(OverlayAccess)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BastionAccess.
+func (in *BastionAccess) DeepCopy() *BastionAccess {
+	if in == nil {
+		return nil
+	}
+	out := new(BastionAccess)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BastionConfig) DeepCopyInto(out *BastionConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BastionConfig.
+func (in *BastionConfig) DeepCopy() *BastionConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BastionConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BastionConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BastionConfigList) DeepCopyInto(out *BastionConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BastionConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BastionConfigList.
+func (in *BastionConfigList) DeepCopy() *BastionConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(BastionConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BastionConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BastionConfigSpec) DeepCopyInto(out *BastionConfigSpec) {
+	*out = *in
+	in.BastionAccess.DeepCopyInto(&out.BastionAccess)
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make([]BastionEnvironment, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BastionConfigSpec.
+func (in *BastionConfigSpec) DeepCopy() *BastionConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BastionConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BastionEnvironment) DeepCopyInto(out *BastionEnvironment) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.BastionAccess.DeepCopyInto(&out.BastionAccess)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BastionEnvironment.
+func (in *BastionEnvironment) DeepCopy() *BastionEnvironment {
+	if in == nil {
+		return nil
+	}
+	out := new(BastionEnvironment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Capture) DeepCopyInto(out *Capture) {
+	*out = *in
+	if in.Profiles != nil {
+		in, out := &in.Profiles, &out.Profiles
+		*out = make([]PprofProfile, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxPackets != nil {
+		in, out := &in.MaxPackets, &out.MaxPackets
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Capture.
+func (in *Capture) DeepCopy() *Capture {
+	if in == nil {
+		return nil
+	}
+	out := new(Capture)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostAttribution) DeepCopyInto(out *CostAttribution) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostAttribution.
+func (in *CostAttribution) DeepCopy() *CostAttribution {
+	if in == nil {
+		return nil
+	}
+	out := new(CostAttribution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DebugSecurityContext) DeepCopyInto(out *DebugSecurityContext) {
+	*out = *in
+	if in.RunAsNonRoot != nil {
+		in, out := &in.RunAsNonRoot, &out.RunAsNonRoot
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RunAsUser != nil {
+		in, out := &in.RunAsUser, &out.RunAsUser
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RunAsGroup != nil {
+		in, out := &in.RunAsGroup, &out.RunAsGroup
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Privileged != nil {
+		in, out := &in.Privileged, &out.Privileged
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AllowPrivilegeEscalation != nil {
+		in, out := &in.AllowPrivilegeEscalation, &out.AllowPrivilegeEscalation
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ReadOnlyRootFilesystem != nil {
+		in, out := &in.ReadOnlyRootFilesystem, &out.ReadOnlyRootFilesystem
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = new(corev1.Capabilities)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugSecurityContext.
+func (in *DebugSecurityContext) DeepCopy() *DebugSecurityContext {
+	if in == nil {
+		return nil
+	}
+	out := new(DebugSecurityContext)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DebugSession) DeepCopyInto(out *DebugSession) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -85,23 +352,89 @@ func (in *DebugSessionList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DebugSessionSpec) DeepCopyInto(out *DebugSessionSpec) {
+func (in *DebugSessionRecord) DeepCopyInto(out *DebugSessionRecord) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugSessionSpec.
-func (in *DebugSessionSpec) DeepCopy() *DebugSessionSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugSessionRecord.
+func (in *DebugSessionRecord) DeepCopy() *DebugSessionRecord {
 	if in == nil {
 		return nil
 	}
-	out := new(DebugSessionSpec)
+	out := new(DebugSessionRecord)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DebugSessionRecord) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DebugSessionStatus) DeepCopyInto(out *DebugSessionStatus) {
+func (in *DebugSessionRecordList) DeepCopyInto(out *DebugSessionRecordList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DebugSessionRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugSessionRecordList.
+func (in *DebugSessionRecordList) DeepCopy() *DebugSessionRecordList {
+	if in == nil {
+		return nil
+	}
+	out := new(DebugSessionRecordList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DebugSessionRecordList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DebugSessionRecordSpec) DeepCopyInto(out *DebugSessionRecordSpec) {
 	*out = *in
+	out.Session = in.Session
+	if in.CostAttribution != nil {
+		in, out := &in.CostAttribution, &out.CostAttribution
+		*out = new(CostAttribution)
+		**out = **in
+	}
+	if in.AttachedClients != nil {
+		in, out := &in.AttachedClients, &out.AttachedClients
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Attachments != nil {
+		in, out := &in.Attachments, &out.Attachments
+		*out = make([]Attachment, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GrantedCapabilities != nil {
+		in, out := &in.GrantedCapabilities, &out.GrantedCapabilities
+		*out = make([]corev1.Capability, len(*in))
+		copy(*out, *in)
+	}
 	if in.StartTime != nil {
 		in, out := &in.StartTime, &out.StartTime
 		*out = (*in).DeepCopy()
@@ -110,6 +443,23 @@ func (in *DebugSessionStatus) DeepCopyInto(out *DebugSessionStatus) {
 		in, out := &in.TerminationTime, &out.TerminationTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Recordings != nil {
+		in, out := &in.Recordings, &out.Recordings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VaultLeases != nil {
+		in, out := &in.VaultLeases, &out.VaultLeases
+		*out = make([]VaultLease, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -119,12 +469,652 @@ func (in *DebugSessionStatus) DeepCopyInto(out *DebugSessionStatus) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugSessionStatus.
-func (in *DebugSessionStatus) DeepCopy() *DebugSessionStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugSessionRecordSpec.
+func (in *DebugSessionRecordSpec) DeepCopy() *DebugSessionRecordSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(DebugSessionStatus)
+	out := new(DebugSessionRecordSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DebugSessionSpec) DeepCopyInto(out *DebugSessionSpec) {
+	*out = *in
+	if in.SetupCommands != nil {
+		in, out := &in.SetupCommands, &out.SetupCommands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Capture != nil {
+		in, out := &in.Capture, &out.Capture
+		*out = new(Capture)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RuntimeAttach != nil {
+		in, out := &in.RuntimeAttach, &out.RuntimeAttach
+		*out = new(RuntimeAttach)
+		**out = **in
+	}
+	if in.VaultCredentials != nil {
+		in, out := &in.VaultCredentials, &out.VaultCredentials
+		*out = make([]VaultCredential, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceAccountToken != nil {
+		in, out := &in.ServiceAccountToken, &out.ServiceAccountToken
+		*out = new(ServiceAccountToken)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretRefs != nil {
+		in, out := &in.SecretRefs, &out.SecretRefs
+		*out = make([]EnvSourceRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConfigMapRefs != nil {
+		in, out := &in.ConfigMapRefs, &out.ConfigMapRefs
+		*out = make([]EnvSourceRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.DebugSecurity != nil {
+		in, out := &in.DebugSecurity, &out.DebugSecurity
+		*out = new(DebugSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TTLAfterFailed != nil {
+		in, out := &in.TTLAfterFailed, &out.TTLAfterFailed
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AttachDeadlineSeconds != nil {
+		in, out := &in.AttachDeadlineSeconds, &out.AttachDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DetachGracePeriodSeconds != nil {
+		in, out := &in.DetachGracePeriodSeconds, &out.DetachGracePeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AuthFailurePolicy != nil {
+		in, out := &in.AuthFailurePolicy, &out.AuthFailurePolicy
+		*out = new(AuthFailurePolicy)
+		**out = **in
+	}
+	if in.RequestedBy != nil {
+		in, out := &in.RequestedBy, &out.RequestedBy
+		*out = new(Requester)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugSessionSpec.
+func (in *DebugSessionSpec) DeepCopy() *DebugSessionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DebugSessionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DebugSessionStatus) DeepCopyInto(out *DebugSessionStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiryTime != nil {
+		in, out := &in.ExpiryTime, &out.ExpiryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ReadyTime != nil {
+		in, out := &in.ReadyTime, &out.ReadyTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FirstAttachTime != nil {
+		in, out := &in.FirstAttachTime, &out.FirstAttachTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastAttachTime != nil {
+		in, out := &in.LastAttachTime, &out.LastAttachTime
+		*out = (*in).DeepCopy()
+	}
+	if in.AttachedClients != nil {
+		in, out := &in.AttachedClients, &out.AttachedClients
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastActivityTime != nil {
+		in, out := &in.LastActivityTime, &out.LastActivityTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDetachTime != nil {
+		in, out := &in.LastDetachTime, &out.LastDetachTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Attachments != nil {
+		in, out := &in.Attachments, &out.Attachments
+		*out = make([]Attachment, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TerminationTime != nil {
+		in, out := &in.TerminationTime, &out.TerminationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PodReplacements != nil {
+		in, out := &in.PodReplacements, &out.PodReplacements
+		*out = make([]PodReplacement, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CostAttribution != nil {
+		in, out := &in.CostAttribution, &out.CostAttribution
+		*out = new(CostAttribution)
+		**out = **in
+	}
+	if in.GrantedCapabilities != nil {
+		in, out := &in.GrantedCapabilities, &out.GrantedCapabilities
+		*out = make([]corev1.Capability, len(*in))
+		copy(*out, *in)
+	}
+	if in.TokenExpiryTime != nil {
+		in, out := &in.TokenExpiryTime, &out.TokenExpiryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.VaultLeases != nil {
+		in, out := &in.VaultLeases, &out.VaultLeases
+		*out = make([]VaultLease, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = new(SessionEndpoints)
+		**out = **in
+	}
+	if in.SSMTunnel != nil {
+		in, out := &in.SSMTunnel, &out.SSMTunnel
+		*out = new(SSMTunnel)
+		**out = **in
+	}
+	if in.LastFailedAuthTime != nil {
+		in, out := &in.LastFailedAuthTime, &out.LastFailedAuthTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LogURLExpiryTime != nil {
+		in, out := &in.LogURLExpiryTime, &out.LogURLExpiryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Recordings != nil {
+		in, out := &in.Recordings, &out.Recordings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RetryHistory != nil {
+		in, out := &in.RetryHistory, &out.RetryHistory
+		*out = make([]RetryAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Diagnostics != nil {
+		in, out := &in.Diagnostics, &out.Diagnostics
+		*out = new(FailureDiagnostics)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugSessionStatus.
+func (in *DebugSessionStatus) DeepCopy() *DebugSessionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DebugSessionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvSourceRef) DeepCopyInto(out *EnvSourceRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvSourceRef.
+func (in *EnvSourceRef) DeepCopy() *EnvSourceRef {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvSourceRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureDiagnostics) DeepCopyInto(out *FailureDiagnostics) {
+	*out = *in
+	in.CollectedAt.DeepCopyInto(&out.CollectedAt)
+	if in.LastContainerState != nil {
+		in, out := &in.LastContainerState, &out.LastContainerState
+		*out = new(corev1.ContainerState)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RecentPodEvents != nil {
+		in, out := &in.RecentPodEvents, &out.RecentPodEvents
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RetryHistory != nil {
+		in, out := &in.RetryHistory, &out.RetryHistory
+		*out = make([]RetryAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureDiagnostics.
+func (in *FailureDiagnostics) DeepCopy() *FailureDiagnostics {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureDiagnostics)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationConfig) DeepCopyInto(out *NotificationConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationConfig.
+func (in *NotificationConfig) DeepCopy() *NotificationConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NotificationConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationConfigList) DeepCopyInto(out *NotificationConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NotificationConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationConfigList.
+func (in *NotificationConfigList) DeepCopy() *NotificationConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NotificationConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationConfigSpec) DeepCopyInto(out *NotificationConfigSpec) {
+	*out = *in
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]NotificationDestination, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationConfigSpec.
+func (in *NotificationConfigSpec) DeepCopy() *NotificationConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationDestination) DeepCopyInto(out *NotificationDestination) {
+	*out = *in
+	if in.URLSecretRef != nil {
+		in, out := &in.URLSecretRef, &out.URLSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.SigningSecretRef != nil {
+		in, out := &in.SigningSecretRef, &out.SigningSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationDestination.
+func (in *NotificationDestination) DeepCopy() *NotificationDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OverlayAccess) DeepCopyInto(out *OverlayAccess) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OverlayAccess.
+func (in *OverlayAccess) DeepCopy() *OverlayAccess {
+	if in == nil {
+		return nil
+	}
+	out := new(OverlayAccess)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodReplacement) DeepCopyInto(out *PodReplacement) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodReplacement.
+func (in *PodReplacement) DeepCopy() *PodReplacement {
+	if in == nil {
+		return nil
+	}
+	out := new(PodReplacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Requester) DeepCopyInto(out *Requester) {
+	*out = *in
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Extra != nil {
+		in, out := &in.Extra, &out.Extra
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Requester.
+func (in *Requester) DeepCopy() *Requester {
+	if in == nil {
+		return nil
+	}
+	out := new(Requester)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryAttempt) DeepCopyInto(out *RetryAttempt) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryAttempt.
+func (in *RetryAttempt) DeepCopy() *RetryAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuntimeAttach) DeepCopyInto(out *RuntimeAttach) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuntimeAttach.
+func (in *RuntimeAttach) DeepCopy() *RuntimeAttach {
+	if in == nil {
+		return nil
+	}
+	out := new(RuntimeAttach)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSMAccess) DeepCopyInto(out *SSMAccess) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSMAccess.
+func (in *SSMAccess) DeepCopy() *SSMAccess {
+	if in == nil {
+		return nil
+	}
+	out := new(SSMAccess)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSMTunnel) DeepCopyInto(out *SSMTunnel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSMTunnel.
+func (in *SSMTunnel) DeepCopy() *SSMTunnel {
+	if in == nil {
+		return nil
+	}
+	out := new(SSMTunnel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAccountToken) DeepCopyInto(out *ServiceAccountToken) {
+	*out = *in
+	if in.Audiences != nil {
+		in, out := &in.Audiences, &out.Audiences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAccountToken.
+func (in *ServiceAccountToken) DeepCopy() *ServiceAccountToken {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAccountToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionEndpoints) DeepCopyInto(out *SessionEndpoints) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionEndpoints.
+func (in *SessionEndpoints) DeepCopy() *SessionEndpoints {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionEndpoints)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionReference) DeepCopyInto(out *SessionReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionReference.
+func (in *SessionReference) DeepCopy() *SessionReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TeleportAccess) DeepCopyInto(out *TeleportAccess) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TeleportAccess.
+func (in *TeleportAccess) DeepCopy() *TeleportAccess {
+	if in == nil {
+		return nil
+	}
+	out := new(TeleportAccess)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultCredential) DeepCopyInto(out *VaultCredential) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultCredential.
+func (in *VaultCredential) DeepCopy() *VaultCredential {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultCredential)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultLease) DeepCopyInto(out *VaultLease) {
+	*out = *in
+	if in.ExpiryTime != nil {
+		in, out := &in.ExpiryTime, &out.ExpiryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RevokeTime != nil {
+		in, out := &in.RevokeTime, &out.RevokeTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultLease.
+func (in *VaultLease) DeepCopy() *VaultLease {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultLease)
 	in.DeepCopyInto(out)
 	return out
 }
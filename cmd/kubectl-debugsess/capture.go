@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/pkg/client"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// capture runs a non-interactive capture action against a pod and prints the storage keys of what it
+// produced once the session has finished.
+func capture(args []string) error {
+	fs := flag.NewFlagSet("capture", flag.ExitOnError)
+	namespace := fs.String("n", "", "Namespace of the DebugSession and target pod (defaults to the kubeconfig namespace).")
+	container := fs.String("c", "", "Target container (defaults to the pod's first container).")
+	captureType := fs.String("type", "", "Capture action: Tcpdump, Strace, Perf, ThreadDump, HeapDump, Pprof or Bpftrace.")
+	filter := fs.String("filter", "", "tcpdump filter or strace -e expression.")
+	duration := fs.Int("duration", 60, "Seconds the capture runs.")
+	port := fs.Int("port", 0, "pprof HTTP port of the target (defaults to 6060).")
+	profiles := fs.String("profiles", "", "Comma-separated pprof profiles: cpu, heap, goroutine (defaults to all).")
+	scriptFile := fs.String("script-file", "", "File holding the bpftrace program of a Bpftrace capture.")
+	image := fs.String("image", "", "Debugger image overriding the action's own.")
+	timeout := fs.Duration("timeout", 10*time.Minute, "How long to wait for the session to finish.")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 || *captureType == "" {
+		usage()
+	}
+
+	cfg, err := loadConfig(namespace)
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, "")
+	if err != nil {
+		return err
+	}
+
+	spec := &debugv1alpha1.Capture{
+		Type:            debugv1alpha1.CaptureType(*captureType),
+		Filter:          *filter,
+		DurationSeconds: int32(*duration),
+		Port:            int32(*port),
+	}
+	if *scriptFile != "" {
+		script, err := os.ReadFile(*scriptFile)
+		if err != nil {
+			return fmt.Errorf("failed to read script: %w", err)
+		}
+		spec.Script = string(script)
+	}
+	if *profiles != "" {
+		for _, p := range strings.Split(*profiles, ",") {
+			spec.Profiles = append(spec.Profiles, debugv1alpha1.PprofProfile(strings.TrimSpace(p)))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	session, err := c.CreateSession(ctx, *namespace, debugv1alpha1.DebugSessionSpec{
+		TargetPodName:       fs.Arg(0),
+		TargetContainerName: *container,
+		DebuggerImage:       *image,
+		Capture:             spec,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create DebugSession: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Created DebugSession %s/%s, waiting for the capture to finish\n", session.Namespace, session.Name)
+
+	session, err = c.WaitForCompletion(ctx, session)
+	if err != nil {
+		return err
+	}
+	if session.Status.Phase == debugv1alpha1.Failed {
+		return fmt.Errorf("session %s failed: %s", session.Name, session.Status.Message)
+	}
+	if len(session.Status.Artifacts) == 0 {
+		if cond := meta.FindStatusCondition(session.Status.Conditions, debugv1alpha1.ConditionCaptured); cond != nil {
+			return fmt.Errorf("no artifacts: %s", cond.Message)
+		}
+		return fmt.Errorf("no artifacts were stored")
+	}
+	for _, key := range session.Status.Artifacts {
+		fmt.Println(key)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Command kubectl-debugsess is a kubectl plugin for working with DebugSessions.
+//
+//	kubectl debugsess replay -n <namespace> <session> [--proxy-url URL] [--speed N] [--text] [--recording N]
+//	kubectl debugsess capture -n <namespace> <pod> --type TYPE [-c container] [--filter F] [--duration N]
+//	kubectl debugsess tunnel -n <namespace> <session> [--profile P]
+//	kubectl debugsess report [--since T] [--until T] [--format csv|json] [-o FILE]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "replay":
+		if err := replay(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	case "capture":
+		if err := capture(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	case "tunnel":
+		if err := tunnel(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	case "report":
+		if err := report(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: kubectl debugsess replay -n <namespace> <session> [flags]")
+	fmt.Fprintln(os.Stderr, "       kubectl debugsess capture -n <namespace> <pod> --type TYPE [flags]")
+	fmt.Fprintln(os.Stderr, "       kubectl debugsess tunnel -n <namespace> <session> [flags]")
+	fmt.Fprintln(os.Stderr, "       kubectl debugsess report [--since T] [--until T] [--format csv|json] [flags]")
+	os.Exit(2)
+}
+
+// loadConfig loads the kubeconfig and defaults *namespace to its namespace.
+func loadConfig(namespace *string) (*rest.Config, error) {
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{})
+	if *namespace == "" {
+		ns, _, err := loader.Namespace()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve namespace: %w", err)
+		}
+		*namespace = ns
+	}
+	cfg, err := loader.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	return cfg, nil
+}
+
+// replay streams a stored session recording from the debug proxy to stdout.
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	namespace := fs.String("n", "", "Namespace of the DebugSession (defaults to the kubeconfig namespace).")
+	proxyURL := fs.String("proxy-url", os.Getenv("KUBEDEBUGSESS_PROXY_URL"), "Base URL of the debug proxy.")
+	speed := fs.Float64("speed", 1, "Playback speed multiplier.")
+	text := fs.Bool("text", false, "Print the whole transcript at once instead of replaying with original timing.")
+	raw := fs.Bool("raw", false, "Download the raw asciicast recording.")
+	recording := fs.Int("recording", -1, "Index of the recording to replay (defaults to the latest).")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	if *proxyURL == "" {
+		return fmt.Errorf("--proxy-url or KUBEDEBUGSESS_PROXY_URL must be set")
+	}
+
+	cfg, err := loadConfig(namespace)
+	if err != nil {
+		return err
+	}
+	// The proxy authenticates bearer tokens only; this reuses the kubeconfig's token or exec plugin.
+	transport, err := rest.HTTPWrappersForConfig(cfg, http.DefaultTransport)
+	if err != nil {
+		return fmt.Errorf("failed to build authenticated transport: %w", err)
+	}
+
+	q := url.Values{"ns": {*namespace}, "session": {fs.Arg(0)}}
+	switch {
+	case *raw:
+		q.Set("mode", "cast")
+	case *text:
+		q.Set("mode", "text")
+	default:
+		q.Set("speed", strconv.FormatFloat(*speed, 'f', -1, 64))
+	}
+	if *recording >= 0 {
+		q.Set("recording", strconv.Itoa(*recording))
+	}
+
+	resp, err := (&http.Client{Transport: transport}).Get(*proxyURL + "/replay?" + q.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("replay failed: %s: %s", resp.Status, msg)
+	}
+	_, err = io.Copy(os.Stdout, resp.Body)
+	return err
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// report downloads the compliance report of the sessions that ended in a time range from the debug
+// proxy, as CSV or JSON.
+func report(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	proxyURL := fs.String("proxy-url", os.Getenv("KUBEDEBUGSESS_PROXY_URL"), "Base URL of the debug proxy.")
+	since := fs.String("since", "", "Start of the range: an RFC 3339 timestamp or a duration before now such as 720h (default 30 days ago).")
+	until := fs.String("until", "", "End of the range, as an RFC 3339 timestamp (default now).")
+	format := fs.String("format", "csv", "Report format: csv or json.")
+	namespace := fs.String("namespace", "", "Only report sessions of this namespace (default all).")
+	user := fs.String("user", "", "Only report sessions requested by this user.")
+	output := fs.String("o", "", "File to write the report to (default stdout).")
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		usage()
+	}
+	if *proxyURL == "" {
+		return fmt.Errorf("--proxy-url or KUBEDEBUGSESS_PROXY_URL must be set")
+	}
+
+	start := time.Now().Add(-30 * 24 * time.Hour)
+	if *since != "" {
+		if d, err := time.ParseDuration(*since); err == nil {
+			start = time.Now().Add(-d)
+		} else if start, err = time.Parse(time.RFC3339, *since); err != nil {
+			return fmt.Errorf("--since must be an RFC 3339 timestamp or a duration")
+		}
+	}
+	q := url.Values{"since": {start.UTC().Format(time.RFC3339)}, "format": {*format}}
+	if *until != "" {
+		q.Set("until", *until)
+	}
+	if *namespace != "" {
+		q.Set("namespace", *namespace)
+	}
+	if *user != "" {
+		q.Set("user", *user)
+	}
+
+	// The report is assembled with the caller's own permission to list debugsessionrecords.
+	var kubeNamespace string
+	cfg, err := loadConfig(&kubeNamespace)
+	if err != nil {
+		return err
+	}
+	transport, err := rest.HTTPWrappersForConfig(cfg, http.DefaultTransport)
+	if err != nil {
+		return fmt.Errorf("failed to build authenticated transport: %w", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(*proxyURL + "/api/v1/report?" + q.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("report failed: %s: %s", resp.Status, msg)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/pkg/client"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// tunnel waits for a session to become ready and runs the AWS Systems Manager port forwarding its
+// BastionConfig hands out, until it is interrupted.
+func tunnel(args []string) error {
+	fs := flag.NewFlagSet("tunnel", flag.ExitOnError)
+	namespace := fs.String("n", "", "Namespace of the DebugSession (defaults to the kubeconfig namespace).")
+	profile := fs.String("profile", "", "AWS CLI profile to start the session with.")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	cfg, err := loadConfig(namespace)
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, "")
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	session := &debugv1alpha1.DebugSession{}
+	if err := c.Kube.Get(ctx, types.NamespacedName{Namespace: *namespace, Name: fs.Arg(0)}, session); err != nil {
+		return err
+	}
+	if session, err = c.WaitForReady(ctx, session); err != nil {
+		return err
+	}
+	if session.Status.SSMTunnel == nil {
+		return fmt.Errorf("session %s is not reached through SSM; see its status message", session.Name)
+	}
+
+	command := session.Status.SSMTunnel.StartSessionCommand()
+	if *profile != "" {
+		command = append(command, "--profile", *profile)
+	}
+	fmt.Fprintf(os.Stderr, "Forwarding localhost:%d to the debug proxy; press Ctrl-C to stop.\n",
+		session.Status.SSMTunnel.LocalPort)
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
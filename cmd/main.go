@@ -17,9 +17,12 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -36,7 +39,18 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/audit"
 	"github.com/OxAN0N/KubeDebugSess/internal/controller"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	"github.com/OxAN0N/KubeDebugSess/internal/profiling"
+	"github.com/OxAN0N/KubeDebugSess/internal/proxy"
+	"github.com/OxAN0N/KubeDebugSess/internal/scope"
+	"github.com/OxAN0N/KubeDebugSess/internal/storage"
+	"github.com/OxAN0N/KubeDebugSess/internal/ticket"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
+	"github.com/OxAN0N/KubeDebugSess/internal/vault"
+	webhookv1alpha1 "github.com/OxAN0N/KubeDebugSess/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -57,9 +71,22 @@ func main() {
 	var metricsAddr string
 	var metricsCertPath, metricsCertName, metricsCertKey string
 	var webhookCertPath, webhookCertName, webhookCertKey string
+	var trustedRequesters, trustedApprovers string
+	var slackAddr string
+	var maxDebuggersPerPod int
+	var debuggerLimitAction string
+	var reasonActions, unknownReasonAction string
 	var enableLeaderElection bool
+	var leaderElectionNamespace string
+	var leaseDuration, renewDeadline, retryPeriod time.Duration
 	var probeAddr string
+	var proxyAddr, proxyGRPCAddr string
+	var maxConcurrentReconciles, rateLimiterBurst int
+	var rateLimiterBaseDelay, rateLimiterMaxDelay time.Duration
+	var rateLimiterQPS float64
+	requeue := session_phases.DefaultRequeueIntervals()
 	var secureMetrics bool
+	var enablePprof bool
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
@@ -68,15 +95,67 @@ func main() {
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the leader election lease. Defaults to the namespace the manager runs in.")
+	flag.DurationVar(&leaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"How long non-leader replicas wait before trying to acquire an unrenewed lease.")
+	flag.DurationVar(&renewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"How long the leader keeps retrying to renew its lease before giving up leadership.")
+	flag.DurationVar(&retryPeriod, "leader-election-retry-period", 2*time.Second,
+		"How often replicas retry acquiring or renewing the lease.")
+	flag.StringVar(&proxyAddr, "proxy-bind-address", "",
+		"Serve the debug proxy from the manager on this address (single-binary mode). Empty disables it.")
+	flag.StringVar(&proxyGRPCAddr, "proxy-grpc-bind-address", "",
+		"The address the embedded debug proxy serves its gRPC API on. Empty disables it.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The number of DebugSessions reconciled in parallel.")
+	flag.DurationVar(&rateLimiterBaseDelay, "rate-limiter-base-delay", 5*time.Millisecond,
+		"The first requeue delay of a failing reconcile; it doubles on every further failure.")
+	flag.DurationVar(&rateLimiterMaxDelay, "rate-limiter-max-delay", 1000*time.Second,
+		"The longest requeue delay of a failing reconcile.")
+	flag.Float64Var(&rateLimiterQPS, "rate-limiter-qps", 10, "The overall requeue rate limit, in requeues per second.")
+	flag.IntVar(&rateLimiterBurst, "rate-limiter-burst", 100, "The burst allowed above --rate-limiter-qps.")
+	flag.DurationVar(&requeue.PendingPod, "requeue-pending-pod", requeue.PendingPod,
+		"How often a Pending session re-checks a target pod that is not running yet.")
+	flag.DurationVar(&requeue.ActiveContainer, "requeue-active-container", requeue.ActiveContainer,
+		"How often an Active session polls until the debugger container reports a status.")
+	flag.DurationVar(&requeue.RetryBase, "retry-base-delay", requeue.RetryBase,
+		"The first backoff of a Retrying session; it doubles on every further retry.")
+	flag.DurationVar(&requeue.RetryMax, "retry-max-delay", requeue.RetryMax, "The longest backoff of a Retrying session.")
+	flag.IntVar(&maxDebuggersPerPod, "max-debuggers-per-pod", 0,
+		"The most debugger containers that may run at once on a pod; 0 means no limit. Namespaces override it "+
+			"with the "+debugv1alpha1.MaxDebuggersPerPodKey+" annotation.")
+	flag.StringVar(&debuggerLimitAction, "debugger-limit-action", string(session_phases.DebuggerLimitQueue),
+		"What to do with sessions over --max-debuggers-per-pod: Queue them in Pending or Reject them.")
+	flag.StringVar(&reasonActions, "reason-actions", "",
+		"Comma-separated overrides of the action taken on debugger container reasons, as state.Reason=Action "+
+			"with state waiting or terminated and Action Wait, Retry, Fail or Succeed, e.g. waiting.ErrImagePull=Retry.")
+	flag.StringVar(&unknownReasonAction, "unknown-reason-action", "Fail",
+		"The action taken on debugger container reasons missing from the built-in and --reason-actions maps.")
+	flag.DurationVar(&requeue.UnknownPhase, "requeue-unknown-phase", requeue.UnknownPhase,
+		"How often a session in an unrecognized phase is looked at again.")
 	flag.BoolVar(&secureMetrics, "metrics-secure", true,
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.StringVar(&webhookCertPath, "webhook-cert-path", "", "The directory that contains the webhook certificate.")
 	flag.StringVar(&webhookCertName, "webhook-cert-name", "tls.crt", "The name of the webhook certificate file.")
 	flag.StringVar(&webhookCertKey, "webhook-cert-key", "tls.key", "The name of the webhook key file.")
+	flag.StringVar(&trustedRequesters, "webhook-trusted-requesters",
+		"system:serviceaccount:kubedebugsess-system:kubedebugsess-proxy-sa,"+
+			"system:serviceaccount:kubedebugsess-system:kubedebugsess-controller-manager",
+		"Comma-separated users allowed to set spec.requestedBy on behalf of someone else.")
+	flag.StringVar(&trustedApprovers, "webhook-trusted-approvers",
+		"system:serviceaccount:kubedebugsess-system:kubedebugsess-controller-manager",
+		"Comma-separated users allowed to record approvals and denials given by someone else, such as in Slack, "+
+			"and to release deleted sessions without their archive.")
+	flag.StringVar(&slackAddr, "slack-bind-address", "",
+		"Serve the Slack app endpoints, for approving sessions and the /debugsess command, on this address. "+
+			"Empty disables them.")
 	flag.StringVar(&metricsCertPath, "metrics-cert-path", "",
 		"The directory that contains the metrics server certificate.")
 	flag.StringVar(&metricsCertName, "metrics-cert-name", "tls.crt", "The name of the metrics server certificate file.")
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
+	flag.BoolVar(&enablePprof, "enable-pprof", false,
+		"Serve /debug/pprof on the metrics endpoint, behind the same authentication and authorization as /metrics.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	opts := zap.Options{
@@ -87,6 +166,14 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	// Tracing is exported only when an OTLP endpoint is configured via the standard OTEL_* variables.
+	shutdownTracing, err := tracing.Setup(context.Background(), "kubedebugsess-controller")
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() { _ = shutdownTracing(context.Background()) }()
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -145,6 +232,14 @@ func main() {
 	// - [METRICS-WITH-CERTS] at config/default/kustomization.yaml to generate and use certificates
 	// managed by cert-manager for the metrics server.
 	// - [PROMETHEUS-WITH-CERTS] at config/prometheus/kustomization.yaml for TLS certification.
+	if enablePprof {
+		if !secureMetrics {
+			setupLog.Error(nil, "--enable-pprof requires --metrics-secure, profiles would be served unauthenticated")
+			os.Exit(1)
+		}
+		metricsServerOptions.ExtraHandlers = profiling.Handlers()
+	}
+
 	if len(metricsCertPath) > 0 {
 		setupLog.Info("Initializing metrics certificate watcher using provided certificates",
 			"metrics-cert-path", metricsCertPath, "metrics-cert-name", metricsCertName, "metrics-cert-key", metricsCertKey)
@@ -154,24 +249,30 @@ func main() {
 		metricsServerOptions.KeyName = metricsCertKey
 	}
 
+	// WATCH_NAMESPACES / WATCH_NAMESPACE_SELECTOR run the controller namespace-scoped.
+	namespaceScope, err := scope.NewFromEnv()
+	if err != nil {
+		setupLog.Error(err, "unable to set up namespace scope")
+		os.Exit(1)
+	}
+	setupLog.Info("reconciling DebugSessions", "scope", namespaceScope.String())
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		Metrics:                metricsServerOptions,
-		WebhookServer:          webhookServer,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "17af02ed.oxan0n.me",
-		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
-		// when the Manager ends. This requires the binary to immediately end when the
-		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
-		// speeds up voluntary leader transitions as the new leader don't have to wait
-		// LeaseDuration time first.
-		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
+		Scheme:                  scheme,
+		Cache:                   controller.CacheOptions(namespaceScope.CacheNamespaces()),
+		Metrics:                 metricsServerOptions,
+		WebhookServer:           webhookServer,
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        "17af02ed.oxan0n.me",
+		LeaderElectionNamespace: leaderElectionNamespace,
+		LeaseDuration:           &leaseDuration,
+		RenewDeadline:           &renewDeadline,
+		RetryPeriod:             &retryPeriod,
+		// The leader steps down as soon as the manager stops, so a standby replica takes over without
+		// waiting out the lease. This is safe because nothing after mgr.Start touches the cluster; the
+		// deferred cleanups only flush tracing and the message bus.
+		LeaderElectionReleaseOnCancel: true,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -184,16 +285,158 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Lifecycle messages go to Kafka and/or NATS when configured via the NOTIFY_* variables.
+	publisher, err := notify.NewPublisherFromEnv()
+	if err != nil {
+		setupLog.Error(err, "unable to set up notification publisher")
+		os.Exit(1)
+	}
+	if publisher != nil {
+		defer func() { _ = publisher.Close() }()
+	}
+	// Audit records are exported to CloudWatch Logs and/or Cloud Logging when configured via the AUDIT_* variables.
+	auditor, err := audit.NewExporterFromEnv(context.Background())
+	if err != nil {
+		setupLog.Error(err, "unable to set up audit export")
+		os.Exit(1)
+	}
+	if auditor != nil {
+		defer func() { _ = auditor.Close() }()
+	}
+	// Webhook destinations come from NotificationConfig resources, falling back to WEBHOOK_URL.
+	notifier, err := notify.NewDispatcherFromEnv(mgr.GetClient(), mgr.GetAPIReader())
+	if err != nil {
+		setupLog.Error(err, "unable to set up webhook notifier")
+		os.Exit(1)
+	}
+	alerter, err := notify.NewAlerterFromEnv()
+	if err != nil {
+		setupLog.Error(err, "unable to set up on-call alerting")
+		os.Exit(1)
+	}
+	// Approve and Deny buttons of ApprovalRequired Slack messages and the /debugsess command call back here.
+	slackApp, err := notify.NewSlackAppFromEnv(mgr.GetClient(), mgr.GetAPIReader(), slackAddr)
+	if err != nil {
+		setupLog.Error(err, "unable to set up the Slack app")
+		os.Exit(1)
+	}
+	var slackThreads *notify.SlackThreads
+	if slackApp != nil {
+		slackThreads = slackApp.Threads
+		if err := mgr.Add(slackApp); err != nil {
+			setupLog.Error(err, "unable to set up the Slack app")
+			os.Exit(1)
+		}
+	}
+
+	// Transcripts are archived to the backend chosen by LOG_STORAGE_BACKEND; archival is skipped without one.
+	logStorage, err := storage.NewFromEnv(context.Background())
+	if err != nil {
+		setupLog.Error(err, "unable to set up log storage")
+		os.Exit(1)
+	}
+	if logStorage == nil {
+		setupLog.Info("no log storage backend configured, debugger transcripts will not be archived")
+	}
+	logURLExpiry, err := storage.PresignExpiryFromEnv()
+	if err != nil {
+		setupLog.Error(err, "unable to set up log storage")
+		os.Exit(1)
+	}
+	logKeyLayout, err := storage.KeyLayoutFromEnv()
+	if err != nil {
+		setupLog.Error(err, "unable to set up log storage")
+		os.Exit(1)
+	}
+
+	// Sessions may request spec.vaultCredentials only when VAULT_ADDR is set.
+	vaultClient, err := vault.NewFromEnv()
+	if err != nil {
+		setupLog.Error(err, "unable to set up Vault")
+		os.Exit(1)
+	}
+
+	// spec.ticketRef is only checked against a ticket system when one is configured.
+	tickets, err := ticket.NewCheckerFromEnv()
+	if err != nil {
+		setupLog.Error(err, "unable to set up ticket validation")
+		os.Exit(1)
+	}
+
+	webhooksEnabled := os.Getenv("ENABLE_WEBHOOKS") == "true"
+	accessCheck, err := session_phases.AccessCheckFromEnv(webhooksEnabled)
+	if err != nil {
+		setupLog.Error(err, "unable to set up requester access check")
+		os.Exit(1)
+	}
+	setupLog.Info("requester access check", "mode", accessCheck)
+
+	limitAction, err := session_phases.ParseDebuggerLimitAction(debuggerLimitAction)
+	if err != nil {
+		setupLog.Error(err, "invalid --debugger-limit-action")
+		os.Exit(1)
+	}
+
+	reasons, err := session_phases.ParseReasonActions(reasonActions, unknownReasonAction)
+	if err != nil {
+		setupLog.Error(err, "invalid --reason-actions or --unknown-reason-action")
+		os.Exit(1)
+	}
+
 	if err := (&controller.DebugSessionReconciler{
-		Client:    mgr.GetClient(),
-		Scheme:    mgr.GetScheme(),
-		ClientSet: cs,
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		ClientSet:    cs,
+		Recorder:     mgr.GetEventRecorderFor("debugsession-controller"),
+		Publisher:    publisher,
+		Auditor:      auditor,
+		SlackThreads: slackThreads,
+		Notifier:     notifier,
+		Alerter:      alerter,
+		Storage:      logStorage,
+		LogURLExpiry: logURLExpiry,
+		KeyLayout:    logKeyLayout,
+		Scope:        namespaceScope,
+		Vault:        vaultClient,
+		Tickets:      tickets,
+
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		RateLimiter:             controller.NewRateLimiter(rateLimiterBaseDelay, rateLimiterMaxDelay, rateLimiterQPS, rateLimiterBurst),
+		Requeue:                 requeue,
+		AccessCheck:             accessCheck,
+		DebuggerLimit:           session_phases.DebuggerLimit{MaxPerPod: maxDebuggersPerPod, Action: limitAction},
+		ReasonActions:           reasons,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DebugSession")
 		os.Exit(1)
 	}
+	// The webhook records who created each session in spec.requestedBy.
+	if webhooksEnabled {
+		if err := webhookv1alpha1.SetupDebugSessionWebhookWithManager(mgr,
+			strings.Split(trustedRequesters, ","), strings.Split(trustedApprovers, ",")); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DebugSession")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	if proxyAddr != "" {
+		// Single-binary mode: the proxy shares the manager's cached client, log storage and scope.
+		proxyServer := proxy.NewServer(cs, mgr.GetConfig(), mgr.GetClient(),
+			mgr.GetEventRecorderFor("kubedebugsess-proxy"), logStorage)
+		proxyServer.Scope = namespaceScope
+		proxyServer.KeyLayout = logKeyLayout
+		proxyServer.Auditor = auditor
+		if err := proxyServer.ConfigureFromEnv(); err != nil {
+			setupLog.Error(err, "unable to set up embedded debug proxy")
+			os.Exit(1)
+		}
+		if err := mgr.Add(&proxy.Embedded{Server: proxyServer, Addr: proxyAddr, GRPCAddr: proxyGRPCAddr}); err != nil {
+			setupLog.Error(err, "unable to set up embedded debug proxy")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -1,35 +1,94 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/audit"
+	"github.com/OxAN0N/KubeDebugSess/internal/profiling"
 	"github.com/OxAN0N/KubeDebugSess/internal/proxy"
+	"github.com/OxAN0N/KubeDebugSess/internal/scope"
+	"github.com/OxAN0N/KubeDebugSess/internal/storage"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 )
 
 func main() {
-	var listenAddr string
+	var listenAddr, grpcAddr string
 	flag.StringVar(&listenAddr, "listen-addr", ":8080", "The address to listen on for HTTP requests.")
+	flag.StringVar(&grpcAddr, "grpc-addr", ":9090", "The address to serve the gRPC API on. Empty disables it.")
+	var metricsAddr string
+	flag.StringVar(&metricsAddr, "metrics-addr", ":8082",
+		"The address to serve Prometheus metrics, such as bytes transferred per session, on, to callers allowed "+
+			"to get /metrics by the Kubernetes API. Empty disables it.")
+	var pprofAddr string
+	flag.StringVar(&pprofAddr, "pprof-addr", "",
+		"The address to serve /debug/pprof on, to callers allowed to get that path by the Kubernetes API. Empty disables it.")
+	var drainTimeout time.Duration
+	flag.DurationVar(&drainTimeout, "drain-timeout", 5*time.Minute,
+		"How long attached clients may keep working after SIGTERM before they are asked to reconnect. "+
+			"Keep it below the pod's termination grace period.")
+	var nodeLocal bool
+	flag.BoolVar(&nodeLocal, "node-local", false,
+		"Only attach to pods on this proxy's node (NODE_NAME), for running the proxy as a DaemonSet.")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	// LOG_LEVEL, set by the Helm chart, is the default of --zap-log-level.
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		if err := flag.Set("zap-log-level", level); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid LOG_LEVEL %q: %v\n", level, err)
+			os.Exit(1)
+		}
+	}
 	flag.Parse()
 
+	logf.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	setupLog := logf.Log.WithName("setup")
+	fatal := func(err error, msg string, keysAndValues ...any) {
+		setupLog.Error(err, msg, keysAndValues...)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "kubedebugsess-proxy")
+	if err != nil {
+		fatal(err, "Failed to set up tracing")
+	}
+	defer func() { _ = shutdownTracing(context.Background()) }()
+
 	// Load Kubernetes configuration
 	cfg, err := config.GetConfig()
 	if err != nil {
-		log.Fatalf("Failed to get kubeconfig: %v", err)
+		fatal(err, "Failed to get kubeconfig")
 	}
 
 	// Create a standard clientset for CoreV1 operations (attach)
 	clientset, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create clientset: %v", err)
+		fatal(err, "Failed to create clientset")
 	}
 
 	// --- 수정된 부분 ---
@@ -40,17 +99,136 @@ func main() {
 	// --- ---
 
 	// Create a controller-runtime client that knows about our custom resources.
-	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	k8sClient, err := client.NewWithWatch(cfg, client.Options{Scheme: scheme})
 	if err != nil {
-		log.Fatalf("Failed to create controller-runtime client: %v", err)
+		fatal(err, "Failed to create controller-runtime client")
+	}
+
+	// Record Events on DebugSessions so attach activity shows up in `kubectl describe`.
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	defer broadcaster.Shutdown()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: "kubedebugsess-proxy"})
+
+	// Attach streams are recorded live to the log storage backend, configured like the controller's.
+	logStorage, err := storage.NewFromEnv(context.Background())
+	if err != nil {
+		fatal(err, "Failed to set up log storage")
+	}
+	if logStorage == nil {
+		setupLog.Info("No log storage backend configured, attach sessions will not be recorded")
+	}
+	logKeyLayout, err := storage.KeyLayoutFromEnv()
+	if err != nil {
+		fatal(err, "Failed to set up log storage")
+	}
+
+	// Attach and detach audit records go to the log services configured like the controller's.
+	auditor, err := audit.NewExporterFromEnv(context.Background())
+	if err != nil {
+		fatal(err, "Failed to set up audit export")
+	}
+	if auditor != nil {
+		defer func() { _ = auditor.Close() }()
 	}
 
 	// Create and register the proxy server
-	proxyServer := proxy.NewServer(clientset, cfg, k8sClient)
-	http.Handle("/attach", proxyServer)
+	proxyServer := proxy.NewServer(clientset, cfg, k8sClient, recorder, logStorage)
+	proxyServer.KeyLayout = logKeyLayout
+	proxyServer.Auditor = auditor
+	if err := proxyServer.ConfigureFromEnv(); err != nil {
+		fatal(err, "Failed to set up debug proxy")
+	}
+	// WATCH_NAMESPACES / WATCH_NAMESPACE_SELECTOR restrict the proxy to the controller's namespaces.
+	proxyServer.Scope, err = scope.NewFromEnv()
+	if err != nil {
+		fatal(err, "Failed to set up namespace scope")
+	}
+	setupLog.Info("Serving DebugSessions", "scope", proxyServer.Scope.String())
+	if nodeLocal {
+		proxyServer.NodeName = os.Getenv("NODE_NAME")
+		if proxyServer.NodeName == "" {
+			fatal(nil, "--node-local requires the NODE_NAME environment variable")
+		}
+		setupLog.Info("Serving attach connections for pods on this node only", "node", proxyServer.NodeName)
+	}
+
+	var grpcServer *grpc.Server
+	if grpcAddr != "" {
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			fatal(err, "Failed to listen for gRPC")
+		}
+		grpcServer = proxy.NewGRPCServer(proxyServer)
+		go func() {
+			setupLog.Info("Starting debug proxy gRPC server", "addr", grpcAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				fatal(err, "Failed to serve gRPC")
+			}
+		}()
+	}
+
+	if metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(ctrlmetrics.Registry, promhttp.HandlerOpts{}))
+		handler, err := authorizedHandler(cfg, "metrics", mux)
+		if err != nil {
+			fatal(err, "Failed to set up metrics")
+		}
+		go func() {
+			setupLog.Info("Serving debug proxy metrics", "addr", metricsAddr)
+			if err := http.ListenAndServe(metricsAddr, handler); err != nil {
+				fatal(err, "Failed to serve metrics")
+			}
+		}()
+	}
 
-	log.Printf("Starting debug proxy server on %s", listenAddr)
-	if err := http.ListenAndServe(listenAddr, nil); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	if pprofAddr != "" {
+		mux := http.NewServeMux()
+		for path, handler := range profiling.Handlers() {
+			mux.Handle(path, handler)
+		}
+		handler, err := authorizedHandler(cfg, "pprof", mux)
+		if err != nil {
+			fatal(err, "Failed to set up pprof")
+		}
+		go func() {
+			setupLog.Info("Serving debug proxy pprof", "addr", pprofAddr)
+			if err := http.ListenAndServe(pprofAddr, handler); err != nil {
+				fatal(err, "Failed to serve pprof")
+			}
+		}()
+	}
+
+	httpServer := proxyServer.HTTPServer(listenAddr)
+	go func() {
+		setupLog.Info("Starting debug proxy server", "addr", listenAddr)
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			fatal(err, "Failed to start server")
+		}
+	}()
+
+	// On SIGTERM the proxy turns unready and lets attached clients finish before it exits.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	<-ctx.Done()
+	setupLog.Info("Shutting down debug proxy", "drainTimeout", drainTimeout)
+	drainCtx, cancel := context.WithTimeout(logf.IntoContext(context.Background(), logf.Log.WithName("drain")), drainTimeout)
+	defer cancel()
+	proxy.Shutdown(drainCtx, proxyServer, httpServer, grpcServer)
+}
+
+// authorizedHandler serves mux to callers whose bearer token the API server authenticates and who may
+// get the requested path, like the controller's metrics endpoint. The metrics carry session, namespace
+// and team names, and pprof exposes the proxy's memory, so neither is served unauthenticated.
+func authorizedHandler(cfg *rest.Config, name string, mux *http.ServeMux) (http.Handler, error) {
+	httpClient, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := filters.WithAuthenticationAndAuthorization(cfg, httpClient)
+	if err != nil {
+		return nil, err
 	}
+	return filter(logf.Log.WithName(name), mux)
 }
@@ -48,3 +48,70 @@ app.kubernetes.io/instance: {{ .Release.Name }}
     $hasValidating = true }}{{- end }}
 {{- end }}
 {{ $hasValidating }}}}{{- end }}
+
+
+{{- define "chart.namespaceScopeEnv" -}}
+{{- with .Values.namespaceScope }}
+{{- if .namespaces }}
+- name: WATCH_NAMESPACES
+  value: {{ join "," .namespaces | quote }}
+{{- end }}
+{{- if .selector }}
+- name: WATCH_NAMESPACE_SELECTOR
+  value: {{ .selector | quote }}
+{{- end }}
+{{- end }}
+{{- end }}
+
+
+{{- define "chart.proxyWebSocketEnv" -}}
+{{- with .Values.debugProxy.websocket }}
+{{- if .compression }}
+- name: PROXY_WS_COMPRESSION
+  value: "true"
+- name: PROXY_WS_COMPRESSION_LEVEL
+  value: {{ .compressionLevel | quote }}
+{{- end }}
+- name: PROXY_WS_MAX_MESSAGE_SIZE
+  value: {{ .maxMessageSize | int64 | quote }}
+- name: PROXY_WS_HANDSHAKE_TIMEOUT
+  value: {{ .handshakeTimeout | quote }}
+- name: PROXY_WS_PING_INTERVAL
+  value: {{ .pingInterval | quote }}
+- name: PROXY_WS_READ_TIMEOUT
+  value: {{ .readTimeout | quote }}
+- name: PROXY_WS_WRITE_TIMEOUT
+  value: {{ .writeTimeout | quote }}
+- name: PROXY_WS_OUTPUT_BUFFER_SIZE
+  value: {{ .outputBufferSize | int64 | quote }}
+- name: PROXY_WS_BACKPRESSURE
+  value: {{ .backpressure | quote }}
+{{- end }}
+{{- end }}
+
+
+{{- define "chart.proxyLimitsEnv" -}}
+{{- with .Values.debugProxy.limits }}
+- name: PROXY_BANDWIDTH_PER_CONNECTION
+  value: {{ .bandwidthPerConnection | int64 | quote }}
+- name: PROXY_BANDWIDTH_TOTAL
+  value: {{ .bandwidthTotal | int64 | quote }}
+- name: PROXY_MAX_CONNECTIONS
+  value: {{ .maxConnections | int64 | quote }}
+- name: PROXY_MAX_CONNECTIONS_PER_SESSION
+  value: {{ .maxConnectionsPerSession | int64 | quote }}
+{{- end }}
+{{- end }}
+
+
+{{- define "chart.proxySubject" -}}
+{{- if .Values.singleBinary.enable }}
+- kind: ServiceAccount
+  name: {{ .Values.controllerManager.serviceAccountName }}
+  namespace: {{ .Release.Namespace }}
+{{- else }}
+- kind: ServiceAccount
+  name: kubedebugsess-proxy-sa
+  namespace: kubedebugsess-system
+{{- end }}
+{{- end }}
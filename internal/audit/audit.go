@@ -0,0 +1,182 @@
+// Package audit exports structured DebugSession audit records to the log services that compliance
+// tooling reads from directly, Amazon CloudWatch Logs and Google Cloud Logging.
+//
+// Records are written by the controller when a session is created and when it terminates, and by the
+// debug proxy when a client attaches or detaches.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+)
+
+// Events of audit records.
+const (
+	EventSessionCreated    = "SessionCreated"
+	EventSessionAttached   = "SessionAttached"
+	EventSessionDetached   = "SessionDetached"
+	EventSessionTerminated = "SessionTerminated"
+)
+
+// Record is the JSON document exported for every audit event.
+type Record struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+	Phase     string `json:"phase"`
+
+	// RequestedBy is the session's requester and ApprovedBy the user who approved it, if it needed
+	// approval.
+	RequestedBy       string   `json:"requestedBy,omitempty"`
+	RequestedByGroups []string `json:"requestedByGroups,omitempty"`
+	ApprovedBy        string   `json:"approvedBy,omitempty"`
+
+	TargetNamespace string `json:"targetNamespace"`
+	TargetPod       string `json:"targetPod"`
+	TargetContainer string `json:"targetContainer,omitempty"`
+	Container       string `json:"container,omitempty"`
+
+	Team       string `json:"team,omitempty"`
+	CostCenter string `json:"costCenter,omitempty"`
+	TicketRef  string `json:"ticketRef,omitempty"`
+
+	// Attachment is the proxy's entry for the connection of SessionAttached and SessionDetached.
+	Attachment *debugv1alpha1.Attachment `json:"attachment,omitempty"`
+
+	// Outcome, Message and the storage keys are set on SessionTerminated.
+	Outcome       string   `json:"outcome,omitempty"`
+	Message       string   `json:"message,omitempty"`
+	TranscriptKey string   `json:"transcriptKey,omitempty"`
+	Recordings    []string `json:"recordings,omitempty"`
+	Artifacts     []string `json:"artifacts,omitempty"`
+}
+
+// NewRecord builds the audit record of event for session. The status message is only carried on
+// SessionTerminated, since the Active message contains the one-time attach token.
+func NewRecord(event string, session *debugv1alpha1.DebugSession) Record {
+	targetNamespace := session.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = session.Namespace
+	}
+	record := Record{
+		Event:           event,
+		Timestamp:       time.Now().UTC(),
+		Namespace:       session.Namespace,
+		Name:            session.Name,
+		UID:             string(session.UID),
+		Phase:           string(session.Status.Phase),
+		RequestedBy:     session.Annotations[debugv1alpha1.RequestedByAnnotation],
+		ApprovedBy:      session.Annotations[debugv1alpha1.ApprovedByKey],
+		TargetNamespace: targetNamespace,
+		TargetPod:       debugv1alpha1.CurrentTargetPod(session),
+		TargetContainer: session.Spec.TargetContainerName,
+		Container:       session.Status.DebuggingContainerName,
+		TicketRef:       session.Spec.TicketRef,
+	}
+	if session.Spec.RequestedBy != nil {
+		record.RequestedBy = session.Spec.RequestedBy.Username
+		record.RequestedByGroups = session.Spec.RequestedBy.Groups
+	}
+	if a := session.Status.CostAttribution; a != nil {
+		record.Team, record.CostCenter = a.Team, a.CostCenter
+	}
+	if event == EventSessionTerminated {
+		record.Outcome = string(session.Status.Phase)
+		record.Message = session.Status.Message
+		record.TranscriptKey = session.Status.LogKey
+		record.Recordings = session.Status.Recordings
+		record.Artifacts = session.Status.Artifacts
+	}
+	return record
+}
+
+// Exporter delivers audit records to a log service.
+type Exporter interface {
+	Export(ctx context.Context, record Record) error
+	Close() error
+}
+
+// NewExporterFromEnv returns the exporter selected by the AUDIT_* environment variables, or nil if
+// no log service is configured.
+//
+//	AUDIT_CLOUDWATCH_LOG_GROUP       CloudWatch Logs group, with the optional AUDIT_CLOUDWATCH_LOG_STREAM
+//	                                 (default "kubedebugsess") and AUDIT_CLOUDWATCH_REGION
+//	AUDIT_CLOUD_LOGGING_PROJECT      Google Cloud project, with the optional AUDIT_CLOUD_LOGGING_LOG_ID
+//	                                 (default "kubedebugsess-audit")
+func NewExporterFromEnv(ctx context.Context) (Exporter, error) {
+	var exporters multiExporter
+
+	if group := os.Getenv("AUDIT_CLOUDWATCH_LOG_GROUP"); group != "" {
+		e, err := newCloudWatchExporter(ctx, group, envOr("AUDIT_CLOUDWATCH_LOG_STREAM", "kubedebugsess"),
+			os.Getenv("AUDIT_CLOUDWATCH_REGION"))
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, e)
+	}
+
+	if project := os.Getenv("AUDIT_CLOUD_LOGGING_PROJECT"); project != "" {
+		e, err := newCloudLoggingExporter(ctx, project, envOr("AUDIT_CLOUD_LOGGING_LOG_ID", "kubedebugsess-audit"))
+		if err != nil {
+			_ = exporters.Close()
+			return nil, err
+		}
+		exporters = append(exporters, e)
+	}
+
+	switch len(exporters) {
+	case 0:
+		return nil, nil
+	case 1:
+		return exporters[0], nil
+	default:
+		return exporters, nil
+	}
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// multiExporter fans a record out to every configured log service.
+type multiExporter []Exporter
+
+func (m multiExporter) Export(ctx context.Context, record Record) error {
+	var errs []string
+	for _, e := range m {
+		if err := e.Export(ctx, record); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("audit export failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (m multiExporter) Close() error {
+	for _, e := range m {
+		_ = e.Close()
+	}
+	return nil
+}
+
+func encode(record Record) ([]byte, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	return data, nil
+}
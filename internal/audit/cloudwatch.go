@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// cloudWatchExporter writes each audit record as one log event to a CloudWatch Logs stream. It speaks
+// the Logs JSON API directly with SigV4-signed requests; credentials come from the default AWS chain,
+// so IRSA and EKS Pod Identity work without key material.
+type cloudWatchExporter struct {
+	cfg      aws.Config
+	endpoint string
+	group    string
+	stream   string
+	client   *http.Client
+	signer   *v4.Signer
+
+	// streamOnce creates the log stream before the first event is put.
+	streamOnce sync.Once
+	streamErr  error
+}
+
+func newCloudWatchExporter(ctx context.Context, group, stream, region string) (*cloudWatchExporter, error) {
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config for CloudWatch Logs: %w", err)
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("AUDIT_CLOUDWATCH_REGION or AWS_REGION must be set for CloudWatch Logs")
+	}
+	return &cloudWatchExporter{
+		cfg:      cfg,
+		endpoint: fmt.Sprintf("https://logs.%s.amazonaws.com/", cfg.Region),
+		group:    group,
+		stream:   stream,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		signer:   v4.NewSigner(),
+	}, nil
+}
+
+func (e *cloudWatchExporter) Export(ctx context.Context, record Record) error {
+	e.streamOnce.Do(func() {
+		err := e.call(ctx, "CreateLogStream", map[string]any{"logGroupName": e.group, "logStreamName": e.stream})
+		if err != nil && !strings.Contains(err.Error(), "ResourceAlreadyExistsException") {
+			e.streamErr = err
+		}
+	})
+	if e.streamErr != nil {
+		return e.streamErr
+	}
+
+	data, err := encode(record)
+	if err != nil {
+		return err
+	}
+	return e.call(ctx, "PutLogEvents", map[string]any{
+		"logGroupName":  e.group,
+		"logStreamName": e.stream,
+		"logEvents": []map[string]any{{
+			"timestamp": record.Timestamp.UnixMilli(),
+			"message":   string(data),
+		}},
+	})
+}
+
+// call invokes a CloudWatch Logs API action.
+func (e *cloudWatchExporter) call(ctx context.Context, action string, input any) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CloudWatch Logs %s request: %w", action, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328."+action)
+
+	creds, err := e.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	if err := e.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "logs", e.cfg.Region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign CloudWatch Logs request: %w", err)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("CloudWatch Logs %s to %s failed: %w", action, e.group, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("CloudWatch Logs %s to %s returned %s: %s", action, e.group, resp.Status, msg)
+	}
+	return nil
+}
+
+func (e *cloudWatchExporter) Close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// lastAppliedAnnotation is written by `kubectl apply` and holds a full copy of the object.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// CacheOptions returns the manager cache options for the given namespaces. The controller watches
+// every pod in them to notice changes to a handful of session targets, so cached pods are trimmed
+// to the fields the phase reconcilers read, and managed fields are dropped from every object.
+func CacheOptions(namespaces map[string]cache.Config) cache.Options {
+	return cache.Options{
+		DefaultNamespaces: namespaces,
+		DefaultTransform:  cache.TransformStripManagedFields(),
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.Pod{}: {Transform: trimPod},
+		},
+	}
+}
+
+// trimPod drops the parts of a pod that make up most of its size and that no phase reconciler
+// reads: managed fields, the last-applied annotation, volumes, and the environment, commands,
+// mounts, probes and lifecycle hooks of its regular and init containers. Ephemeral containers are
+// kept whole, since they are written back when a debugger is injected.
+func trimPod(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	pod.ManagedFields = nil
+	delete(pod.Annotations, lastAppliedAnnotation)
+	pod.Spec.Volumes = nil
+	for _, containers := range [][]corev1.Container{pod.Spec.Containers, pod.Spec.InitContainers} {
+		for i := range containers {
+			c := &containers[i]
+			c.Env, c.EnvFrom, c.VolumeMounts, c.VolumeDevices = nil, nil, nil, nil
+			c.Command, c.Args = nil, nil
+			c.LivenessProbe, c.ReadinessProbe, c.StartupProbe, c.Lifecycle = nil, nil, nil, nil
+		}
+	}
+	return pod, nil
+}
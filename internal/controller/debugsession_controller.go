@@ -21,19 +21,33 @@ import (
 	"fmt"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/audit"
 	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
 	_ "github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases/reconcilers"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	"github.com/OxAN0N/KubeDebugSess/internal/scope"
+	"github.com/OxAN0N/KubeDebugSess/internal/storage"
+	"github.com/OxAN0N/KubeDebugSess/internal/ticket"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
+	"github.com/OxAN0N/KubeDebugSess/internal/vault"
 )
 
 // DebugSessionReconciler reconciles a DebugSession object
@@ -41,10 +55,52 @@ type DebugSessionReconciler struct {
 	client.Client
 	ClientSet        kubernetes.Interface
 	Scheme           *runtime.Scheme
+	Recorder         record.EventRecorder
 	PhaseReconcilers map[debugv1alpha1.SessionPhase]session_phases.PhaseReconciler
+	// Publisher, when set, receives a message for every phase transition.
+	Publisher notify.Publisher
+	// Auditor, when set, exports an audit record when a session is created and when it terminates.
+	Auditor audit.Exporter
+	// SlackThreads, when set, posts the progress of sessions created from Slack in their thread.
+	SlackThreads *notify.SlackThreads
+	// Notifier, when set, routes webhook notifications from the phase reconcilers.
+	Notifier *notify.Dispatcher
+	// Alerter, when set, raises an on-call alert while a session is live in a critical namespace.
+	Alerter *notify.Alerter
+	// Storage archives debugger transcripts; nil disables archival.
+	Storage storage.Storage
+	// Vault issues spec.vaultCredentials; nil rejects sessions that request them.
+	Vault *vault.Client
+	// Tickets checks spec.ticketRef; nil accepts any reference.
+	Tickets *ticket.Checker
+	// LogURLExpiry is the lifetime of presigned transcript URLs; zero uses storage.DefaultPresignExpiry.
+	LogURLExpiry time.Duration
+	// KeyLayout renders the storage keys of archived objects; nil uses storage.DefaultKeyTemplate.
+	KeyLayout *storage.KeyLayout
+	// Scope limits the namespaces whose DebugSessions are reconciled; nil reconciles all of them.
+	Scope *scope.Namespaces
+	// MaxConcurrentReconciles is the number of sessions reconciled in parallel; zero means one.
+	MaxConcurrentReconciles int
+	// RateLimiter paces requeues of failing reconciles; nil uses the controller-runtime default.
+	RateLimiter workqueue.TypedRateLimiter[reconcile.Request]
+	// Requeue holds the phase polling intervals; zero fields use session_phases.DefaultRequeueIntervals.
+	Requeue session_phases.RequeueIntervals
+	// AccessCheck is the permission requesters must hold on the target pod; empty skips the check.
+	AccessCheck session_phases.AccessCheck
+	// DebuggerLimit caps the running debuggers per target pod.
+	DebuggerLimit session_phases.DebuggerLimit
+	// ReasonActions maps debugger container reasons to actions; nil uses the built-in maps.
+	ReasonActions *session_phases.ReasonActions
 }
 
-const targetPodIndexKey = "targetPodIndexKey"
+// NewRateLimiter returns a rate limiter that backs off failing sessions exponentially from baseDelay to
+// maxDelay, and caps the overall requeue rate at qps with the given burst.
+func NewRateLimiter(baseDelay, maxDelay time.Duration, qps float64, burst int) workqueue.TypedRateLimiter[reconcile.Request] {
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](baseDelay, maxDelay),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
+}
 
 // +kubebuilder:rbac:groups=ajou.oxan0n.me,resources=debugsessions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=ajou.oxan0n.me,resources=debugsessions/status,verbs=get;update;patch
@@ -53,8 +109,20 @@ const targetPodIndexKey = "targetPodIndexKey"
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=pods/ephemeralcontainers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=pods/log,verbs=get;list;watch
-// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;delete
 // +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=replicasets;deployments,verbs=get
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;create;patch
+// +kubebuilder:rbac:groups=ajou.oxan0n.me,resources=notificationconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=ajou.oxan0n.me,resources=bastionconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=ajou.oxan0n.me,resources=debugsessionrecords,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;create;update;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups="",resources=serviceaccounts/token,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;delete
 func (r *DebugSessionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
@@ -64,13 +132,228 @@ func (r *DebugSessionReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, nil
 	}
 
+	// A deleted session is archived once more before the object goes away. This comes before the scope
+	// check, so that a session whose namespace left the scope can still be deleted.
+	if !debugSession.DeletionTimestamp.IsZero() {
+		return r.finalizeSession(ctx, &debugSession)
+	}
+
+	// The cache already drops allowlisted-out namespaces; a namespace selector is checked here.
+	if allowed, err := r.Scope.Allows(ctx, r.Client, debugSession.Namespace); err != nil || !allowed {
+		return ctrl.Result{}, err
+	}
+	if err := r.syncSessionMetadata(ctx, &debugSession); err != nil {
+		return ctrl.Result{}, err
+	}
+	if updated, err := r.handleAuthFailures(ctx, &debugSession); updated || err != nil {
+		return ctrl.Result{}, err
+	}
+	if updated, err := r.closeLostAttachments(ctx, &debugSession); updated || err != nil {
+		return ctrl.Result{}, err
+	}
+
 	reconciler, ok := r.PhaseReconcilers[debugSession.Status.Phase]
 	if !ok {
 		logger.Info("Reconciling DebugSession")
-		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		return ctrl.Result{RequeueAfter: r.Requeue.UnknownPhase}, nil
+	}
+
+	ctx, err := r.sessionTraceContext(ctx, &debugSession)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	previousPhase := debugSession.Status.Phase
+	phaseName := string(previousPhase)
+	if phaseName == "" {
+		phaseName = "New"
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "Reconcile "+phaseName, trace.WithAttributes(
+		append(tracing.SessionAttributes(&debugSession), tracing.AttrSessionPhase.String(phaseName))...))
+	defer span.End()
+
+	// Any status write made while handling this generation reports it as observed.
+	debugSession.Status.ObservedGeneration = debugSession.Generation
+
+	result, err := reconciler.Reconcile(ctx, &debugSession)
+	if apierrors.IsConflict(err) {
+		// The session changed since it was read, e.g. by a previous leader or the proxy. Every phase
+		// reconciler re-derives its work from the stored status, so retrying against a fresh copy is safe.
+		logger.V(1).Info("DebugSession was modified concurrently, requeueing")
+		return ctrl.Result{RequeueAfter: time.Second}, nil
+	}
+	tracing.RecordError(span, err)
+	if err == nil && debugSession.Status.Phase != previousPhase {
+		span.AddEvent("PhaseChanged", trace.WithAttributes(tracing.AttrSessionPhase.String(string(debugSession.Status.Phase))))
+		r.recordPhaseTransition(&debugSession, previousPhase)
+		r.publishPhaseTransition(ctx, &debugSession, previousPhase)
+		r.auditPhaseTransition(ctx, &debugSession, previousPhase)
+		r.threadPhaseTransition(ctx, &debugSession, previousPhase)
+		r.alertPhaseTransition(ctx, &debugSession, previousPhase)
+	}
+	return result, err
+}
+
+// syncSessionMetadata adds the archive finalizer and keeps the proxy lookup labels in step with the
+// session's status, in a single update.
+func (r *DebugSessionReconciler) syncSessionMetadata(ctx context.Context, session *debugv1alpha1.DebugSession) error {
+	changed := r.addArchiveFinalizer(session)
+	labels := map[string]string{
+		debugv1alpha1.SessionUIDLabel:        string(session.UID),
+		debugv1alpha1.DebuggerContainerLabel: session.Status.DebuggingContainerName,
+	}
+	for key, value := range labels {
+		if current, ok := session.Labels[key]; value == "" && ok {
+			delete(session.Labels, key)
+			changed = true
+		} else if value != "" && current != value {
+			if session.Labels == nil {
+				session.Labels = map[string]string{}
+			}
+			session.Labels[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := r.Update(ctx, session); err != nil {
+		return fmt.Errorf("failed to update session metadata: %w", err)
+	}
+	return nil
+}
+
+// sessionTraceContext parents ctx to the session's root span. The root span is created on first sight of
+// the session and its context persisted as an annotation, so that every later reconcile and proxy attach
+// joins the same trace.
+func (r *DebugSessionReconciler) sessionTraceContext(ctx context.Context, session *debugv1alpha1.DebugSession) (context.Context, error) {
+	if _, ok := session.Annotations[tracing.TraceParentAnnotation]; ok {
+		return tracing.ContextWithSessionTrace(ctx, session), nil
+	}
+
+	rootCtx, root := tracing.Tracer().Start(ctx, "DebugSession", trace.WithAttributes(tracing.SessionAttributes(session)...))
+	defer root.End()
+
+	traceParent := tracing.TraceParent(rootCtx)
+	if traceParent == "" {
+		// Tracing is disabled; nothing to persist.
+		return ctx, nil
+	}
+
+	patch := client.MergeFrom(session.DeepCopy())
+	if session.Annotations == nil {
+		session.Annotations = map[string]string{}
+	}
+	session.Annotations[tracing.TraceParentAnnotation] = traceParent
+	if err := r.Patch(ctx, session, patch); err != nil {
+		return ctx, fmt.Errorf("failed to record trace context on session: %w", err)
 	}
+	return rootCtx, nil
+}
+
+// recordPhaseTransition emits an Event for a completed phase transition so it shows up in `kubectl describe`.
+// The status message is only included for failures, since the Active message carries the session token.
+func (r *DebugSessionReconciler) recordPhaseTransition(session *debugv1alpha1.DebugSession, from debugv1alpha1.SessionPhase) {
+	if session.Status.Phase == debugv1alpha1.Failed {
+		r.Recorder.Eventf(session, corev1.EventTypeWarning, session_phases.EventReasonSessionFailed,
+			"Session failed in phase %s: %s", from, session.Status.Message)
+		return
+	}
+	if from == "" {
+		from = "New"
+	}
+	r.Recorder.Eventf(session, corev1.EventTypeNormal, session_phases.EventReasonPhaseChanged,
+		"Session moved from %s to %s", from, session.Status.Phase)
+}
+
+// publishPhaseTransition sends the transition to the configured message bus. Delivery failures are logged
+// and never block the session's progress.
+func (r *DebugSessionReconciler) publishPhaseTransition(ctx context.Context, session *debugv1alpha1.DebugSession, from debugv1alpha1.SessionPhase) {
+	if r.Publisher == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := r.Publisher.Publish(ctx, notify.NewMessage(session_phases.EventReasonPhaseChanged, session, from)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to publish session lifecycle message")
+	}
+}
+
+// auditPhaseTransition exports SessionCreated when a new session enters its first phase and
+// SessionTerminated when it completes or fails. Export failures are logged and never block the session.
+func (r *DebugSessionReconciler) auditPhaseTransition(ctx context.Context, session *debugv1alpha1.DebugSession, from debugv1alpha1.SessionPhase) {
+	if r.Auditor == nil {
+		return
+	}
+	var event string
+	switch phase := session.Status.Phase; {
+	case from == "":
+		event = audit.EventSessionCreated
+	case phase == debugv1alpha1.Completed || phase == debugv1alpha1.Failed:
+		event = audit.EventSessionTerminated
+	default:
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := r.Auditor.Export(ctx, audit.NewRecord(event, session)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to export session audit record", "event", event)
+	}
+}
 
-	return reconciler.Reconcile(ctx, &debugSession)
+// threadPhaseTransition posts the transition in the Slack thread of a session created from Slack.
+// Delivery failures are logged and never block the session's progress.
+func (r *DebugSessionReconciler) threadPhaseTransition(ctx context.Context, session *debugv1alpha1.DebugSession, from debugv1alpha1.SessionPhase) {
+	if r.SlackThreads == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := r.SlackThreads.PostPhase(ctx, session, from); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to post session progress to Slack")
+	}
+}
+
+// alertPhaseTransition opens the on-call alert when a session against an alerting namespace becomes
+// Active, and resolves it once the session has ended.
+func (r *DebugSessionReconciler) alertPhaseTransition(ctx context.Context, session *debugv1alpha1.DebugSession, from debugv1alpha1.SessionPhase) {
+	if r.Alerter == nil {
+		return
+	}
+	phase := session.Status.Phase
+	trigger := phase == debugv1alpha1.Active && from == debugv1alpha1.Injecting
+	// Only sessions that went Active (and so got a StartTime) can have an open alert.
+	resolve := (phase == debugv1alpha1.Completed || phase == debugv1alpha1.Failed) && session.Status.StartTime != nil
+	if !trigger && !resolve {
+		return
+	}
+
+	logger := log.FromContext(ctx)
+	// The phase reconcilers default an empty targetNamespace in memory only; not every path does.
+	targetNamespace := session.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = session.Namespace
+	}
+	var ns corev1.Namespace
+	if err := r.Get(ctx, types.NamespacedName{Name: targetNamespace}, &ns); err != nil {
+		logger.Error(err, "Failed to get target namespace for alerting")
+		return
+	}
+	if !r.Alerter.Applies(&ns) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	msg := notify.NewMessage(session_phases.EventReasonPhaseChanged, session, from)
+	if trigger {
+		if err := r.Alerter.Trigger(ctx, msg); err != nil {
+			logger.Error(err, "Failed to trigger on-call alert")
+		}
+		return
+	}
+	if err := r.Alerter.Resolve(ctx, msg); err != nil {
+		logger.Error(err, "Failed to resolve on-call alert")
+	}
 }
 
 func (r *DebugSessionReconciler) findSessionsForPod(ctx context.Context, pod client.Object) []reconcile.Request {
@@ -78,13 +361,17 @@ func (r *DebugSessionReconciler) findSessionsForPod(ctx context.Context, pod cli
 	attachedSessions := &debugv1alpha1.DebugSessionList{}
 	podKey := fmt.Sprintf("%s/%s", pod.GetNamespace(), pod.GetName())
 
-	if err := r.List(ctx, attachedSessions, client.MatchingFields{targetPodIndexKey: podKey}); err != nil {
+	if err := r.List(ctx, attachedSessions, client.MatchingFields{session_phases.TargetPodIndexKey: podKey}); err != nil {
 		logger.Error(err, "failed to list attached debug sessions using index", "podKey", podKey)
 		return []reconcile.Request{}
 	}
 
+	// Most pods are no session's target; they are only looked up in the index.
+	if len(attachedSessions.Items) == 0 {
+		return nil
+	}
 	requests := make([]reconcile.Request, len(attachedSessions.Items))
-	logger.Info("Found attached sessions for pod", "count", len(requests), "pod", pod.GetName())
+	logger.V(1).Info("Found attached sessions for pod", "count", len(requests), "pod", pod.GetName())
 
 	for i, item := range attachedSessions.Items {
 		requests[i] = reconcile.Request{
@@ -99,24 +386,49 @@ func (r *DebugSessionReconciler) findSessionsForPod(ctx context.Context, pod cli
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DebugSessionReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	r.PhaseReconcilers = session_phases.GetReconcilers(mgr.GetClient(), r.ClientSet)
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("debugsession-controller")
+	}
+	r.Requeue = r.Requeue.WithDefaults()
+	r.PhaseReconcilers = session_phases.GetReconcilers(session_phases.Dependencies{
+		Client:        mgr.GetClient(),
+		ClientSet:     r.ClientSet,
+		RESTConfig:    mgr.GetConfig(),
+		Recorder:      r.Recorder,
+		Notifier:      r.Notifier,
+		Storage:       r.Storage,
+		LogURLExpiry:  r.LogURLExpiry,
+		KeyLayout:     r.KeyLayout,
+		Scope:         r.Scope,
+		Requeue:       r.Requeue,
+		AccessCheck:   r.AccessCheck,
+		DebuggerLimit: r.DebuggerLimit,
+		ReasonActions: r.ReasonActions,
+		Vault:         r.Vault,
+		Tickets:       r.Tickets,
+	})
 
-	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &debugv1alpha1.DebugSession{}, targetPodIndexKey, func(rawObj client.Object) []string {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &debugv1alpha1.DebugSession{}, session_phases.TargetPodIndexKey, func(rawObj client.Object) []string {
 		session := rawObj.(*debugv1alpha1.DebugSession)
 		targetNamespace := session.Spec.TargetNamespace
 		if targetNamespace == "" {
 			targetNamespace = session.Namespace
 		}
-		return []string{fmt.Sprintf("%s/%s", targetNamespace, session.Spec.TargetPodName)}
+		return []string{fmt.Sprintf("%s/%s", targetNamespace, debugv1alpha1.CurrentTargetPod(session))}
 	}); err != nil {
 		return err
 	}
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&debugv1alpha1.DebugSession{}).
+		WithOptions(crcontroller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter:             r.RateLimiter,
+		}).
 		Watches(
 			&corev1.Pod{},
 			handler.EnqueueRequestsFromMapFunc(r.findSessionsForPod),
+			builder.WithPredicates(podChangePredicate()),
 		).
 		Complete(r)
 }
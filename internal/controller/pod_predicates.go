@@ -0,0 +1,71 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// podChangePredicate only passes the pod events the phase reconcilers act on: creation and deletion
+// of a pod, which may be a session's (re)created target, and updates that change what they read,
+// such as the phase, node, readiness, deletion, and the state of the ephemeral containers. Label,
+// annotation and resourceVersion-only updates, which make up most pod churn, are dropped.
+func podChangePredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod, ok := e.ObjectOld.(*corev1.Pod)
+			if !ok {
+				return true
+			}
+			newPod, ok := e.ObjectNew.(*corev1.Pod)
+			if !ok {
+				return true
+			}
+			return podChanged(oldPod, newPod)
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// podChanged reports whether an update changes any pod field a phase reconciler reads.
+func podChanged(oldPod, newPod *corev1.Pod) bool {
+	switch {
+	case oldPod.DeletionTimestamp.IsZero() != newPod.DeletionTimestamp.IsZero(),
+		oldPod.Spec.NodeName != newPod.Spec.NodeName,
+		oldPod.Status.Phase != newPod.Status.Phase,
+		oldPod.Status.Reason != newPod.Status.Reason,
+		len(oldPod.Spec.EphemeralContainers) != len(newPod.Spec.EphemeralContainers):
+		return true
+	}
+	return !equality.Semantic.DeepEqual(oldPod.Status.EphemeralContainerStatuses, newPod.Status.EphemeralContainerStatuses) ||
+		!containerStatesEqual(oldPod.Status.ContainerStatuses, newPod.Status.ContainerStatuses) ||
+		podReadyStatus(oldPod) != podReadyStatus(newPod)
+}
+
+// containerStatesEqual compares the state and readiness of the regular containers, ignoring the
+// restart counters and probe timestamps that change on their own.
+func containerStatesEqual(a, b []corev1.ContainerStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Ready != b[i].Ready ||
+			(a[i].State.Running == nil) != (b[i].State.Running == nil) ||
+			(a[i].State.Terminated == nil) != (b[i].State.Terminated == nil) {
+			return false
+		}
+	}
+	return true
+}
+
+func podReadyStatus(pod *corev1.Pod) corev1.ConditionStatus {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status
+		}
+	}
+	return corev1.ConditionUnknown
+}
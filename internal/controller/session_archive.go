@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/storage"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// sessionArchiveFinalizer holds a deleted DebugSession until its final state is archived, so that
+// deleting the object never deletes the historical record. It is only added when storage is configured.
+const sessionArchiveFinalizer = "ajou.oxan0n.me/session-archive"
+
+// sessionArchiveSuffix is appended to the archived session's key, next to its transcript.
+const sessionArchiveSuffix = ".debugsession.json"
+
+// addArchiveFinalizer adds the archive finalizer to a live session when storage is configured, and
+// reports whether it did.
+func (r *DebugSessionReconciler) addArchiveFinalizer(session *debugv1alpha1.DebugSession) bool {
+	if r.Storage == nil {
+		return false
+	}
+	return controllerutil.AddFinalizer(session, sessionArchiveFinalizer)
+}
+
+// finalizeSession uploads the deleted session's final spec and status to storage and releases it.
+// Under ArchivePolicy Required a failed upload keeps the session until it succeeds, with the
+// controller's backoff, or until the session is annotated with debugv1alpha1.SkipArchiveKey; otherwise
+// the failure is reported in an Event and the session is released anyway.
+func (r *DebugSessionReconciler) finalizeSession(ctx context.Context, session *debugv1alpha1.DebugSession) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(session, sessionArchiveFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	logger := log.FromContext(ctx)
+
+	if r.Storage != nil {
+		key, err := r.archiveSession(ctx, session)
+		if err != nil {
+			r.Recorder.Eventf(session, corev1.EventTypeWarning, session_phases.EventReasonSessionArchiveFailed,
+				"Failed to archive the session before deletion: %v", err)
+			_, skip := session.Annotations[debugv1alpha1.SkipArchiveKey]
+			if session.Spec.ArchivePolicy != debugv1alpha1.ArchivePolicyBestEffort && !skip {
+				return ctrl.Result{}, err
+			}
+			logger.Error(err, "Releasing the deleted session without an archive", "archivePolicy", session.Spec.ArchivePolicy)
+		} else {
+			logger.Info("Archived the deleted session", "key", key)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(session, sessionArchiveFinalizer)
+	if err := r.Update(ctx, session); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to remove archive finalizer: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// archiveSession uploads the session as JSON, next to its transcript if it has one, and returns the key.
+func (r *DebugSessionReconciler) archiveSession(ctx context.Context, session *debugv1alpha1.DebugSession) (string, error) {
+	archived := session.DeepCopy()
+	archived.ManagedFields = nil
+	archived.APIVersion = debugv1alpha1.GroupVersion.String()
+	archived.Kind = "DebugSession"
+	data, err := json.MarshalIndent(archived, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	info := r.sessionObjectInfo(session)
+	key, ok := transcriptBaseKey(session)
+	if !ok {
+		if key, err = r.KeyLayout.Key(info); err != nil {
+			return "", err
+		}
+	}
+	key += sessionArchiveSuffix
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := r.Storage.Put(ctx, key, bytes.NewReader(data), info.Tags()); err != nil {
+		return "", fmt.Errorf("failed to upload session: %w", err)
+	}
+	return key, nil
+}
+
+// transcriptBaseKey returns the key layout's rendering for the session's transcript, i.e. its key
+// without the extension the log archiver appended. The layout cannot be rendered again instead, since
+// it may include the time the transcript was archived.
+func transcriptBaseKey(session *debugv1alpha1.DebugSession) (string, bool) {
+	for _, format := range []debugv1alpha1.ArchiveFormat{debugv1alpha1.ArchiveFormatLog, debugv1alpha1.ArchiveFormatBundle} {
+		if base, ok := strings.CutSuffix(session.Status.LogKey, "."+format.Extension()); ok && base != "" {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+// sessionObjectInfo describes the archived session for its key and tags. Sessions that never got a
+// debugger container are keyed by their own name.
+func (r *DebugSessionReconciler) sessionObjectInfo(session *debugv1alpha1.DebugSession) storage.ObjectInfo {
+	now := time.Now()
+	targetNamespace := session.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = session.Namespace
+	}
+	container := session.Status.DebuggingContainerName
+	if container == "" {
+		container = session.Name
+	}
+	info := storage.ObjectInfo{
+		Namespace:        targetNamespace,
+		SessionNamespace: session.Namespace,
+		Session:          session.Name,
+		UID:              string(session.UID),
+		Pod:              debugv1alpha1.CurrentTargetPod(session),
+		Container:        container,
+		Ticket:           session.Spec.TicketRef,
+		Time:             now,
+		Timestamp:        now.Unix(),
+	}
+	if session.Spec.RequestedBy != nil {
+		info.User = session.Spec.RequestedBy.Username
+	} else {
+		info.User = session.Annotations[debugv1alpha1.RequestedByAnnotation]
+	}
+	if attribution := session.Status.CostAttribution; attribution != nil {
+		info.Team, info.CostCenter = attribution.Team, attribution.CostCenter
+	}
+	return info
+}
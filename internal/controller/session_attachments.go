@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// closeLostAttachments closes the open entries in status.attachments whose debug proxy stopped
+// refreshing their heartbeat, e.g. because it crashed or was rolled out mid-connection, and recounts
+// status.connectedClients from the entries left open. Lost connections count as detached at their
+// last heartbeat, so spec.detachGracePeriodSeconds and spec.terminateOnDetach still apply to them.
+// It reports whether it wrote the session.
+func (r *DebugSessionReconciler) closeLostAttachments(ctx context.Context, session *debugv1alpha1.DebugSession) (bool, error) {
+	now := time.Now()
+	var lost []string
+	var lastSeen time.Time
+	for i := range session.Status.Attachments {
+		attachment := &session.Status.Attachments[i]
+		if attachment.DetachTime != nil || now.Before(attachment.StaleAt()) {
+			continue
+		}
+		heartbeat := metav1.NewTime(attachment.LastHeartbeat())
+		attachment.DetachTime = &heartbeat
+		lost = append(lost, fmt.Sprintf("%s via %s", attachment.SourceIP, attachment.Proxy))
+		if heartbeat.After(lastSeen) {
+			lastSeen = heartbeat.Time
+		}
+	}
+	connected := debugv1alpha1.OpenAttachments(&session.Status)
+	if len(lost) == 0 && connected == session.Status.ConnectedClients {
+		return false, nil
+	}
+
+	wasAttached := session.Status.ConnectedClients > 0
+	session.Status.ConnectedClients = connected
+	if len(lost) > 0 {
+		message := fmt.Sprintf("The debug proxy stopped reporting %d connection(s): %s", len(lost), strings.Join(lost, ", "))
+		log.FromContext(ctx).Info("Closing lost attach connections", "connections", lost)
+		r.Recorder.Event(session, corev1.EventTypeWarning, session_phases.EventReasonAttachmentsLost, message)
+	}
+	if connected == 0 && wasAttached {
+		if lastSeen.IsZero() {
+			lastSeen = now
+		}
+		session.Status.LastDetachTime = &metav1.Time{Time: lastSeen}
+		session_phases.SetCondition(session, debugv1alpha1.ConditionAttached, metav1.ConditionFalse, "ConnectionsLost",
+			"The debug proxy stopped reporting the attached clients")
+	}
+	return true, r.Status().Update(ctx, session)
+}
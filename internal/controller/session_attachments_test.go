@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCloseLostAttachments(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = debugv1alpha1.AddToScheme(scheme)
+
+	now := time.Now()
+	ago := func(d time.Duration) *metav1.Time { return &metav1.Time{Time: now.Add(-d)} }
+	open := func(id string, heartbeat *metav1.Time) debugv1alpha1.Attachment {
+		return debugv1alpha1.Attachment{ID: id, Proxy: "proxy-0", AttachTime: *ago(time.Hour), HeartbeatTime: heartbeat}
+	}
+	closed := debugv1alpha1.Attachment{ID: "closed", AttachTime: *ago(time.Hour), DetachTime: ago(30 * time.Minute)}
+
+	tests := []struct {
+		name          string
+		connected     int32
+		attachments   []debugv1alpha1.Attachment
+		wantUpdated   bool
+		wantConnected int32
+		wantDetached  *metav1.Time
+	}{
+		{name: "no attachments"},
+		{name: "live connection", connected: 1, attachments: []debugv1alpha1.Attachment{open("a", ago(10*time.Second))}, wantConnected: 1},
+		{
+			name:         "lost connection",
+			connected:    1,
+			attachments:  []debugv1alpha1.Attachment{closed, open("a", ago(5*time.Minute))},
+			wantUpdated:  true,
+			wantDetached: ago(5 * time.Minute),
+		},
+		{
+			name:         "lost connection without heartbeat",
+			connected:    1,
+			attachments:  []debugv1alpha1.Attachment{open("a", nil)},
+			wantUpdated:  true,
+			wantDetached: ago(time.Hour),
+		},
+		{
+			name:          "one of two lost",
+			connected:     2,
+			attachments:   []debugv1alpha1.Attachment{open("a", ago(5*time.Minute)), open("b", ago(10*time.Second))},
+			wantUpdated:   true,
+			wantConnected: 1,
+		},
+		{
+			name:         "count without open connections",
+			connected:    2,
+			attachments:  []debugv1alpha1.Attachment{closed},
+			wantUpdated:  true,
+			wantDetached: &metav1.Time{Time: now},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &debugv1alpha1.DebugSession{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "s1"},
+				Status:     debugv1alpha1.DebugSessionStatus{ConnectedClients: tt.connected, Attachments: tt.attachments},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(session).WithStatusSubresource(session).Build()
+			r := &DebugSessionReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+			updated, err := r.closeLostAttachments(context.Background(), session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated != tt.wantUpdated {
+				t.Errorf("closeLostAttachments() updated = %v, want %v", updated, tt.wantUpdated)
+			}
+			var got debugv1alpha1.DebugSession
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(session), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.ConnectedClients != tt.wantConnected {
+				t.Errorf("connectedClients = %d, want %d", got.Status.ConnectedClients, tt.wantConnected)
+			}
+			if debugv1alpha1.OpenAttachments(&got.Status) != tt.wantConnected {
+				t.Errorf("open attachments = %d, want %d", debugv1alpha1.OpenAttachments(&got.Status), tt.wantConnected)
+			}
+			detached := got.Status.LastDetachTime
+			switch {
+			case tt.wantDetached == nil && detached != nil:
+				t.Errorf("lastDetachTime = %v, want unset", detached)
+			case tt.wantDetached != nil && (detached == nil || tt.wantDetached.Sub(detached.Time).Abs() > 2*time.Second):
+				t.Errorf("lastDetachTime = %v, want %v", detached, tt.wantDetached)
+			}
+			if tt.wantDetached != nil && !meta.IsStatusConditionFalse(got.Status.Conditions, debugv1alpha1.ConditionAttached) {
+				t.Errorf("Attached condition is not False after the last client was lost")
+			}
+		})
+	}
+}
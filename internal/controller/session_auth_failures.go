@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// handleAuthFailures acts on the invalid-token attempts the debug proxy counted in the status: it
+// unlocks a session annotated with debug.ajou.oxan0n.me/unlock, and once the attempts reach the
+// threshold of spec.authFailurePolicy it raises the alarm a single time and locks the session if the
+// policy says so. The proxy counts attempts in every phase, so this runs before the phase reconcilers.
+// It reports whether it wrote the session.
+func (r *DebugSessionReconciler) handleAuthFailures(ctx context.Context, session *debugv1alpha1.DebugSession) (bool, error) {
+	if _, ok := session.Annotations[debugv1alpha1.UnlockKey]; ok {
+		return true, r.unlockSession(ctx, session)
+	}
+
+	policy := session.Spec.AuthFailurePolicy
+	if policy == nil || session.Status.FailedAuthAttempts < policy.Threshold ||
+		meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionAuthFailuresExceeded) {
+		return false, nil
+	}
+
+	message := fmt.Sprintf("%d attach attempts with an invalid token", session.Status.FailedAuthAttempts)
+	if last := session.Status.LastFailedAuthTime; last != nil {
+		message += ", the last at " + last.UTC().Format(time.RFC3339)
+	}
+	log.FromContext(ctx).Info("Authentication failure threshold reached", "attempts", session.Status.FailedAuthAttempts)
+	session_phases.SetCondition(session, debugv1alpha1.ConditionAuthFailuresExceeded, metav1.ConditionTrue, "ThresholdReached", message)
+	r.Recorder.Event(session, corev1.EventTypeWarning, session_phases.EventReasonAuthFailuresExceeded, message)
+	if policy.Lock {
+		session_phases.SetCondition(session, debugv1alpha1.ConditionLocked, metav1.ConditionTrue, "AuthFailuresExceeded",
+			"Locked after repeated invalid tokens; annotate with "+debugv1alpha1.UnlockKey+" to unlock")
+		r.Recorder.Event(session, corev1.EventTypeWarning, session_phases.EventReasonSessionLocked,
+			"The debug proxy refuses connections until the session is unlocked")
+	}
+	msg := notify.NewMessage(notify.EventAuthFailures, session, "")
+	msg.Message = message
+	session_phases.NotifySession(ctx, r.Notifier, session, msg)
+	return true, r.Status().Update(ctx, session)
+}
+
+// unlockSession clears the lock and the failure count and issues a new token, since the old one may
+// have been guessed or leaked. On a session that is not locked the annotation is only removed, so that
+// it cannot be used to reset the count of invalid attempts.
+func (r *DebugSessionReconciler) unlockSession(ctx context.Context, session *debugv1alpha1.DebugSession) error {
+	// The annotation goes first, so that a failed status write cannot unlock the session twice.
+	base := session.DeepCopy()
+	delete(session.Annotations, debugv1alpha1.UnlockKey)
+	if err := r.Patch(ctx, session, client.MergeFrom(base)); err != nil {
+		return err
+	}
+	if !debugv1alpha1.IsLocked(session) {
+		log.FromContext(ctx).Info("Ignoring unlock annotation on a session that is not locked")
+		return nil
+	}
+
+	token, err := session_phases.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+	if old := session.Status.OneTimeToken; old != "" {
+		session.Status.Message = strings.ReplaceAll(session.Status.Message, old, token)
+	}
+	session.Status.OneTimeToken = token
+	session.Status.FailedAuthAttempts = 0
+	meta.RemoveStatusCondition(&session.Status.Conditions, debugv1alpha1.ConditionAuthFailuresExceeded)
+	session_phases.SetCondition(session, debugv1alpha1.ConditionLocked, metav1.ConditionFalse, "Unlocked",
+		"Unlocked with "+debugv1alpha1.UnlockKey)
+	if err := r.Status().Update(ctx, session); err != nil {
+		return err
+	}
+	r.Recorder.Event(session, corev1.EventTypeNormal, session_phases.EventReasonSessionUnlocked,
+		"Session unlocked and issued a new token")
+	return nil
+}
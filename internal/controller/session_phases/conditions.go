@@ -0,0 +1,19 @@
+package session_phases
+
+import (
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SetCondition records a condition on the session status, stamping the observed generation.
+// It returns true if the condition changed and the status needs to be written.
+func SetCondition(session *debugv1alpha1.DebugSession, conditionType string, status metav1.ConditionStatus, reason, message string) bool {
+	return meta.SetStatusCondition(&session.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: session.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
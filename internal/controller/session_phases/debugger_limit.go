@@ -0,0 +1,74 @@
+package session_phases
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TargetPodIndexKey indexes DebugSessions by the "namespace/name" of their target pod.
+const TargetPodIndexKey = "targetPodIndexKey"
+
+// DebuggerLimitAction decides what happens to a session whose target pod already runs the maximum
+// number of debuggers.
+type DebuggerLimitAction string
+
+const (
+	// DebuggerLimitQueue keeps the session Pending until one of the running debuggers exits.
+	DebuggerLimitQueue DebuggerLimitAction = "Queue"
+	// DebuggerLimitReject fails the session.
+	DebuggerLimitReject DebuggerLimitAction = "Reject"
+)
+
+// DebuggerLimit caps the debugger ephemeral containers running at once on a single pod. Ephemeral
+// containers cannot be removed, so every session leaves one behind; the cap keeps live ones from
+// piling up.
+type DebuggerLimit struct {
+	// MaxPerPod is the default cap; 0 means no limit. Namespaces override it with the
+	// debugv1alpha1.MaxDebuggersPerPodKey annotation.
+	MaxPerPod int
+	// Action is applied to sessions over the cap.
+	Action DebuggerLimitAction
+}
+
+// ParseDebuggerLimitAction validates a --debugger-limit-action value.
+func ParseDebuggerLimitAction(s string) (DebuggerLimitAction, error) {
+	switch action := DebuggerLimitAction(s); action {
+	case DebuggerLimitQueue, DebuggerLimitReject:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unsupported debugger limit action %q (want Queue or Reject)", s)
+	}
+}
+
+// MaxFor returns the cap that applies to pods in namespace.
+func (l DebuggerLimit) MaxFor(namespace *corev1.Namespace) (int, error) {
+	value, ok := namespace.Annotations[debugv1alpha1.MaxDebuggersPerPodKey]
+	if !ok {
+		return l.MaxPerPod, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid %s annotation %q on namespace %s", debugv1alpha1.MaxDebuggersPerPodKey, value, namespace.Name)
+	}
+	return limit, nil
+}
+
+// RunningDebuggers counts the debugger containers on pod that have not terminated, other than the
+// one named exclude.
+func RunningDebuggers(pod *corev1.Pod, exclude string) int {
+	terminated := make(map[string]bool, len(pod.Status.EphemeralContainerStatuses))
+	for _, cs := range pod.Status.EphemeralContainerStatuses {
+		terminated[cs.Name] = cs.State.Terminated != nil
+	}
+	count := 0
+	for _, ec := range pod.Spec.EphemeralContainers {
+		if strings.HasPrefix(ec.Name, "debugger-") && ec.Name != exclude && !terminated[ec.Name] {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,30 @@
+package session_phases
+
+// Reasons used for the Kubernetes Events emitted on DebugSessions and their target Pods.
+const (
+	EventReasonPhaseChanged            = "PhaseChanged"
+	EventReasonSessionFailed           = "SessionFailed"
+	EventReasonSessionRetried          = "SessionRetried"
+	EventReasonDebuggerInjected        = "DebuggerInjected"
+	EventReasonDebuggerReused          = "DebuggerReused"
+	EventReasonSecurityContextAdjusted = "SecurityContextAdjusted"
+	EventReasonDebuggerReady           = "DebuggerReady"
+	EventReasonTargetPodLost           = "TargetPodLost"
+	EventReasonTargetPodReplaced       = "TargetPodReplaced"
+	EventReasonDebuggerStopped         = "DebuggerStopped"
+	EventReasonDebuggerStopFailed      = "DebuggerStopFailed"
+	EventReasonDebuggerRetained        = "DebuggerRetained"
+	EventReasonPodRecreated            = "PodRecreated"
+	EventReasonPodRecreateSkipped      = "PodRecreateSkipped"
+	EventReasonTranscriptSaved         = "TranscriptArchived"
+	EventReasonArchiveFailed           = "TranscriptArchiveFailed"
+	EventReasonArtifactsSaved          = "ArtifactsArchived"
+	EventReasonCaptureFailed           = "CaptureArchiveFailed"
+	EventReasonSessionTerminated       = "SessionTerminated"
+	EventReasonSessionArchiveFailed    = "SessionArchiveFailed"
+	EventReasonAuthFailuresExceeded    = "AuthFailuresExceeded"
+	EventReasonSessionLocked           = "SessionLocked"
+	EventReasonSessionUnlocked         = "SessionUnlocked"
+	EventReasonEgressNotRestricted     = "EgressNotRestricted"
+	EventReasonAttachmentsLost         = "AttachmentsLost"
+)
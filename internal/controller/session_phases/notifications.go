@@ -0,0 +1,33 @@
+package session_phases
+
+import (
+	"context"
+	"fmt"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// NotifySession delivers msg to the webhook destinations routed for the session and records the outcome
+// in the Notified condition, which the caller persists with its next status write. It returns false when
+// nothing was sent because notifications are disabled (nil notifier) or no destination takes the event.
+func NotifySession(ctx context.Context, notifier *notify.Dispatcher, session *debugv1alpha1.DebugSession, msg notify.Message) bool {
+	if notifier == nil {
+		return false
+	}
+	attempted, err := notifier.Dispatch(ctx, session, msg)
+	if attempted == 0 {
+		return false
+	}
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to deliver webhook notification", "event", msg.Event)
+		SetCondition(session, debugv1alpha1.ConditionNotified, metav1.ConditionFalse, "DeliveryFailed",
+			fmt.Sprintf("%s: %v", msg.Event, err))
+		return true
+	}
+	SetCondition(session, debugv1alpha1.ConditionNotified, metav1.ConditionTrue, "Delivered",
+		fmt.Sprintf("%s notification delivered", msg.Event))
+	return true
+}
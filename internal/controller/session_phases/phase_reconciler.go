@@ -2,9 +2,18 @@ package session_phases
 
 import (
 	"context"
+	"time"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	"github.com/OxAN0N/KubeDebugSess/internal/scope"
+	"github.com/OxAN0N/KubeDebugSess/internal/storage"
+	"github.com/OxAN0N/KubeDebugSess/internal/ticket"
+	"github.com/OxAN0N/KubeDebugSess/internal/vault"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -17,7 +26,38 @@ type PhaseReconciler interface {
 	Reconcile(ctx context.Context, session *debugv1alpha1.DebugSession) (ctrl.Result, error)
 }
 
-type PhaseReconcilerFactory func(client client.Client, cs kubernetes.Interface) PhaseReconciler
+// Dependencies bundles the shared clients handed to every phase reconciler factory.
+type Dependencies struct {
+	Client    client.Client
+	ClientSet kubernetes.Interface
+	// RESTConfig is used to exec into debugger containers; nil disables stopping them at termination.
+	RESTConfig *rest.Config
+	Recorder   record.EventRecorder
+	// Notifier routes webhook notifications to their destinations; nil disables notifications.
+	Notifier *notify.Dispatcher
+	// Storage archives debugger transcripts; nil disables archival.
+	Storage storage.Storage
+	// LogURLExpiry is the lifetime of the presigned transcript URL written to the session status.
+	LogURLExpiry time.Duration
+	// KeyLayout renders the storage keys of archived objects; nil uses storage.DefaultKeyTemplate.
+	KeyLayout *storage.KeyLayout
+	// Scope limits the namespaces sessions may target; nil allows all.
+	Scope *scope.Namespaces
+	// Requeue holds the polling intervals of the phase reconcilers.
+	Requeue RequeueIntervals
+	// AccessCheck is the permission requesters must hold on their target pod.
+	AccessCheck AccessCheck
+	// DebuggerLimit caps the running debuggers per target pod.
+	DebuggerLimit DebuggerLimit
+	// Vault issues spec.vaultCredentials; nil rejects sessions that request them.
+	Vault *vault.Client
+	// Tickets checks that spec.ticketRef is open; nil accepts any reference.
+	Tickets *ticket.Checker
+	// ReasonActions maps debugger container reasons to actions; nil uses the built-in maps.
+	ReasonActions *ReasonActions
+}
+
+type PhaseReconcilerFactory func(deps Dependencies) PhaseReconciler
 
 var reconcilerRegistry = make(map[debugv1alpha1.SessionPhase]PhaseReconcilerFactory)
 
@@ -25,10 +65,10 @@ func Register(phase debugv1alpha1.SessionPhase, factory PhaseReconcilerFactory)
 	reconcilerRegistry[phase] = factory
 }
 
-func GetReconcilers(client client.Client, cs kubernetes.Interface) map[debugv1alpha1.SessionPhase]PhaseReconciler {
+func GetReconcilers(deps Dependencies) map[debugv1alpha1.SessionPhase]PhaseReconciler {
 	reconcilers := make(map[debugv1alpha1.SessionPhase]PhaseReconciler)
 	for phase, factory := range reconcilerRegistry {
-		reconcilers[phase] = factory(client, cs)
+		reconcilers[phase] = factory(deps)
 	}
 	return reconcilers
 }
@@ -38,6 +78,10 @@ func UpdateSessionStatus(ctx context.Context, c client.Client, session *debugv1a
 
 	session.Status.Phase = newPhase
 	session.Status.Message = message
+	// Readiness is only ever granted by the Active phase once the debugger runs; every other phase revokes it.
+	if newPhase != debugv1alpha1.Active {
+		SetCondition(session, debugv1alpha1.ConditionReady, metav1.ConditionFalse, string(newPhase), message)
+	}
 
 	if err := c.Status().Update(ctx, session); err != nil {
 		logger.Error(err, "Failed to update DebugSession status", "targetPhase", newPhase)
@@ -2,6 +2,8 @@ package session_phases
 
 import (
 	"fmt"
+	"maps"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 )
@@ -39,26 +41,105 @@ var terminatedReasonMap = map[string]ReasonAction{
 	"DeadlineExceeded":   ActionFail,
 }
 
-// AnalyzeContainerStatus는 ContainerStatus를 분석하여 수행할 Action을 반환합니다.
+// actionNames are the names ReasonAction values are configured by.
+var actionNames = map[string]ReasonAction{
+	"Wait":    ActionWait,
+	"Retry":   ActionRetry,
+	"Fail":    ActionFail,
+	"Succeed": ActionSucceed,
+}
+
+// ParseReasonAction parses an action name: Wait, Retry, Fail or Succeed.
+func ParseReasonAction(s string) (ReasonAction, error) {
+	action, ok := actionNames[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown reason action %q, want Wait, Retry, Fail or Succeed", s)
+	}
+	return action, nil
+}
+
+// ReasonActions maps the reasons a debugger container reports while waiting or after it terminated
+// to the action taken. A nil *ReasonActions uses the built-in maps.
+type ReasonActions struct {
+	Waiting    map[string]ReasonAction
+	Terminated map[string]ReasonAction
+	// Unknown is taken for reasons found in neither map.
+	Unknown ReasonAction
+}
+
+// DefaultReasonActions returns a copy of the built-in maps, which fail on unknown reasons.
+func DefaultReasonActions() *ReasonActions {
+	return &ReasonActions{
+		Waiting:    maps.Clone(waitingReasonMap),
+		Terminated: maps.Clone(terminatedReasonMap),
+		Unknown:    ActionFail,
+	}
+}
+
+// ParseReasonActions returns the built-in maps with overrides applied. overrides is a comma-separated
+// list of state.Reason=Action entries, where state is waiting or terminated, e.g.
+// "waiting.ErrImagePull=Retry,terminated.OOMKilled=Retry". unknown names the action for unmapped reasons.
+func ParseReasonActions(overrides, unknown string) (*ReasonActions, error) {
+	actions := DefaultReasonActions()
+	var err error
+	if actions.Unknown, err = ParseReasonAction(unknown); err != nil {
+		return nil, err
+	}
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, name, ok := strings.Cut(entry, "=")
+		state, reason, okState := strings.Cut(key, ".")
+		if !ok || !okState || reason == "" {
+			return nil, fmt.Errorf("invalid reason action %q, want state.Reason=Action", entry)
+		}
+		action, err := ParseReasonAction(name)
+		if err != nil {
+			return nil, err
+		}
+		switch state {
+		case "waiting":
+			actions.Waiting[reason] = action
+		case "terminated":
+			actions.Terminated[reason] = action
+		default:
+			return nil, fmt.Errorf("invalid reason action %q: state must be waiting or terminated", entry)
+		}
+	}
+	return actions, nil
+}
+
+// AnalyzeContainerStatus는 기본 매핑으로 ContainerStatus를 분석하여 수행할 Action을 반환합니다.
 func AnalyzeContainerStatus(status corev1.ContainerStatus) (action ReasonAction, message string) {
+	return (*ReasonActions)(nil).Analyze(status)
+}
+
+// Analyze는 ContainerStatus를 분석하여 수행할 Action을 반환합니다.
+func (m *ReasonActions) Analyze(status corev1.ContainerStatus) (action ReasonAction, message string) {
+	if m == nil {
+		m = &ReasonActions{Waiting: waitingReasonMap, Terminated: terminatedReasonMap, Unknown: ActionFail}
+	}
+
 	if status.State.Running != nil {
 		return ActionWait, "Session is running."
 	}
 
 	if status.State.Waiting != nil {
 		reason := status.State.Waiting.Reason
-		action, ok := waitingReasonMap[reason]
+		action, ok := m.Waiting[reason]
 		if !ok {
-			return ActionFail, fmt.Sprintf("Unknown waiting reason '%s'. Attempting to retry.", reason)
+			return m.Unknown, fmt.Sprintf("Unknown waiting reason '%s'.", reason)
 		}
 		return action, fmt.Sprintf("Container is waiting. Reason: %s", reason)
 	}
 
 	if status.State.Terminated != nil {
 		reason := status.State.Terminated.Reason
-		action, ok := terminatedReasonMap[reason]
+		action, ok := m.Terminated[reason]
 		if !ok {
-			return ActionFail, fmt.Sprintf("Container terminated with unknown reason '%s'.", reason)
+			return m.Unknown, fmt.Sprintf("Container terminated with unknown reason '%s'.", reason)
 		}
 		return action, fmt.Sprintf("Container terminated. Reason: %s", reason)
 	}
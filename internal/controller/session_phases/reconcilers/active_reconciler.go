@@ -1,21 +1,23 @@
 package reconcilers
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"os"
-	"strings"
 	"time"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
 	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	"github.com/OxAN0N/KubeDebugSess/internal/vault"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -29,10 +31,16 @@ func init() {
 }
 
 // NewActiveReconciler creates a new reconciler for the Active phase.
-func NewActiveReconciler(client client.Client, cs kubernetes.Interface) session_phases.PhaseReconciler {
+func NewActiveReconciler(deps session_phases.Dependencies) session_phases.PhaseReconciler {
 	r := &ActiveReconciler{
-		Client:    client,
-		Clientset: cs,
+		Client:    deps.Client,
+		Clientset: deps.ClientSet,
+		Recorder:  deps.Recorder,
+		Notifier:  deps.Notifier,
+		Requeue:   deps.Requeue,
+		Reasons:   deps.ReasonActions,
+		Vault:     deps.Vault,
+		archiver:  newLogArchiver(deps),
 	}
 	r.actionHandlers = map[session_phases.ReasonAction]ActionHandler{
 		session_phases.ActionRetry:   r.handleRetry,
@@ -47,6 +55,12 @@ func NewActiveReconciler(client client.Client, cs kubernetes.Interface) session_
 type ActiveReconciler struct {
 	client.Client
 	Clientset      kubernetes.Interface
+	Recorder       record.EventRecorder
+	Notifier       *notify.Dispatcher
+	Requeue        session_phases.RequeueIntervals
+	Reasons        *session_phases.ReasonActions
+	Vault          *vault.Client
+	archiver       *logArchiver
 	actionHandlers map[session_phases.ReasonAction]ActionHandler
 }
 
@@ -59,124 +73,114 @@ func (r *ActiveReconciler) Reconcile(ctx context.Context, session *debugv1alpha1
 	}
 
 	pod := &corev1.Pod{}
-	podKey := types.NamespacedName{Name: session.Spec.TargetPodName, Namespace: session.Spec.TargetNamespace}
+	podKey := types.NamespacedName{Name: debugv1alpha1.CurrentTargetPod(session), Namespace: session.Spec.TargetNamespace}
 	if err := r.Get(ctx, podKey, pod); err != nil {
 		if errors.IsNotFound(err) {
-			return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, "Target pod not found.")
+			if followsWorkload(session) {
+				return r.followWorkload(ctx, session, nil, fmt.Sprintf("target pod '%s' was deleted", podKey.Name))
+			}
+			session.Status.ReadyForAttach = false
+			return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed,
+				fmt.Sprintf("Session aborted: target pod '%s' was deleted. No debugger transcript to salvage.", podKey.Name))
 		}
 		return ctrl.Result{}, err
 	}
 
-	debuggerContainerName := fmt.Sprintf("debugger-%s", session.UID)
+	if reason, lost := targetPodLossReason(pod); lost {
+		r.Recorder.Event(session, corev1.EventTypeWarning, session_phases.EventReasonTargetPodLost, reason)
+		if followsWorkload(session) {
+			return r.followWorkload(ctx, session, pod, reason)
+		}
+		return failOnTargetPodLoss(ctx, r.Client, r.archiver, session, pod, reason)
+	}
+
+	debuggerContainerName := sessionDebuggerName(session)
 	session.Status.DebuggingContainerName = debuggerContainerName
 
+	if expiry := session.Status.ExpiryTime; expiry != nil && !time.Now().Before(expiry.Time) {
+		logger.Info("Session TTL elapsed, terminating.", "expiryTime", expiry.Time)
+		session.Status.ReadyForAttach = false
+		session_phases.SetCondition(session, debugv1alpha1.ConditionExpired, metav1.ConditionTrue, "TTLElapsed",
+			fmt.Sprintf("Session expired at %s", expiry.UTC().Format(time.RFC3339)))
+		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Terminating, "Session TTL elapsed.")
+	}
+
+	if deadline := attachDeadline(session); deadline != nil && !time.Now().Before(*deadline) {
+		logger.Info("No client attached before the attach deadline, terminating.", "attachDeadline", *deadline)
+		session.Status.ReadyForAttach = false
+		session_phases.SetCondition(session, debugv1alpha1.ConditionExpired, metav1.ConditionTrue, "AttachDeadlineElapsed",
+			fmt.Sprintf("No client attached by %s", deadline.UTC().Format(time.RFC3339)))
+		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Terminating, "No client attached in time.")
+	}
+
+	if deadline := detachDeadline(session); deadline != nil && !time.Now().Before(*deadline) {
+		logger.Info("All clients detached and the grace period elapsed, terminating.", "detachDeadline", *deadline)
+		session.Status.ReadyForAttach = false
+		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Terminating, "All clients detached.")
+	}
+
 	for _, containerStatus := range pod.Status.EphemeralContainerStatuses {
 		if containerStatus.Name == debuggerContainerName {
 			if containerStatus.State.Running != nil && !session.Status.ReadyForAttach {
 
 				session.Status.ReadyForAttach = true
-				sendWebhookIfConfigured(session)
+				firstReady := session.Status.ReadyTime == nil
+				if firstReady {
+					now := metav1.Now()
+					session.Status.ReadyTime = &now
+				}
+				r.recordTargetDeployment(ctx, session, pod)
+				session_phases.SetCondition(session, debugv1alpha1.ConditionReady, metav1.ConditionTrue, "DebuggerRunning",
+					fmt.Sprintf("Debugger container %s is running", debuggerContainerName))
+				session_phases.NotifySession(ctx, r.Notifier, session, notify.NewWebhookMessage(notify.EventSessionReady, session))
 				if err := r.Status().Update(ctx, session); err != nil {
 					logger.Error(err, "Failed to Update before Attach")
 					return ctrl.Result{}, err
 				}
-				return ctrl.Result{}, nil
+				if firstReady {
+					observeTimeToReady(session)
+				}
+				trace.SpanFromContext(ctx).AddEvent(session_phases.EventReasonDebuggerReady)
+				r.Recorder.Eventf(session, corev1.EventTypeNormal, session_phases.EventReasonDebuggerReady,
+					"Debugger container %s is running and ready for attach", debuggerContainerName)
+				return requeueUntilExpiry(session), nil
 			}
 
-			action, message := session_phases.AnalyzeContainerStatus(containerStatus)
+			markExpiredIfTTLElapsed(session, containerStatus)
+
+			action, message := r.Reasons.Analyze(containerStatus)
 			if handler, ok := r.actionHandlers[action]; ok {
 				if action != session_phases.ActionWait {
 					session.Status.ReadyForAttach = false
 				}
 				return handler(ctx, session, message)
 			}
-			return ctrl.Result{}, nil
+			return requeueUntilExpiry(session), nil
 		}
 	}
 
 	logger.Info("Ephemeral container status not found yet, requeueing.")
-	return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+	return ctrl.Result{RequeueAfter: r.Requeue.ActiveContainer}, nil
 }
 
-// sendWebhookIfConfigured sends the session message to a webhook if WEBHOOK_URL is set.
-// Slack / Discord detection is done by inspecting the webhook domain.
-func sendWebhookIfConfigured(session *debugv1alpha1.DebugSession) {
-	webhookURL := os.Getenv("WEBHOOK_URL")
-	if webhookURL == "" {
+// markExpiredIfTTLElapsed sets the Expired condition when the debugger exited because its TTL ran out
+// rather than because the user left the shell.
+func markExpiredIfTTLElapsed(session *debugv1alpha1.DebugSession, status corev1.ContainerStatus) {
+	terminated := status.State.Terminated
+	if terminated == nil || session.Spec.TTL <= 0 {
 		return
 	}
-
-	payload := buildWebhookPayload(webhookURL, session)
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to marshal webhook payload: %v\n", err)
-		return
-	}
-
-	go func() {
-		req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(data))
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to create webhook request: %v\n", err)
-			return
-		}
-		req.Header.Set("Content-Type", "application/json")
-
-		client := &http.Client{Timeout: 5 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to send webhook: %v\n", err)
-			return
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			fmt.Fprintf(os.Stderr, "webhook returned non-2xx status: %s\n", resp.Status)
-		}
-	}()
-}
-
-// buildWebhookPayload builds the message body depending on webhook domain type.
-func buildWebhookPayload(webhookURL string, session *debugv1alpha1.DebugSession) interface{} {
-	msg := session.Status.Message
-	ns := session.Spec.TargetNamespace
-	pod := session.Spec.TargetPodName
-	container := session.Status.DebuggingContainerName
-
-	switch {
-	case strings.Contains(webhookURL, "hooks.slack.com"):
-		return map[string]interface{}{
-			"text": fmt.Sprintf(
-				"*KubeDebugSess – Debug session ready*\nNamespace: `%s`\nPod: `%s`\nContainer: `%s`\n\n```%s```",
-				ns, pod, container, msg),
-		}
-
-	case strings.Contains(webhookURL, "discord.com/api/webhooks"):
-		return map[string]interface{}{
-			"embeds": []map[string]interface{}{
-				{
-					"title":       "🐳 KubeDebugSess – Debug session ready",
-					"description": fmt.Sprintf("**Namespace:** `%s`\n**Pod:** `%s`\n**Container:** `%s`\n\n```\n%s\n```", ns, pod, container, msg),
-					"color":       0x00bfff,
-					"timestamp":   time.Now().UTC().Format(time.RFC3339),
-				},
-			},
-		}
-
-	default:
-		return map[string]interface{}{
-			"namespace": ns,
-			"pod":       pod,
-			"container": container,
-			"message":   msg,
-			"timestamp": time.Now().UTC().Format(time.RFC3339),
-		}
+	ttl := time.Duration(session.Spec.TTL) * time.Second
+	if terminated.FinishedAt.Sub(terminated.StartedAt.Time) >= ttl {
+		session_phases.SetCondition(session, debugv1alpha1.ConditionExpired, metav1.ConditionTrue, "TTLElapsed",
+			fmt.Sprintf("Debugger ran for its full TTL of %s", ttl))
 	}
 }
 
 // --- Handler functions for different container states ---
 func (r *ActiveReconciler) handleRetry(ctx context.Context, session *debugv1alpha1.DebugSession, message string) (ctrl.Result, error) {
 	session.Status.RetryCount = 1
+	recordRetry(session, message)
 	return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Retrying, message)
 }
 
@@ -189,5 +193,101 @@ func (r *ActiveReconciler) handleSucceed(ctx context.Context, session *debugv1al
 }
 
 func (r *ActiveReconciler) handleWait(ctx context.Context, session *debugv1alpha1.DebugSession, message string) (ctrl.Result, error) {
-	return ctrl.Result{}, nil
+	return requeueUntilExpiry(session), nil
+}
+
+// requeueUntilExpiry schedules the next reconcile at the session's expiry, or at its attach or detach
+// deadline or when an open connection goes stale if one comes first, so all are enforced even if no
+// pod or session events arrive in the meantime.
+func requeueUntilExpiry(session *debugv1alpha1.DebugSession) ctrl.Result {
+	var next *time.Time
+	if session.Status.ExpiryTime != nil {
+		next = &session.Status.ExpiryTime.Time
+	}
+	for _, deadline := range []*time.Time{attachDeadline(session), detachDeadline(session), attachmentStaleTime(session)} {
+		if deadline != nil && (next == nil || deadline.Before(*next)) {
+			next = deadline
+		}
+	}
+	if next == nil {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: time.Until(*next) + time.Second}
+}
+
+// attachDeadline returns when a Ready session with spec.attachDeadlineSeconds is terminated because
+// nobody attached to it, or nil once a client attached or before the session is Ready.
+func attachDeadline(session *debugv1alpha1.DebugSession) *time.Time {
+	seconds := session.Spec.AttachDeadlineSeconds
+	if seconds == nil || session.Status.AttachCount > 0 || session.Status.FirstAttachTime != nil {
+		return nil
+	}
+	ready := meta.FindStatusCondition(session.Status.Conditions, debugv1alpha1.ConditionReady)
+	if ready == nil || ready.Status != metav1.ConditionTrue {
+		return nil
+	}
+	deadline := ready.LastTransitionTime.Add(time.Duration(*seconds) * time.Second)
+	return &deadline
+}
+
+// attachmentStaleTime returns when the first open connection is presumed lost unless its proxy
+// refreshes the heartbeat, or nil while no client is attached. The controller closes it then.
+func attachmentStaleTime(session *debugv1alpha1.DebugSession) *time.Time {
+	var first *time.Time
+	for i := range session.Status.Attachments {
+		attachment := &session.Status.Attachments[i]
+		if attachment.DetachTime != nil {
+			continue
+		}
+		if staleAt := attachment.StaleAt(); first == nil || staleAt.Before(*first) {
+			first = &staleAt
+		}
+	}
+	return first
+}
+
+// detachDeadline returns when a session with spec.detachGracePeriodSeconds or spec.terminateOnDetach
+// is terminated because nobody is attached, or nil while clients are attached or none has detached yet.
+func detachDeadline(session *debugv1alpha1.DebugSession) *time.Time {
+	grace := session.Spec.DetachGracePeriodSeconds
+	if session.Spec.TerminateOnDetach {
+		grace = ptr.To[int32](0)
+	}
+	if grace == nil {
+		return nil
+	}
+	detached := detachedSince(session)
+	if detached == nil {
+		return nil
+	}
+	deadline := detached.Add(time.Duration(*grace) * time.Second)
+	return &deadline
+}
+
+// detachedSince returns when the last client detached, or nil while one is attached. Clients that are
+// still counted as attached, but whose connections have neither a heartbeat nor terminal activity
+// within debugv1alpha1.AttachmentStaleAfter, count as detached since the later of the two; so a proxy
+// lost mid-connection cannot keep the session alive even before the controller closes its entries.
+func detachedSince(session *debugv1alpha1.DebugSession) *time.Time {
+	status := &session.Status
+	if status.ConnectedClients == 0 {
+		if status.LastDetachTime == nil {
+			return nil
+		}
+		return &status.LastDetachTime.Time
+	}
+	var since time.Time
+	if status.LastActivityTime != nil {
+		since = status.LastActivityTime.Time
+	}
+	for i := range status.Attachments {
+		attachment := &status.Attachments[i]
+		if heartbeat := attachment.LastHeartbeat(); attachment.DetachTime == nil && heartbeat.After(since) {
+			since = heartbeat
+		}
+	}
+	if since.IsZero() || time.Now().Before(since.Add(debugv1alpha1.AttachmentStaleAfter)) {
+		return nil
+	}
+	return &since
 }
@@ -0,0 +1,97 @@
+package reconcilers
+
+import (
+	"testing"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestDetachDeadline(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) *metav1.Time { return &metav1.Time{Time: now.Add(-d)} }
+	open := func(heartbeat *metav1.Time) []debugv1alpha1.Attachment {
+		return []debugv1alpha1.Attachment{{ID: "a", AttachTime: *ago(time.Hour), HeartbeatTime: heartbeat}}
+	}
+	grace := ptr.To[int32](60)
+
+	tests := []struct {
+		name      string
+		spec      debugv1alpha1.DebugSessionSpec
+		status    debugv1alpha1.DebugSessionStatus
+		want      *metav1.Time
+		wantUnset bool
+	}{
+		{
+			name:      "no grace period",
+			status:    debugv1alpha1.DebugSessionStatus{LastDetachTime: ago(time.Minute)},
+			wantUnset: true,
+		},
+		{
+			name:      "never attached",
+			spec:      debugv1alpha1.DebugSessionSpec{DetachGracePeriodSeconds: grace},
+			wantUnset: true,
+		},
+		{
+			name:   "grace period after detach",
+			spec:   debugv1alpha1.DebugSessionSpec{DetachGracePeriodSeconds: grace},
+			status: debugv1alpha1.DebugSessionStatus{LastDetachTime: ago(time.Minute)},
+			want:   ago(0),
+		},
+		{
+			name:   "terminate on detach",
+			spec:   debugv1alpha1.DebugSessionSpec{TerminateOnDetach: true, DetachGracePeriodSeconds: grace},
+			status: debugv1alpha1.DebugSessionStatus{LastDetachTime: ago(time.Minute)},
+			want:   ago(time.Minute),
+		},
+		{
+			name: "live connection",
+			spec: debugv1alpha1.DebugSessionSpec{TerminateOnDetach: true},
+			status: debugv1alpha1.DebugSessionStatus{
+				ConnectedClients: 1, Attachments: open(ago(10 * time.Second)), LastDetachTime: ago(time.Hour),
+			},
+			wantUnset: true,
+		},
+		{
+			name: "stale connection",
+			spec: debugv1alpha1.DebugSessionSpec{TerminateOnDetach: true},
+			status: debugv1alpha1.DebugSessionStatus{
+				ConnectedClients: 1, Attachments: open(ago(5 * time.Minute)), LastActivityTime: ago(10 * time.Minute),
+			},
+			want: ago(5 * time.Minute),
+		},
+		{
+			name: "stale heartbeat but recent activity",
+			spec: debugv1alpha1.DebugSessionSpec{TerminateOnDetach: true},
+			status: debugv1alpha1.DebugSessionStatus{
+				ConnectedClients: 1, Attachments: open(ago(5 * time.Minute)), LastActivityTime: ago(10 * time.Second),
+			},
+			wantUnset: true,
+		},
+		{
+			name: "counted clients without open connections",
+			spec: debugv1alpha1.DebugSessionSpec{TerminateOnDetach: true},
+			status: debugv1alpha1.DebugSessionStatus{
+				ConnectedClients: 1, LastActivityTime: ago(10 * time.Minute),
+			},
+			want: ago(10 * time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detachDeadline(&debugv1alpha1.DebugSession{Spec: tt.spec, Status: tt.status})
+			switch {
+			case tt.wantUnset:
+				if got != nil {
+					t.Errorf("detachDeadline() = %v, want nil", got)
+				}
+			case got == nil:
+				t.Errorf("detachDeadline() = nil, want %v", tt.want.Time)
+			case !got.Equal(tt.want.Time):
+				t.Errorf("detachDeadline() = %v, want %v", got, tt.want.Time)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package reconcilers
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultBastionHost = "your-user@bastion.example.com"
+	defaultLocalPort   = 8080
+)
+
+// bastionAccess returns the access path for sessions targeting targetNamespace. It is read from the
+// BastionConfig resources through the manager's cache, so changes apply to the next session; without
+// any, BASTION_HOST is used.
+func bastionAccess(ctx context.Context, c client.Reader, targetNamespace string) (debugv1alpha1.BastionAccess, error) {
+	fallback := debugv1alpha1.BastionAccess{Host: os.Getenv("BASTION_HOST"), LocalPort: defaultLocalPort}
+	if fallback.Host == "" {
+		fallback.Host = defaultBastionHost
+	}
+
+	var configs debugv1alpha1.BastionConfigList
+	if err := c.List(ctx, &configs); err != nil {
+		return fallback, fmt.Errorf("failed to list BastionConfigs: %w", err)
+	}
+	if len(configs.Items) == 0 {
+		return fallback, nil
+	}
+	// Several configs are not expected; the first by name wins so that the choice is stable.
+	slices.SortFunc(configs.Items, func(a, b debugv1alpha1.BastionConfig) int { return cmp.Compare(a.Name, b.Name) })
+	spec := configs.Items[0].Spec
+
+	access := spec.BastionAccess
+	var namespace *corev1.Namespace
+	for _, env := range spec.Environments {
+		if env.NamespaceSelector != nil && namespace == nil {
+			namespace = &corev1.Namespace{}
+			if err := c.Get(ctx, types.NamespacedName{Name: targetNamespace}, namespace); err != nil {
+				return fallback, fmt.Errorf("failed to get namespace %s: %w", targetNamespace, err)
+			}
+		}
+		matched, err := environmentMatches(env, targetNamespace, namespace)
+		if err != nil {
+			return fallback, fmt.Errorf("environment %s: %w", env.Name, err)
+		}
+		if matched {
+			if env.Host != "" || env.Teleport != nil || env.SSM != nil || env.Overlay != nil {
+				access.Teleport, access.SSM, access.Overlay = env.Teleport, env.SSM, env.Overlay
+			}
+			access.Host = cmp.Or(env.Host, access.Host)
+			access.Port = cmp.Or(env.Port, access.Port)
+			access.LocalPort = cmp.Or(env.LocalPort, access.LocalPort)
+			break
+		}
+	}
+	access.Host = cmp.Or(access.Host, fallback.Host)
+	access.LocalPort = cmp.Or(access.LocalPort, defaultLocalPort)
+	return access, nil
+}
+
+// environmentMatches reports whether env applies to targetNamespace. An environment without
+// namespaces or a selector applies to none, so that a half-written entry does not take over.
+func environmentMatches(env debugv1alpha1.BastionEnvironment, targetNamespace string, namespace *corev1.Namespace) (bool, error) {
+	if len(env.Namespaces) == 0 && env.NamespaceSelector == nil {
+		return false, nil
+	}
+	if len(env.Namespaces) > 0 && !slices.Contains(env.Namespaces, targetNamespace) {
+		return false, nil
+	}
+	if env.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(env.NamespaceSelector)
+		if err != nil {
+			return false, fmt.Errorf("invalid namespaceSelector: %w", err)
+		}
+		return selector.Matches(labels.Set(namespace.Labels)), nil
+	}
+	return true, nil
+}
@@ -0,0 +1,297 @@
+package reconcilers
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// artifactLinePrefix marks the debugger output lines that carry a capture artifact, as
+// "artifact:<file>:<base64 chunk>". The root filesystem is read-only and ephemeral containers cannot
+// mount volumes of their own, so artifacts leave the pod through the container log.
+const artifactLinePrefix = "artifact:"
+
+// captureScriptPrelude defines emit, which writes its stdin into the container log as the named artifact.
+const captureScriptPrelude = `
+emit() { base64 | sed "s/^/artifact:$1:/"; }
+`
+
+// captureProfile is what a spec.capture action runs and needs.
+type captureProfile struct {
+	image        string
+	capabilities []corev1.Capability
+	script       string
+}
+
+// captureProfiles grants each capture action only the capabilities it needs.
+var captureProfiles = map[debugv1alpha1.CaptureType]captureProfile{
+	debugv1alpha1.CaptureTcpdump: {
+		image:        "docker.io/nicolaka/netshoot:v0.13",
+		capabilities: []corev1.Capability{"NET_RAW"},
+		script: `
+set -- -p -U -i "${CAPTURE_INTERFACE:-any}" -w -
+[ -z "${CAPTURE_MAX_PACKETS-}" ] || set -- "$@" -c "$CAPTURE_MAX_PACKETS"
+[ -z "${CAPTURE_FILTER-}" ] || set -- "$@" "$CAPTURE_FILTER"
+echo "capturing for ${CAPTURE_DURATION}s: tcpdump $*" >&2
+timeout "$CAPTURE_DURATION" tcpdump "$@" | emit capture.pcap
+`,
+	},
+	debugv1alpha1.CaptureStrace: {
+		image:        "docker.io/nicolaka/netshoot:v0.13",
+		capabilities: []corev1.Capability{"SYS_PTRACE"},
+		script: targetPIDScript + `
+set -- -f -tt -T -p "$pid"
+[ -z "${CAPTURE_FILTER-}" ] || set -- "$@" -e "$CAPTURE_FILTER"
+echo "tracing for ${CAPTURE_DURATION}s: strace $*" >&2
+timeout "$CAPTURE_DURATION" strace "$@" 2>&1 | emit strace.txt
+`,
+	},
+	debugv1alpha1.CapturePerf: {
+		capabilities: []corev1.Capability{"PERFMON", "SYS_PTRACE"},
+		script: targetPIDScript + `
+echo "sampling pid $pid for ${CAPTURE_DURATION}s with perf" >&2
+# perf only finishes the file on SIGINT.
+timeout -s INT "$CAPTURE_DURATION" perf record -F 99 -g -p "$pid" -o - | emit perf.data
+`,
+	},
+	debugv1alpha1.CaptureThreadDump: {
+		image:        "docker.io/library/eclipse-temurin:21-jdk",
+		capabilities: []corev1.Capability{"SYS_PTRACE"},
+		script: targetPIDScript + `
+echo "dumping the threads of pid $pid" >&2
+jcmd "$pid" Thread.print -l | emit threads.txt
+`,
+	},
+	debugv1alpha1.CaptureHeapDump: {
+		image:        "docker.io/library/eclipse-temurin:21-jdk",
+		capabilities: []corev1.Capability{"SYS_PTRACE"},
+		script: targetPIDScript + `
+# The JVM writes the dump inside its own mount namespace, which is reachable through /proc.
+dump=/tmp/kubedebugsess-$$.hprof
+echo "dumping the heap of pid $pid" >&2
+jcmd "$pid" GC.heap_dump "$dump" >&2 && emit heap.hprof < "/proc/$pid/root$dump"
+rm -f "/proc/$pid/root$dump"
+`,
+	},
+	debugv1alpha1.CapturePprof: {
+		image: "docker.io/nicolaka/netshoot:v0.13",
+		script: `
+base="http://127.0.0.1:$CAPTURE_PORT/debug/pprof"
+for profile in $CAPTURE_PROFILES; do
+  case "$profile" in
+    cpu) path="profile?seconds=$CAPTURE_DURATION" ;;
+    *) path=$profile ;;
+  esac
+  echo "fetching $base/$path" >&2
+  curl -sSf --max-time $((CAPTURE_DURATION+30)) "$base/$path" | emit "$profile.pb.gz"
+done
+`,
+	},
+	debugv1alpha1.CaptureBpftrace: {
+		image:        "quay.io/iovisor/bpftrace:v0.21.2",
+		capabilities: []corev1.Capability{"BPF", "PERFMON", "SYS_PTRACE", "SYS_RESOURCE"},
+		script: targetPIDScript + `
+echo "tracing pid $pid for ${CAPTURE_DURATION}s with bpftrace" >&2
+# bpftrace prints its maps on SIGINT.
+timeout -s INT "$CAPTURE_DURATION" bpftrace -e "$CAPTURE_SCRIPT" "$pid" 2>&1 | emit bpftrace.txt
+`,
+	},
+}
+
+// captureScript returns the script the debugger runs in place of the shell for a capture action.
+func captureScript(capture *debugv1alpha1.Capture) string {
+	return captureScriptPrelude + captureProfiles[capture.Type].script
+}
+
+// captureEnv returns the variables that parameterize the capture script.
+func captureEnv(session *debugv1alpha1.DebugSession, pod *corev1.Pod) []corev1.EnvVar {
+	capture := session.Spec.Capture
+	env := []corev1.EnvVar{
+		{Name: "CAPTURE_FILTER", Value: capture.Filter},
+		{Name: "CAPTURE_INTERFACE", Value: capture.Interface},
+		{Name: "CAPTURE_DURATION", Value: strconv.Itoa(int(capture.DurationSeconds))},
+	}
+	if capture.Type == debugv1alpha1.CapturePprof {
+		profiles := make([]string, 0, len(capture.Profiles))
+		for _, p := range capture.Profiles {
+			profiles = append(profiles, string(p))
+		}
+		env = append(env,
+			corev1.EnvVar{Name: "CAPTURE_PORT", Value: strconv.Itoa(int(capture.Port))},
+			corev1.EnvVar{Name: "CAPTURE_PROFILES", Value: strings.Join(profiles, " ")})
+	}
+	if capture.Script != "" {
+		env = append(env, corev1.EnvVar{Name: "CAPTURE_SCRIPT", Value: capture.Script})
+	}
+	if capture.MaxPackets != nil {
+		env = append(env, corev1.EnvVar{Name: "CAPTURE_MAX_PACKETS", Value: strconv.Itoa(int(*capture.MaxPackets))})
+	}
+	return append(env, targetContainerEnv(session, pod)...)
+}
+
+// addCaptureCapabilities grants the capabilities of the session's capture action, unless
+// spec.debugSecurity sets the capabilities itself.
+func addCaptureCapabilities(session *debugv1alpha1.DebugSession, sc *corev1.SecurityContext) {
+	if session.Spec.Capture == nil {
+		return
+	}
+	addCapabilities(session, sc, captureProfiles[session.Spec.Capture.Type].capabilities...)
+}
+
+// archiveArtifacts uploads the artifacts of a capture session and records them in the Captured
+// condition. A failed upload is reported but does not hold up termination.
+func (r *TerminatingReconciler) archiveArtifacts(ctx context.Context, session *debugv1alpha1.DebugSession,
+	pod *corev1.Pod, containerName string) {
+	keys, err := r.archiver.archiveArtifacts(ctx, session, pod, containerName)
+	session.Status.Artifacts = keys
+	switch {
+	case errors.Is(err, errArchivingDisabled):
+		session_phases.SetCondition(session, debugv1alpha1.ConditionCaptured, metav1.ConditionFalse, "StorageDisabled", err.Error())
+	case err != nil:
+		session_phases.SetCondition(session, debugv1alpha1.ConditionCaptured, metav1.ConditionFalse, reasonUploadFailed, err.Error())
+		r.Recorder.Eventf(session, corev1.EventTypeWarning, session_phases.EventReasonCaptureFailed,
+			"Capture upload failed: %v", err)
+	case len(keys) == 0:
+		session_phases.SetCondition(session, debugv1alpha1.ConditionCaptured, metav1.ConditionFalse, "NoArtifacts",
+			"The capture produced no output; see the transcript for its errors")
+	default:
+		session_phases.SetCondition(session, debugv1alpha1.ConditionCaptured, metav1.ConditionTrue, "ArtifactsUploaded",
+			fmt.Sprintf("Capture stored at %s", strings.Join(keys, ", ")))
+		r.Recorder.Eventf(session, corev1.EventTypeNormal, session_phases.EventReasonArtifactsSaved,
+			"Capture uploaded to %s", strings.Join(keys, ", "))
+	}
+}
+
+// archiveArtifacts decodes the artifacts from the debugger's log and streams each to storage,
+// returning the keys of those that were uploaded completely.
+func (a *logArchiver) archiveArtifacts(ctx context.Context, session *debugv1alpha1.DebugSession, pod *corev1.Pod,
+	containerName string) (_ []string, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ArchiveArtifacts")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	if a.Storage == nil {
+		return nil, errArchivingDisabled
+	}
+
+	stream, err := a.ClientSet.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: containerName}).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log stream: %w", err)
+	}
+	defer stream.Close()
+
+	info := objectInfo(session, pod, containerName)
+	keyPrefix, err := a.KeyLayout.Key(info)
+	if err != nil {
+		return nil, err
+	}
+	keyPrefix += "-"
+	tags := info.Tags()
+	var keys []string
+	var current *artifactUpload
+	defer func() {
+		if current != nil {
+			current.abort(err)
+		}
+	}()
+
+	flush := func() error {
+		upload := current
+		current = nil
+		if err := upload.finish(); err != nil {
+			return err
+		}
+		keys = append(keys, upload.key)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		rest, ok := strings.CutPrefix(scanner.Text(), artifactLinePrefix)
+		if !ok {
+			continue
+		}
+		name, chunk, ok := strings.Cut(rest, ":")
+		if !ok {
+			continue
+		}
+		if current != nil && current.name != name {
+			if err := flush(); err != nil {
+				return keys, err
+			}
+		}
+		if current == nil {
+			current = a.startArtifactUpload(ctx, name, keyPrefix+name, tags)
+		}
+		data, err := base64.StdEncoding.DecodeString(chunk)
+		if err != nil {
+			return keys, fmt.Errorf("artifact %s is corrupt: %w", name, err)
+		}
+		if _, err := current.pw.Write(data); err != nil {
+			return keys, fmt.Errorf("failed to upload artifact %s: %w", name, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return keys, fmt.Errorf("failed to read debugger log: %w", err)
+	}
+	if current != nil {
+		if err := flush(); err != nil {
+			return keys, err
+		}
+	}
+
+	log.FromContext(ctx).Info("Uploaded capture artifacts", "keys", keys)
+	return keys, nil
+}
+
+// artifactUpload is an upload to storage that is fed while the debugger log is read.
+type artifactUpload struct {
+	name string
+	key  string
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (a *logArchiver) startArtifactUpload(ctx context.Context, name, key string, tags map[string]string) *artifactUpload {
+	pr, pw := io.Pipe()
+	upload := &artifactUpload{name: name, key: key, pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := a.Storage.Put(ctx, key, pr, tags)
+		// Unblock the log reader if the upload stopped reading early.
+		pr.CloseWithError(err)
+		upload.done <- err
+	}()
+	return upload
+}
+
+// finish ends the artifact and waits for its upload.
+func (u *artifactUpload) finish() error {
+	_ = u.pw.Close()
+	if err := <-u.done; err != nil {
+		return fmt.Errorf("failed to upload artifact %s: %w", u.name, err)
+	}
+	return nil
+}
+
+// abort cancels an unfinished upload.
+func (u *artifactUpload) abort(err error) {
+	if err == nil {
+		err = errors.New("artifact upload aborted")
+	}
+	u.pw.CloseWithError(err)
+	<-u.done
+}
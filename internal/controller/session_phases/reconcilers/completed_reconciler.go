@@ -2,33 +2,121 @@ package reconcilers
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
 	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// maxArchiveAttempts bounds the uploads tried for a BestEffort session, including the one at termination.
+	maxArchiveAttempts = 5
+	// archiveRetryBaseDelay is doubled after every failed background upload.
+	archiveRetryBaseDelay = 30 * time.Second
 )
 
 func init() {
 	session_phases.Register(debugv1alpha1.Completed, NewCompletedReconciler)
 }
 
-func NewCompletedReconciler(client client.Client, cs kubernetes.Interface) session_phases.PhaseReconciler {
-	return &CompletedReconciler{Client: client, ClientSet: cs}
+func NewCompletedReconciler(deps session_phases.Dependencies) session_phases.PhaseReconciler {
+	return &CompletedReconciler{
+		Client:    deps.Client,
+		ClientSet: deps.ClientSet,
+		Recorder:  deps.Recorder,
+		archiver:  newLogArchiver(deps),
+	}
 }
 
 type CompletedReconciler struct {
 	client.Client
 	ClientSet kubernetes.Interface
+	Recorder  record.EventRecorder
+	archiver  *logArchiver
 }
 
 func (r *CompletedReconciler) Reconcile(ctx context.Context, session *debugv1alpha1.DebugSession) (ctrl.Result, error) {
 	// TODO: implement alert for slack or other messengers
 	// to manually delete the DebugSession CRD on GitOps
+	result := r.retryArchive(ctx, session)
+	// Background uploads may still fill in the transcript; the record is written once they are done.
+	if result.RequeueAfter == 0 {
+		// The pod holds the only copy of the transcript until the uploads are done.
+		result = r.recreateTargetPod(ctx, session)
+		if _, err := writeSessionRecord(ctx, r.Client, session); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to write session record")
+			result.RequeueAfter = archiveRetryBaseDelay
+		}
+	}
+
 	session.Status.Message = "Session Completed."
 	if err := r.Status().Update(ctx, session); err != nil {
 		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, err.Error())
 	}
-	return ctrl.Result{}, nil
+	return result, nil
+}
+
+// retryArchive re-uploads the transcript of a BestEffort session whose upload failed at termination,
+// backing off exponentially until maxArchiveAttempts is reached.
+func (r *CompletedReconciler) retryArchive(ctx context.Context, session *debugv1alpha1.DebugSession) ctrl.Result {
+	cond := meta.FindStatusCondition(session.Status.Conditions, debugv1alpha1.ConditionArchived)
+	if session.Spec.ArchivePolicy != debugv1alpha1.ArchivePolicyBestEffort ||
+		cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != reasonUploadFailed ||
+		session.Status.ArchiveAttempts >= maxArchiveAttempts {
+		return ctrl.Result{}
+	}
+
+	if remaining := time.Until(cond.LastTransitionTime.Add(archiveRetryDelay(session.Status.ArchiveAttempts))); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}
+	}
+
+	logger := log.FromContext(ctx)
+	logger.Info("Retrying transcript upload", "attempt", session.Status.ArchiveAttempts+1)
+
+	if session.Spec.TargetNamespace == "" {
+		session.Spec.TargetNamespace = session.Namespace
+	}
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, types.NamespacedName{Name: debugv1alpha1.CurrentTargetPod(session), Namespace: session.Spec.TargetNamespace}, pod); err != nil {
+		session.Status.ArchiveAttempts = maxArchiveAttempts
+		session_phases.SetCondition(session, debugv1alpha1.ConditionArchived, metav1.ConditionFalse, "TranscriptLost",
+			fmt.Sprintf("Target pod is no longer readable: %v", err))
+		return ctrl.Result{}
+	}
+
+	logKey, err := r.archiver.archive(ctx, session, pod, sessionDebuggerName(session))
+	if err != nil {
+		session.Status.ArchiveAttempts++
+		// Reset the transition time so the next backoff is measured from this attempt.
+		meta.RemoveStatusCondition(&session.Status.Conditions, debugv1alpha1.ConditionArchived)
+		session_phases.SetCondition(session, debugv1alpha1.ConditionArchived, metav1.ConditionFalse, reasonUploadFailed, err.Error())
+		if session.Status.ArchiveAttempts >= maxArchiveAttempts {
+			r.Recorder.Eventf(session, corev1.EventTypeWarning, session_phases.EventReasonArchiveFailed,
+				"Giving up on transcript upload after %d attempts: %v", session.Status.ArchiveAttempts, err)
+			return ctrl.Result{}
+		}
+		return ctrl.Result{RequeueAfter: archiveRetryDelay(session.Status.ArchiveAttempts)}
+	}
+
+	session_phases.SetCondition(session, debugv1alpha1.ConditionArchived, metav1.ConditionTrue, "TranscriptUploaded",
+		fmt.Sprintf("Transcript stored at %s", logKey))
+	r.archiver.recordTranscript(ctx, session, logKey)
+	r.Recorder.Eventf(session, corev1.EventTypeNormal, session_phases.EventReasonTranscriptSaved,
+		"Debugger transcript uploaded to %s", logKey)
+	return ctrl.Result{}
+}
+
+// archiveRetryDelay is the wait before the next upload after the given number of failed attempts.
+func archiveRetryDelay(attempts int32) time.Duration {
+	return archiveRetryBaseDelay << max(attempts-1, 0)
 }
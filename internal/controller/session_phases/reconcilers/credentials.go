@@ -0,0 +1,160 @@
+package reconcilers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/vault"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var errVaultDisabled = errors.New("spec.vaultCredentials needs Vault, but VAULT_ADDR is not configured")
+
+func vaultSecretName(session *debugv1alpha1.DebugSession) string {
+	return fmt.Sprintf("debugsession-%s-vault", session.UID)
+}
+
+// issueVaultCredentials reads the session's spec.vaultCredentials from Vault into a Secret the debugger
+// exports as environment variables, so the credentials never appear in the pod spec. The Secret is
+// owned by the target pod like the egress NetworkPolicy; the leases are recorded in status.vaultLeases.
+func issueVaultCredentials(ctx context.Context, c client.Client, v *vault.Client, session *debugv1alpha1.DebugSession,
+	pod *corev1.Pod) error {
+	if len(session.Spec.VaultCredentials) == 0 {
+		return nil
+	}
+	if cond := meta.FindStatusCondition(session.Status.Conditions, debugv1alpha1.ConditionCredentialsIssued); cond != nil &&
+		cond.Status == metav1.ConditionTrue && cond.Reason == "LeasesIssued" {
+		return nil
+	}
+	if v == nil {
+		return errVaultDisabled
+	}
+	// Leases of an earlier attempt that did not finish are revoked rather than handed out.
+	if err := revokeVaultCredentials(ctx, c, v, session); err != nil {
+		return err
+	}
+
+	data := map[string][]byte{}
+	for _, cred := range session.Spec.VaultCredentials {
+		secret, err := v.Read(ctx, cred.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read Vault secret %s: %w", cred.Path, err)
+		}
+		lease := debugv1alpha1.VaultLease{Path: cred.Path, LeaseID: secret.LeaseID}
+		if secret.LeaseDuration > 0 {
+			expiry := metav1.NewTime(metav1.Now().Add(secret.LeaseDuration))
+			lease.ExpiryTime = &expiry
+		}
+		// Record the lease before anything else can fail, so that it is revoked either way.
+		session.Status.VaultLeases = append(session.Status.VaultLeases, lease)
+		session_phases.SetCondition(session, debugv1alpha1.ConditionCredentialsIssued, metav1.ConditionTrue, "IssuingLeases",
+			fmt.Sprintf("Issued Vault lease for %s", cred.Path))
+		for key, value := range secret.Data {
+			data[cred.EnvPrefix+"_"+strings.ToUpper(key)] = []byte(value)
+		}
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vaultSecretName(session),
+			Namespace: pod.Namespace,
+			Labels:    map[string]string{debugv1alpha1.SessionNamespaceLabel: session.Namespace},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       pod.Name,
+				UID:        pod.UID,
+			}},
+		},
+		Data: data,
+	}
+	// A Secret left by an attempt whose status write was lost holds revoked credentials.
+	if err := c.Delete(ctx, secret.DeepCopy()); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to replace Vault credentials Secret: %w", err)
+	}
+	if err := c.Create(ctx, secret); err != nil {
+		return fmt.Errorf("failed to create Vault credentials Secret: %w", err)
+	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionCredentialsIssued, metav1.ConditionTrue, "LeasesIssued",
+		fmt.Sprintf("Issued %d Vault lease(s) to the debugger", len(session.Spec.VaultCredentials)))
+	return nil
+}
+
+// vaultCredentialsEnvFrom exports the session's Vault credentials Secret into the debugger.
+func vaultCredentialsEnvFrom(session *debugv1alpha1.DebugSession) []corev1.EnvFromSource {
+	if len(session.Spec.VaultCredentials) == 0 {
+		return nil
+	}
+	return []corev1.EnvFromSource{{
+		SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: vaultSecretName(session)}},
+	}}
+}
+
+// revokeVaultCredentials revokes the session's unrevoked Vault leases and deletes their Secret. It only
+// acts while the CredentialsIssued condition is True, so calling it again is cheap.
+func revokeVaultCredentials(ctx context.Context, c client.Client, v *vault.Client, session *debugv1alpha1.DebugSession) error {
+	if !meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionCredentialsIssued) {
+		return nil
+	}
+	if v == nil {
+		return errVaultDisabled
+	}
+	for i := range session.Status.VaultLeases {
+		lease := &session.Status.VaultLeases[i]
+		if lease.RevokeTime != nil || lease.LeaseID == "" {
+			continue
+		}
+		if err := v.Revoke(ctx, lease.LeaseID); err != nil {
+			return fmt.Errorf("failed to revoke Vault lease %s: %w", lease.LeaseID, err)
+		}
+		now := metav1.Now()
+		lease.RevokeTime = &now
+		log.FromContext(ctx).Info("Revoked Vault lease", "path", lease.Path, "leaseID", lease.LeaseID)
+	}
+
+	targetNamespace := session.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = session.Namespace
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: vaultSecretName(session), Namespace: targetNamespace}}
+	if err := c.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete Vault credentials Secret: %w", err)
+	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionCredentialsIssued, metav1.ConditionFalse, "LeasesRevoked",
+		fmt.Sprintf("Revoked %d Vault lease(s)", len(session.Status.VaultLeases)))
+	return nil
+}
+
+// configRefsEnvFrom exports the session's spec.secretRefs and spec.configMapRefs into the debugger. The
+// namespace allowlist is checked before injection, in validatePrerequisites and at admission.
+func configRefsEnvFrom(session *debugv1alpha1.DebugSession) []corev1.EnvFromSource {
+	var envFrom []corev1.EnvFromSource
+	for _, ref := range session.Spec.ConfigMapRefs {
+		envFrom = append(envFrom, corev1.EnvFromSource{
+			Prefix:       ref.Prefix,
+			ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: ref.Name}},
+		})
+	}
+	for _, ref := range session.Spec.SecretRefs {
+		envFrom = append(envFrom, corev1.EnvFromSource{
+			Prefix:    ref.Prefix,
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: ref.Name}},
+		})
+	}
+	return envFrom
+}
+
+// exportsCredentials reports whether the session exports Secrets, ConfigMaps, a ServiceAccount token or
+// Vault credentials into its debugger, which observers of it must not see.
+func exportsCredentials(session *debugv1alpha1.DebugSession) bool {
+	return len(session.Spec.VaultCredentials) > 0 || len(session.Spec.SecretRefs) > 0 || len(session.Spec.ConfigMapRefs) > 0 ||
+		session.Spec.ServiceAccountToken != nil
+}
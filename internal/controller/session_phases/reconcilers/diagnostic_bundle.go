@@ -0,0 +1,112 @@
+package reconcilers
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// maxBundleEvents bounds how many pod Events are written to a diagnostic bundle.
+const maxBundleEvents = 100
+
+// writeBundle writes a zip archive with the debugger transcript and the cluster state around the
+// session. Zip entries are streamed with data descriptors, so the transcript never needs to be
+// buffered to learn its size. Parts other than the transcript are best effort and are replaced by
+// an error note when they cannot be collected.
+func (a *logArchiver) writeBundle(ctx context.Context, w io.Writer, session *debugv1alpha1.DebugSession,
+	pod *corev1.Pod, transcript io.Reader, cleaner *logCleaner) error {
+	zw := zip.NewWriter(w)
+	now := time.Now()
+
+	add := func(name string, write func(io.Writer) error) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		return write(f)
+	}
+	addYAML := func(name string, obj interface{}) error {
+		return add(name, func(f io.Writer) error {
+			data, err := yaml.Marshal(obj)
+			if err != nil {
+				return err
+			}
+			_, err = f.Write(data)
+			return err
+		})
+	}
+
+	if err := addYAML("session.yaml", redactSession(session)); err != nil {
+		return err
+	}
+	// The cached pod is trimmed to what the reconcilers read; the bundle gets the whole pod.
+	fullPod, err := a.ClientSet.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		fullPod = pod.DeepCopy()
+	}
+	if err := addYAML("pod.yaml", trimObject(fullPod)); err != nil {
+		return err
+	}
+	if err := add("events.txt", func(f io.Writer) error {
+		events, err := recentPodEvents(ctx, a.ClientSet, types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, maxBundleEvents)
+		if err != nil {
+			_, err = fmt.Fprintf(f, "failed to list pod events: %v\n", err)
+			return err
+		}
+		_, err = io.WriteString(f, strings.Join(events, "\n")+"\n")
+		return err
+	}); err != nil {
+		return err
+	}
+	if pod.Spec.NodeName != "" {
+		node, err := a.ClientSet.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Failed to get node for diagnostic bundle", "node", pod.Spec.NodeName)
+			err = add("node.txt", func(f io.Writer) error {
+				_, err := fmt.Fprintf(f, "failed to get node %s: %v\n", pod.Spec.NodeName, err)
+				return err
+			})
+		} else {
+			node = trimObject(node)
+			// The image list is large and rarely relevant to an incident.
+			node.Status.Images = nil
+			err = addYAML("node.yaml", node)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := add("debugger.log", func(f io.Writer) error {
+		cleaner.w = f
+		_, err := io.Copy(cleaner, transcript)
+		return err
+	}); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// redactSession returns a copy of session without the attach token and server-side bookkeeping.
+func redactSession(session *debugv1alpha1.DebugSession) *debugv1alpha1.DebugSession {
+	s := trimObject(session.DeepCopy())
+	s.Status.OneTimeToken = ""
+	s.Status.LogURL = ""
+	s.Status.LogURLExpiryTime = nil
+	return s
+}
+
+// trimObject drops managed fields, which only add noise to the bundle.
+func trimObject[T metav1.Object](obj T) T {
+	obj.SetManagedFields(nil)
+	return obj
+}
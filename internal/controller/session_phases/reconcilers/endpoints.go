@@ -0,0 +1,62 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// attachPath is the path and query of the session's attach endpoint on the debug proxy.
+func attachPath(session *debugv1alpha1.DebugSession) string {
+	return fmt.Sprintf("/attach?ns=%s&pod=%s&container=%s",
+		session.Spec.TargetNamespace, debugv1alpha1.CurrentTargetPod(session), session.Status.DebuggingContainerName)
+}
+
+// proxyServiceURL returns the base URL of the debug proxy's Service as resolved from inside the cluster.
+func proxyServiceURL(ctx context.Context, clientset kubernetes.Interface) (string, error) {
+	svc, err := clientset.CoreV1().Services(proxyNamespace).Get(ctx, proxyServiceName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get proxy service: %w", err)
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.Name == "http" {
+			host := fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace)
+			return "ws://" + net.JoinHostPort(host, fmt.Sprint(port.Port)), nil
+		}
+	}
+	return "", fmt.Errorf("proxy service has no http port")
+}
+
+// sessionEndpoints builds the session's attach URLs. The external one follows the bastion access the
+// connection instructions use: the overlay address, or the NodePort the SSH or SSM tunnel forwards to.
+// Sessions with connectionMode InCluster, and Teleport, get none.
+func sessionEndpoints(session *debugv1alpha1.DebugSession, bastion debugv1alpha1.BastionAccess,
+	internalURL, nodeIP, nodePort string) *debugv1alpha1.SessionEndpoints {
+	endpoints := &debugv1alpha1.SessionEndpoints{}
+	if internalURL != "" {
+		endpoints.Internal = internalURL + attachPath(session)
+	}
+	switch {
+	case session.Spec.ConnectionMode == debugv1alpha1.ConnectionModeInCluster:
+	case bastion.Overlay != nil:
+		// http:// becomes ws:// and https:// becomes wss://.
+		endpoints.External = "ws" + strings.TrimPrefix(bastion.Overlay.URL, "http") + attachPath(session)
+	case nodeIP != "" && nodePort != "":
+		endpoints.External = "ws://" + net.JoinHostPort(nodeIP, nodePort) + attachPath(session)
+	}
+	return endpoints
+}
+
+// buildInClusterConnectionString creates the instructions for clients in the cluster, which connect to
+// the debug proxy's Service directly.
+func buildInClusterConnectionString(session *debugv1alpha1.DebugSession) string {
+	return fmt.Sprintf(`Session is ready. From a pod in the cluster, run this command to connect.
+It uses the one-time token for authorization.
+   websocat --no-line --binary --header="Authorization: Bearer %s" "%s"`,
+		session.Status.OneTimeToken, session.Status.Endpoints.Internal)
+}
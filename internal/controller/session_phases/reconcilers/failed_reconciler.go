@@ -2,28 +2,271 @@ package reconcilers
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
 	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	"github.com/OxAN0N/KubeDebugSess/internal/vault"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// maxDiagnosticEvents bounds how many pod Events are copied into the session status.
+const maxDiagnosticEvents = 10
+
 func init() {
 	session_phases.Register(debugv1alpha1.Failed, NewFailedReconciler)
 }
 
-func NewFailedReconciler(client client.Client, cs kubernetes.Interface) session_phases.PhaseReconciler {
-	return &FailedReconciler{Client: client, ClientSet: cs}
+func NewFailedReconciler(deps session_phases.Dependencies) session_phases.PhaseReconciler {
+	return &FailedReconciler{
+		Client:    deps.Client,
+		ClientSet: deps.ClientSet,
+		Recorder:  deps.Recorder,
+		Notifier:  deps.Notifier,
+		Vault:     deps.Vault,
+	}
 }
 
 type FailedReconciler struct {
 	client.Client
 	ClientSet kubernetes.Interface
+	Recorder  record.EventRecorder
+	Notifier  *notify.Dispatcher
+	Vault     *vault.Client
 }
 
+// Reconcile collects failure diagnostics and notifies administrators once, writes the session's audit
+// record, then deletes the session when its ttlAfterFailed has elapsed.
 func (r *FailedReconciler) Reconcile(ctx context.Context, session *debugv1alpha1.DebugSession) (ctrl.Result, error) {
-	// TOOD: implement alert to admin or slack
+	if meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionEgressRestricted) ||
+		meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionAppRegistered) ||
+		meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionCredentialsIssued) ||
+		meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionTokenIssued) {
+		if err := releaseEgress(ctx, r.Client, session); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := releaseTeleportApp(ctx, r.Client, session); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := revokeVaultCredentials(ctx, r.Client, r.Vault, session); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := revokeServiceAccountToken(ctx, r.Client, session); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.Status().Update(ctx, session); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if session.Status.Diagnostics == nil {
+		if err := r.diagnose(ctx, session); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	changed, err := writeSessionRecord(ctx, r.Client, session)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if changed {
+		if err := r.Status().Update(ctx, session); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if _, ok := session.Annotations[debugv1alpha1.RetryKey]; ok {
+		return r.retryFromScratch(ctx, session)
+	}
+	return r.scheduleCleanup(ctx, session)
+}
+
+// retryFromScratch starts a session annotated with debug.ajou.oxan0n.me/retry over from Pending. The
+// failed attempt keeps its audit record; the next one injects a new debugger container and gets a
+// new token.
+func (r *FailedReconciler) retryFromScratch(ctx context.Context, session *debugv1alpha1.DebugSession) (ctrl.Result, error) {
+	log.FromContext(ctx).Info("Retrying failed session from scratch.", "restarts", session.Status.Restarts+1)
+
+	// The annotation goes first, so that a failed status write cannot make the session retry twice.
+	base := session.DeepCopy()
+	delete(session.Annotations, debugv1alpha1.RetryKey)
+	if err := r.Patch(ctx, session, client.MergeFrom(base)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	session.Status = debugv1alpha1.DebugSessionStatus{
+		ObservedGeneration: session.Status.ObservedGeneration,
+		Restarts:           session.Status.Restarts + 1,
+	}
+	r.Recorder.Eventf(session, corev1.EventTypeNormal, session_phases.EventReasonSessionRetried,
+		"Session restarted from scratch (restart %d)", session.Status.Restarts)
+	return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Pending,
+		fmt.Sprintf("Retrying from scratch (restart %d).", session.Status.Restarts))
+}
+
+// diagnose records the debugger's last state, recent pod events and retry history in the status and
+// sends the SessionFailed notification. Diagnostics being set marks both as done.
+func (r *FailedReconciler) diagnose(ctx context.Context, session *debugv1alpha1.DebugSession) error {
+	logger := log.FromContext(ctx)
+
+	if session.Spec.TargetNamespace == "" {
+		session.Spec.TargetNamespace = session.Namespace
+	}
+
+	diagnostics := &debugv1alpha1.FailureDiagnostics{
+		CollectedAt: metav1.Now(),
+		Reason:      session.Status.Message,
+		RetryCount:  session.Status.RetryCount,
+	}
+	if len(session.Status.RetryHistory) > 0 {
+		diagnostics.RetryHistory = append([]debugv1alpha1.RetryAttempt(nil), session.Status.RetryHistory...)
+	}
+
+	pod := &corev1.Pod{}
+	podKey := types.NamespacedName{Name: debugv1alpha1.CurrentTargetPod(session), Namespace: session.Spec.TargetNamespace}
+	if err := r.Get(ctx, podKey, pod); err == nil {
+		debuggerName := sessionDebuggerName(session)
+		for _, cs := range pod.Status.EphemeralContainerStatuses {
+			if cs.Name == debuggerName {
+				state := cs.State
+				diagnostics.LastContainerState = &state
+				switch {
+				case state.Waiting != nil:
+					diagnostics.ContainerReason = state.Waiting.Reason
+				case state.Terminated != nil:
+					diagnostics.ContainerReason = state.Terminated.Reason
+					diagnostics.ExitCode = &state.Terminated.ExitCode
+				}
+				break
+			}
+		}
+	} else if !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get target pod for diagnostics")
+	}
+
+	events, err := recentPodEvents(ctx, r.ClientSet, podKey, maxDiagnosticEvents)
+	if err != nil {
+		logger.Error(err, "Failed to list target pod events for diagnostics")
+	}
+	diagnostics.RecentPodEvents = events
+
+	diagnostics.Class = classifyFailure(session, diagnostics)
+
+	session.Status.Diagnostics = diagnostics
+	if session.Status.TerminationTime == nil {
+		session.Status.TerminationTime = &diagnostics.CollectedAt
+	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionDiagnosed, metav1.ConditionTrue, string(diagnostics.Class),
+		summarizeDiagnostics(diagnostics))
+
+	msg := notify.NewWebhookMessage(notify.EventSessionFailed, session)
+	msg.Message = fmt.Sprintf("%s\n%s", session.Status.Message, summarizeDiagnostics(diagnostics))
+	session_phases.NotifySession(ctx, r.Notifier, session, msg)
+
+	if err := r.Status().Update(ctx, session); err != nil {
+		logger.Error(err, "Failed to record failure diagnostics")
+		return err
+	}
+	return nil
+}
+
+// imagePullReasons are the debugger container waiting reasons caused by its image.
+var imagePullReasons = map[string]bool{
+	"ErrImagePull":        true,
+	"ImagePullBackOff":    true,
+	"InvalidImageName":    true,
+	"RegistryUnavailable": true,
+}
+
+// classifyFailure works out the cause of a failure from the conditions the phases left behind and the
+// debugger container's last state.
+func classifyFailure(session *debugv1alpha1.DebugSession, d *debugv1alpha1.FailureDiagnostics) debugv1alpha1.FailureClass {
+	conditionReason := func(conditionType string) string {
+		cond := meta.FindStatusCondition(session.Status.Conditions, conditionType)
+		if cond == nil || cond.Status != metav1.ConditionFalse {
+			return ""
+		}
+		return cond.Reason
+	}
+
+	if conditionReason(debugv1alpha1.ConditionRequesterAuthorized) != "" || conditionReason(debugv1alpha1.ConditionApproved) == "Denied" {
+		return debugv1alpha1.FailureAccessDenied
+	}
+	if conditionReason(debugv1alpha1.ConditionTicketValidated) == "TicketRejected" {
+		return debugv1alpha1.FailureTicketRejected
+	}
+	switch conditionReason(debugv1alpha1.ConditionTargetValidated) {
+	case "TargetProtected":
+		return debugv1alpha1.FailureTargetProtected
+	case "TargetPodLost":
+		return debugv1alpha1.FailureTargetPodLost
+	case "DebuggerLimitReached":
+		return debugv1alpha1.FailureDebuggerLimitReached
+	case "ValidationFailed":
+		return debugv1alpha1.FailureTargetInvalid
+	}
+	switch conditionReason(debugv1alpha1.ConditionInjected) {
+	case "PolicyViolation":
+		return debugv1alpha1.FailurePolicyViolation
+	case "InjectionFailed":
+		return debugv1alpha1.FailureInjectionFailed
+	}
+	if d.RetryCount > 0 && int32(d.RetryCount) >= session.Spec.MaxRetryCount {
+		return debugv1alpha1.FailureRetriesExhausted
+	}
+	if cs := d.LastContainerState; cs != nil {
+		if cs.Waiting != nil && imagePullReasons[cs.Waiting.Reason] {
+			return debugv1alpha1.FailureImagePullFailed
+		}
+		if cs.Terminated != nil && cs.Terminated.Reason != "Completed" {
+			return debugv1alpha1.FailureContainerFailed
+		}
+	}
+	if conditionReason(debugv1alpha1.ConditionArchived) == reasonUploadFailed {
+		return debugv1alpha1.FailureArchiveFailed
+	}
+	return debugv1alpha1.FailureUnknown
+}
+
+// summarizeDiagnostics renders the diagnostics as a one-line summary for conditions and notifications.
+func summarizeDiagnostics(d *debugv1alpha1.FailureDiagnostics) string {
+	state := "unknown"
+	if cs := d.LastContainerState; cs != nil {
+		switch {
+		case cs.Waiting != nil:
+			state = "waiting (" + cs.Waiting.Reason + ")"
+		case cs.Running != nil:
+			state = "running"
+		case cs.Terminated != nil:
+			state = fmt.Sprintf("terminated (%s, exit code %d)", cs.Terminated.Reason, cs.Terminated.ExitCode)
+		}
+	}
+	return fmt.Sprintf("Failure class: %s; debugger state: %s; retries: %d; pod events captured: %d",
+		d.Class, state, d.RetryCount, len(d.RecentPodEvents))
+}
+
+// scheduleCleanup deletes the session once ttlAfterFailed has elapsed since it failed.
+func (r *FailedReconciler) scheduleCleanup(ctx context.Context, session *debugv1alpha1.DebugSession) (ctrl.Result, error) {
+	if session.Spec.TTLAfterFailed == nil || session.Status.TerminationTime == nil {
+		return ctrl.Result{}, nil
+	}
+
+	deadline := session.Status.TerminationTime.Add(time.Duration(*session.Spec.TTLAfterFailed) * time.Second)
+	if remaining := time.Until(deadline); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	log.FromContext(ctx).Info("Deleting failed session after ttlAfterFailed elapsed.")
+	if err := r.Delete(ctx, session); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
 	return ctrl.Result{}, nil
 }
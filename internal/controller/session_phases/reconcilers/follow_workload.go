@@ -0,0 +1,189 @@
+package reconcilers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// replacementWaitTimeout bounds how long a session with spec.followWorkload waits for a replacement
+	// of its lost target pod to run before it fails.
+	replacementWaitTimeout = 5 * time.Minute
+	// replacementPollInterval is how often the replacement is looked for meanwhile.
+	replacementPollInterval = 5 * time.Second
+	// maxPodReplacements is how many moves status.podReplacements keeps.
+	maxPodReplacements = 10
+)
+
+// followsWorkload reports whether a lost target pod should be replaced rather than fail the session.
+// Observers do not own the debugger they watch and fail with the session they observe.
+func followsWorkload(session *debugv1alpha1.DebugSession) bool {
+	return session.Spec.FollowWorkload && session.Status.ReusedFrom == ""
+}
+
+// recordTargetDeployment remembers the Deployment of the target pod for spec.followWorkload while the
+// pod still exists. A pod that no Deployment manages is left unrecorded and cannot be followed.
+func (r *ActiveReconciler) recordTargetDeployment(ctx context.Context, session *debugv1alpha1.DebugSession, pod *corev1.Pod) {
+	if !followsWorkload(session) || session.Status.TargetDeployment != "" {
+		return
+	}
+	deployment, err := r.deploymentOf(ctx, pod)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to resolve the target pod's Deployment")
+		return
+	}
+	session.Status.TargetDeployment = deployment
+}
+
+// deploymentOf returns the name of the Deployment whose ReplicaSet controls pod, or "" if there is none.
+func (r *ActiveReconciler) deploymentOf(ctx context.Context, pod *corev1.Pod) (string, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return "", nil
+	}
+	// ReplicaSets are read uncached: the controller has no reason to watch all of them.
+	rs, err := r.Clientset.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get ReplicaSet %s: %w", owner.Name, err)
+	}
+	if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == "Deployment" {
+		return owner.Name, nil
+	}
+	return "", nil
+}
+
+// followWorkload moves a session whose target pod went away to a running replacement of the pod's
+// Deployment: it salvages the old debugger's transcript, releases what was bound to the old pod and
+// sends the session back to Injecting against the replacement, keeping its token and expiry. Until a
+// replacement runs, it waits up to replacementWaitTimeout. pod is nil once the old pod is gone.
+func (r *ActiveReconciler) followWorkload(ctx context.Context, session *debugv1alpha1.DebugSession, pod *corev1.Pod,
+	reason string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	oldPod := debugv1alpha1.CurrentTargetPod(session)
+	session.Status.ReadyForAttach = false
+
+	if pod != nil && session.Status.TargetDeployment == "" {
+		r.recordTargetDeployment(ctx, session, pod)
+	}
+	deployment := session.Status.TargetDeployment
+	if deployment == "" {
+		reason += ", and it belongs to no Deployment to follow"
+		if pod != nil {
+			return failOnTargetPodLoss(ctx, r.Client, r.archiver, session, pod, reason)
+		}
+		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed,
+			fmt.Sprintf("Session aborted: %s.", reason))
+	}
+
+	cond := meta.FindStatusCondition(session.Status.Conditions, debugv1alpha1.ConditionTargetReplaced)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "WaitingForReplacement" {
+		logger.Info("Target pod lost, following its Deployment to a replacement.", "reason", reason, "deployment", deployment)
+		replacement := debugv1alpha1.PodReplacement{From: oldPod, Time: metav1.Now()}
+		// The old debugger's transcript is salvaged while the terminating pod can still be read.
+		if pod != nil && isEphemeralContainerPresent(pod, sessionDebuggerName(session)) {
+			key, err := r.archiver.archive(ctx, session, pod, sessionDebuggerName(session))
+			switch {
+			case err == nil:
+				replacement.TranscriptKey = key
+			case !errors.Is(err, errArchivingDisabled):
+				logger.Error(err, "Failed to salvage the transcript of the lost target pod")
+			}
+		}
+		session.Status.PodReplacements = append(session.Status.PodReplacements, replacement)
+		if n := len(session.Status.PodReplacements); n > maxPodReplacements {
+			session.Status.PodReplacements = session.Status.PodReplacements[n-maxPodReplacements:]
+		}
+		session_phases.SetCondition(session, debugv1alpha1.ConditionTargetReplaced, metav1.ConditionFalse,
+			"WaitingForReplacement", reason)
+		cond = meta.FindStatusCondition(session.Status.Conditions, debugv1alpha1.ConditionTargetReplaced)
+	}
+
+	replacement, err := r.findReplacement(ctx, session.Spec.TargetNamespace, deployment, oldPod)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if replacement == nil {
+		if time.Since(cond.LastTransitionTime.Time) > replacementWaitTimeout {
+			return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed,
+				fmt.Sprintf("Session aborted: %s, and no replacement of Deployment %s ran within %s.",
+					reason, deployment, replacementWaitTimeout))
+		}
+		session.Status.Message = fmt.Sprintf("Target pod lost: %s. Waiting for a replacement of Deployment %s.", reason, deployment)
+		if err := r.Status().Update(ctx, session); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: replacementPollInterval}, nil
+	}
+
+	// The NetworkPolicy and the credential Secrets are owned by the old pod; they are set up again for
+	// the replacement.
+	if err := releaseEgress(ctx, r.Client, session); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := revokeVaultCredentials(ctx, r.Client, r.Vault, session); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := revokeServiceAccountToken(ctx, r.Client, session); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	session.Status.TargetPodName = replacement.Name
+	session.Status.PodReplacements[len(session.Status.PodReplacements)-1].To = replacement.Name
+	message := fmt.Sprintf("Target pod %s was replaced by %s of Deployment %s", oldPod, replacement.Name, deployment)
+	session_phases.SetCondition(session, debugv1alpha1.ConditionTargetReplaced, metav1.ConditionTrue, "ReplacementFound", message)
+	r.Recorder.Event(session, corev1.EventTypeNormal, session_phases.EventReasonTargetPodReplaced, message)
+	session_phases.NotifySession(ctx, r.Notifier, session, notify.NewWebhookMessage(notify.EventTargetPodReplaced, session))
+	return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Injecting,
+		message+"; injecting the debugger there. Reconnect once the session is ready.")
+}
+
+// findReplacement returns the newest running and ready pod of the Deployment other than the lost one,
+// or nil if there is none yet.
+func (r *ActiveReconciler) findReplacement(ctx context.Context, namespace, deployment, lostPod string) (*corev1.Pod, error) {
+	d, err := r.Clientset.AppsV1().Deployments(namespace).Get(ctx, deployment, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Deployment %s: %w", deployment, err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of Deployment %s: %w", deployment, err)
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list pods of Deployment %s: %w", deployment, err)
+	}
+
+	var newest *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Name == lostPod || pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning || !podReady(pod) {
+			continue
+		}
+		if newest == nil || pod.CreationTimestamp.After(newest.CreationTimestamp.Time) {
+			newest = pod
+		}
+	}
+	return newest, nil
+}
+
+// podReady reports whether the pod's Ready condition is True.
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
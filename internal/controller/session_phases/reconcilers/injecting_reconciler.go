@@ -2,18 +2,23 @@ package reconcilers
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
 	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
+	"github.com/OxAN0N/KubeDebugSess/internal/vault"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -24,16 +29,22 @@ func init() {
 	session_phases.Register(debugv1alpha1.Injecting, NewInjectingReconciler)
 }
 
-func NewInjectingReconciler(c client.Client, cs kubernetes.Interface) session_phases.PhaseReconciler {
+func NewInjectingReconciler(deps session_phases.Dependencies) session_phases.PhaseReconciler {
 	return &InjectingReconciler{
-		Client:    c,
-		ClientSet: cs,
+		Client:    deps.Client,
+		ClientSet: deps.ClientSet,
+		Recorder:  deps.Recorder,
+		Notifier:  deps.Notifier,
+		Vault:     deps.Vault,
 	}
 }
 
 type InjectingReconciler struct {
 	client.Client
 	ClientSet kubernetes.Interface
+	Recorder  record.EventRecorder
+	Notifier  *notify.Dispatcher
+	Vault     *vault.Client
 }
 
 func (r *InjectingReconciler) Reconcile(ctx context.Context, session *debugv1alpha1.DebugSession) (ctrl.Result, error) {
@@ -43,63 +54,208 @@ func (r *InjectingReconciler) Reconcile(ctx context.Context, session *debugv1alp
 		session.Spec.TargetNamespace = session.Namespace
 	}
 
-	podName := session.Spec.TargetPodName
+	podName := debugv1alpha1.CurrentTargetPod(session)
 	pod := &corev1.Pod{}
 
 	if err := r.Get(ctx, types.NamespacedName{
 		Name:      podName,
 		Namespace: session.Spec.TargetNamespace,
 	}, pod); err != nil {
-		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, "Failed to find Target Pod")
+		return r.failInjection(ctx, session, "Failed to find Target Pod")
 	}
 
 	if session.Spec.TargetContainerName == "" {
 		if len(pod.Spec.Containers) > 0 {
 			session.Spec.TargetContainerName = pod.Spec.Containers[0].Name
 		} else {
-			return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, "Failed to find Target Container")
+			return r.failInjection(ctx, session, "Failed to find Target Container")
 		}
 	}
 
-	nodeIP, nodePort, err := r.checkInjectingCondition(ctx, pod)
+	if pod.Spec.ShareProcessNamespace == nil || !*pod.Spec.ShareProcessNamespace {
+		return r.rejectByPolicy(ctx, session, "target pod does not share its process namespace (spec.shareProcessNamespace is false)")
+	}
+
+	inCluster := session.Spec.ConnectionMode == debugv1alpha1.ConnectionModeInCluster
+	var bastion debugv1alpha1.BastionAccess
+	var err error
+	if !inCluster {
+		if bastion, err = bastionAccess(ctx, r.Client, session.Spec.TargetNamespace); err != nil {
+			logger.Error(err, "Failed to read the bastion config, using the defaults")
+		}
+	}
+
+	// In-cluster clients, Teleport and overlay networks reach the proxy without a node address, so no
+	// NodePort is handed out.
+	var nodeIP, nodePort string
+	session.Status.SSMTunnel = nil
+	if !inCluster && bastion.Teleport == nil && bastion.Overlay == nil {
+		var node *corev1.Node
+		node, nodeIP, nodePort, err = r.checkInjectingCondition(ctx, pod)
+		if err != nil {
+			return r.failInjection(ctx, session, fmt.Sprintf("Inject Failed: %v", err))
+		}
+		if bastion.SSM != nil {
+			tunnel, err := ssmTunnel(node, bastion, nodeIP, nodePort)
+			if err != nil {
+				return r.failInjection(ctx, session, fmt.Sprintf("Inject Failed: %v", err))
+			}
+			session.Status.SSMTunnel = tunnel
+		}
+	}
+
+	internalURL, err := proxyServiceURL(ctx, r.ClientSet)
 	if err != nil {
-		return session_phases.UpdateSessionStatus(ctx, r.Client, session,
-			debugv1alpha1.Failed, fmt.Sprintf("Inject Failed: %v", err))
+		if inCluster {
+			return r.failInjection(ctx, session, fmt.Sprintf("Inject Failed: %v", err))
+		}
+		logger.Error(err, "Failed to resolve the in-cluster proxy endpoint")
+	}
+
+	reused, err := findReusableSession(ctx, r.Client, session)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
 	if _, err := r.setUpDebugSess(ctx, session); err != nil {
-		return session_phases.UpdateSessionStatus(ctx, r.Client, session,
-			debugv1alpha1.Failed, fmt.Sprintf("Setup Failed: %v", err))
+		return r.failInjection(ctx, session, fmt.Sprintf("Setup Failed: %v", err))
 	}
 
-	logger.Info("Injection Started")
-	if err := r.injectEphemeralContainer(ctx, session, pod); err != nil {
-		return session_phases.UpdateSessionStatus(ctx, r.Client, session,
-			debugv1alpha1.Failed, fmt.Sprintf("Inject Failed: %v", err))
+	if bastion.Teleport != nil {
+		if err := registerTeleportApp(ctx, r.Client, r.ClientSet, session, bastion.Teleport); err != nil {
+			return r.failInjection(ctx, session, fmt.Sprintf("Setup Failed: %v", err))
+		}
 	}
-	return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Active, buildConnectionString(session, nodeIP, nodePort))
+
+	if reused != nil {
+		// The session gets its own token but observes the other session's debugger.
+		session.Status.ReusedFrom = reused.Namespace + "/" + reused.Name
+		session.Status.DebuggingContainerName = reused.Status.DebuggingContainerName
+		session.Status.DebuggerImage = reused.Status.DebuggerImage
+		session.Status.ToolboxContainerName = reused.Status.ToolboxContainerName
+		session.Status.GrantedCapabilities = reused.Status.GrantedCapabilities
+		reusedMsg := fmt.Sprintf("Observing debugger container %s of session %s instead of injecting another",
+			session.Status.DebuggingContainerName, session.Status.ReusedFrom)
+		r.Recorder.Event(session, corev1.EventTypeNormal, session_phases.EventReasonDebuggerReused, reusedMsg)
+		session_phases.SetCondition(session, debugv1alpha1.ConditionInjected, metav1.ConditionTrue, "DebuggerReused", reusedMsg)
+	} else {
+		securityContext, err := r.debuggerSecurityContext(ctx, session, pod)
+		if err != nil {
+			return r.rejectByPolicy(ctx, session, err.Error())
+		}
+
+		// Restrict egress before the debugger exists, so that it never runs unrestricted.
+		if err := restrictEgress(ctx, r.Client, r.Recorder, session, pod); err != nil {
+			return r.failInjection(ctx, session, fmt.Sprintf("Inject Failed: %v", err))
+		}
+
+		if err := issueVaultCredentials(ctx, r.Client, r.Vault, session, pod); err != nil {
+			return r.failInjection(ctx, session, fmt.Sprintf("Inject Failed: %v", err))
+		}
+		if err := issueServiceAccountToken(ctx, r.Client, r.ClientSet, session, pod); err != nil {
+			return r.failInjection(ctx, session, fmt.Sprintf("Inject Failed: %v", err))
+		}
+
+		logger.Info("Injection Started")
+		if err := r.injectEphemeralContainer(ctx, session, pod, securityContext); err != nil {
+			return r.failInjection(ctx, session, fmt.Sprintf("Inject Failed: %v", err))
+		}
+
+		injectedMsg := fmt.Sprintf("Debugger container %s (image %s) injected targeting container %s",
+			session.Status.DebuggingContainerName, debuggerImage(session), session.Spec.TargetContainerName)
+		r.Recorder.Event(session, corev1.EventTypeNormal, session_phases.EventReasonDebuggerInjected, injectedMsg)
+		r.Recorder.Eventf(pod, corev1.EventTypeNormal, session_phases.EventReasonDebuggerInjected,
+			"%s by DebugSession %s/%s", injectedMsg, session.Namespace, session.Name)
+		session_phases.SetCondition(session, debugv1alpha1.ConditionInjected, metav1.ConditionTrue, "EphemeralContainerCreated", injectedMsg)
+	}
+	// A previous leader may have started the session before losing its status write.
+	if session.Status.StartTime == nil {
+		startTime := metav1.Now()
+		session.Status.StartTime = &startTime
+	}
+	startTime := *session.Status.StartTime
+	if session.Spec.TTL > 0 {
+		expiry := metav1.NewTime(startTime.Add(time.Duration(session.Spec.TTL) * time.Second))
+		session.Status.ExpiryTime = &expiry
+	}
+	// An observer cannot outlive the debugger it observes.
+	if reused != nil && reused.Status.ExpiryTime != nil &&
+		(session.Status.ExpiryTime == nil || reused.Status.ExpiryTime.Before(session.Status.ExpiryTime)) {
+		session.Status.ExpiryTime = reused.Status.ExpiryTime.DeepCopy()
+	}
+	session.Status.Endpoints = sessionEndpoints(session, bastion, internalURL, nodeIP, nodePort)
+	connection := buildConnectionString(session, bastion, nodeIP, nodePort)
+	if inCluster {
+		connection = buildInClusterConnectionString(session)
+	}
+	return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Active, connection)
 }
 
-func (r *InjectingReconciler) checkInjectingCondition(ctx context.Context, pod *corev1.Pod) (string, string, error) {
-	logger := log.FromContext(ctx)
+// failInjection records why the debugger could not be injected and fails the session.
+func (r *InjectingReconciler) failInjection(ctx context.Context, session *debugv1alpha1.DebugSession, message string) (ctrl.Result, error) {
+	session_phases.SetCondition(session, debugv1alpha1.ConditionInjected, metav1.ConditionFalse, "InjectionFailed", message)
+	return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, message)
+}
 
-	if pod.Spec.ShareProcessNamespace == nil || !*pod.Spec.ShareProcessNamespace {
-		return "", "", fmt.Errorf("pod.Spec.ShareProcessNamespace is false")
+// rejectByPolicy fails a session whose target does not allow debugging and sends a PolicyViolation notification.
+func (r *InjectingReconciler) rejectByPolicy(ctx context.Context, session *debugv1alpha1.DebugSession, reason string) (ctrl.Result, error) {
+	message := fmt.Sprintf("Inject Failed: %s", reason)
+	session.Status.Message = message
+	session_phases.NotifySession(ctx, r.Notifier, session, notify.NewWebhookMessage(notify.EventPolicyViolation, session))
+	session_phases.SetCondition(session, debugv1alpha1.ConditionInjected, metav1.ConditionFalse, "PolicyViolation", message)
+	return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, message)
+}
+
+// debuggerSecurityContext builds the debugger's security context and conforms it to the target
+// namespace's Pod Security Standards level, recording any adjustment made.
+func (r *InjectingReconciler) debuggerSecurityContext(ctx context.Context, session *debugv1alpha1.DebugSession,
+	pod *corev1.Pod) (*corev1.SecurityContext, error) {
+	sc := buildSecurityContext(session.Spec.DebugSecurity)
+	addToolsetCapabilities(session, sc)
+	addRuntimeAttachCapabilities(session, sc)
+	addCaptureCapabilities(session, sc)
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: pod.Namespace}, namespace); err != nil {
+		return nil, fmt.Errorf("failed to get target namespace: %w", err)
 	}
+	adjustments, err := conformToPodSecurity(namespace, pod, sc)
+	if err != nil {
+		return nil, err
+	}
+	if len(adjustments) > 0 {
+		r.Recorder.Eventf(session, corev1.EventTypeNormal, session_phases.EventReasonSecurityContextAdjusted,
+			"Adjusted the debugger security context for the %s Pod Security Standard: %s",
+			namespace.Labels[podSecurityEnforceLabel], strings.Join(adjustments, ", "))
+	}
+	session.Status.GrantedCapabilities = nil
+	if sc.Capabilities != nil {
+		session.Status.GrantedCapabilities = append([]corev1.Capability(nil), sc.Capabilities.Add...)
+	}
+	return sc, nil
+}
 
-	nodeIP, nodePort, err := getProxyServiceNodeInfo(ctx, r.ClientSet)
+func (r *InjectingReconciler) checkInjectingCondition(ctx context.Context, pod *corev1.Pod) (*corev1.Node, string, string, error) {
+	logger := log.FromContext(ctx)
+
+	node, nodeIP, nodePort, err := getProxyServiceNodeInfo(ctx, r.ClientSet, pod.Spec.NodeName)
 	if err != nil {
 		logger.Error(err, "Failed to get proxy NodePort info")
-		return nodeIP, nodePort, err
+		return nil, nodeIP, nodePort, err
 	}
 
-	return nodeIP, nodePort, nil
+	return node, nodeIP, nodePort, nil
 }
 
 func (r *InjectingReconciler) setUpDebugSess(ctx context.Context, session *debugv1alpha1.DebugSession) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
-	token, err := generateSecureToken(32)
+	// Keep a token issued by an earlier attempt so a client that already read it can still attach.
+	if session.Status.OneTimeToken != "" {
+		return ctrl.Result{}, nil
+	}
+
+	token, err := session_phases.GenerateSecureToken(32)
 	if err != nil {
 		logger.Error(err, "Failed to generate session token")
 		return ctrl.Result{}, err
@@ -115,19 +271,35 @@ func (r *InjectingReconciler) setUpDebugSess(ctx context.Context, session *debug
 	return ctrl.Result{}, nil
 }
 
-func (r *InjectingReconciler) injectEphemeralContainer(ctx context.Context, session *debugv1alpha1.DebugSession, pod *corev1.Pod) error {
+func (r *InjectingReconciler) injectEphemeralContainer(ctx context.Context, session *debugv1alpha1.DebugSession, pod *corev1.Pod,
+	securityContext *corev1.SecurityContext) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "InjectEphemeralContainer")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	// Setup commands are passed as SETUP_COMMAND_<n> variables and eval'd in the shell that turns
+	// interactive, so that their cd and export carry over. The runtime attach script runs after them.
 	debugScript := `
     trap 'exit 0' EXIT TERM INT
     ( sleep ${TTL:-300} && exit 0 ) &
+    i=0
+    while eval "c=\${SETUP_COMMAND_$i-}"; [ -n "$c" ]; do
+      echo "+ $c"
+      eval "$c" || echo "setup command exited with status $?"
+      i=$((i+1))
+    done
+    [ -z "${RUNTIME_ATTACH_SCRIPT-}" ] || /bin/sh -c "$RUNTIME_ATTACH_SCRIPT"
     exec /bin/sh -i
 	`
 
-	debuggerName := fmt.Sprintf("debugger-%s", session.UID)
+	debuggerName := debugv1alpha1.DebuggerContainerName(session)
 
 	ec := corev1.EphemeralContainer{
 		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
 			Name:    debuggerName,
-			Image:   session.Spec.DebuggerImage,
+			Image:   debuggerImage(session),
 			Command: []string{"/bin/sh"},
 			Args:    []string{"-c", debugScript},
 			Stdin:   true,
@@ -138,17 +310,58 @@ func (r *InjectingReconciler) injectEphemeralContainer(ctx context.Context, sess
 		},
 		TargetContainerName: session.Spec.TargetContainerName,
 	}
+	for i, command := range setupCommands(session) {
+		ec.Env = append(ec.Env, corev1.EnvVar{Name: fmt.Sprintf("SETUP_COMMAND_%d", i), Value: command})
+	}
+	ec.Env = append(ec.Env, runtimeAttachEnv(session, pod)...)
+	ec.Env = append(ec.Env, serviceAccountTokenEnv(session)...)
+	ec.EnvFrom = append(configRefsEnvFrom(session), vaultCredentialsEnvFrom(session)...)
+	// A capture action runs unattended in place of the shell and exits when it is done.
+	if capture := session.Spec.Capture; capture != nil {
+		ec.Args = []string{"-c", captureScript(capture)}
+		ec.Stdin, ec.TTY = false, false
+		ec.Env = append(ec.Env, captureEnv(session, pod)...)
+	}
 
-	ec.SecurityContext = buildSecurityContext(session.Spec.DebugSecurity)
+	ec.SecurityContext = securityContext
+	containers := []corev1.EphemeralContainer{ec}
+
+	var toolboxName string
+	if session.Spec.ToolboxImage != "" {
+		toolboxName = debugv1alpha1.ToolboxContainerName(session)
+		ec.Env = append(ec.Env, corev1.EnvVar{Name: "TOOLBOX_CONTAINER", Value: toolboxName})
+		containers[0] = ec
+		containers = append(containers, corev1.EphemeralContainer{
+			EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+				Name:            toolboxName,
+				Image:           session.Spec.ToolboxImage,
+				Command:         []string{"/bin/sh", "-c", toolboxScript},
+				SecurityContext: securityContext,
+			},
+			TargetContainerName: session.Spec.TargetContainerName,
+		})
+	}
 
-	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, ec)
-	if _, err := r.ClientSet.CoreV1().
-		Pods(session.Spec.TargetNamespace).
-		UpdateEphemeralContainers(ctx, pod.Name, pod, metav1.UpdateOptions{}); err != nil {
-		return fmt.Errorf("failed to update ephemeral containers: %w", err)
+	// Ephemeral containers cannot be removed or renamed, so a container injected by an earlier attempt
+	// (e.g. before a leader failover) is reused rather than added twice.
+	added := false
+	for _, c := range containers {
+		if !hasEphemeralContainer(pod, c.Name) {
+			pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, c)
+			added = true
+		}
+	}
+	if added {
+		if _, err := r.ClientSet.CoreV1().
+			Pods(session.Spec.TargetNamespace).
+			UpdateEphemeralContainers(ctx, pod.Name, pod, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update ephemeral containers: %w", err)
+		}
 	}
 
 	session.Status.DebuggingContainerName = debuggerName
+	session.Status.DebuggerImage = debuggerImage(session)
+	session.Status.ToolboxContainerName = toolboxName
 	if err := r.Status().Update(ctx, session); err != nil {
 		return fmt.Errorf("failed to update session status with debugging container name: %w", err)
 	}
@@ -156,75 +369,103 @@ func (r *InjectingReconciler) injectEphemeralContainer(ctx context.Context, sess
 	return nil
 }
 
-// buildConnectionString creates the user instructions for connecting to the debug proxy.
-func buildConnectionString(session *debugv1alpha1.DebugSession, nodeIP, nodePort string) string {
-	bastionHost := os.Getenv("BASTION_HOST")
-	if bastionHost == "" {
-		bastionHost = "your-user@bastion.example.com"
+// toolboxScript keeps the toolbox container alive, without a TTY, until it is stopped.
+const toolboxScript = `trap 'exit 0' TERM INT; while :; do sleep 3600 & wait $!; done`
+
+// hasEphemeralContainer reports whether the pod already has an ephemeral container with the given name.
+func hasEphemeralContainer(pod *corev1.Pod, name string) bool {
+	for _, ec := range pod.Spec.EphemeralContainers {
+		if ec.Name == name {
+			return true
+		}
 	}
-	localPort := "8080"
+	return false
+}
 
-	return fmt.Sprintf(`Session is ready. Open TWO terminals and follow the steps:
+// buildConnectionString creates the user instructions for connecting to the debug proxy: straight over
+// an overlay network, or through an SSH tunnel to the proxy's node, the session's Teleport app or an
+// SSM port forwarding.
+func buildConnectionString(session *debugv1alpha1.DebugSession, bastion debugv1alpha1.BastionAccess, nodeIP, nodePort string) string {
+	localPort := strconv.Itoa(int(bastion.LocalPort))
+	attachQuery := attachPath(session)
+
+	if overlay := bastion.Overlay; overlay != nil {
+		// http:// becomes ws:// and https:// becomes wss://.
+		wsURL := "ws" + strings.TrimPrefix(overlay.URL, "http") + attachQuery
+		return fmt.Sprintf(`Session is ready. From a machine on the overlay network, run this command to connect.
+It uses the one-time token for authorization.
+   websocat --no-line --binary --header="Authorization: Bearer %s" "%s"`,
+			session.Status.OneTimeToken, wsURL)
+	}
 
---- Terminal 1: Create a secure tunnel ---
+	var tunnel string
+	if teleport := bastion.Teleport; teleport != nil {
+		login := "tsh login --proxy=" + teleport.Proxy
+		if teleport.Cluster != "" {
+			login += " " + teleport.Cluster
+		}
+		tunnel = fmt.Sprintf(`--- Terminal 1: Open a local proxy to the session's Teleport app ---
+1. Log in to Teleport, then run the second command and leave it running. It forwards local port %s to the debug proxy.
+   %s
+   tsh proxy app %s --port %s`,
+			localPort, login, teleportAppName(session), localPort)
+	} else if ssm := session.Status.SSMTunnel; bastion.SSM != nil && ssm != nil {
+		tunnel = fmt.Sprintf(`--- Terminal 1: Start an SSM port-forwarding session ---
+1. Run this command and leave it running, or run "kubectl debugsess tunnel -n %s %s". It forwards local port %s to the debug proxy through AWS Systems Manager.
+   %s`,
+			session.Namespace, session.Name, localPort, strings.Join(ssm.StartSessionCommand(), " "))
+	} else {
+		sshPort := ""
+		if bastion.Port != 0 {
+			sshPort = fmt.Sprintf("-p %d ", bastion.Port)
+		}
+		tunnel = fmt.Sprintf(`--- Terminal 1: Create a secure tunnel ---
 1. Run this command and leave it running. It forwards local port %s to the debug proxy via the bastion host.
-   ssh -L %s:%s:%s %s
+   ssh %s-L %s:%s %s`,
+			localPort, sshPort, localPort, net.JoinHostPort(nodeIP, nodePort), bastion.Host)
+	}
+
+	return fmt.Sprintf(`Session is ready. Open TWO terminals and follow the steps:
+
+%s
 
 --- Terminal 2: Connect to the debug session ---
 2. Once the tunnel is active, run this command in a new terminal. It uses the one-time token for authorization.
-   websocat --no-line --binary --header="Authorization: Bearer %s" "ws://localhost:%s/attach?ns=%s&pod=%s&container=%s"`,
-		localPort, localPort, nodeIP, nodePort, bastionHost,
+   websocat --no-line --binary --header="Authorization: Bearer %s" "ws://localhost:%s%s"`,
+		tunnel,
 		session.Status.OneTimeToken,
 		localPort,
-		session.Spec.TargetNamespace,
-		session.Spec.TargetPodName,
-		session.Status.DebuggingContainerName,
+		attachQuery,
 	)
 }
 
-// generateSecureToken creates a cryptographically secure, random hex string.
-func generateSecureToken(length int) (string, error) {
-	bytes := make([]byte, length)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
-	}
-	return hex.EncodeToString(bytes), nil
-}
-
-func getProxyServiceNodeInfo(ctx context.Context, clientset kubernetes.Interface) (string, string, error) {
-	svc, err := clientset.CoreV1().Services("kubedebugsess-system").Get(ctx, "kubedebugsess-proxy-svc", metav1.GetOptions{})
+// getProxyServiceNodeInfo returns the node, node address and port clients connect to the proxy on. When
+// the proxy runs node-local (PROXY_NODE_LOCAL=true, a DaemonSet), that is the target pod's node so the
+// attach never leaves it; PROXY_HOST_PORT replaces the NodePort for a hostNetwork proxy.
+// Otherwise it is the target pod's node too, if it is Ready, schedulable and matches PROXY_NODE_SELECTOR,
+// or else the first node that is. PROXY_NODE_ADDRESS and PROXY_ADDRESS_FAMILY choose which of the
+// node's addresses is handed out.
+func getProxyServiceNodeInfo(ctx context.Context, clientset kubernetes.Interface, targetNodeName string) (*corev1.Node, string, string, error) {
+	svc, err := clientset.CoreV1().Services(proxyNamespace).Get(ctx, proxyServiceName, metav1.GetOptions{})
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get service: %w", err)
+		return nil, "", "", fmt.Errorf("failed to get service: %w", err)
 	}
 
 	if len(svc.Spec.Ports) == 0 {
-		return "", "", fmt.Errorf("no ports found in service")
+		return nil, "", "", fmt.Errorf("no ports found in service")
 	}
 
 	nodePort := fmt.Sprintf("%d", svc.Spec.Ports[0].NodePort)
-	nodeList, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return "", "", fmt.Errorf("failed to list nodes: %w", err)
-	}
-	if len(nodeList.Items) == 0 {
-		return "", "", fmt.Errorf("no nodes found in cluster")
+	if hostPort := os.Getenv("PROXY_HOST_PORT"); hostPort != "" {
+		nodePort = hostPort
 	}
 
-	var nodeIP string
-	for _, addr := range nodeList.Items[0].Status.Addresses {
-		if addr.Type == corev1.NodeExternalIP {
-			nodeIP = addr.Address
-			break
-		}
-		if addr.Type == corev1.NodeInternalIP && nodeIP == "" {
-			nodeIP = addr.Address
-		}
-	}
-	if nodeIP == "" {
-		nodeIP = "127.0.0.1"
+	node, err := proxyNode(ctx, clientset, targetNodeName, os.Getenv("PROXY_NODE_LOCAL") == "true")
+	if err != nil {
+		return nil, "", "", err
 	}
 
-	return nodeIP, nodePort, nil
+	return node, selectNodeAddress(node, os.Getenv("PROXY_NODE_ADDRESS"), os.Getenv("PROXY_ADDRESS_FAMILY")), nodePort, nil
 }
 
 // --- helpers ---
@@ -0,0 +1,218 @@
+package reconcilers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/storage"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// reasonUploadFailed is the Archived condition reason of a transcript upload that did not succeed.
+const reasonUploadFailed = "UploadFailed"
+
+// errArchivingDisabled is returned by archive when no log storage backend is configured.
+var errArchivingDisabled = errors.New("transcript archival is disabled: no log storage backend configured")
+
+// logArchiver fetches the debugger container's logs and uploads them to log storage.
+// It is shared by every phase that may need to preserve a session transcript.
+type logArchiver struct {
+	ClientSet kubernetes.Interface
+	Storage   storage.Storage
+	URLExpiry time.Duration
+	KeyLayout *storage.KeyLayout
+}
+
+func newLogArchiver(deps session_phases.Dependencies) *logArchiver {
+	expiry := deps.LogURLExpiry
+	if expiry == 0 {
+		expiry = storage.DefaultPresignExpiry
+	}
+	return &logArchiver{
+		ClientSet: deps.ClientSet,
+		Storage:   deps.Storage,
+		URLExpiry: expiry,
+		KeyLayout: deps.KeyLayout,
+	}
+}
+
+// objectInfo describes the objects archived for containerName in the session's target pod.
+func objectInfo(session *debugv1alpha1.DebugSession, pod *corev1.Pod, containerName string) storage.ObjectInfo {
+	now := time.Now()
+	info := storage.ObjectInfo{
+		Namespace:        pod.Namespace,
+		SessionNamespace: session.Namespace,
+		Session:          session.Name,
+		UID:              string(session.UID),
+		User:             requestedBy(session),
+		Pod:              pod.Name,
+		Container:        containerName,
+		Time:             now,
+		Ticket:           session.Spec.TicketRef,
+		Timestamp:        now.Unix(),
+	}
+	if attribution := session.Status.CostAttribution; attribution != nil {
+		info.Team, info.CostCenter = attribution.Team, attribution.CostCenter
+	}
+	return info
+}
+
+// archive streams the logs of the given debugger container to storage, returning the storage key.
+// The transcript is never held in memory as a whole, so chatty sessions cannot exhaust the controller.
+// Sessions with archiveFormat Bundle get a zip with the surrounding cluster state instead.
+func (a *logArchiver) archive(ctx context.Context, session *debugv1alpha1.DebugSession, pod *corev1.Pod,
+	containerName string) (_ string, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ArchiveTranscript")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	if a.Storage == nil {
+		return "", errArchivingDisabled
+	}
+
+	stream, err := a.fetchEphemeralLogs(ctx, pod, containerName)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch ephemeral logs: %w", err)
+	}
+	defer stream.Close()
+
+	cleaner := &logCleaner{}
+	produce := func(w io.Writer) error {
+		cleaner.w = w
+		_, err := io.Copy(cleaner, stream)
+		return err
+	}
+	if session.Spec.ArchiveFormat == debugv1alpha1.ArchiveFormatBundle {
+		produce = func(w io.Writer) error {
+			return a.writeBundle(ctx, w, session, pod, stream, cleaner)
+		}
+	}
+
+	info := objectInfo(session, pod, containerName)
+	key, err := a.KeyLayout.Key(info)
+	if err != nil {
+		return "", err
+	}
+	key += "." + session.Spec.ArchiveFormat.Extension()
+	if err := a.upload(ctx, key, info.Tags(), produce); err != nil {
+		return "", fmt.Errorf("failed to upload logs: %w", err)
+	}
+
+	log.FromContext(ctx).Info("Streamed and cleaned ephemeral container logs",
+		"logKey", key, "rawSize", cleaner.read, "cleanSize", cleaner.written)
+	return key, nil
+}
+
+// recordTranscript stores the transcript key and, when the backend supports it, a presigned
+// download URL in the session status. A failed presign only leaves the URL empty.
+func (a *logArchiver) recordTranscript(ctx context.Context, session *debugv1alpha1.DebugSession, key string) {
+	session.Status.LogKey = key
+	session.Status.LogURL = ""
+	session.Status.LogURLExpiryTime = nil
+
+	expiresAt := metav1.NewTime(time.Now().Add(a.URLExpiry))
+	url, err := a.Storage.Presign(ctx, key, a.URLExpiry)
+	if errors.Is(err, storage.ErrPresignUnsupported) {
+		return
+	}
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to presign transcript URL", "logKey", key)
+		return
+	}
+	session.Status.LogURL = url
+	session.Status.LogURLExpiryTime = &expiresAt
+}
+
+func (a *logArchiver) fetchEphemeralLogs(ctx context.Context, pod *corev1.Pod, containerName string) (io.ReadCloser, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("Fetching logs for ephemeral container", "container", containerName)
+
+	opts := &corev1.PodLogOptions{
+		Container:  containerName,
+		Timestamps: true,
+	}
+
+	req := a.ClientSet.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts)
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log stream: %w", err)
+	}
+	return stream, nil
+}
+
+// upload pipes whatever produce writes into storage under key.
+func (a *logArchiver) upload(ctx context.Context, key string, tags map[string]string, produce func(io.Writer) error) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(produce(pw))
+	}()
+
+	err := a.Storage.Put(ctx, key, pr, tags)
+	// Unblock the producer if the upload stopped reading early.
+	pr.CloseWithError(err)
+	return err
+}
+
+// logCleaner strips terminal escape sequences and control characters from a debugger transcript
+// and collapses runs of blank lines. It keeps its state between writes, so sequences split across
+// chunks are handled.
+type logCleaner struct {
+	w        io.Writer
+	inEscape bool
+	newlines int
+	buf      []byte
+	read     int64
+	written  int64
+}
+
+func (c *logCleaner) Write(p []byte) (int, error) {
+	c.buf = c.buf[:0]
+	for _, b := range p {
+		if b == 0x1b {
+			c.inEscape = true
+			continue
+		}
+
+		if c.inEscape {
+			if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || b == '~' {
+				c.inEscape = false
+			}
+			continue
+		}
+
+		if b == '\r' || b == '\x07' || b == '\x08' {
+			continue
+		}
+
+		// 연속 공백/개행 정리 (선택)
+		if b == '\n' {
+			c.newlines++
+			if c.newlines > 2 {
+				continue
+			}
+		} else {
+			c.newlines = 0
+		}
+
+		c.buf = append(c.buf, b)
+	}
+
+	c.read += int64(len(p))
+	n, err := c.w.Write(c.buf)
+	c.written += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
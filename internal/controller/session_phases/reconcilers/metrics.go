@@ -0,0 +1,56 @@
+package reconcilers
+
+import (
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	sessionsFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubedebugsess_sessions_finished_total",
+		Help: "DebugSessions that finished, per cost attribution and outcome.",
+	}, []string{"team", "cost_center", "outcome"})
+
+	sessionSeconds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubedebugsess_session_seconds_total",
+		Help: "Seconds finished DebugSessions were live, per cost attribution.",
+	}, []string{"team", "cost_center"})
+
+	timeToReady = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kubedebugsess_session_time_to_ready_seconds",
+		Help:    "Seconds from the creation of a DebugSession until its debugger was ready for attach, per target namespace and debugger image.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10), // 1s .. 8.5m
+	}, []string{"namespace", "image"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(sessionsFinished, sessionSeconds, timeToReady)
+}
+
+// observeTimeToReady records how long the session took to become ready, once, when it first does.
+func observeTimeToReady(session *debugv1alpha1.DebugSession) {
+	if session.Status.ReadyTime == nil {
+		return
+	}
+	image := session.Status.DebuggerImage
+	if image == "" {
+		image = debuggerImage(session)
+	}
+	namespace := session.Spec.TargetNamespace
+	if namespace == "" {
+		namespace = session.Namespace
+	}
+	elapsed := session.Status.ReadyTime.Sub(session.CreationTimestamp.Time).Seconds()
+	timeToReady.WithLabelValues(namespace, image).Observe(elapsed)
+}
+
+// observeFinishedSession counts a session once its DebugSessionRecord is written.
+func observeFinishedSession(record *debugv1alpha1.DebugSessionRecord) {
+	var team, costCenter string
+	if attribution := record.Spec.CostAttribution; attribution != nil {
+		team, costCenter = attribution.Team, attribution.CostCenter
+	}
+	sessionsFinished.WithLabelValues(team, costCenter, string(record.Spec.Outcome)).Inc()
+	sessionSeconds.WithLabelValues(team, costCenter).Add(float64(record.Spec.DurationSeconds))
+}
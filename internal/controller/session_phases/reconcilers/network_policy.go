@@ -0,0 +1,153 @@
+package reconcilers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// egressLabelPrefix marks the target pod of a session with a restricted egress policy, so that the
+// NetworkPolicy selects that pod alone and not its replicas. Each session gets its own key.
+const egressLabelPrefix = "egress.debug.ajou.oxan0n.me/"
+
+func egressPolicyName(session *debugv1alpha1.DebugSession) string {
+	return fmt.Sprintf("debugsession-%s", session.UID)
+}
+
+// restrictEgress labels the target pod and creates the NetworkPolicy for the session's egressPolicy.
+// The policy is owned by the pod so that it goes away with it even if the session never terminates.
+// NetworkPolicies add up, so when another policy already allows the pod egress the restriction cannot
+// take effect: nothing is created, and the EgressRestricted condition says so.
+func restrictEgress(ctx context.Context, c client.Client, recorder record.EventRecorder, session *debugv1alpha1.DebugSession, pod *corev1.Pod) error {
+	policy := session.Spec.EgressPolicy
+	if policy == "" || policy == debugv1alpha1.EgressUnrestricted {
+		return nil
+	}
+
+	others, err := egressAllowingPolicies(ctx, c, pod, egressPolicyName(session))
+	if err != nil {
+		return fmt.Errorf("failed to list NetworkPolicies: %w", err)
+	}
+	if len(others) > 0 {
+		message := fmt.Sprintf("NetworkPolicies %s already allow the target pod egress, so it cannot be restricted to %s",
+			strings.Join(others, ", "), policy)
+		session_phases.SetCondition(session, debugv1alpha1.ConditionEgressRestricted, metav1.ConditionFalse, "NotEnforceable", message)
+		recorder.Event(session, corev1.EventTypeWarning, session_phases.EventReasonEgressNotRestricted, message)
+		return nil
+	}
+
+	label := egressLabelPrefix + string(session.UID)
+	if err := patchPodLabel(ctx, c, pod, label, ptr.To("true")); err != nil {
+		return fmt.Errorf("failed to label target pod: %w", err)
+	}
+
+	dnsPort := intstr.FromInt32(53)
+	udp, tcp := corev1.ProtocolUDP, corev1.ProtocolTCP
+	rules := []networkingv1.NetworkPolicyEgressRule{{
+		Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: &dnsPort}, {Protocol: &tcp, Port: &dnsPort}},
+	}}
+	if policy == debugv1alpha1.EgressClusterInternal {
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			To: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}},
+		})
+	}
+
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      egressPolicyName(session),
+			Namespace: pod.Namespace,
+			Labels:    map[string]string{debugv1alpha1.SessionNamespaceLabel: session.Namespace},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       pod.Name,
+				UID:        pod.UID,
+			}},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{label: "true"}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      rules,
+		},
+	}
+	if err := c.Create(ctx, np); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create NetworkPolicy: %w", err)
+	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionEgressRestricted, metav1.ConditionTrue, string(policy),
+		fmt.Sprintf("NetworkPolicy %s restricts the target pod's egress to %s", np.Name, policy))
+	return nil
+}
+
+// egressAllowingPolicies returns the names of the NetworkPolicies, other than skip, that select the
+// pod and allow it some egress.
+func egressAllowingPolicies(ctx context.Context, c client.Reader, pod *corev1.Pod, skip string) ([]string, error) {
+	var policies networkingv1.NetworkPolicyList
+	if err := c.List(ctx, &policies, client.InNamespace(pod.Namespace)); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, np := range policies.Items {
+		if np.Name == skip || len(np.Spec.Egress) == 0 {
+			continue
+		}
+		// Without policyTypes, a policy with egress rules restricts, and so allows, egress.
+		if len(np.Spec.PolicyTypes) > 0 && !slices.Contains(np.Spec.PolicyTypes, networkingv1.PolicyTypeEgress) {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+		if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		names = append(names, np.Name)
+	}
+	return names, nil
+}
+
+// releaseEgress deletes the session's NetworkPolicy and unlabels the target pod. It only acts while
+// the EgressRestricted condition is True, so calling it again is cheap.
+func releaseEgress(ctx context.Context, c client.Client, session *debugv1alpha1.DebugSession) error {
+	if !meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionEgressRestricted) {
+		return nil
+	}
+	targetNamespace := session.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = session.Namespace
+	}
+
+	np := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: egressPolicyName(session), Namespace: targetNamespace}}
+	if err := c.Delete(ctx, np); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete NetworkPolicy: %w", err)
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: debugv1alpha1.CurrentTargetPod(session), Namespace: targetNamespace}}
+	if err := patchPodLabel(ctx, c, pod, egressLabelPrefix+string(session.UID), nil); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to unlabel target pod: %w", err)
+	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionEgressRestricted, metav1.ConditionFalse, "PolicyRemoved",
+		fmt.Sprintf("NetworkPolicy %s was deleted", np.Name))
+	return nil
+}
+
+// patchPodLabel sets the label on the pod, or removes it when value is nil, without touching the others.
+func patchPodLabel(ctx context.Context, c client.Client, pod *corev1.Pod, key string, value *string) error {
+	patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"labels": map[string]*string{key: value}}})
+	if err != nil {
+		return err
+	}
+	return c.Patch(ctx, pod, client.RawPatch(types.MergePatchType, patch))
+}
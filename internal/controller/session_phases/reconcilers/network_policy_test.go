@@ -0,0 +1,63 @@
+package reconcilers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEgressAllowingPolicies(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web-0", Labels: map[string]string{"app": "web"}}}
+	allowAll := []networkingv1.NetworkPolicyEgressRule{{}}
+	policy := func(name, namespace string, selector map[string]string, types []networkingv1.PolicyType,
+		egress []networkingv1.NetworkPolicyEgressRule) client.Object {
+		return &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: selector},
+				PolicyTypes: types,
+				Egress:      egress,
+			},
+		}
+	}
+	egressOnly := []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}
+	ingressOnly := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+
+	tests := []struct {
+		name     string
+		policies []client.Object
+		want     []string
+	}{
+		{name: "no policies"},
+		{name: "session's own policy", policies: []client.Object{policy("own", "apps", nil, egressOnly, allowAll)}},
+		{name: "allowing policy", policies: []client.Object{policy("allow", "apps", map[string]string{"app": "web"}, egressOnly, allowAll)}, want: []string{"allow"}},
+		{name: "selects every pod", policies: []client.Object{policy("allow", "apps", nil, nil, allowAll)}, want: []string{"allow"}},
+		{name: "deny all egress", policies: []client.Object{policy("deny", "apps", nil, egressOnly, nil)}},
+		{name: "ingress only", policies: []client.Object{policy("ingress", "apps", nil, ingressOnly, allowAll)}},
+		{name: "other pods", policies: []client.Object{policy("db", "apps", map[string]string{"app": "db"}, egressOnly, allowAll)}},
+		{name: "other namespace", policies: []client.Object{policy("allow", "other", nil, egressOnly, allowAll)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.policies...).Build()
+			got, err := egressAllowingPolicies(context.Background(), c, pod, "own")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("egressAllowingPolicies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
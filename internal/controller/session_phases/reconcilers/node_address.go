@@ -0,0 +1,130 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Values of PROXY_NODE_ADDRESS. Besides these, "label:<key>" hands out the value of the node label <key>.
+const (
+	// nodeAddressExternalIP prefers the node's external address and falls back to its internal one.
+	nodeAddressExternalIP = "ExternalIP"
+	// nodeAddressInternalIP prefers the node's internal address, for private clusters whose external
+	// addresses are unreachable from the bastion.
+	nodeAddressInternalIP = "InternalIP"
+	// nodeAddressHostname prefers the node's hostname.
+	nodeAddressHostname = "Hostname"
+
+	nodeAddressLabelPrefix = "label:"
+)
+
+// nodeAddressTypes are the address types tried in order for each strategy.
+var nodeAddressTypes = map[string][]corev1.NodeAddressType{
+	nodeAddressExternalIP: {corev1.NodeExternalIP, corev1.NodeInternalIP},
+	nodeAddressInternalIP: {corev1.NodeInternalIP, corev1.NodeExternalIP},
+	nodeAddressHostname:   {corev1.NodeHostName, corev1.NodeInternalDNS, corev1.NodeInternalIP},
+}
+
+// proxyNode returns the node whose address clients are sent to. A node-local proxy only serves its own
+// node, so that is always the target pod's node. Otherwise any node forwards the NodePort, and the
+// target pod's node is preferred only if it is usable, so users are never sent to a cordoned, NotReady
+// or unrelated node.
+func proxyNode(ctx context.Context, clientset kubernetes.Interface, targetNodeName string, nodeLocal bool) (*corev1.Node, error) {
+	selector, err := labels.Parse(os.Getenv("PROXY_NODE_SELECTOR"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY_NODE_SELECTOR: %w", err)
+	}
+
+	if targetNodeName != "" {
+		node, err := clientset.CoreV1().Nodes().Get(ctx, targetNodeName, metav1.GetOptions{})
+		if err != nil && nodeLocal {
+			return nil, fmt.Errorf("failed to get target pod's node: %w", err)
+		}
+		if err == nil && (nodeLocal || usableNode(node, selector)) {
+			return node, nil
+		}
+	}
+
+	nodeList, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for i := range nodeList.Items {
+		if usableNode(&nodeList.Items[i], selector) {
+			return &nodeList.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no Ready, schedulable node matches PROXY_NODE_SELECTOR %q", selector.String())
+}
+
+// usableNode reports whether the node is Ready, not cordoned and matches the selector.
+func usableNode(node *corev1.Node, selector labels.Selector) bool {
+	if node.Spec.Unschedulable || !selector.Matches(labels.Set(node.Labels)) {
+		return false
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// selectNodeAddress returns the node address clients are sent to under the given strategy, which
+// defaults to nodeAddressExternalIP; a label strategy falls back to it when the node lacks the label.
+// On dual-stack nodes an address of the preferred family ("IPv4" or "IPv6") wins within each type;
+// without a preference the node's own order decides. A node without addresses yields the loopback
+// address of the family.
+func selectNodeAddress(node *corev1.Node, strategy, family string) string {
+	if key, ok := strings.CutPrefix(strategy, nodeAddressLabelPrefix); ok {
+		if address := node.Labels[key]; address != "" {
+			return address
+		}
+	}
+	addrTypes, ok := nodeAddressTypes[strategy]
+	if !ok {
+		addrTypes = nodeAddressTypes[nodeAddressExternalIP]
+	}
+
+	for _, addrType := range addrTypes {
+		var fallback string
+		for _, addr := range node.Status.Addresses {
+			if addr.Type != addrType {
+				continue
+			}
+			if matchesFamily(addr.Address, family) {
+				return addr.Address
+			}
+			if fallback == "" {
+				fallback = addr.Address
+			}
+		}
+		if fallback != "" {
+			return fallback
+		}
+	}
+	if family == "IPv6" {
+		return "::1"
+	}
+	return "127.0.0.1"
+}
+
+// matchesFamily reports whether address belongs to family. Host names match every family.
+func matchesFamily(address, family string) bool {
+	ip := net.ParseIP(address)
+	if family == "" || ip == nil {
+		return true
+	}
+	if ip.To4() == nil {
+		return family == "IPv6"
+	}
+	return family == "IPv4"
+}
@@ -1,15 +1,24 @@
 package reconcilers
 
 import (
+	"cmp"
 	"context"
 	default_errors "errors"
 	"fmt"
-	"time"
+	"slices"
+	"strings"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
 	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	"github.com/OxAN0N/KubeDebugSess/internal/scope"
+	"github.com/OxAN0N/KubeDebugSess/internal/ticket"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -22,13 +31,32 @@ func init() {
 	session_phases.Register("", NewPendingReconciler)
 }
 
-func NewPendingReconciler(client client.Client, cs kubernetes.Interface) session_phases.PhaseReconciler {
-	return &PendingReconciler{Client: client, ClientSet: cs}
+func NewPendingReconciler(deps session_phases.Dependencies) session_phases.PhaseReconciler {
+	return &PendingReconciler{
+		Client:        deps.Client,
+		ClientSet:     deps.ClientSet,
+		Scope:         deps.Scope,
+		Requeue:       deps.Requeue,
+		AccessCheck:   deps.AccessCheck,
+		DebuggerLimit: deps.DebuggerLimit,
+		Notifier:      deps.Notifier,
+		Tickets:       deps.Tickets,
+	}
 }
 
 type PendingReconciler struct {
 	client.Client
 	ClientSet kubernetes.Interface
+	Scope     *scope.Namespaces
+	Requeue   session_phases.RequeueIntervals
+	// AccessCheck is the permission the session's requester must hold on the target pod.
+	AccessCheck session_phases.AccessCheck
+	// DebuggerLimit caps the running debuggers on the target pod.
+	DebuggerLimit session_phases.DebuggerLimit
+	// Notifier asks approvers to approve sessions that need approval.
+	Notifier *notify.Dispatcher
+	// Tickets checks spec.ticketRef before approvers are asked.
+	Tickets *ticket.Checker
 }
 
 func (r *PendingReconciler) Reconcile(ctx context.Context, session *debugv1alpha1.DebugSession) (ctrl.Result, error) {
@@ -50,23 +78,130 @@ func (r *PendingReconciler) Reconcile(ctx context.Context, session *debugv1alpha
 		// errors.As를 사용해 err가 RequeueError 타입인지 확인합니다.
 		if default_errors.As(err, &requeueErr) {
 			logger.Info("Pod is not ready yet, requeueing.", "reason", requeueErr.Reason)
+			if session_phases.SetCondition(session, debugv1alpha1.ConditionTargetValidated, metav1.ConditionFalse, "TargetNotReady", requeueErr.Reason) {
+				if err := r.Status().Update(ctx, session); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
 			return ctrl.Result{RequeueAfter: requeueErr.RequeueAfter}, nil
 		}
 
 		// 그 외의 유효성 검사 실패는 Failed 상태로 변경
 		logger.Info("Prerequisite validation failed.")
+		reason := "ValidationFailed"
+		if default_errors.Is(err, errTargetProtected) {
+			reason = "TargetProtected"
+		}
+		session_phases.SetCondition(session, debugv1alpha1.ConditionTargetValidated, metav1.ConditionFalse, reason, err.Error())
 		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, err.Error())
 	}
 
+	// 요청자가 대상 파드에 exec 권한을 가지고 있는지 확인한다.
+	allowed, reason, err := r.authorizeRequester(ctx, session)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !allowed {
+		logger.Info("Requester is not allowed to debug the target pod.", "reason", reason)
+		session_phases.SetCondition(session, debugv1alpha1.ConditionRequesterAuthorized, metav1.ConditionFalse, "AccessDenied", reason)
+		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, reason)
+	}
+
+	// 티켓이 존재하고 열려 있는지 확인한다. 확인된 티켓은 다시 조회하지 않는다.
+	if result, done, err := r.validateTicket(ctx, session); done {
+		return result, err
+	}
+
+	// 승인이 필요한 세션은 승인될 때까지 기다린다. 어노테이션이 바뀌면 다시 reconcile된다.
+	if denier := deniedBy(session); denier != "" {
+		message := fmt.Sprintf("Session denied by %s", denier)
+		logger.Info("Session was denied.", "deniedBy", denier)
+		session_phases.SetCondition(session, debugv1alpha1.ConditionApproved, metav1.ConditionFalse, "Denied", message)
+		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, message)
+	}
+	if approved, message := approvalStatus(session); !approved {
+		logger.Info("Session is waiting for approval.")
+		if session_phases.SetCondition(session, debugv1alpha1.ConditionApproved, metav1.ConditionFalse, "AwaitingApproval", message) {
+			session.Status.Message = message
+			// Approvers are asked once, when the session starts waiting.
+			session_phases.NotifySession(ctx, r.Notifier, session, notify.NewWebhookMessage(notify.EventApprovalRequired, session))
+			if err := r.Status().Update(ctx, session); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// 대상 파드에서 실행 중인 디버거 수가 한도를 넘지 않는지 확인한다.
+	if reason, position, err := r.checkDebuggerLimit(ctx, session); err != nil {
+		return ctrl.Result{}, err
+	} else if reason != "" {
+		if r.DebuggerLimit.Action == session_phases.DebuggerLimitReject {
+			logger.Info("Target pod is at its debugger limit, rejecting.", "reason", reason)
+			session_phases.SetCondition(session, debugv1alpha1.ConditionTargetValidated, metav1.ConditionFalse, "DebuggerLimitReached", reason)
+			return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, reason)
+		}
+		logger.Info("Target pod is at its debugger limit, queueing.", "reason", reason, "position", position)
+		changed := session_phases.SetCondition(session, debugv1alpha1.ConditionTargetValidated, metav1.ConditionFalse, "DebuggerLimitReached", reason)
+		if changed || session.Status.QueuePosition != position {
+			session.Status.QueuePosition = position
+			session.Status.Message = fmt.Sprintf("Queued at position %d: %s", position, reason)
+			if err := r.Status().Update(ctx, session); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: r.Requeue.PendingPod}, nil
+	}
+	session.Status.QueuePosition = 0
+
 	// 시나리오 3: 모든 조건을 만족했는가? -> 다음 단계(Injecting)로 넘어간다.
 	logger.Info("All prerequisites are satisfied. Transitioning to the next phase.")
+	session_phases.SetCondition(session, debugv1alpha1.ConditionTargetValidated, metav1.ConditionTrue, "PrerequisitesMet",
+		fmt.Sprintf("Target container %s/%s/%s is running", session.Spec.TargetNamespace, debugv1alpha1.CurrentTargetPod(session), session.Spec.TargetContainerName))
 	return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Injecting, "Prerequisites validated successfully.")
 }
 
+// validateTicket checks spec.ticketRef against the ticket system once. It reports done when the
+// session failed or must be checked again later.
+func (r *PendingReconciler) validateTicket(ctx context.Context, session *debugv1alpha1.DebugSession) (ctrl.Result, bool, error) {
+	if r.Tickets == nil || session.Spec.TicketRef == "" ||
+		meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionTicketValidated) {
+		return ctrl.Result{}, false, nil
+	}
+	err := r.Tickets.Check(ctx, session.Spec.TicketRef)
+	switch {
+	case err == nil:
+		session_phases.SetCondition(session, debugv1alpha1.ConditionTicketValidated, metav1.ConditionTrue, "TicketOpen",
+			fmt.Sprintf("Ticket %s is open", session.Spec.TicketRef))
+		return ctrl.Result{}, false, nil
+	case ticket.Rejected(err):
+		log.FromContext(ctx).Info("Ticket was rejected.", "ticketRef", session.Spec.TicketRef, "reason", err.Error())
+		session_phases.SetCondition(session, debugv1alpha1.ConditionTicketValidated, metav1.ConditionFalse, "TicketRejected", err.Error())
+		result, err := session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, err.Error())
+		return result, true, err
+	default:
+		log.FromContext(ctx).Info("Ticket could not be checked, requeueing.", "ticketRef", session.Spec.TicketRef, "reason", err.Error())
+		if session_phases.SetCondition(session, debugv1alpha1.ConditionTicketValidated, metav1.ConditionFalse, "TicketCheckFailed", err.Error()) {
+			if err := r.Status().Update(ctx, session); err != nil {
+				return ctrl.Result{}, true, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: r.Requeue.PendingPod}, true, nil
+	}
+}
+
+// errTargetProtected marks validation failures caused by the debug.ajou.oxan0n.me/deny opt-out.
+var errTargetProtected = default_errors.New("target is protected")
+
 // validatePrerequisites는 디버그 세션 주입에 필요한 모든 전제 조건들을 검사합니다.
 // 모든 조건이 충족되면 nil을 반환합니다.
 // 조건이 충족되지 않으면, 실패 원인을 담은 에러를 반환합니다.
-func (r *PendingReconciler) validatePrerequisites(ctx context.Context, session *debugv1alpha1.DebugSession) error {
+func (r *PendingReconciler) validatePrerequisites(ctx context.Context, session *debugv1alpha1.DebugSession) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ValidatePrerequisites")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
 
 	if session.Spec.TargetNamespace == "" {
 		session.Spec.TargetNamespace = session.Namespace
@@ -81,17 +216,35 @@ func (r *PendingReconciler) validatePrerequisites(ctx context.Context, session *
 		}
 		return err
 	}
+	if !r.Scope.Contains(namespace) {
+		return fmt.Errorf("target namespace '%s' is outside the namespaces served by this controller", namespace.Name)
+	}
 
 	// 2. Pod 검사
 	pod := &corev1.Pod{}
-	podKey := types.NamespacedName{Name: session.Spec.TargetPodName, Namespace: session.Spec.TargetNamespace}
+	podKey := types.NamespacedName{Name: debugv1alpha1.CurrentTargetPod(session), Namespace: session.Spec.TargetNamespace}
 	if err := r.Get(ctx, podKey, pod); err != nil {
 		if errors.IsNotFound(err) {
-			return fmt.Errorf("target pod '%s' not found", session.Spec.TargetPodName)
+			return fmt.Errorf("target pod '%s' not found", debugv1alpha1.CurrentTargetPod(session))
 		}
 		return err
 	}
 
+	// 보호 대상으로 지정된 네임스페이스나 파드에는 주입하지 않는다.
+	if reason := debugv1alpha1.ProtectedReason(namespace, pod); reason != "" {
+		return fmt.Errorf("%w: %s", errTargetProtected, reason)
+	}
+	if reason := debugv1alpha1.EBPFDeniedReason(session, namespace); reason != "" {
+		return fmt.Errorf("%w: %s", errTargetProtected, reason)
+	}
+	if reason := debugv1alpha1.ConfigRefDeniedReason(session, namespace); reason != "" {
+		return fmt.Errorf("%w: %s", errTargetProtected, reason)
+	}
+	if reason := debugv1alpha1.TicketDeniedReason(session, namespace); reason != "" {
+		return fmt.Errorf("%w: %s", errTargetProtected, reason)
+	}
+	session.Status.CostAttribution = debugv1alpha1.CostAttributionOf(session, namespace)
+
 	// 3. Pod 상태 검사
 	if pod.Status.Phase != corev1.PodRunning {
 		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
@@ -99,7 +252,7 @@ func (r *PendingReconciler) validatePrerequisites(ctx context.Context, session *
 		}
 		return &session_phases.RequeueError{
 			Reason:       fmt.Sprintf("pod is not running yet (current phase: %s)", pod.Status.Phase),
-			RequeueAfter: 30 * time.Second,
+			RequeueAfter: r.Requeue.PendingPod,
 		}
 	}
 
@@ -120,6 +273,146 @@ func (r *PendingReconciler) validatePrerequisites(ctx context.Context, session *
 	return nil
 }
 
+// checkDebuggerLimit returns why the session cannot be injected yet because its target pod already
+// runs the maximum number of debuggers, and its position in the queue for that pod, or "" if it can.
+// Sessions waiting for the pod are admitted by descending spec.priority, then in creation order.
+func (r *PendingReconciler) checkDebuggerLimit(ctx context.Context, session *debugv1alpha1.DebugSession) (string, int32, error) {
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: session.Spec.TargetNamespace}, namespace); err != nil {
+		return "", 0, err
+	}
+	limit, err := r.DebuggerLimit.MaxFor(namespace)
+	if err != nil || limit == 0 {
+		return "", 0, err
+	}
+	// A session that will observe another session's debugger does not add one.
+	if reused, err := findReusableSession(ctx, r.Client, session); err != nil || reused != nil {
+		return "", 0, err
+	}
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: session.Spec.TargetNamespace, Name: debugv1alpha1.CurrentTargetPod(session)}, pod); err != nil {
+		return "", 0, err
+	}
+	running := session_phases.RunningDebuggers(pod, debugv1alpha1.DebuggerContainerName(session))
+
+	var sessions debugv1alpha1.DebugSessionList
+	if err := r.List(ctx, &sessions, client.MatchingFields{
+		session_phases.TargetPodIndexKey: fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+	}); err != nil {
+		return "", 0, fmt.Errorf("failed to list sessions queued for the target pod: %w", err)
+	}
+	queue := []*debugv1alpha1.DebugSession{session}
+	for i := range sessions.Items {
+		other := &sessions.Items[i]
+		if other.UID == session.UID {
+			continue
+		}
+		switch other.Status.Phase {
+		case debugv1alpha1.Injecting:
+			// An admitted session holds its slot until its debugger shows up in the pod.
+			if other.Status.ReusedFrom == "" && !hasEphemeralContainer(pod, debugv1alpha1.DebuggerContainerName(other)) {
+				running++
+			}
+		case debugv1alpha1.Pending:
+			if isQueuedForDebugger(other) {
+				queue = append(queue, other)
+			}
+		}
+	}
+	slices.SortFunc(queue, func(a, b *debugv1alpha1.DebugSession) int {
+		if c := cmp.Compare(b.Spec.Priority, a.Spec.Priority); c != 0 {
+			return c
+		}
+		if c := a.CreationTimestamp.Compare(b.CreationTimestamp.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.UID), string(b.UID))
+	})
+
+	free := limit - running
+	index := slices.Index(queue, session)
+	if index < free {
+		return "", 0, nil
+	}
+	if free <= 0 {
+		return fmt.Sprintf("pod '%s' already runs %d of at most %d debuggers", pod.Name, running, limit),
+			int32(index - free + 1), nil
+	}
+	return fmt.Sprintf("the %d free debugger slot(s) on pod '%s' go to sessions ahead in the queue", free, pod.Name),
+		int32(index - free + 1), nil
+}
+
+// isQueuedForDebugger reports whether a Pending session is waiting for a debugger slot on its target pod.
+func isQueuedForDebugger(session *debugv1alpha1.DebugSession) bool {
+	cond := meta.FindStatusCondition(session.Status.Conditions, debugv1alpha1.ConditionTargetValidated)
+	return cond != nil && cond.Status == metav1.ConditionFalse && cond.Reason == "DebuggerLimitReached"
+}
+
+// authorizeRequester runs a SubjectAccessReview for the session's requester against the configured
+// access check. It returns whether the requester is allowed and, if not, why.
+func (r *PendingReconciler) authorizeRequester(ctx context.Context, session *debugv1alpha1.DebugSession) (bool, string, error) {
+	if r.AccessCheck == "" || r.AccessCheck == session_phases.AccessCheckNone {
+		return true, "", nil
+	}
+	requester := session.Spec.RequestedBy
+	if requester == nil {
+		return false, "Session has no spec.requestedBy to authorize; is the admission webhook enabled?", nil
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(requester.Extra))
+	for k, v := range requester.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	attrs := r.AccessCheck.ResourceAttributes(session)
+	sar, err := r.ClientSet.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               requester.Username,
+			UID:                requester.UID,
+			Groups:             requester.Groups,
+			Extra:              extra,
+			ResourceAttributes: attrs,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, "", fmt.Errorf("failed to check requester access: %w", err)
+	}
+	if !sar.Status.Allowed {
+		resource := attrs.Resource
+		if attrs.Subresource != "" {
+			resource += "/" + attrs.Subresource
+		}
+		return false, fmt.Sprintf("Requester %s cannot %s %s %s in namespace %s", requester.Username,
+			attrs.Verb, resource, attrs.Name, attrs.Namespace), nil
+	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionRequesterAuthorized, metav1.ConditionTrue, "AccessGranted",
+		fmt.Sprintf("Requester %s may %s the target pod", requester.Username, r.AccessCheck))
+	return true, "", nil
+}
+
+// approvalStatus reports whether the session may proceed as far as approval goes, recording the
+// approver in the Approved condition, or else what it is waiting for.
+func approvalStatus(session *debugv1alpha1.DebugSession) (bool, string) {
+	if !debugv1alpha1.RequiresApproval(session) {
+		return true, ""
+	}
+	approver := session.Annotations[debugv1alpha1.ApprovedByKey]
+	if approver == "" {
+		return false, fmt.Sprintf("Waiting for approval: annotate the session with %s, or %s to deny it",
+			debugv1alpha1.ApprovedByKey, debugv1alpha1.DeniedByKey)
+	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionApproved, metav1.ConditionTrue, "Approved",
+		fmt.Sprintf("Approved by %s", approver))
+	return true, ""
+}
+
+// deniedBy returns who denied a session that needs approval and was not approved, or "".
+func deniedBy(session *debugv1alpha1.DebugSession) string {
+	if !debugv1alpha1.RequiresApproval(session) || session.Annotations[debugv1alpha1.ApprovedByKey] != "" {
+		return ""
+	}
+	return session.Annotations[debugv1alpha1.DeniedByKey]
+}
+
 func findContainerInPod(pod *corev1.Pod, containerName string) bool {
 	for _, container := range pod.Spec.Containers {
 		if container.Name == containerName {
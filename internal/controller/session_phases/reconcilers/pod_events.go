@@ -0,0 +1,54 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// recentPodEvents returns up to limit of the latest Events on the target pod, oldest first.
+func recentPodEvents(ctx context.Context, cs kubernetes.Interface, podKey types.NamespacedName, limit int) ([]string, error) {
+	list, err := cs.CoreV1().Events(podKey.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.Set{
+			"involvedObject.kind": "Pod",
+			"involvedObject.name": podKey.Name,
+		}.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := list.Items
+	sort.Slice(items, func(i, j int) bool {
+		return eventTime(items[i]).Before(eventTime(items[j]))
+	})
+	if len(items) > limit {
+		items = items[len(items)-limit:]
+	}
+
+	events := make([]string, 0, len(items))
+	for _, e := range items {
+		events = append(events, fmt.Sprintf("%s %s %s: %s",
+			eventTime(e).UTC().Format(time.RFC3339), e.Type, e.Reason, strings.TrimSpace(e.Message)))
+	}
+	return events, nil
+}
+
+func eventTime(e corev1.Event) time.Time {
+	switch {
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	default:
+		return e.CreationTimestamp.Time
+	}
+}
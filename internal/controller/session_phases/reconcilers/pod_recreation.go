@@ -0,0 +1,96 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// podRecreateRetryDelay is the wait before evicting again when a PodDisruptionBudget or another
+// session's debugger holds the target pod.
+const podRecreateRetryDelay = 30 * time.Second
+
+// recreateTargetPod evicts the target pod of a session with CleanupPolicy RecreatePod, so that its
+// ReplicaSet replaces it with a pod that never had the debugger. The outcome is kept in the
+// PodRecreated condition; only a blocked eviction is retried.
+func (r *CompletedReconciler) recreateTargetPod(ctx context.Context, session *debugv1alpha1.DebugSession) ctrl.Result {
+	if session.Spec.CleanupPolicy != debugv1alpha1.CleanupPolicyRecreatePod {
+		return ctrl.Result{}
+	}
+	if cond := meta.FindStatusCondition(session.Status.Conditions, debugv1alpha1.ConditionPodRecreated); cond != nil &&
+		(cond.Status == metav1.ConditionTrue || cond.Reason != "EvictionBlocked") {
+		return ctrl.Result{}
+	}
+
+	// Observers do not own the debugger and must not take down the pod of the session they watch.
+	if session.Status.ReusedFrom != "" {
+		session_phases.SetCondition(session, debugv1alpha1.ConditionPodRecreated, metav1.ConditionFalse, "Observer",
+			fmt.Sprintf("The debugger belongs to session %s", session.Status.ReusedFrom))
+		return ctrl.Result{}
+	}
+
+	if session.Spec.TargetNamespace == "" {
+		session.Spec.TargetNamespace = session.Namespace
+	}
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, types.NamespacedName{Name: debugv1alpha1.CurrentTargetPod(session), Namespace: session.Spec.TargetNamespace}, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			session_phases.SetCondition(session, debugv1alpha1.ConditionPodRecreated, metav1.ConditionTrue, "PodGone",
+				"The target pod no longer exists")
+			return ctrl.Result{}
+		}
+		log.FromContext(ctx).Error(err, "Failed to get target pod for recreation")
+		return ctrl.Result{RequeueAfter: podRecreateRetryDelay}
+	}
+
+	if owner := metav1.GetControllerOf(pod); owner == nil || owner.Kind != "ReplicaSet" {
+		session_phases.SetCondition(session, debugv1alpha1.ConditionPodRecreated, metav1.ConditionFalse, "NotReplicaSetManaged",
+			fmt.Sprintf("Target pod '%s' is not managed by a ReplicaSet and would not be replaced", pod.Name))
+		r.Recorder.Eventf(session, corev1.EventTypeWarning, session_phases.EventReasonPodRecreateSkipped,
+			"Not evicting target pod %s: it is not managed by a ReplicaSet", pod.Name)
+		return ctrl.Result{}
+	}
+
+	if n := session_phases.RunningDebuggers(pod, sessionDebuggerName(session)); n > 0 {
+		session_phases.SetCondition(session, debugv1alpha1.ConditionPodRecreated, metav1.ConditionFalse, "EvictionBlocked",
+			fmt.Sprintf("Waiting for %d other debugger(s) on the target pod to exit", n))
+		return ctrl.Result{RequeueAfter: podRecreateRetryDelay}
+	}
+
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &pod.UID}},
+	}
+	err := r.ClientSet.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+	switch {
+	case err == nil || apierrors.IsNotFound(err):
+	case apierrors.IsTooManyRequests(err):
+		// A PodDisruptionBudget does not allow the disruption right now.
+		session_phases.SetCondition(session, debugv1alpha1.ConditionPodRecreated, metav1.ConditionFalse, "EvictionBlocked",
+			err.Error())
+		return ctrl.Result{RequeueAfter: podRecreateRetryDelay}
+	default:
+		session_phases.SetCondition(session, debugv1alpha1.ConditionPodRecreated, metav1.ConditionFalse, "EvictionFailed",
+			err.Error())
+		r.Recorder.Eventf(session, corev1.EventTypeWarning, session_phases.EventReasonPodRecreateSkipped,
+			"Failed to evict target pod %s: %v", pod.Name, err)
+		return ctrl.Result{}
+	}
+
+	session_phases.SetCondition(session, debugv1alpha1.ConditionPodRecreated, metav1.ConditionTrue, "PodEvicted",
+		fmt.Sprintf("Target pod '%s' was evicted to remove the debugger", pod.Name))
+	r.Recorder.Eventf(session, corev1.EventTypeNormal, session_phases.EventReasonPodRecreated,
+		"Evicted target pod %s so its ReplicaSet replaces it without the debugger", pod.Name)
+	return ctrl.Result{}
+}
@@ -0,0 +1,83 @@
+package reconcilers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// podSecurityEnforceLabel is the Pod Security Admission label holding a namespace's enforced level.
+const podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+// Pod Security Standards levels.
+const (
+	podSecurityRestricted = "restricted"
+	podSecurityBaseline   = "baseline"
+)
+
+// baselineCapabilities are the capabilities the baseline level allows containers to add.
+var baselineCapabilities = []corev1.Capability{
+	"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD", "NET_BIND_SERVICE",
+	"SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT",
+}
+
+// conformToPodSecurity checks the debugger's security context against the Pod Security Standards
+// level enforced on the target namespace, since the kubelet would otherwise only refuse the ephemeral
+// container after it was added. Settings the level merely requires to be spelled out (a seccomp
+// profile, dropping ALL capabilities) are filled in and described in the returned adjustments;
+// settings the user asked for that the level forbids are rejected with an error.
+func conformToPodSecurity(namespace *corev1.Namespace, pod *corev1.Pod, sc *corev1.SecurityContext) ([]string, error) {
+	level := namespace.Labels[podSecurityEnforceLabel]
+	if level != podSecurityRestricted && level != podSecurityBaseline {
+		return nil, nil
+	}
+
+	var violations, adjustments []string
+	if sc.Privileged != nil && *sc.Privileged {
+		violations = append(violations, "privileged containers are not allowed")
+	}
+	allowedCaps := baselineCapabilities
+	if level == podSecurityRestricted {
+		allowedCaps = []corev1.Capability{"NET_BIND_SERVICE"}
+	}
+	if sc.Capabilities != nil {
+		for _, c := range sc.Capabilities.Add {
+			if !slices.Contains(allowedCaps, c) {
+				violations = append(violations, fmt.Sprintf("capability %s may not be added", c))
+			}
+		}
+	}
+
+	if level == podSecurityRestricted {
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			violations = append(violations, "allowPrivilegeEscalation must be false")
+		}
+		if sc.RunAsNonRoot != nil && !*sc.RunAsNonRoot {
+			violations = append(violations, "runAsNonRoot must be true")
+		}
+		if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			violations = append(violations, "runAsUser must not be 0")
+		}
+
+		if sc.Capabilities == nil {
+			sc.Capabilities = &corev1.Capabilities{}
+		}
+		if !slices.Contains(sc.Capabilities.Drop, "ALL") {
+			sc.Capabilities.Drop = append(sc.Capabilities.Drop, "ALL")
+			adjustments = append(adjustments, "dropped ALL capabilities")
+		}
+		podSeccomp := pod.Spec.SecurityContext != nil && pod.Spec.SecurityContext.SeccompProfile != nil
+		if sc.SeccompProfile == nil && !podSeccomp {
+			sc.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+			adjustments = append(adjustments, "set the RuntimeDefault seccomp profile")
+		}
+	}
+
+	if len(violations) > 0 {
+		return nil, fmt.Errorf("namespace %s enforces the %s Pod Security Standard: %s",
+			namespace.Name, level, strings.Join(violations, "; "))
+	}
+	return adjustments, nil
+}
@@ -0,0 +1,81 @@
+package reconcilers
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestConformToPodSecurity(t *testing.T) {
+	namespace := func(level string) *corev1.Namespace {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+		if level != "" {
+			ns.Labels = map[string]string{podSecurityEnforceLabel: level}
+		}
+		return ns
+	}
+	seccompPod := &corev1.Pod{Spec: corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}}}
+	restrictedSC := func() *corev1.SecurityContext {
+		return &corev1.SecurityContext{AllowPrivilegeEscalation: ptr.To(false)}
+	}
+	withCaps := func(sc *corev1.SecurityContext, caps ...corev1.Capability) *corev1.SecurityContext {
+		sc.Capabilities = &corev1.Capabilities{Add: caps}
+		return sc
+	}
+
+	tests := []struct {
+		name            string
+		level           string
+		pod             *corev1.Pod
+		sc              *corev1.SecurityContext
+		wantErr         bool
+		wantAdjustments int
+		wantSeccomp     bool
+	}{
+		{name: "unlabelled namespace", sc: &corev1.SecurityContext{Privileged: ptr.To(true)}},
+		{name: "privileged level", level: "privileged", sc: &corev1.SecurityContext{Privileged: ptr.To(true)}},
+		{name: "baseline privileged", level: podSecurityBaseline, sc: &corev1.SecurityContext{Privileged: ptr.To(true)}, wantErr: true},
+		{name: "baseline allowed capability", level: podSecurityBaseline, sc: withCaps(&corev1.SecurityContext{}, "CHOWN")},
+		{name: "baseline NET_RAW", level: podSecurityBaseline, sc: withCaps(&corev1.SecurityContext{}, "NET_RAW"), wantErr: true},
+		{name: "restricted filled in", level: podSecurityRestricted, sc: restrictedSC(), wantAdjustments: 2, wantSeccomp: true},
+		{name: "restricted pod seccomp", level: podSecurityRestricted, pod: seccompPod, sc: restrictedSC(), wantAdjustments: 1},
+		{name: "restricted privilege escalation", level: podSecurityRestricted, sc: &corev1.SecurityContext{}, wantErr: true},
+		{
+			name:    "restricted root",
+			level:   podSecurityRestricted,
+			sc:      &corev1.SecurityContext{AllowPrivilegeEscalation: ptr.To(false), RunAsUser: ptr.To[int64](0)},
+			wantErr: true,
+		},
+		{name: "restricted NET_BIND_SERVICE", level: podSecurityRestricted, sc: withCaps(restrictedSC(), "NET_BIND_SERVICE"), wantAdjustments: 2, wantSeccomp: true},
+		{name: "restricted CHOWN", level: podSecurityRestricted, sc: withCaps(restrictedSC(), "CHOWN"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := tt.pod
+			if pod == nil {
+				pod = &corev1.Pod{}
+			}
+			adjustments, err := conformToPodSecurity(namespace(tt.level), pod, tt.sc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("conformToPodSecurity() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(adjustments) != tt.wantAdjustments {
+				t.Errorf("conformToPodSecurity() adjustments = %q, want %d", adjustments, tt.wantAdjustments)
+			}
+			if tt.wantErr || tt.level != podSecurityRestricted {
+				return
+			}
+			if tt.sc.Capabilities == nil || !slices.Contains(tt.sc.Capabilities.Drop, "ALL") {
+				t.Errorf("capabilities = %+v, want ALL dropped", tt.sc.Capabilities)
+			}
+			if (tt.sc.SeccompProfile != nil) != tt.wantSeccomp {
+				t.Errorf("seccompProfile = %+v, want set %v", tt.sc.SeccompProfile, tt.wantSeccomp)
+			}
+		})
+	}
+}
@@ -3,13 +3,14 @@ package reconcilers
 import (
 	"context"
 	"fmt"
-	"time"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
 	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -19,6 +20,10 @@ import (
 type RetryingReconciler struct {
 	client.Client
 	ClientSet      kubernetes.Interface
+	Recorder       record.EventRecorder
+	Requeue        session_phases.RequeueIntervals
+	Reasons        *session_phases.ReasonActions
+	archiver       *logArchiver
 	actionHandlers map[session_phases.ReasonAction]ActionHandler // Action별 핸들러 함수를 저장하는 맵
 }
 
@@ -26,10 +31,14 @@ func init() {
 	session_phases.Register(debugv1alpha1.Retrying, NewRetryingReconciler)
 }
 
-func NewRetryingReconciler(c client.Client, cs kubernetes.Interface) session_phases.PhaseReconciler {
+func NewRetryingReconciler(deps session_phases.Dependencies) session_phases.PhaseReconciler {
 	r := &RetryingReconciler{
-		Client:    c,
-		ClientSet: cs,
+		Client:    deps.Client,
+		ClientSet: deps.ClientSet,
+		Recorder:  deps.Recorder,
+		Requeue:   deps.Requeue,
+		Reasons:   deps.ReasonActions,
+		archiver:  newLogArchiver(deps),
 	}
 	// TODO: Refactor for OCP
 	r.actionHandlers = map[session_phases.ReasonAction]ActionHandler{
@@ -51,16 +60,21 @@ func (r *RetryingReconciler) Reconcile(ctx context.Context, session *debugv1alph
 
 	// 1. Pod 상태를 다시 확인합니다.
 	pod := &corev1.Pod{}
-	podKey := types.NamespacedName{Name: session.Spec.TargetPodName, Namespace: session.Spec.TargetNamespace}
+	podKey := types.NamespacedName{Name: debugv1alpha1.CurrentTargetPod(session), Namespace: session.Spec.TargetNamespace}
 	if err := r.Get(ctx, podKey, pod); err != nil {
 		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, "Target pod not found during retry.")
 	}
 
+	if reason, lost := targetPodLossReason(pod); lost {
+		r.Recorder.Event(session, corev1.EventTypeWarning, session_phases.EventReasonTargetPodLost, reason)
+		return failOnTargetPodLoss(ctx, r.Client, r.archiver, session, pod, reason)
+	}
+
 	// 2. 디버깅 컨테이너의 상태를 분석합니다.
-	debuggerContainerName := fmt.Sprintf("debugger-%s", session.UID)
+	debuggerContainerName := sessionDebuggerName(session)
 	for _, cs := range pod.Status.EphemeralContainerStatuses {
 		if cs.Name == debuggerContainerName {
-			action, message := session_phases.AnalyzeContainerStatus(cs)
+			action, message := r.Reasons.Analyze(cs)
 
 			// 3. 분석된 Action에 맞는 핸들러를 동적으로 호출합니다.
 			if handler, ok := r.actionHandlers[action]; ok {
@@ -103,9 +117,10 @@ func (r *RetryingReconciler) handleRetry(ctx context.Context, session *debugv1al
 
 	// 재시도 횟수를 증가시키고 지수 백오프 대기 시간을 계산합니다.
 	session.Status.RetryCount++
-	waitDuration := time.Second * 5 * (1 << (session.Status.RetryCount - 1)) // 5s, 10s, 20s, 40s...
-	if waitDuration > time.Minute {
-		waitDuration = time.Minute // 최대 대기 시간은 1분으로 제한
+	recordRetry(session, message)
+	waitDuration := r.Requeue.RetryBase * (1 << (session.Status.RetryCount - 1)) // 5s, 10s, 20s, 40s...
+	if waitDuration > r.Requeue.RetryMax || waitDuration <= 0 {
+		waitDuration = r.Requeue.RetryMax // 최대 대기 시간 (기본 1분)으로 제한
 	}
 
 	logger.Info("Problem persists. Waiting for next retry.", "RetryCount", session.Status.RetryCount, "WaitDuration", waitDuration)
@@ -118,3 +133,17 @@ func (r *RetryingReconciler) handleRetry(ctx context.Context, session *debugv1al
 	// 계산된 시간 이후에 다시 Reconcile 하도록 예약합니다.
 	return ctrl.Result{RequeueAfter: waitDuration}, nil
 }
+
+// maxRetryHistory bounds the retries kept in the session status.
+const maxRetryHistory = 10
+
+// recordRetry appends a retry to the session's history, dropping the oldest beyond maxRetryHistory.
+func recordRetry(session *debugv1alpha1.DebugSession, reason string) {
+	session.Status.RetryHistory = append(session.Status.RetryHistory, debugv1alpha1.RetryAttempt{
+		Time:   metav1.Now(),
+		Reason: reason,
+	})
+	if n := len(session.Status.RetryHistory); n > maxRetryHistory {
+		session.Status.RetryHistory = session.Status.RetryHistory[n-maxRetryHistory:]
+	}
+}
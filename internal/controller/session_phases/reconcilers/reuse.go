@@ -0,0 +1,55 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sessionDebuggerName returns the debugger container the session attaches to: the one it reuses, or
+// its own.
+func sessionDebuggerName(session *debugv1alpha1.DebugSession) string {
+	if session.Status.ReusedFrom != "" && session.Status.DebuggingContainerName != "" {
+		return session.Status.DebuggingContainerName
+	}
+	return debugv1alpha1.DebuggerContainerName(session)
+}
+
+// findReusableSession returns an Active session whose debugger the session may observe under
+// ReusePolicy IfCompatible: one on the same target pod and container, running the same debugger
+// and toolbox images and runtime attach, that injected its own debugger. It returns nil if there is none or reuse is not allowed.
+func findReusableSession(ctx context.Context, c client.Reader, session *debugv1alpha1.DebugSession) (*debugv1alpha1.DebugSession, error) {
+	// A capture action or exported credentials need a debugger of its own.
+	if session.Spec.ReusePolicy != debugv1alpha1.ReusePolicyIfCompatible || session.Spec.Capture != nil ||
+		exportsCredentials(session) {
+		return nil, nil
+	}
+
+	var sessions debugv1alpha1.DebugSessionList
+	if err := c.List(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to list sessions to reuse: %w", err)
+	}
+	for i := range sessions.Items {
+		other := &sessions.Items[i]
+		if other.UID == session.UID || other.Status.Phase != debugv1alpha1.Active || !other.Status.ReadyForAttach ||
+			other.Status.ReusedFrom != "" || other.Spec.Capture != nil || exportsCredentials(other) {
+			continue
+		}
+		otherNamespace := other.Spec.TargetNamespace
+		if otherNamespace == "" {
+			otherNamespace = other.Namespace
+		}
+		if otherNamespace == session.Spec.TargetNamespace &&
+			debugv1alpha1.CurrentTargetPod(other) == debugv1alpha1.CurrentTargetPod(session) &&
+			other.Spec.TargetContainerName == session.Spec.TargetContainerName &&
+			debuggerImage(other) == debuggerImage(session) &&
+			other.Spec.ToolboxImage == session.Spec.ToolboxImage &&
+			ptr.Equal(other.Spec.RuntimeAttach, session.Spec.RuntimeAttach) {
+			return other, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,93 @@
+package reconcilers
+
+import (
+	"strconv"
+	"strings"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// targetPIDScript sets pid to the target container's main process: the lowest-numbered process in the
+// cgroup of TARGET_CONTAINER_ID, or else PID 1 of the process namespace.
+const targetPIDScript = `
+pid=
+if [ -n "$TARGET_CONTAINER_ID" ]; then
+  for p in $(ls /proc | grep -E '^[0-9]+$' | sort -n); do
+    if grep -q "$TARGET_CONTAINER_ID" /proc/$p/cgroup 2>/dev/null; then pid=$p; break; fi
+  done
+fi
+[ -n "$pid" ] || pid=1
+`
+
+// runtimeAttachScript finds the target container's main process, detects its runtime unless RUNTIME
+// names one, and attaches the matching debugger listening on the pod's loopback DEBUG_PORT. It runs
+// before the shell turns interactive, so its output lands in the session transcript.
+const runtimeAttachScript = targetPIDScript + `
+exe=$(readlink /proc/$pid/exe 2>/dev/null)
+runtime=$RUNTIME
+if [ "$runtime" = Auto ]; then
+  case "$exe" in
+    *java) runtime=JVM ;;
+    *python*) runtime=Python ;;
+    *) grep -aq "Go buildinf" /proc/$pid/exe 2>/dev/null && runtime=Go ;;
+  esac
+fi
+echo "runtime attach: pid $pid ($exe), runtime $runtime, port $DEBUG_PORT"
+case "$runtime" in
+  Go)
+    dlv attach "$pid" --headless --listen="127.0.0.1:$DEBUG_PORT" --api-version=2 --accept-multiclient --continue &
+    ;;
+  Python)
+    py-spy dump --pid "$pid"
+    python3 -m debugpy --listen "127.0.0.1:$DEBUG_PORT" --pid "$pid"
+    ;;
+  JVM)
+    jattach "$pid" jcmd "ManagementAgent.start jmxremote.port=$DEBUG_PORT jmxremote.rmi.port=$DEBUG_PORT jmxremote.authenticate=false jmxremote.ssl=false jmxremote.host=127.0.0.1"
+    ;;
+  *)
+    echo "runtime attach: could not detect the runtime of pid $pid"
+    ;;
+esac
+`
+
+// runtimeAttachEnv returns the variables the debug script passes to runtimeAttachScript, or nil if the
+// session does not attach a runtime debugger.
+func runtimeAttachEnv(session *debugv1alpha1.DebugSession, pod *corev1.Pod) []corev1.EnvVar {
+	attach := session.Spec.RuntimeAttach
+	if attach == nil {
+		return nil
+	}
+	runtime := attach.Runtime
+	if runtime == "" {
+		runtime = debugv1alpha1.RuntimeAuto
+	}
+	env := []corev1.EnvVar{
+		{Name: "RUNTIME_ATTACH_SCRIPT", Value: runtimeAttachScript},
+		{Name: "RUNTIME", Value: string(runtime)},
+		{Name: "DEBUG_PORT", Value: strconv.Itoa(int(attach.Port))},
+	}
+	return append(env, targetContainerEnv(session, pod)...)
+}
+
+// targetContainerEnv returns TARGET_CONTAINER_ID for targetPIDScript, or nil before the target
+// container has started.
+func targetContainerEnv(session *debugv1alpha1.DebugSession, pod *corev1.Pod) []corev1.EnvVar {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == session.Spec.TargetContainerName && status.ContainerID != "" {
+			// ContainerID is "<runtime>://<id>"; the id alone appears in the process's cgroup path.
+			id := status.ContainerID[strings.LastIndex(status.ContainerID, "/")+1:]
+			return []corev1.EnvVar{{Name: "TARGET_CONTAINER_ID", Value: id}}
+		}
+	}
+	return nil
+}
+
+// addRuntimeAttachCapabilities grants SYS_PTRACE to a session that attaches a runtime debugger, unless
+// spec.debugSecurity sets the capabilities itself.
+func addRuntimeAttachCapabilities(session *debugv1alpha1.DebugSession, sc *corev1.SecurityContext) {
+	if session.Spec.RuntimeAttach == nil {
+		return
+	}
+	addCapabilities(session, sc, "SYS_PTRACE")
+}
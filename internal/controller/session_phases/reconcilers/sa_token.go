@@ -0,0 +1,125 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// minTokenExpirationSeconds is the shortest lifetime the API server issues tokens for.
+const minTokenExpirationSeconds = 600
+
+func tokenSecretName(session *debugv1alpha1.DebugSession) string {
+	return fmt.Sprintf("debugsession-%s-token", session.UID)
+}
+
+// issueServiceAccountToken requests a token of the target pod's ServiceAccount for the session's
+// spec.serviceAccountToken and stores it in a Secret the debugger reads KUBE_TOKEN from. The token is
+// bound to that Secret, so revokeServiceAccountToken invalidates it by deleting the Secret. Only the
+// pod's own ServiceAccount is used: whoever may exec into the pod can already read its token.
+func issueServiceAccountToken(ctx context.Context, c client.Client, clientset kubernetes.Interface,
+	session *debugv1alpha1.DebugSession, pod *corev1.Pod) error {
+	spec := session.Spec.ServiceAccountToken
+	if spec == nil || meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionTokenIssued) {
+		return nil
+	}
+	serviceAccount := pod.Spec.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tokenSecretName(session),
+			Namespace: pod.Namespace,
+			Labels:    map[string]string{debugv1alpha1.SessionNamespaceLabel: session.Namespace},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       pod.Name,
+				UID:        pod.UID,
+			}},
+		},
+	}
+	// A Secret left by an attempt whose status write was lost may hold a token already handed out.
+	if err := c.Delete(ctx, secret.DeepCopy()); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to replace ServiceAccount token Secret: %w", err)
+	}
+	if err := c.Create(ctx, secret); err != nil {
+		return fmt.Errorf("failed to create ServiceAccount token Secret: %w", err)
+	}
+
+	expiration := spec.ExpirationSeconds
+	if ttl := int64(session.Spec.TTL); ttl > 0 && ttl < expiration {
+		expiration = max(ttl, minTokenExpirationSeconds)
+	}
+	request := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         spec.Audiences,
+			ExpirationSeconds: &expiration,
+			BoundObjectRef: &authenticationv1.BoundObjectReference{
+				APIVersion: "v1",
+				Kind:       "Secret",
+				Name:       secret.Name,
+				UID:        secret.UID,
+			},
+		},
+	}
+	token, err := clientset.CoreV1().ServiceAccounts(pod.Namespace).CreateToken(ctx, serviceAccount, request, metav1.CreateOptions{})
+	if err != nil {
+		_ = c.Delete(ctx, secret)
+		return fmt.Errorf("failed to request a token for ServiceAccount %s: %w", serviceAccount, err)
+	}
+
+	secret.Data = map[string][]byte{"token": []byte(token.Status.Token)}
+	if err := c.Update(ctx, secret); err != nil {
+		_ = c.Delete(ctx, secret)
+		return fmt.Errorf("failed to store ServiceAccount token: %w", err)
+	}
+	session.Status.TokenExpiryTime = token.Status.ExpirationTimestamp.DeepCopy()
+	session_phases.SetCondition(session, debugv1alpha1.ConditionTokenIssued, metav1.ConditionTrue, "TokenRequested",
+		fmt.Sprintf("Issued a token of ServiceAccount %s bound to Secret %s", serviceAccount, secret.Name))
+	return nil
+}
+
+// serviceAccountTokenEnv exports the session's ServiceAccount token into the debugger as KUBE_TOKEN.
+func serviceAccountTokenEnv(session *debugv1alpha1.DebugSession) []corev1.EnvVar {
+	if session.Spec.ServiceAccountToken == nil {
+		return nil
+	}
+	return []corev1.EnvVar{{
+		Name: "KUBE_TOKEN",
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: tokenSecretName(session)},
+			Key:                  "token",
+		}},
+	}}
+}
+
+// revokeServiceAccountToken deletes the Secret the session's token is bound to, which invalidates the
+// token. It only acts while the TokenIssued condition is True, so calling it again is cheap.
+func revokeServiceAccountToken(ctx context.Context, c client.Client, session *debugv1alpha1.DebugSession) error {
+	if !meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionTokenIssued) {
+		return nil
+	}
+	targetNamespace := session.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = session.Namespace
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: tokenSecretName(session), Namespace: targetNamespace}}
+	if err := c.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete ServiceAccount token Secret: %w", err)
+	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionTokenIssued, metav1.ConditionFalse, "TokenRevoked",
+		fmt.Sprintf("Secret %s the token was bound to was deleted", secret.Name))
+	return nil
+}
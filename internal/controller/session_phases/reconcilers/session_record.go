@@ -0,0 +1,108 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sessionRecordLabel carries the UID of the session a DebugSessionRecord was written for.
+const sessionRecordLabel = "ajou.oxan0n.me/session-uid"
+
+// writeSessionRecord creates the immutable DebugSessionRecord of a finished session and marks the
+// session Recorded. It reports whether the status changed; a record that already exists counts as
+// written.
+func writeSessionRecord(ctx context.Context, c client.Client, session *debugv1alpha1.DebugSession) (bool, error) {
+	if meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionRecorded) {
+		return false, nil
+	}
+
+	record := newSessionRecord(session)
+	if err := c.Create(ctx, record); err == nil {
+		observeFinishedSession(record)
+	} else if !apierrors.IsAlreadyExists(err) {
+		return false, fmt.Errorf("failed to write DebugSessionRecord: %w", err)
+	}
+	return session_phases.SetCondition(session, debugv1alpha1.ConditionRecorded, metav1.ConditionTrue, "RecordWritten",
+		fmt.Sprintf("Audit record %s written", record.Name)), nil
+}
+
+func newSessionRecord(session *debugv1alpha1.DebugSession) *debugv1alpha1.DebugSessionRecord {
+	targetNamespace := session.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = session.Namespace
+	}
+
+	spec := debugv1alpha1.DebugSessionRecordSpec{
+		Session: debugv1alpha1.SessionReference{
+			Namespace: session.Namespace,
+			Name:      session.Name,
+			UID:       string(session.UID),
+		},
+		RequestedBy:         requestedBy(session),
+		ApprovedBy:          session.Annotations[debugv1alpha1.ApprovedByKey],
+		DeniedBy:            session.Annotations[debugv1alpha1.DeniedByKey],
+		CostAttribution:     session.Status.CostAttribution,
+		TicketRef:           session.Spec.TicketRef,
+		AttachedClients:     session.Status.AttachedClients,
+		Attachments:         session.Status.Attachments,
+		TargetNamespace:     targetNamespace,
+		TargetPodName:       session.Spec.TargetPodName,
+		TargetContainerName: session.Spec.TargetContainerName,
+		DebuggerImage:       debuggerImage(session),
+		GrantedCapabilities: session.Status.GrantedCapabilities,
+		Outcome:             session.Status.Phase,
+		Message:             session.Status.Message,
+		StartTime:           session.Status.StartTime,
+		TerminationTime:     session.Status.TerminationTime,
+		TranscriptKey:       session.Status.LogKey,
+		Recordings:          session.Status.Recordings,
+		Artifacts:           session.Status.Artifacts,
+		VaultLeases:         session.Status.VaultLeases,
+		Conditions:          session.Status.Conditions,
+	}
+	if spec.StartTime != nil && spec.TerminationTime != nil {
+		spec.DurationSeconds = int64(spec.TerminationTime.Sub(spec.StartTime.Time).Seconds())
+	}
+
+	labels := session.Status.CostAttribution.Labels()
+	labels[sessionRecordLabel] = string(session.UID)
+	labels[debugv1alpha1.SessionNamespaceLabel] = session.Namespace
+	return &debugv1alpha1.DebugSessionRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   sessionRecordName(session),
+			Labels: labels,
+		},
+		Spec: spec,
+	}
+}
+
+// sessionRecordName is unique per session attempt and readable in `kubectl get debugsessionrecords`.
+func sessionRecordName(session *debugv1alpha1.DebugSession) string {
+	uid := string(session.UID)
+	if len(uid) > 8 {
+		uid = uid[:8]
+	}
+	if session.Status.Restarts > 0 {
+		uid = fmt.Sprintf("%s.r%d", uid, session.Status.Restarts)
+	}
+	prefix := session.Namespace + "." + session.Name
+	if limit := 253 - len(uid) - 1; len(prefix) > limit {
+		prefix = prefix[:limit]
+	}
+	return prefix + "." + uid
+}
+
+// requestedBy prefers the requester recorded by the admission webhook over the annotation.
+func requestedBy(session *debugv1alpha1.DebugSession) string {
+	if session.Spec.RequestedBy != nil {
+		return session.Spec.RequestedBy.Username
+	}
+	return session.Annotations[debugv1alpha1.RequestedByAnnotation]
+}
@@ -0,0 +1,46 @@
+package reconcilers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ssmTunnel returns the SSM port forwarding from the node's EC2 instance to the proxy at
+// nodeIP:nodePort. The instance ID and zone come from the node's AWS provider ID,
+// "aws:///<zone>/<instance-id>".
+func ssmTunnel(node *corev1.Node, bastion debugv1alpha1.BastionAccess, nodeIP, nodePort string) (*debugv1alpha1.SSMTunnel, error) {
+	rest, ok := strings.CutPrefix(node.Spec.ProviderID, "aws:///")
+	zone, instanceID, found := strings.Cut(rest, "/")
+	if !ok || !found || !strings.HasPrefix(instanceID, "i-") {
+		return nil, fmt.Errorf("node %s is not an EC2 instance (provider ID %q)", node.Name, node.Spec.ProviderID)
+	}
+	region := bastion.SSM.Region
+	if region == "" {
+		region = zoneRegion(zone)
+	}
+	port, err := strconv.ParseInt(nodePort, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy port %q: %w", nodePort, err)
+	}
+	return &debugv1alpha1.SSMTunnel{
+		Target:    instanceID,
+		Region:    region,
+		Host:      nodeIP,
+		Port:      int32(port),
+		LocalPort: bastion.LocalPort,
+	}, nil
+}
+
+// zoneRegion returns the region of an availability zone ("us-east-1a") or Local Zone
+// ("us-west-2-lax-1a").
+func zoneRegion(zone string) string {
+	parts := strings.Split(zone, "-")
+	if len(parts) > 3 {
+		return strings.Join(parts[:3], "-")
+	}
+	return strings.TrimRight(zone, "abcdefghijklmnopqrstuvwxyz")
+}
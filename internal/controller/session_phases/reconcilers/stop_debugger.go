@@ -0,0 +1,78 @@
+package reconcilers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// stopDebuggerTimeout bounds the exec that stops a debugger.
+const stopDebuggerTimeout = 15 * time.Second
+
+// stopDebuggerScript kills every process of the debugger container except itself. The container
+// shares the pod's process namespace, so its processes are told apart from the workload's by their
+// cgroup rather than by user, which the workload may share.
+const stopDebuggerScript = `self=$$; cg=$(cat /proc/self/cgroup)
+for p in /proc/[0-9]*; do
+  pid=${p#/proc/}
+  [ "$pid" = "$self" ] && continue
+  [ "$(cat "$p/cgroup" 2>/dev/null)" = "$cg" ] && kill -KILL "$pid" 2>/dev/null
+done
+exit 0`
+
+// debuggerRunning reports whether the named ephemeral container is running.
+func debuggerRunning(pod *corev1.Pod, name string) bool {
+	for _, cs := range pod.Status.EphemeralContainerStatuses {
+		if cs.Name == name {
+			return cs.State.Running != nil
+		}
+	}
+	return false
+}
+
+// stopDebugger execs into the debugger container and kills its processes, so that the debugger stops
+// at termination even if the user killed the TTL timer of the debug script. Ephemeral containers
+// cannot be removed, so this is the only way to end one early.
+func stopDebugger(ctx context.Context, clientset kubernetes.Interface, restCfg *rest.Config, pod *corev1.Pod, container string) error {
+	if restCfg == nil {
+		return fmt.Errorf("no REST config to exec with")
+	}
+	ctx, cancel := context.WithTimeout(ctx, stopDebuggerTimeout)
+	defer cancel()
+
+	req := clientset.CoreV1().RESTClient().
+		Post().
+		Resource("pods").
+		Name(pod.Name).
+		Namespace(pod.Namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   []string{"/bin/sh", "-c", stopDebuggerScript},
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(restCfg, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create SPDY executor: %w", err)
+	}
+	var output bytes.Buffer
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &output, Stderr: &output})
+	// A non-zero exit means the exec was killed along with the container it stopped.
+	var exitErr utilexec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("failed to stop debugger container %s: %w (output: %s)", container, err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
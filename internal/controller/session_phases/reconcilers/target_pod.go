@@ -0,0 +1,65 @@
+package reconcilers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// targetPodLossReason reports whether the target pod is going away (deleted, evicted or finished)
+// and can no longer host the debug session.
+func targetPodLossReason(pod *corev1.Pod) (string, bool) {
+	switch {
+	case pod.DeletionTimestamp != nil:
+		return fmt.Sprintf("target pod '%s' is being deleted", pod.Name), true
+	case pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == "Evicted":
+		return fmt.Sprintf("target pod '%s' was evicted: %s", pod.Name, pod.Status.Message), true
+	case pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded:
+		return fmt.Sprintf("target pod '%s' is no longer running (phase: %s)", pod.Name, pod.Status.Phase), true
+	}
+	return "", false
+}
+
+// failOnTargetPodLoss uploads whatever transcript the debugger left behind and fails the session.
+// The pod is still readable while it terminates, so the logs can usually be salvaged.
+func failOnTargetPodLoss(ctx context.Context, c client.Client, archiver *logArchiver,
+	session *debugv1alpha1.DebugSession, pod *corev1.Pod, reason string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("Target pod lost during session, salvaging transcript.", "reason", reason)
+
+	debuggerName := sessionDebuggerName(session)
+	session.Status.ReadyForAttach = false
+	session_phases.SetCondition(session, debugv1alpha1.ConditionTargetValidated, metav1.ConditionFalse, "TargetPodLost", reason)
+
+	if !isEphemeralContainerPresent(pod, debuggerName) {
+		return session_phases.UpdateSessionStatus(ctx, c, session, debugv1alpha1.Failed,
+			fmt.Sprintf("Session aborted: %s. No debugger transcript to salvage.", reason))
+	}
+
+	logKey, err := archiver.archive(ctx, session, pod, debuggerName)
+	if errors.Is(err, errArchivingDisabled) {
+		session_phases.SetCondition(session, debugv1alpha1.ConditionArchived, metav1.ConditionFalse, "StorageDisabled", err.Error())
+		return session_phases.UpdateSessionStatus(ctx, c, session, debugv1alpha1.Failed,
+			fmt.Sprintf("Session aborted: %s.", reason))
+	}
+	if err != nil {
+		logger.Error(err, "Failed to salvage debugger transcript")
+		session_phases.SetCondition(session, debugv1alpha1.ConditionArchived, metav1.ConditionFalse, reasonUploadFailed, err.Error())
+		return session_phases.UpdateSessionStatus(ctx, c, session, debugv1alpha1.Failed,
+			fmt.Sprintf("Session aborted: %s. Transcript could not be salvaged: %v", reason, err))
+	}
+
+	session_phases.SetCondition(session, debugv1alpha1.ConditionArchived, metav1.ConditionTrue, "TranscriptSalvaged",
+		fmt.Sprintf("Transcript stored at %s", logKey))
+	archiver.recordTranscript(ctx, session, logKey)
+	return session_phases.UpdateSessionStatus(ctx, c, session, debugv1alpha1.Failed,
+		fmt.Sprintf("Session aborted: %s. Transcript salvaged to %s.", reason, logKey))
+}
@@ -0,0 +1,101 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	proxyNamespace   = "kubedebugsess-system"
+	proxyServiceName = "kubedebugsess-proxy-svc"
+)
+
+func teleportServiceName(session *debugv1alpha1.DebugSession) string {
+	return fmt.Sprintf("debugsession-%s", session.UID)
+}
+
+// teleportAppName is the name clients pass to tsh: "debug-<namespace>-<name>", or the session UID when
+// that is not a valid app name.
+func teleportAppName(session *debugv1alpha1.DebugSession) string {
+	name := fmt.Sprintf("debug-%s-%s", session.Namespace, session.Name)
+	if len(validation.IsDNS1123Label(name)) > 0 {
+		return teleportServiceName(session)
+	}
+	return name
+}
+
+// registerTeleportApp creates a Service in front of the debug proxy that the Teleport discovery service
+// registers as an app for the session. The Service lives in the proxy's namespace, where the session
+// cannot own it, so releaseTeleportApp deletes it when the session ends.
+func registerTeleportApp(ctx context.Context, c client.Client, clientset kubernetes.Interface,
+	session *debugv1alpha1.DebugSession, teleport *debugv1alpha1.TeleportAccess) error {
+	proxySvc, err := clientset.CoreV1().Services(proxyNamespace).Get(ctx, proxyServiceName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get proxy service: %w", err)
+	}
+	var port *corev1.ServicePort
+	for i := range proxySvc.Spec.Ports {
+		if proxySvc.Spec.Ports[i].Name == "http" {
+			port = &proxySvc.Spec.Ports[i]
+		}
+	}
+	if port == nil {
+		return fmt.Errorf("proxy service has no http port")
+	}
+
+	labels := maps.Clone(teleport.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[debugv1alpha1.SessionNamespaceLabel] = session.Namespace
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      teleportServiceName(session),
+			Namespace: proxyNamespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				"teleport.dev/discovery-type": "app",
+				"teleport.dev/name":           teleportAppName(session),
+				"teleport.dev/protocol":       "http",
+				"teleport.dev/port":           port.Name,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: proxySvc.Spec.Selector,
+			Ports:    []corev1.ServicePort{{Name: port.Name, Protocol: port.Protocol, Port: port.Port, TargetPort: port.TargetPort}},
+		},
+	}
+	if err := c.Create(ctx, svc); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create Teleport app service: %w", err)
+	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionAppRegistered, metav1.ConditionTrue, "ServiceCreated",
+		fmt.Sprintf("Service %s/%s publishes the session as Teleport app %s", svc.Namespace, svc.Name, teleportAppName(session)))
+	return nil
+}
+
+// releaseTeleportApp deletes the session's Teleport app Service. It only acts while the AppRegistered
+// condition is True, so calling it again is cheap.
+func releaseTeleportApp(ctx context.Context, c client.Client, session *debugv1alpha1.DebugSession) error {
+	if !meta.IsStatusConditionTrue(session.Status.Conditions, debugv1alpha1.ConditionAppRegistered) {
+		return nil
+	}
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: teleportServiceName(session), Namespace: proxyNamespace}}
+	if err := c.Delete(ctx, svc); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete Teleport app service: %w", err)
+	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionAppRegistered, metav1.ConditionFalse, "ServiceDeleted",
+		fmt.Sprintf("Service %s was deleted", svc.Name))
+	return nil
+}
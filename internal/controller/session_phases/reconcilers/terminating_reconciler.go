@@ -1,25 +1,23 @@
 package reconcilers
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"os"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
 	"github.com/OxAN0N/KubeDebugSess/internal/controller/session_phases"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/OxAN0N/KubeDebugSess/internal/notify"
+	"github.com/OxAN0N/KubeDebugSess/internal/vault"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -27,44 +25,27 @@ import (
 
 type TerminatingReconciler struct {
 	client.Client
-	ClientSet kubernetes.Interface
-	S3Client  *s3.Client
-	S3Bucket  string
+	ClientSet  kubernetes.Interface
+	RESTConfig *rest.Config
+	Recorder   record.EventRecorder
+	Notifier   *notify.Dispatcher
+	Vault      *vault.Client
+	archiver   *logArchiver
 }
 
 func init() {
 	session_phases.Register(debugv1alpha1.Terminating, NewTerminatingReconciler)
 }
 
-func NewTerminatingReconciler(c client.Client, cs kubernetes.Interface) session_phases.PhaseReconciler {
-	region := os.Getenv("AWS_REGION")
-	bucket := os.Getenv("S3_BUCKET_NAME")
-	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-
-	var cfg aws.Config
-	var err error
-
-	cfg, err = config.LoadDefaultConfig(context.Background(),
-		config.WithRegion(region),
-	)
-	if err != nil {
-		panic(fmt.Sprintf("failed to load default AWS config: %v", err))
-	}
-
-	if accessKey != "" && secretKey != "" {
-		cfg.Credentials = aws.NewCredentialsCache(
-			credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""),
-		)
-	}
-
-	s3Client := s3.NewFromConfig(cfg)
-
+func NewTerminatingReconciler(deps session_phases.Dependencies) session_phases.PhaseReconciler {
 	return &TerminatingReconciler{
-		Client:    c,
-		ClientSet: cs,
-		S3Client:  s3Client,
-		S3Bucket:  bucket,
+		Client:     deps.Client,
+		ClientSet:  deps.ClientSet,
+		RESTConfig: deps.RESTConfig,
+		Recorder:   deps.Recorder,
+		Notifier:   deps.Notifier,
+		Vault:      deps.Vault,
+		archiver:   newLogArchiver(deps),
 	}
 }
 
@@ -72,18 +53,40 @@ func (r *TerminatingReconciler) Reconcile(ctx context.Context, session *debugv1a
 	logger := log.FromContext(ctx)
 	logger.Info("Starting cleanup for Terminating session.")
 
+	// Revoke the attach token first; under CleanupPolicy Retain the debugger outlives the session.
+	session.Status.ReadyForAttach = false
+	session.Status.OneTimeToken = ""
+
 	if err := r.cleanupEphemeralContainer(ctx, session); err != nil {
 		logger.Error(err, "Failed to cleanup ephemeral container.")
 		return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Failed, err.Error())
 	}
 
+	if err := releaseEgress(ctx, r.Client, session); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := releaseTeleportApp(ctx, r.Client, session); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := revokeVaultCredentials(ctx, r.Client, r.Vault, session); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := revokeServiceAccountToken(ctx, r.Client, session); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	logger.Info("Successfully terminated debugging session. Transitioning to Completed.")
 	now := metav1.NewTime(time.Now())
 	session.Status.TerminationTime = &now
+	r.Recorder.Event(session, corev1.EventTypeNormal, session_phases.EventReasonSessionTerminated,
+		"Debug session terminated and cleaned up")
+
+	session_phases.NotifySession(ctx, r.Notifier, session, notify.NewWebhookMessage(notify.EventSessionTerminated, session))
 
 	return session_phases.UpdateSessionStatus(ctx, r.Client, session, debugv1alpha1.Completed, "Termination Completed")
 }
 
+// cleanupEphemeralContainer archives the debugger transcript and records its location in the session status.
 func (r *TerminatingReconciler) cleanupEphemeralContainer(ctx context.Context, session *debugv1alpha1.DebugSession) error {
 	logger := log.FromContext(ctx)
 
@@ -92,31 +95,78 @@ func (r *TerminatingReconciler) cleanupEphemeralContainer(ctx context.Context, s
 		return err
 	}
 
-	debuggerName := fmt.Sprintf("debugger-%s", session.UID)
-	if !r.isEphemeralContainerPresent(pod, debuggerName) {
+	debuggerName := sessionDebuggerName(session)
+	if !isEphemeralContainerPresent(pod, debuggerName) {
 		return fmt.Errorf("debugger container '%s' not found in pod '%s'", debuggerName, pod.Name)
 	}
 
-	logData, err := r.fetchEphemeralLogs(ctx, pod, debuggerName)
-	if err != nil {
-		return fmt.Errorf("failed to fetch ephemeral logs: %w", err)
+	// Observers share the debugger of the session they reuse and must not stop it.
+	if session.Status.ReusedFrom == "" {
+		r.stopSessionContainers(ctx, session, pod, debuggerName, session.Status.ToolboxContainerName)
+	}
+
+	if session.Spec.Capture != nil {
+		r.archiveArtifacts(ctx, session, pod, debuggerName)
 	}
 
-	s3Key, err := r.uploadLogsToS3(ctx, pod, debuggerName, logData)
+	logKey, err := r.archiver.archive(ctx, session, pod, debuggerName)
+	if errors.Is(err, errArchivingDisabled) {
+		session_phases.SetCondition(session, debugv1alpha1.ConditionArchived, metav1.ConditionFalse, "StorageDisabled", err.Error())
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to upload logs to S3: %w", err)
+		session_phases.SetCondition(session, debugv1alpha1.ConditionArchived, metav1.ConditionFalse, reasonUploadFailed, err.Error())
+		if session.Spec.ArchivePolicy != debugv1alpha1.ArchivePolicyBestEffort {
+			return err
+		}
+		// BestEffort: finish terminating and let the Completed phase retry the upload.
+		session.Status.ArchiveAttempts++
+		r.Recorder.Eventf(session, corev1.EventTypeWarning, session_phases.EventReasonArchiveFailed,
+			"Transcript upload failed, retrying in the background: %v", err)
+		return nil
 	}
+	session_phases.SetCondition(session, debugv1alpha1.ConditionArchived, metav1.ConditionTrue, "TranscriptUploaded",
+		fmt.Sprintf("Transcript stored at %s", logKey))
+	r.archiver.recordTranscript(ctx, session, logKey)
+
+	r.Recorder.Eventf(session, corev1.EventTypeNormal, session_phases.EventReasonTranscriptSaved,
+		"Debugger transcript uploaded to %s", logKey)
 
 	if err := r.Status().Update(ctx, session); err != nil {
 		logger.Error(err, "Failed to update session with log URL")
 	}
 
 	logger.Info("Ephemeral container cleanup complete",
-		"pod", pod.Name, "container", debuggerName, "s3Key", s3Key)
+		"pod", pod.Name, "container", debuggerName, "logKey", logKey)
 
 	return nil
 }
 
+// stopSessionContainers stops the session's running debugger and toolbox containers, unless
+// cleanupPolicy Retain keeps them. Failures are reported but do not hold up termination.
+func (r *TerminatingReconciler) stopSessionContainers(ctx context.Context, session *debugv1alpha1.DebugSession,
+	pod *corev1.Pod, names ...string) {
+	logger := log.FromContext(ctx)
+	for _, name := range names {
+		if name == "" || !debuggerRunning(pod, name) {
+			continue
+		}
+		if session.Spec.CleanupPolicy == debugv1alpha1.CleanupPolicyRetain {
+			r.Recorder.Eventf(session, corev1.EventTypeNormal, session_phases.EventReasonDebuggerRetained,
+				"Container %s left running as requested by cleanupPolicy Retain", name)
+			continue
+		}
+		if err := stopDebugger(ctx, r.ClientSet, r.RESTConfig, pod, name); err != nil {
+			logger.Error(err, "Failed to stop debugger; relying on its TTL timer", "container", name)
+			r.Recorder.Eventf(session, corev1.EventTypeWarning, session_phases.EventReasonDebuggerStopFailed,
+				"Failed to stop container %s: %v", name, err)
+			continue
+		}
+		r.Recorder.Eventf(session, corev1.EventTypeNormal, session_phases.EventReasonDebuggerStopped,
+			"Container %s stopped", name)
+	}
+}
+
 func (r *TerminatingReconciler) getTargetPod(ctx context.Context, session *debugv1alpha1.DebugSession) (*corev1.Pod, error) {
 	if session.Spec.TargetNamespace == "" {
 		session.Spec.TargetNamespace = session.Namespace
@@ -124,12 +174,12 @@ func (r *TerminatingReconciler) getTargetPod(ctx context.Context, session *debug
 
 	pod := &corev1.Pod{}
 	key := types.NamespacedName{
-		Name:      session.Spec.TargetPodName,
+		Name:      debugv1alpha1.CurrentTargetPod(session),
 		Namespace: session.Spec.TargetNamespace,
 	}
 
 	if err := r.Get(ctx, key, pod); err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			return nil, fmt.Errorf("target pod '%s' not found", key.Name)
 		}
 		return nil, fmt.Errorf("failed to get target pod: %w", err)
@@ -138,7 +188,7 @@ func (r *TerminatingReconciler) getTargetPod(ctx context.Context, session *debug
 	return pod, nil
 }
 
-func (r *TerminatingReconciler) isEphemeralContainerPresent(pod *corev1.Pod, containerName string) bool {
+func isEphemeralContainerPresent(pod *corev1.Pod, containerName string) bool {
 	for _, ec := range pod.Spec.EphemeralContainers {
 		if ec.Name == containerName {
 			return true
@@ -146,87 +196,3 @@ func (r *TerminatingReconciler) isEphemeralContainerPresent(pod *corev1.Pod, con
 	}
 	return false
 }
-
-func (r *TerminatingReconciler) fetchEphemeralLogs(ctx context.Context, pod *corev1.Pod, containerName string) ([]byte, error) {
-	logger := log.FromContext(ctx)
-	logger.Info("Fetching logs for ephemeral container", "container", containerName)
-
-	opts := &corev1.PodLogOptions{
-		Container:  containerName,
-		Timestamps: true,
-	}
-
-	req := r.ClientSet.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts)
-	stream, err := req.Stream(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open log stream: %w", err)
-	}
-	defer stream.Close()
-
-	var logs bytes.Buffer
-	buf := make([]byte, 4096)
-	for {
-		n, err := stream.Read(buf)
-		if n > 0 {
-			logs.Write(buf[:n])
-		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("error reading log stream: %w", err)
-		}
-	}
-
-	rawLogs := logs.Bytes()
-	cleaned := r.cleanLogData(rawLogs)
-
-	logger.Info("Fetched and cleaned ephemeral container logs", "rawSize", len(rawLogs), "cleanSize", len(cleaned))
-	return cleaned, nil
-}
-
-func (r *TerminatingReconciler) cleanLogData(data []byte) []byte {
-	var cleaned []byte
-	inEscape := false
-
-	for i := 0; i < len(data); i++ {
-		b := data[i]
-
-		if b == 0x1b {
-			inEscape = true
-			continue
-		}
-
-		if inEscape {
-			if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || b == '~' {
-				inEscape = false
-			}
-			continue
-		}
-
-		if b == '\r' || b == '\x07' || b == '\x08' {
-			continue
-		}
-
-		cleaned = append(cleaned, b)
-	}
-
-	// 연속 공백/개행 정리 (선택)
-	cleaned = bytes.ReplaceAll(cleaned, []byte("\n\n\n"), []byte("\n\n"))
-	return cleaned
-}
-
-func (r *TerminatingReconciler) uploadLogsToS3(ctx context.Context, pod *corev1.Pod, containerName string, data []byte) (string, error) {
-	s3Key := fmt.Sprintf("debug-sessions/%s/%s-%d.log", pod.Namespace, containerName, time.Now().Unix())
-
-	_, err := r.S3Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: &r.S3Bucket,
-		Key:    &s3Key,
-		Body:   bytes.NewReader(data),
-	})
-	if err != nil {
-		return "", fmt.Errorf("S3 upload failed: %w", err)
-	}
-
-	return s3Key, nil
-}
@@ -0,0 +1,83 @@
+package reconcilers
+
+import (
+	"slices"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// toolsetProfile is what a spec.toolset preset supplies to the debugger.
+type toolsetProfile struct {
+	image         string
+	capabilities  []corev1.Capability
+	setupCommands []string
+}
+
+// toolsetProfiles pins each preset to a maintained image. The debugger's root filesystem is read-only
+// by default, so the setup commands keep the tools from writing to it.
+var toolsetProfiles = map[debugv1alpha1.Toolset]toolsetProfile{
+	debugv1alpha1.ToolsetNetwork: {
+		image:        "docker.io/nicolaka/netshoot:v0.13",
+		capabilities: []corev1.Capability{"NET_ADMIN", "NET_RAW"},
+	},
+	debugv1alpha1.ToolsetDatabase: {
+		image:         "docker.io/library/postgres:16-alpine",
+		setupCommands: []string{"export PGCONNECT_TIMEOUT=5 PSQL_HISTORY=/dev/null"},
+	},
+	debugv1alpha1.ToolsetJVM: {
+		image:        "docker.io/library/eclipse-temurin:21-jdk",
+		capabilities: []corev1.Capability{"SYS_PTRACE"},
+	},
+	debugv1alpha1.ToolsetGolang: {
+		image:         "docker.io/library/golang:1.24-alpine",
+		capabilities:  []corev1.Capability{"SYS_PTRACE"},
+		setupCommands: []string{"export GOTOOLCHAIN=local GOCACHE=/tmp/go-cache GOPATH=/tmp/go"},
+	},
+	debugv1alpha1.ToolsetPython: {
+		image:         "docker.io/library/python:3.12-slim",
+		capabilities:  []corev1.Capability{"SYS_PTRACE"},
+		setupCommands: []string{"export PYTHONDONTWRITEBYTECODE=1"},
+	},
+}
+
+// debuggerImage returns the session's debugger image: spec.debuggerImage, or else its toolset's, or
+// else its capture action's. Bpftrace captures always run their pinned image, which is what their
+// approver approved.
+func debuggerImage(session *debugv1alpha1.DebugSession) string {
+	if capture := session.Spec.Capture; capture != nil && capture.Type == debugv1alpha1.CaptureBpftrace {
+		return captureProfiles[capture.Type].image
+	}
+	if session.Spec.DebuggerImage != "" {
+		return session.Spec.DebuggerImage
+	}
+	if session.Spec.Toolset == "" && session.Spec.Capture != nil {
+		return captureProfiles[session.Spec.Capture.Type].image
+	}
+	return toolsetProfiles[session.Spec.Toolset].image
+}
+
+// setupCommands returns the session's toolset setup commands followed by its own.
+func setupCommands(session *debugv1alpha1.DebugSession) []string {
+	profile := toolsetProfiles[session.Spec.Toolset]
+	return append(append([]string(nil), profile.setupCommands...), session.Spec.SetupCommands...)
+}
+
+// addToolsetCapabilities grants the capabilities of the session's toolset, unless spec.debugSecurity
+// sets the capabilities itself.
+func addToolsetCapabilities(session *debugv1alpha1.DebugSession, sc *corev1.SecurityContext) {
+	addCapabilities(session, sc, toolsetProfiles[session.Spec.Toolset].capabilities...)
+}
+
+// addCapabilities adds the given capabilities to the debugger's, skipping those it already has. It
+// does nothing when spec.debugSecurity sets the capabilities itself.
+func addCapabilities(session *debugv1alpha1.DebugSession, sc *corev1.SecurityContext, capabilities ...corev1.Capability) {
+	if sec := session.Spec.DebugSecurity; sec != nil && sec.Capabilities != nil {
+		return
+	}
+	for _, c := range capabilities {
+		if !slices.Contains(sc.Capabilities.Add, c) {
+			sc.Capabilities.Add = append(sc.Capabilities.Add, c)
+		}
+	}
+}
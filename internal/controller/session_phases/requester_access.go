@@ -0,0 +1,57 @@
+package session_phases
+
+import (
+	"fmt"
+	"os"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+// AccessCheck selects the permission a session's requester must hold on the target pod, so that
+// creating a DebugSession cannot be used to get a shell where `kubectl exec` is not allowed.
+type AccessCheck string
+
+const (
+	// AccessCheckExec requires create on pods/exec, the permission behind `kubectl exec`.
+	AccessCheckExec AccessCheck = "exec"
+	// AccessCheckDebug requires the dedicated "debug" verb on pods, for clusters that grant debugging
+	// separately from exec.
+	AccessCheckDebug AccessCheck = "debug"
+	// AccessCheckNone skips the check. spec.requestedBy cannot be trusted without the admission webhook,
+	// so this is the default when webhooks are disabled.
+	AccessCheckNone AccessCheck = "none"
+)
+
+// AccessCheckFromEnv reads REQUESTER_ACCESS_CHECK, defaulting to exec when the webhook that fills in
+// spec.requestedBy is served and to none otherwise.
+func AccessCheckFromEnv(webhooksEnabled bool) (AccessCheck, error) {
+	check := AccessCheck(os.Getenv("REQUESTER_ACCESS_CHECK"))
+	switch check {
+	case "":
+		if webhooksEnabled {
+			return AccessCheckExec, nil
+		}
+		return AccessCheckNone, nil
+	case AccessCheckExec, AccessCheckDebug, AccessCheckNone:
+		return check, nil
+	default:
+		return "", fmt.Errorf("unsupported REQUESTER_ACCESS_CHECK %q (want exec, debug or none)", check)
+	}
+}
+
+// ResourceAttributes returns the access the requester needs on the target pod.
+func (c AccessCheck) ResourceAttributes(session *debugv1alpha1.DebugSession) *authorizationv1.ResourceAttributes {
+	attrs := &authorizationv1.ResourceAttributes{
+		Namespace: session.Spec.TargetNamespace,
+		Name:      debugv1alpha1.CurrentTargetPod(session),
+		Resource:  "pods",
+	}
+	if c == AccessCheckDebug {
+		attrs.Verb = "debug"
+	} else {
+		attrs.Verb = "create"
+		attrs.Subresource = "exec"
+	}
+	return attrs
+}
@@ -0,0 +1,45 @@
+package session_phases
+
+import "time"
+
+// RequeueIntervals tunes how often the phase reconcilers poll while they wait on the cluster.
+// Zero fields fall back to DefaultRequeueIntervals.
+type RequeueIntervals struct {
+	// PendingPod is how often Pending re-checks a target pod that is not running yet.
+	PendingPod time.Duration
+	// ActiveContainer is how often Active polls until the debugger container reports a status.
+	ActiveContainer time.Duration
+	// RetryBase is the first Retrying backoff; it doubles on every further retry up to RetryMax.
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// UnknownPhase is how often a session in a phase without a reconciler is looked at again.
+	UnknownPhase time.Duration
+}
+
+// DefaultRequeueIntervals returns the intervals used when none are configured.
+func DefaultRequeueIntervals() RequeueIntervals {
+	return RequeueIntervals{
+		PendingPod:      30 * time.Second,
+		ActiveContainer: 5 * time.Second,
+		RetryBase:       5 * time.Second,
+		RetryMax:        time.Minute,
+		UnknownPhase:    30 * time.Second,
+	}
+}
+
+// WithDefaults returns i with its zero fields set from DefaultRequeueIntervals.
+func (i RequeueIntervals) WithDefaults() RequeueIntervals {
+	defaults := DefaultRequeueIntervals()
+	for _, f := range []struct{ value, fallback *time.Duration }{
+		{&i.PendingPod, &defaults.PendingPod},
+		{&i.ActiveContainer, &defaults.ActiveContainer},
+		{&i.RetryBase, &defaults.RetryBase},
+		{&i.RetryMax, &defaults.RetryMax},
+		{&i.UnknownPhase, &defaults.UnknownPhase},
+	} {
+		if *f.value <= 0 {
+			*f.value = *f.fallback
+		}
+	}
+	return i
+}
@@ -0,0 +1,15 @@
+package session_phases
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// GenerateSecureToken creates a cryptographically secure, random hex string.
+func GenerateSecureToken(length int) (string, error) {
+	bytes := make([]byte, length)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// alertProvider opens and resolves an incident keyed by session UID.
+type alertProvider interface {
+	trigger(ctx context.Context, msg Message) error
+	resolve(ctx context.Context, msg Message) error
+}
+
+// Alerter raises an informational on-call alert while a session is live in a critical namespace,
+// so on-call leads see live debugging on production systems.
+type Alerter struct {
+	provider alertProvider
+	selector labels.Selector
+}
+
+// NewAlerterFromEnv builds an Alerter from the environment, or returns nil if no provider is configured.
+//
+//	PAGERDUTY_ROUTING_KEY     Events API v2 integration key
+//	OPSGENIE_API_KEY          API integration key (OPSGENIE_API_URL selects the EU instance)
+//	ALERT_NAMESPACE_SELECTOR  label selector for alerting namespaces (default environment=production)
+func NewAlerterFromEnv() (*Alerter, error) {
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+
+	var provider alertProvider
+	switch {
+	case os.Getenv("PAGERDUTY_ROUTING_KEY") != "":
+		provider = &pagerDuty{routingKey: os.Getenv("PAGERDUTY_ROUTING_KEY"), client: httpClient}
+	case os.Getenv("OPSGENIE_API_KEY") != "":
+		apiURL := os.Getenv("OPSGENIE_API_URL")
+		if apiURL == "" {
+			apiURL = "https://api.opsgenie.com"
+		}
+		provider = &opsgenie{apiKey: os.Getenv("OPSGENIE_API_KEY"), apiURL: apiURL, client: httpClient}
+	default:
+		return nil, nil
+	}
+
+	rawSelector := os.Getenv("ALERT_NAMESPACE_SELECTOR")
+	if rawSelector == "" {
+		rawSelector = "environment=production"
+	}
+	selector, err := labels.Parse(rawSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ALERT_NAMESPACE_SELECTOR %q: %w", rawSelector, err)
+	}
+
+	return &Alerter{provider: provider, selector: selector}, nil
+}
+
+// Applies reports whether sessions targeting ns should raise an alert.
+func (a *Alerter) Applies(ns *corev1.Namespace) bool {
+	return a.selector.Matches(labels.Set(ns.Labels))
+}
+
+// Trigger opens the alert for the session described by msg.
+func (a *Alerter) Trigger(ctx context.Context, msg Message) error {
+	return a.provider.trigger(ctx, msg)
+}
+
+// Resolve closes the alert for the session described by msg.
+func (a *Alerter) Resolve(ctx context.Context, msg Message) error {
+	return a.provider.resolve(ctx, msg)
+}
+
+func alertSummary(msg Message) string {
+	return fmt.Sprintf("Live debug session %s/%s attached to pod %s/%s", msg.Namespace, msg.Name, msg.TargetNamespace, msg.TargetPod)
+}
+
+// pagerDuty talks to the PagerDuty Events API v2.
+type pagerDuty struct {
+	routingKey string
+	client     *http.Client
+}
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+func (p *pagerDuty) trigger(ctx context.Context, msg Message) error {
+	return p.enqueue(ctx, map[string]interface{}{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    msg.UID,
+		"payload": map[string]interface{}{
+			"summary":        alertSummary(msg),
+			"source":         msg.TargetNamespace + "/" + msg.TargetPod,
+			"severity":       "info",
+			"component":      msg.TargetContainer,
+			"group":          msg.TargetNamespace,
+			"class":          "kubedebugsess",
+			"custom_details": msg,
+		},
+	})
+}
+
+func (p *pagerDuty) resolve(ctx context.Context, msg Message) error {
+	return p.enqueue(ctx, map[string]interface{}{
+		"routing_key":  p.routingKey,
+		"event_action": "resolve",
+		"dedup_key":    msg.UID,
+	})
+}
+
+func (p *pagerDuty) enqueue(ctx context.Context, event map[string]interface{}) error {
+	return postJSON(ctx, p.client, pagerDutyEventsURL, nil, event)
+}
+
+// opsgenie talks to the Opsgenie Alert API.
+type opsgenie struct {
+	apiKey string
+	apiURL string
+	client *http.Client
+}
+
+func (o *opsgenie) trigger(ctx context.Context, msg Message) error {
+	return postJSON(ctx, o.client, o.apiURL+"/v2/alerts", o.headers(), map[string]interface{}{
+		"message":     alertSummary(msg),
+		"alias":       msg.UID,
+		"description": fmt.Sprintf("DebugSession %s/%s is running a debugger in container %s.", msg.Namespace, msg.Name, msg.Container),
+		"source":      "kubedebugsess",
+		"priority":    "P5",
+		"tags":        []string{"kubedebugsess", msg.TargetNamespace},
+		"details": map[string]string{
+			"session":   msg.Namespace + "/" + msg.Name,
+			"targetPod": msg.TargetNamespace + "/" + msg.TargetPod,
+			"container": msg.Container,
+		},
+	})
+}
+
+func (o *opsgenie) resolve(ctx context.Context, msg Message) error {
+	closeURL := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", o.apiURL, url.PathEscape(msg.UID))
+	return postJSON(ctx, o.client, closeURL, o.headers(), map[string]interface{}{
+		"source": "kubedebugsess",
+		"note":   fmt.Sprintf("DebugSession ended in phase %s", msg.Phase),
+	})
+}
+
+func (o *opsgenie) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.apiKey}
+}
+
+// postJSON sends body as JSON and treats any non-2xx response as an error.
+func postJSON(ctx context.Context, c *http.Client, endpoint string, headers map[string]string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("alert request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert provider returned non-2xx status: %s", resp.Status)
+	}
+	return nil
+}
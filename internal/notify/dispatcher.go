@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Dispatcher routes webhook notifications to the destinations declared in NotificationConfig resources.
+// Configs are read on every delivery, so edits take effect without restarting the controller. When no
+// NotificationConfig exists, the WEBHOOK_URL destination from the environment is used instead.
+type Dispatcher struct {
+	// Reader reads NotificationConfigs and Namespaces, normally through the manager's cache.
+	Reader client.Reader
+	// SecretReader reads referenced Secrets. It should bypass the cache so the controller
+	// does not watch every Secret in the cluster.
+	SecretReader client.Reader
+
+	defaults *WebhookNotifier
+	fallback *WebhookNotifier
+}
+
+// NewDispatcherFromEnv builds a Dispatcher whose shared delivery settings and fallback destination
+// come from the WEBHOOK_* environment variables.
+func NewDispatcherFromEnv(reader, secretReader client.Reader) (*Dispatcher, error) {
+	defaults, err := webhookDefaultsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	fallback, err := NewWebhookNotifierFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &Dispatcher{Reader: reader, SecretReader: secretReader, defaults: defaults, fallback: fallback}, nil
+}
+
+// Dispatch delivers msg to every destination routed for session and returns how many destinations
+// were attempted. Failures of individual destinations are joined into the returned error.
+func (d *Dispatcher) Dispatch(ctx context.Context, session *debugv1alpha1.DebugSession, msg Message) (int, error) {
+	destinations, errs := d.resolve(ctx, session)
+
+	attempted := 0
+	for name, n := range destinations {
+		if !n.Enabled(msg.Event) {
+			continue
+		}
+		attempted++
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return max(attempted, 1), fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return attempted, nil
+}
+
+// resolve returns the notifiers routed for session, keyed by destination name, along with any
+// destinations that could not be built.
+func (d *Dispatcher) resolve(ctx context.Context, session *debugv1alpha1.DebugSession) (map[string]*WebhookNotifier, []string) {
+	var configs debugv1alpha1.NotificationConfigList
+	if err := d.Reader.List(ctx, &configs); err != nil {
+		return nil, []string{fmt.Sprintf("failed to list NotificationConfigs: %v", err)}
+	}
+
+	destinations := map[string]*WebhookNotifier{}
+	if len(configs.Items) == 0 {
+		if d.fallback != nil {
+			destinations["env"] = d.fallback
+		}
+		return destinations, nil
+	}
+
+	targetNamespace := session.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = session.Namespace
+	}
+	var namespace *corev1.Namespace
+
+	var errs []string
+	for _, config := range configs.Items {
+		for _, dest := range config.Spec.Destinations {
+			name := config.Name + "/" + dest.Name
+
+			if dest.NamespaceSelector != nil && namespace == nil {
+				namespace = &corev1.Namespace{}
+				if err := d.Reader.Get(ctx, types.NamespacedName{Name: targetNamespace}, namespace); err != nil {
+					errs = append(errs, fmt.Sprintf("%s: failed to get namespace %s: %v", name, targetNamespace, err))
+					continue
+				}
+			}
+			routed, err := routes(dest, targetNamespace, namespace)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			if !routed {
+				continue
+			}
+
+			n, err := d.build(ctx, dest)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			destinations[name] = n
+		}
+	}
+	return destinations, errs
+}
+
+// routes reports whether sessions targeting targetNamespace go to dest.
+func routes(dest debugv1alpha1.NotificationDestination, targetNamespace string, namespace *corev1.Namespace) (bool, error) {
+	if len(dest.Namespaces) > 0 && !slices.Contains(dest.Namespaces, targetNamespace) {
+		return false, nil
+	}
+	if dest.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(dest.NamespaceSelector)
+		if err != nil {
+			return false, fmt.Errorf("invalid namespaceSelector: %w", err)
+		}
+		return selector.Matches(labelsOf(namespace)), nil
+	}
+	return true, nil
+}
+
+func labelsOf(ns *corev1.Namespace) labels.Set {
+	if ns == nil {
+		return nil
+	}
+	return labels.Set(ns.Labels)
+}
+
+// build creates the notifier for dest on top of the shared delivery settings.
+func (d *Dispatcher) build(ctx context.Context, dest debugv1alpha1.NotificationDestination) (*WebhookNotifier, error) {
+	n := *d.defaults
+	n.URL = dest.URL
+	n.Format = dest.Format
+
+	if dest.URLSecretRef != nil {
+		url, err := d.secretValue(ctx, dest.URLSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		n.URL = url
+	}
+	if n.URL == "" {
+		return nil, fmt.Errorf("neither url nor urlSecretRef is set")
+	}
+
+	if dest.SigningSecretRef != nil {
+		secret, err := d.secretValue(ctx, dest.SigningSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		n.Secret = []byte(secret)
+	}
+
+	if len(dest.Events) > 0 {
+		events, err := parseEvents(dest.Events)
+		if err != nil {
+			return nil, err
+		}
+		n.Events = events
+	}
+	return &n, nil
+}
+
+func (d *Dispatcher) secretValue(ctx context.Context, ref *debugv1alpha1.SecretKeyReference) (string, error) {
+	var secret corev1.Secret
+	if err := d.SecretReader.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, &secret); err != nil {
+		return "", fmt.Errorf("failed to read secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %q", ref.Namespace, ref.Name, ref.Key)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaPublisher writes lifecycle messages to a Kafka topic, keyed by session UID so that
+// all events of one session land on the same partition in order.
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func newKafkaPublisher(brokers []string, topic string) *kafkaPublisher {
+	return &kafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireOne,
+			BatchTimeout:           10 * time.Millisecond,
+			AllowAutoTopicCreation: false,
+		},
+	}
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, msg Message) error {
+	data, err := encode(msg)
+	if err != nil {
+		return err
+	}
+	if err := p.writer.WriteMessages(ctx, kafka.Message{Key: []byte(msg.UID), Value: data}); err != nil {
+		return fmt.Errorf("kafka publish to %s failed: %w", p.writer.Topic, err)
+	}
+	return nil
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
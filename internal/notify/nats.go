@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsPublisher publishes lifecycle messages to a NATS subject.
+type natsPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+func newNATSPublisher(url, subject string) (*natsPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("kubedebugsess-controller"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS at %s: %w", url, err)
+	}
+	return &natsPublisher{conn: conn, subject: subject}, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, msg Message) error {
+	data, err := encode(msg)
+	if err != nil {
+		return err
+	}
+	if err := p.conn.Publish(p.subject, data); err != nil {
+		return fmt.Errorf("nats publish to %s failed: %w", p.subject, err)
+	}
+	// Flush so delivery failures surface here rather than being silently buffered.
+	if err := p.conn.FlushTimeout(5 * time.Second); err != nil {
+		return fmt.Errorf("nats flush failed: %w", err)
+	}
+	return nil
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,141 @@
+// Package notify publishes DebugSession lifecycle messages to external systems.
+//
+// Two delivery paths exist: WebhookNotifier for chat and HTTP webhooks, and Publisher for the message buses
+// (Kafka, NATS) that organizations use to route audit and ops events.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+)
+
+// Message is the JSON document published for every session lifecycle event.
+type Message struct {
+	Event           string    `json:"event"`
+	Namespace       string    `json:"namespace"`
+	Name            string    `json:"name"`
+	UID             string    `json:"uid"`
+	Phase           string    `json:"phase"`
+	PreviousPhase   string    `json:"previousPhase,omitempty"`
+	TargetNamespace string    `json:"targetNamespace"`
+	TargetPod       string    `json:"targetPod"`
+	TargetContainer string    `json:"targetContainer,omitempty"`
+	Container       string    `json:"container,omitempty"`
+	RequestedBy     string    `json:"requestedBy,omitempty"`
+	TicketRef       string    `json:"ticketRef,omitempty"`
+	Message         string    `json:"message,omitempty"`
+	LogKey          string    `json:"logKey,omitempty"`
+	LogURL          string    `json:"logURL,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// NewMessage builds the lifecycle message for session. The status message is only carried for failures,
+// since the Active message contains the one-time attach token.
+func NewMessage(event string, session *debugv1alpha1.DebugSession, previous debugv1alpha1.SessionPhase) Message {
+	msg := Message{
+		Event:           event,
+		Namespace:       session.Namespace,
+		Name:            session.Name,
+		UID:             string(session.UID),
+		Phase:           string(session.Status.Phase),
+		PreviousPhase:   string(previous),
+		TargetNamespace: session.Spec.TargetNamespace,
+		TargetPod:       debugv1alpha1.CurrentTargetPod(session),
+		TargetContainer: session.Spec.TargetContainerName,
+		Container:       session.Status.DebuggingContainerName,
+		RequestedBy:     session.Annotations[debugv1alpha1.RequestedByAnnotation],
+		TicketRef:       session.Spec.TicketRef,
+		LogKey:          session.Status.LogKey,
+		LogURL:          session.Status.LogURL,
+		Timestamp:       time.Now().UTC(),
+	}
+	if session.Spec.RequestedBy != nil {
+		msg.RequestedBy = session.Spec.RequestedBy.Username
+	}
+	if session.Status.Phase == debugv1alpha1.Failed {
+		msg.Message = session.Status.Message
+	}
+	return msg
+}
+
+// Publisher delivers lifecycle messages to a message bus.
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// NewPublisherFromEnv returns the publisher selected by the NOTIFY_* environment variables,
+// or nil if no message bus is configured.
+//
+//	NOTIFY_KAFKA_BROKERS  comma separated broker addresses, with NOTIFY_KAFKA_TOPIC
+//	NOTIFY_NATS_URL       NATS server URL, with NOTIFY_NATS_SUBJECT
+func NewPublisherFromEnv() (Publisher, error) {
+	var publishers multiPublisher
+
+	if brokers := os.Getenv("NOTIFY_KAFKA_BROKERS"); brokers != "" {
+		topic := os.Getenv("NOTIFY_KAFKA_TOPIC")
+		if topic == "" {
+			return nil, fmt.Errorf("NOTIFY_KAFKA_TOPIC must be set when NOTIFY_KAFKA_BROKERS is configured")
+		}
+		publishers = append(publishers, newKafkaPublisher(strings.Split(brokers, ","), topic))
+	}
+
+	if url := os.Getenv("NOTIFY_NATS_URL"); url != "" {
+		subject := os.Getenv("NOTIFY_NATS_SUBJECT")
+		if subject == "" {
+			return nil, fmt.Errorf("NOTIFY_NATS_SUBJECT must be set when NOTIFY_NATS_URL is configured")
+		}
+		p, err := newNATSPublisher(url, subject)
+		if err != nil {
+			_ = publishers.Close()
+			return nil, err
+		}
+		publishers = append(publishers, p)
+	}
+
+	switch len(publishers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return publishers[0], nil
+	default:
+		return publishers, nil
+	}
+}
+
+// multiPublisher fans a message out to every configured bus.
+type multiPublisher []Publisher
+
+func (m multiPublisher) Publish(ctx context.Context, msg Message) error {
+	var errs []string
+	for _, p := range m {
+		if err := p.Publish(ctx, msg); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("publish failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (m multiPublisher) Close() error {
+	for _, p := range m {
+		_ = p.Close()
+	}
+	return nil
+}
+
+func encode(msg Message) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return data, nil
+}
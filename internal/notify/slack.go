@@ -0,0 +1,345 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Action IDs of the buttons on ApprovalRequired Slack messages.
+const (
+	slackActionApprove = "kubedebugsess-approve"
+	slackActionDeny    = "kubedebugsess-deny"
+)
+
+// SlackUserPrefix marks approvers and deniers recorded from Slack, whose names are Slack usernames
+// rather than Kubernetes users.
+const SlackUserPrefix = "slack:"
+
+// slackMaxClockSkew is how old a signed Slack request may be before it is rejected as a replay.
+const slackMaxClockSkew = 5 * time.Minute
+
+// slackApprovalPayload renders an ApprovalRequired message with Approve and Deny buttons. The buttons
+// carry the session's namespace, name and UID, so that a recreated session of the same name cannot be
+// approved from an old message.
+func slackApprovalPayload(title string, msg Message) map[string]interface{} {
+	value := strings.Join([]string{msg.Namespace, msg.Name, msg.UID}, "/")
+	text := fmt.Sprintf("*%s*\nSession: `%s/%s`\nRequested by: `%s`\nTarget: `%s/%s`",
+		title, msg.Namespace, msg.Name, msg.RequestedBy, msg.TargetNamespace, msg.TargetPod)
+	if msg.TicketRef != "" {
+		text += fmt.Sprintf("\nTicket: %s", msg.TicketRef)
+	}
+	return map[string]interface{}{
+		"text": text,
+		"blocks": []map[string]interface{}{
+			{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}},
+			{"type": "actions", "elements": []map[string]interface{}{
+				{
+					"type": "button", "action_id": slackActionApprove, "value": value, "style": "primary",
+					"text": map[string]string{"type": "plain_text", "text": "Approve"},
+				},
+				{
+					"type": "button", "action_id": slackActionDeny, "value": value, "style": "danger",
+					"text": map[string]string{"type": "plain_text", "text": "Deny"},
+				},
+			}},
+		},
+	}
+}
+
+// SlackApp serves the endpoints of a Slack app, verifying every request's Slack signature:
+//
+//	/slack/interactions  the interactivity request URL. When an approver presses Approve or Deny on an
+//	                     ApprovalRequired message, the decision is recorded on the session in the
+//	                     approved-by or denied-by annotation, as "slack:<username>".
+//	/slack/command       the /debugsess slash command, see serveCommand.
+//
+// It implements the controller-runtime Runnable interface.
+type SlackApp struct {
+	// Client creates sessions and patches their annotations. Its identity must be one of the admission
+	// webhook's trusted requesters and approvers.
+	Client client.Client
+	// Reader reads the user map. It should bypass the cache so the controller does not watch every
+	// ConfigMap in the cluster.
+	Reader client.Reader
+	// Addr is the HTTP listen address.
+	Addr string
+	// SigningSecret is the Slack app's signing secret.
+	SigningSecret []byte
+	// Approvers are the Slack user IDs allowed to decide; empty disables interactive approval.
+	Approvers map[string]bool
+	// UserMap is the ConfigMap mapping Slack user IDs to Kubernetes usernames; sessions are only
+	// created for, and only approved by, mapped users. Empty disables the slash command and approval.
+	UserMap types.NamespacedName
+	// Threads, when set, posts the progress of sessions created from Slack in a thread.
+	Threads *SlackThreads
+	// HTTPClient updates the original message through the interaction's response URL.
+	HTTPClient *http.Client
+}
+
+// NewSlackAppFromEnv builds a SlackApp listening on addr, or returns nil if addr is empty.
+//
+//	SLACK_SIGNING_SECRET      the Slack app's signing secret (required)
+//	SLACK_APPROVER_IDS        comma separated Slack user IDs allowed to approve and deny; interactive
+//	                          approval is disabled unless it is set
+//	SLACK_USER_MAP_CONFIGMAP  <namespace>/<name> of the ConfigMap whose keys are Slack user IDs and
+//	                          values Kubernetes usernames, enabling the slash command and approval
+//	SLACK_BOT_TOKEN           bot token used to post session progress in threads, see SlackThreads
+func NewSlackAppFromEnv(c client.Client, reader client.Reader, addr string) (*SlackApp, error) {
+	if addr == "" {
+		return nil, nil
+	}
+	secret := os.Getenv("SLACK_SIGNING_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("SLACK_SIGNING_SECRET must be set when the Slack app is enabled")
+	}
+	s := &SlackApp{
+		Client:        c,
+		Reader:        reader,
+		Addr:          addr,
+		SigningSecret: []byte(secret),
+		Threads:       NewSlackThreadsFromEnv(),
+		HTTPClient:    &http.Client{Timeout: 5 * time.Second},
+	}
+	if v := os.Getenv("SLACK_APPROVER_IDS"); v != "" {
+		s.Approvers = map[string]bool{}
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				s.Approvers[id] = true
+			}
+		}
+	}
+	if v := os.Getenv("SLACK_USER_MAP_CONFIGMAP"); v != "" {
+		namespace, name, ok := strings.Cut(v, "/")
+		if !ok || namespace == "" || name == "" {
+			return nil, fmt.Errorf("invalid SLACK_USER_MAP_CONFIGMAP %q: must be <namespace>/<name>", v)
+		}
+		s.UserMap = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	return s, nil
+}
+
+// NeedLeaderElection returns false so that every manager replica answers Slack.
+func (s *SlackApp) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves until ctx is cancelled.
+func (s *SlackApp) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	if len(s.Approvers) > 0 {
+		mux.Handle("/slack/interactions", s.verified(s.serveInteraction))
+	} else {
+		log.FromContext(ctx).Info("SLACK_APPROVER_IDS is not set, Slack approval buttons are disabled")
+	}
+	mux.Handle("/slack/command", s.verified(s.serveCommand))
+	server := &http.Server{Addr: s.Addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.ListenAndServe() }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+// verified only passes POST requests carrying a valid Slack signature on to handle, with their form.
+func (s *SlackApp) verified(handle func(http.ResponseWriter, *http.Request, url.Values)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read request", http.StatusBadRequest)
+			return
+		}
+		if err := s.verify(r.Header, body); err != nil {
+			log.FromContext(r.Context()).Info("Rejected Slack request", "path", r.URL.Path, "reason", err.Error())
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "malformed payload", http.StatusBadRequest)
+			return
+		}
+		handle(w, r, form)
+	})
+}
+
+// slackInteraction is the part of a Slack block_actions payload SlackApp reads.
+type slackInteraction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	ResponseURL string `json:"response_url"`
+	Actions     []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// serveInteraction handles a button press. Errors are shown to the approver only; the original
+// message is replaced with the outcome once the decision is recorded.
+func (s *SlackApp) serveInteraction(w http.ResponseWriter, r *http.Request, form url.Values) {
+	var interaction slackInteraction
+	if err := json.Unmarshal([]byte(form.Get("payload")), &interaction); err != nil {
+		http.Error(w, "malformed payload", http.StatusBadRequest)
+		return
+	}
+	if interaction.Type != "block_actions" || len(interaction.Actions) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	outcome, err := s.decide(r.Context(), interaction)
+	if err != nil {
+		replyEphemeral(w, err.Error())
+		return
+	}
+	s.respond(r.Context(), interaction.ResponseURL, outcome)
+	w.WriteHeader(http.StatusOK)
+}
+
+// replyEphemeral answers a Slack request with text shown only to the user who sent it.
+func replyEphemeral(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"response_type": "ephemeral", "replace_original": false, "text": text,
+	})
+}
+
+// verify checks Slack's v0 request signature, the HMAC-SHA256 of "v0:<timestamp>:<body>".
+func (s *SlackApp) verify(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("missing request timestamp")
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > slackMaxClockSkew || skew < -slackMaxClockSkew {
+		return errors.New("request timestamp too far from now")
+	}
+	mac := hmac.New(sha256.New, s.SigningSecret)
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// decide records the pressed button on the session and returns the outcome to show in Slack. Only the
+// Approvers may decide, and a session is only approved by a user mapped in UserMap to someone other
+// than its requester: the decision is patched with the app's identity, so the admission webhook cannot
+// tell the approver and the requester apart itself.
+func (s *SlackApp) decide(ctx context.Context, interaction slackInteraction) (string, error) {
+	action := interaction.Actions[0]
+	var key, verb string
+	switch action.ActionID {
+	case slackActionApprove:
+		key, verb = debugv1alpha1.ApprovedByKey, "approved"
+	case slackActionDeny:
+		key, verb = debugv1alpha1.DeniedByKey, "denied"
+	default:
+		return "", fmt.Errorf("unknown action %q", action.ActionID)
+	}
+	if !s.Approvers[interaction.User.ID] {
+		return "", errors.New("you are not allowed to approve or deny debug sessions")
+	}
+	parts := strings.Split(action.Value, "/")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed session reference %q", action.Value)
+	}
+	sessionKey := types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+
+	var session debugv1alpha1.DebugSession
+	if err := s.Client.Get(ctx, sessionKey, &session); err != nil {
+		return "", fmt.Errorf("session %s not found", sessionKey)
+	}
+	if string(session.UID) != parts[2] {
+		return "", fmt.Errorf("session %s was recreated since this request was posted", sessionKey)
+	}
+	if session.Status.Phase != debugv1alpha1.Pending {
+		return "", fmt.Errorf("session %s is %s and no longer awaits approval", sessionKey, session.Status.Phase)
+	}
+	for _, k := range []string{debugv1alpha1.ApprovedByKey, debugv1alpha1.DeniedByKey} {
+		if by := session.Annotations[k]; by != "" {
+			return "", fmt.Errorf("session %s was already decided by %s", sessionKey, by)
+		}
+	}
+	if key == debugv1alpha1.ApprovedByKey {
+		mapped, err := s.kubernetesUser(ctx, interaction.User.ID)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Failed to read the Slack user map")
+			return "", errors.New("the Slack user map could not be read; try again later")
+		}
+		if mapped == "" {
+			return "", errors.New("your Slack account is not mapped to a Kubernetes user, so it cannot approve sessions")
+		}
+		requester := session.Spec.RequestedBy
+		if requester == nil {
+			return "", fmt.Errorf("session %s has no recorded requester and cannot be approved from Slack", sessionKey)
+		}
+		if requester.Username == mapped {
+			return "", fmt.Errorf("session %s cannot be approved by its requester", sessionKey)
+		}
+	}
+
+	by := SlackUserPrefix + interaction.User.Username
+	patch := client.MergeFrom(session.DeepCopy())
+	if session.Annotations == nil {
+		session.Annotations = map[string]string{}
+	}
+	session.Annotations[key] = by
+	if err := s.Client.Patch(ctx, &session, patch); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to record Slack decision", "session", sessionKey, "user", by)
+		return "", fmt.Errorf("failed to record the decision on session %s: %v", sessionKey, err)
+	}
+	log.FromContext(ctx).Info("Session decided in Slack", "session", sessionKey, "decision", verb, "user", by)
+	return fmt.Sprintf("Debug session `%s` was %s by <@%s>.", sessionKey, verb, interaction.User.ID), nil
+}
+
+// respond replaces the original message with the outcome, removing its buttons. Failures are only
+// logged: the decision is already recorded.
+func (s *SlackApp) respond(ctx context.Context, responseURL, text string) {
+	if !strings.HasPrefix(responseURL, "https://hooks.slack.com/") {
+		return
+	}
+	body, _ := json.Marshal(map[string]interface{}{"replace_original": true, "text": text})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update the Slack approval message")
+		return
+	}
+	_ = resp.Body.Close()
+}
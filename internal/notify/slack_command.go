@@ -0,0 +1,241 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// SlackThreadAnnotation, on a session created from Slack, is the "<channel>/<ts>" of the thread its
+// progress is posted in.
+const SlackThreadAnnotation = "ajou.oxan0n.me/slack-thread"
+
+// slackCommandUsage is shown for "/debugsess help" and malformed commands.
+const slackCommandUsage = "Usage: `/debugsess create <namespace>/<pod> [--container=NAME] [--image=IMAGE] [--toolset=TOOLSET] [--ttl=SECONDS]`"
+
+// serveCommand handles the /debugsess slash command. "create" creates a DebugSession against the pod
+// on behalf of the Kubernetes user the caller is mapped to in UserMap, so that the requester access
+// check and the audit trail name that user. The session's progress is posted in a thread when Threads
+// is set.
+func (s *SlackApp) serveCommand(w http.ResponseWriter, r *http.Request, form url.Values) {
+	ctx := r.Context()
+	args := strings.Fields(form.Get("text"))
+	if len(args) == 0 || args[0] != "create" {
+		replyEphemeral(w, slackCommandUsage)
+		return
+	}
+	session, err := parseCreateCommand(args[1:])
+	if err != nil {
+		replyEphemeral(w, fmt.Sprintf("%v\n%s", err, slackCommandUsage))
+		return
+	}
+
+	userID := form.Get("user_id")
+	username, err := s.kubernetesUser(ctx, userID)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to read the Slack user map")
+		replyEphemeral(w, "The Slack user map could not be read; try again later.")
+		return
+	}
+	if username == "" {
+		replyEphemeral(w, "Your Slack account is not mapped to a Kubernetes user; ask an administrator to add it.")
+		return
+	}
+	session.Spec.RequestedBy = &debugv1alpha1.Requester{Username: username}
+	session.Annotations = map[string]string{debugv1alpha1.RequestedByAnnotation: username}
+
+	var thread string
+	if s.Threads != nil {
+		ts, err := s.Threads.post(ctx, form.Get("channel_id"), "", fmt.Sprintf(
+			"<@%s> requested a debug session for `%s/%s`.", userID, session.Spec.TargetNamespace, session.Spec.TargetPodName))
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Failed to start the Slack thread of a session")
+		} else {
+			thread = form.Get("channel_id") + "/" + ts
+			session.Annotations[SlackThreadAnnotation] = thread
+		}
+	}
+
+	if err := s.Client.Create(ctx, session); err != nil {
+		message := fmt.Sprintf("Failed to create the debug session: %v", err)
+		if thread != "" {
+			_ = s.Threads.reply(ctx, thread, message)
+		}
+		replyEphemeral(w, message)
+		return
+	}
+	log.FromContext(ctx).Info("Session created from Slack", "session", session.Namespace+"/"+session.Name,
+		"user", username, "slackUser", userID)
+
+	message := fmt.Sprintf("Created debug session `%s/%s` as %s.", session.Namespace, session.Name, username)
+	if thread != "" {
+		_ = s.Threads.reply(ctx, thread, message)
+		message += " Its progress is posted in the thread."
+	}
+	replyEphemeral(w, message)
+}
+
+// parseCreateCommand builds the session of "create <namespace>/<pod> [flags]". It is created in the
+// target namespace under a generated name.
+func parseCreateCommand(args []string) (*debugv1alpha1.DebugSession, error) {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	container := fs.String("container", "", "")
+	image := fs.String("image", "", "")
+	toolset := fs.String("toolset", "", "")
+	ttl := fs.Int("ttl", 0, "")
+
+	var target string
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if args = fs.Args(); len(args) > 0 {
+			if target != "" {
+				return nil, fmt.Errorf("unexpected argument %q", args[0])
+			}
+			target, args = args[0], args[1:]
+		}
+	}
+	namespace, pod, ok := strings.Cut(target, "/")
+	if !ok || namespace == "" || pod == "" {
+		return nil, errors.New("the target must be given as <namespace>/<pod>")
+	}
+	if *ttl < 0 {
+		return nil, errors.New("--ttl must not be negative")
+	}
+
+	return &debugv1alpha1.DebugSession{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "slack-", Namespace: namespace},
+		Spec: debugv1alpha1.DebugSessionSpec{
+			TargetNamespace:     namespace,
+			TargetPodName:       pod,
+			TargetContainerName: *container,
+			DebuggerImage:       *image,
+			Toolset:             debugv1alpha1.Toolset(*toolset),
+			TTL:                 int32(*ttl),
+		},
+	}, nil
+}
+
+// kubernetesUser returns the Kubernetes username the Slack user is mapped to, or "" if the user is
+// not mapped or no user map is configured.
+func (s *SlackApp) kubernetesUser(ctx context.Context, slackUserID string) (string, error) {
+	if s.UserMap.Name == "" || slackUserID == "" {
+		return "", nil
+	}
+	var users corev1.ConfigMap
+	if err := s.Reader.Get(ctx, s.UserMap, &users); err != nil {
+		return "", fmt.Errorf("failed to get ConfigMap %s: %w", s.UserMap, err)
+	}
+	return strings.TrimSpace(users.Data[slackUserID]), nil
+}
+
+// SlackThreads posts the progress of sessions created from Slack in the thread named by their
+// SlackThreadAnnotation, through the Slack Web API.
+type SlackThreads struct {
+	// Token is the bot token; the bot needs the chat:write scope and must be in the channels the
+	// command is used in.
+	Token  string
+	Client *http.Client
+	// APIURL is the Slack Web API base URL.
+	APIURL string
+}
+
+// NewSlackThreadsFromEnv returns the SlackThreads of SLACK_BOT_TOKEN, or nil if it is not set.
+func NewSlackThreadsFromEnv() *SlackThreads {
+	token := os.Getenv("SLACK_BOT_TOKEN")
+	if token == "" {
+		return nil
+	}
+	return &SlackThreads{Token: token, Client: &http.Client{Timeout: 5 * time.Second}, APIURL: "https://slack.com/api/"}
+}
+
+// PostPhase posts a phase transition of session in its thread, if it has one. The Active status
+// message is never posted, since it carries the one-time attach token.
+func (t *SlackThreads) PostPhase(ctx context.Context, session *debugv1alpha1.DebugSession, from debugv1alpha1.SessionPhase) error {
+	thread := session.Annotations[SlackThreadAnnotation]
+	if thread == "" || from == "" {
+		return nil
+	}
+	name := session.Namespace + "/" + session.Name
+	var text string
+	switch session.Status.Phase {
+	case debugv1alpha1.Active:
+		text = fmt.Sprintf("Session `%s` is active; attach to it with your usual client.", name)
+		if session.Status.ExpiryTime != nil {
+			text += fmt.Sprintf(" It expires at %s.", session.Status.ExpiryTime.UTC().Format(time.RFC3339))
+		}
+	case debugv1alpha1.Failed:
+		text = fmt.Sprintf("Session `%s` failed: %s", name, session.Status.Message)
+	case debugv1alpha1.Completed:
+		text = fmt.Sprintf("Session `%s` completed.", name)
+		if session.Status.LogKey != "" {
+			text += fmt.Sprintf(" Transcript: `%s`", session.Status.LogKey)
+		}
+	default:
+		text = fmt.Sprintf("Session `%s` moved from %s to %s.", name, from, session.Status.Phase)
+	}
+	return t.reply(ctx, thread, text)
+}
+
+// reply posts text in the "<channel>/<ts>" thread.
+func (t *SlackThreads) reply(ctx context.Context, thread, text string) error {
+	channel, ts, ok := strings.Cut(thread, "/")
+	if !ok {
+		return fmt.Errorf("malformed Slack thread %q", thread)
+	}
+	_, err := t.post(ctx, channel, ts, text)
+	return err
+}
+
+// post sends a chat.postMessage to channel, in the thread of threadTS if set, and returns the ts of
+// the new message.
+func (t *SlackThreads) post(ctx context.Context, channel, threadTS, text string) (string, error) {
+	payload := map[string]string{"channel": channel, "text": text}
+	if threadTS != "" {
+		payload["thread_ts"] = threadTS
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.APIURL+"chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+t.Token)
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("slack chat.postMessage failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("slack chat.postMessage returned %s", resp.Status)
+	}
+	if !result.OK {
+		return "", fmt.Errorf("slack chat.postMessage failed: %s", result.Error)
+	}
+	return result.TS, nil
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func signedHeader(secret, timestamp string, body []byte) http.Header {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestSlackVerify(t *testing.T) {
+	s := &SlackApp{SigningSecret: []byte("secret")}
+	body := []byte("payload=%7B%7D")
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		valid  bool
+	}{
+		{name: "valid", header: signedHeader("secret", now, body), body: body, valid: true},
+		{name: "wrong secret", header: signedHeader("other", now, body), body: body},
+		{name: "tampered body", header: signedHeader("secret", now, body), body: []byte("payload=%7B%22a%22%7D")},
+		{name: "stale timestamp", header: signedHeader("secret", stale, body), body: body},
+		{name: "missing timestamp", header: http.Header{}, body: body},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.verify(tt.header, tt.body)
+			if (err == nil) != tt.valid {
+				t.Errorf("verify() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestSlackDecide(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = debugv1alpha1.AddToScheme(scheme)
+
+	userMap := types.NamespacedName{Namespace: "kubedebugsess-system", Name: "slack-users"}
+	users := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: userMap.Namespace, Name: userMap.Name},
+		Data:       map[string]string{"UALICE": "alice", "UBOB": "bob"},
+	}
+	newSession := func() *debugv1alpha1.DebugSession {
+		return &debugv1alpha1.DebugSession{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "s1", UID: "uid-1"},
+			Spec:       debugv1alpha1.DebugSessionSpec{RequestedBy: &debugv1alpha1.Requester{Username: "alice"}},
+			Status:     debugv1alpha1.DebugSessionStatus{Phase: debugv1alpha1.Pending},
+		}
+	}
+	interaction := func(userID, actionID string) slackInteraction {
+		var i slackInteraction
+		payload := fmt.Sprintf(`{"type":"block_actions","user":{"id":%q,"username":%q},"actions":[{"action_id":%q,"value":"apps/s1/uid-1"}]}`,
+			userID, strings.ToLower(userID), actionID)
+		if err := json.Unmarshal([]byte(payload), &i); err != nil {
+			t.Fatal(err)
+		}
+		return i
+	}
+
+	tests := []struct {
+		name        string
+		approvers   map[string]bool
+		userMap     types.NamespacedName
+		interaction slackInteraction
+		wantKey     string
+	}{
+		{
+			name:        "mapped approver approves",
+			approvers:   map[string]bool{"UBOB": true},
+			userMap:     userMap,
+			interaction: interaction("UBOB", slackActionApprove),
+			wantKey:     debugv1alpha1.ApprovedByKey,
+		},
+		{
+			name:        "no approver allowlist",
+			userMap:     userMap,
+			interaction: interaction("UBOB", slackActionApprove),
+		},
+		{
+			name:        "not an approver",
+			approvers:   map[string]bool{"UALICE": true},
+			userMap:     userMap,
+			interaction: interaction("UBOB", slackActionApprove),
+		},
+		{
+			name:        "requester approves own session",
+			approvers:   map[string]bool{"UALICE": true},
+			userMap:     userMap,
+			interaction: interaction("UALICE", slackActionApprove),
+		},
+		{
+			name:        "unmapped approver",
+			approvers:   map[string]bool{"UCAROL": true},
+			userMap:     userMap,
+			interaction: interaction("UCAROL", slackActionApprove),
+		},
+		{
+			name:        "no user map",
+			approvers:   map[string]bool{"UBOB": true},
+			interaction: interaction("UBOB", slackActionApprove),
+		},
+		{
+			name:        "user map cannot be read",
+			approvers:   map[string]bool{"UBOB": true},
+			userMap:     types.NamespacedName{Namespace: "kubedebugsess-system", Name: "missing"},
+			interaction: interaction("UBOB", slackActionApprove),
+		},
+		{
+			name:        "requester denies own session",
+			approvers:   map[string]bool{"UALICE": true},
+			userMap:     userMap,
+			interaction: interaction("UALICE", slackActionDeny),
+			wantKey:     debugv1alpha1.DeniedByKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newSession(), users.DeepCopy()).Build()
+			s := &SlackApp{Client: c, Reader: c, Approvers: tt.approvers, UserMap: tt.userMap}
+
+			_, err := s.decide(context.Background(), tt.interaction)
+			if (err == nil) != (tt.wantKey != "") {
+				t.Fatalf("decide() error = %v, want success %v", err, tt.wantKey != "")
+			}
+			var got debugv1alpha1.DebugSession
+			if err := c.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: "s1"}, &got); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{debugv1alpha1.ApprovedByKey, debugv1alpha1.DeniedByKey} {
+				recorded := got.Annotations[key] != ""
+				if recorded != (key == tt.wantKey) {
+					t.Errorf("annotation %s recorded = %v, want %v", key, recorded, key == tt.wantKey)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+)
+
+// Events delivered to webhooks.
+const (
+	EventSessionReady      = "SessionReady"
+	EventSessionFailed     = "SessionFailed"
+	EventSessionTerminated = "SessionTerminated"
+	EventPolicyViolation   = "PolicyViolation"
+	EventTargetPodReplaced = "TargetPodReplaced"
+	EventApprovalRequired  = "ApprovalRequired"
+	EventAuthFailures      = "AuthFailureThresholdExceeded"
+)
+
+// eventTitles are the headlines used by the built-in chat payloads.
+var eventTitles = map[string]string{
+	EventSessionReady:      "Debug session ready",
+	EventSessionFailed:     "Debug session failed",
+	EventSessionTerminated: "Debug session terminated",
+	EventPolicyViolation:   "Debug session rejected by policy",
+	EventTargetPodReplaced: "Debug session moved to a replacement pod",
+	EventApprovalRequired:  "Debug session awaiting approval",
+	EventAuthFailures:      "Repeated invalid tokens for debug session",
+}
+
+// Headers carrying the HMAC signature of a webhook request.
+const (
+	SignatureHeader = "X-KubeDebugSess-Signature"
+	TimestampHeader = "X-KubeDebugSess-Timestamp"
+)
+
+// WebhookNotifier delivers session notifications to a chat or generic HTTP webhook.
+// Deliveries are retried with exponential backoff, optionally signed with HMAC-SHA256 and
+// rendered from per-event templates when one is configured.
+type WebhookNotifier struct {
+	URL         string
+	Format      string
+	Secret      []byte
+	Templates   map[string]*template.Template
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration
+	// Events lists the enabled events; nil enables all of them.
+	Events map[string]bool
+}
+
+// NewWebhookNotifierFromEnv builds a WebhookNotifier from the environment, or returns nil if
+// WEBHOOK_URL is not set.
+//
+//	WEBHOOK_URL           destination; Slack, Discord, Teams and Google Chat are detected from the domain
+//	WEBHOOK_FORMAT        forces a payload format (generic, slack, discord, teams, googlechat)
+//	WEBHOOK_SECRET        HMAC key used to sign every request
+//	WEBHOOK_EVENTS        comma separated events to deliver (default all)
+//
+// Delivery settings shared with NotificationConfig destinations are read by webhookDefaultsFromEnv.
+func NewWebhookNotifierFromEnv() (*WebhookNotifier, error) {
+	webhookURL := os.Getenv("WEBHOOK_URL")
+	if webhookURL == "" {
+		return nil, nil
+	}
+
+	n, err := webhookDefaultsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	n.URL = webhookURL
+	n.Format = os.Getenv("WEBHOOK_FORMAT")
+	n.Secret = []byte(os.Getenv("WEBHOOK_SECRET"))
+
+	if err := validateFormat(n.Format); err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_FORMAT: %w", err)
+	}
+
+	if v := os.Getenv("WEBHOOK_EVENTS"); v != "" {
+		events, err := parseEvents(strings.Split(v, ","))
+		if err != nil {
+			return nil, fmt.Errorf("invalid WEBHOOK_EVENTS: %w", err)
+		}
+		n.Events = events
+	}
+
+	return n, nil
+}
+
+// webhookDefaultsFromEnv returns a notifier without a destination, carrying the delivery settings
+// shared by every webhook.
+//
+//	WEBHOOK_TEMPLATE_DIR  directory of <Event>.tmpl files overriding the built-in payloads
+//	WEBHOOK_MAX_ATTEMPTS  delivery attempts before giving up (default 3)
+func webhookDefaultsFromEnv() (*WebhookNotifier, error) {
+	n := &WebhookNotifier{
+		Client:      &http.Client{Timeout: 5 * time.Second},
+		MaxAttempts: 3,
+		Backoff:     time.Second,
+	}
+
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil || attempts < 1 {
+			return nil, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS %q", v)
+		}
+		n.MaxAttempts = attempts
+	}
+
+	if dir := os.Getenv("WEBHOOK_TEMPLATE_DIR"); dir != "" {
+		templates, err := loadTemplates(dir)
+		if err != nil {
+			return nil, err
+		}
+		n.Templates = templates
+	}
+
+	return n, nil
+}
+
+func validateFormat(format string) error {
+	switch format {
+	case FormatAuto, FormatGeneric, FormatSlack, FormatDiscord, FormatTeams, FormatGoogleChat:
+		return nil
+	}
+	return fmt.Errorf("unsupported payload format %q", format)
+}
+
+// parseEvents turns a list of event names into an enabled-events set.
+func parseEvents(names []string) (map[string]bool, error) {
+	events := map[string]bool{}
+	for _, event := range names {
+		event = strings.TrimSpace(event)
+		if _, ok := eventTitles[event]; !ok {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+		events[event] = true
+	}
+	return events, nil
+}
+
+// loadTemplates parses every <Event>.tmpl file in dir, keyed by event name.
+func loadTemplates(dir string) (map[string]*template.Template, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	templates := make(map[string]*template.Template, len(paths))
+	for _, path := range paths {
+		event := strings.TrimSuffix(filepath.Base(path), ".tmpl")
+		tmpl, err := template.New(filepath.Base(path)).Funcs(template.FuncMap{"json": jsonString}).ParseFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse webhook template %s: %w", path, err)
+		}
+		templates[event] = tmpl
+	}
+	return templates, nil
+}
+
+// jsonString quotes s for safe embedding in a JSON template.
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// Enabled reports whether event should be delivered.
+func (n *WebhookNotifier) Enabled(event string) bool {
+	return n.Events == nil || n.Events[event]
+}
+
+// NewWebhookMessage builds the webhook message for session. Unlike bus messages it always carries the
+// status message, since webhooks are the user's delivery channel for connection instructions.
+func NewWebhookMessage(event string, session *debugv1alpha1.DebugSession) Message {
+	msg := NewMessage(event, session, "")
+	msg.Message = session.Status.Message
+	return msg
+}
+
+// Notify delivers msg, returning the last error once all attempts are exhausted.
+func (n *WebhookNotifier) Notify(ctx context.Context, msg Message) error {
+	body, err := n.render(msg)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.Backoff
+	for attempt := 1; ; attempt++ {
+		retryable, err := n.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= n.MaxAttempts {
+			return fmt.Errorf("webhook delivery failed after %d attempt(s): %w", attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook delivery aborted: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (n *WebhookNotifier) render(msg Message) ([]byte, error) {
+	if tmpl, ok := n.Templates[msg.Event]; ok {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, msg); err != nil {
+			return nil, fmt.Errorf("failed to render webhook template for %s: %w", msg.Event, err)
+		}
+		return buf.Bytes(), nil
+	}
+	format := n.Format
+	if format == FormatAuto {
+		format = detectFormat(n.URL)
+	}
+	data, err := json.Marshal(buildWebhookPayload(format, msg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	return data, nil
+}
+
+// send performs a single delivery attempt and reports whether a failure is worth retrying.
+func (n *WebhookNotifier) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.Secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+sign(n.Secret, timestamp, body))
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		// url.Error embeds the webhook URL, which for chat services contains the secret token.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("webhook returned non-2xx status: %s", resp.Status)
+	}
+	return false, nil
+}
+
+// sign computes the HMAC-SHA256 of "<timestamp>.<body>", so receivers can reject replayed requests.
+func sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Webhook payload formats. FormatAuto picks one from the webhook URL.
+const (
+	FormatAuto       = ""
+	FormatGeneric    = "generic"
+	FormatSlack      = "slack"
+	FormatDiscord    = "discord"
+	FormatTeams      = "teams"
+	FormatGoogleChat = "googlechat"
+)
+
+// detectFormat infers the payload format from well-known webhook domains.
+func detectFormat(webhookURL string) string {
+	switch {
+	case strings.Contains(webhookURL, "hooks.slack.com"):
+		return FormatSlack
+	case strings.Contains(webhookURL, "discord.com/api/webhooks"):
+		return FormatDiscord
+	case strings.Contains(webhookURL, ".webhook.office.com"), strings.Contains(webhookURL, ".logic.azure.com"):
+		return FormatTeams
+	case strings.Contains(webhookURL, "chat.googleapis.com"):
+		return FormatGoogleChat
+	default:
+		return FormatGeneric
+	}
+}
+
+// buildWebhookPayload builds the message body for the given payload format.
+func buildWebhookPayload(format string, msg Message) interface{} {
+	ns := msg.TargetNamespace
+	pod := msg.TargetPod
+	container := msg.Container
+	timestamp := msg.Timestamp.Format(time.RFC3339)
+	title := "KubeDebugSess – " + eventTitles[msg.Event]
+	text := msg.Message
+	switch {
+	case msg.LogURL != "":
+		text = fmt.Sprintf("%s\nTranscript: %s", text, msg.LogURL)
+	case msg.LogKey != "":
+		text = fmt.Sprintf("%s\nTranscript: %s", text, msg.LogKey)
+	}
+
+	switch format {
+	case FormatSlack:
+		if msg.Event == EventApprovalRequired {
+			return slackApprovalPayload(title, msg)
+		}
+		return map[string]interface{}{
+			"text": fmt.Sprintf(
+				"*%s*\nNamespace: `%s`\nPod: `%s`\nContainer: `%s`\n\n```%s```",
+				title, ns, pod, container, text),
+		}
+
+	case FormatDiscord:
+		return map[string]interface{}{
+			"embeds": []map[string]interface{}{
+				{
+					"title":       "🐳 " + title,
+					"description": fmt.Sprintf("**Namespace:** `%s`\n**Pod:** `%s`\n**Container:** `%s`\n\n```\n%s\n```", ns, pod, container, text),
+					"color":       0x00bfff,
+					"timestamp":   timestamp,
+				},
+			},
+		}
+
+	case FormatTeams:
+		// Adaptive Card, accepted by both Teams incoming webhooks and Workflows.
+		return map[string]interface{}{
+			"type": "message",
+			"attachments": []map[string]interface{}{
+				{
+					"contentType": "application/vnd.microsoft.card.adaptive",
+					"content": map[string]interface{}{
+						"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+						"type":    "AdaptiveCard",
+						"version": "1.4",
+						"body": []map[string]interface{}{
+							{"type": "TextBlock", "text": title, "weight": "Bolder", "size": "Medium", "wrap": true},
+							{"type": "FactSet", "facts": []map[string]string{
+								{"title": "Namespace", "value": ns},
+								{"title": "Pod", "value": pod},
+								{"title": "Container", "value": container},
+								{"title": "Time", "value": timestamp},
+							}},
+							{"type": "TextBlock", "text": text, "fontType": "Monospace", "wrap": true},
+						},
+					},
+				},
+			},
+		}
+
+	case FormatGoogleChat:
+		return map[string]interface{}{
+			"cardsV2": []map[string]interface{}{
+				{
+					"cardId": "kubedebugsess-" + msg.UID,
+					"card": map[string]interface{}{
+						"header": map[string]interface{}{
+							"title":    title,
+							"subtitle": fmt.Sprintf("%s/%s", ns, pod),
+						},
+						"sections": []map[string]interface{}{
+							{
+								"widgets": []map[string]interface{}{
+									{"decoratedText": map[string]string{"topLabel": "Namespace", "text": ns}},
+									{"decoratedText": map[string]string{"topLabel": "Pod", "text": pod}},
+									{"decoratedText": map[string]string{"topLabel": "Container", "text": container}},
+									{"textParagraph": map[string]string{"text": "<pre>" + html.EscapeString(text) + "</pre>"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+	default:
+		return map[string]interface{}{
+			"event":     msg.Event,
+			"namespace": ns,
+			"pod":       pod,
+			"container": container,
+			"message":   msg.Message,
+			"logKey":    msg.LogKey,
+			"logURL":    msg.LogURL,
+			"timestamp": timestamp,
+		}
+	}
+}
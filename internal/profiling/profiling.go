@@ -0,0 +1,19 @@
+// Package profiling exposes the net/http/pprof endpoints of the controller and the debug proxy.
+package profiling
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// Handlers returns the pprof handlers by path, for mounting under /debug/pprof/ behind
+// authentication. Profiles hold memory contents, so they must never be served unauthenticated.
+func Handlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		"/debug/pprof/":        http.HandlerFunc(pprof.Index),
+		"/debug/pprof/cmdline": http.HandlerFunc(pprof.Cmdline),
+		"/debug/pprof/profile": http.HandlerFunc(pprof.Profile),
+		"/debug/pprof/symbol":  http.HandlerFunc(pprof.Symbol),
+		"/debug/pprof/trace":   http.HandlerFunc(pprof.Trace),
+	}
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// activityInterval bounds how often an attach connection writes status.lastActivityTime.
+const activityInterval = 30 * time.Second
+
+// activity tracks the terminal data passing through one attach connection. It counts the bytes in
+// each direction and stamps status.lastActivityTime, throttled to one status write per
+// activityInterval.
+type activity struct {
+	s   *Server
+	key types.NamespacedName
+	log logr.Logger
+
+	bytesIn, bytesOut   atomic.Int64
+	metricIn, metricOut prometheus.Counter
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func (s *Server) newActivity(ctx context.Context, session *debugv1alpha1.DebugSession) *activity {
+	holdSessionBytes(session.Namespace, session.Name)
+	labels := prometheus.Labels{"namespace": session.Namespace, "session": session.Name, "team": "", "cost_center": ""}
+	if attribution := session.Status.CostAttribution; attribution != nil {
+		labels["team"], labels["cost_center"] = attribution.Team, attribution.CostCenter
+	}
+	metric := func(direction string) prometheus.Counter {
+		labels["direction"] = direction
+		return sessionBytes.With(labels)
+	}
+	return &activity{
+		s:         s,
+		key:       types.NamespacedName{Namespace: session.Namespace, Name: session.Name},
+		log:       log.FromContext(ctx),
+		metricIn:  metric(directionIn),
+		metricOut: metric(directionOut),
+		// The attach itself has just stamped lastActivityTime.
+		last: time.Now(),
+	}
+}
+
+// received records n bytes of terminal input from the client.
+func (a *activity) received(n int) {
+	if a == nil {
+		return
+	}
+	a.bytesIn.Add(int64(n))
+	a.metricIn.Add(float64(n))
+	a.touch()
+}
+
+// sent records n bytes of terminal output to the client.
+func (a *activity) sent(n int) {
+	if a == nil {
+		return
+	}
+	a.bytesOut.Add(int64(n))
+	a.metricOut.Add(float64(n))
+	a.touch()
+}
+
+// close observes the connection's totals and returns them.
+func (a *activity) close() (in, out int64) {
+	if a == nil {
+		return 0, 0
+	}
+	in, out = a.bytesIn.Load(), a.bytesOut.Load()
+	connectionBytes.WithLabelValues(directionIn).Observe(float64(in))
+	connectionBytes.WithLabelValues(directionOut).Observe(float64(out))
+	releaseSessionBytes(a.key.Namespace, a.key.Name)
+	return in, out
+}
+
+func (a *activity) touch() {
+	a.mu.Lock()
+	now := time.Now()
+	if now.Sub(a.last) < activityInterval {
+		a.mu.Unlock()
+		return
+	}
+	a.last = now
+	a.mu.Unlock()
+
+	go func() {
+		err := a.s.updateSessionStatus(context.Background(), a.key, func(st *debugv1alpha1.DebugSessionStatus) {
+			st.LastActivityTime = &metav1.Time{Time: now}
+		})
+		if err != nil {
+			a.log.Error(err, "Failed to record activity")
+		}
+	}()
+}
+
+// activityWriter forwards terminal output and records it as activity.
+type activityWriter struct {
+	io.Writer
+	act *activity
+}
+
+func (w activityWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.act.sent(n)
+	return n, err
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// authorize authenticates the bearer token with a TokenReview and checks with a SubjectAccessReview
+// that its user may perform attrs. It returns the HTTP status to answer with when access is denied.
+func (s *Server) authorize(ctx context.Context, authHeader string, attrs *authorizationv1.ResourceAttributes) (int, error) {
+	user, status, err := s.authenticate(ctx, authHeader)
+	if err != nil {
+		return status, err
+	}
+	return s.checkAccess(ctx, user, attrs)
+}
+
+// authenticate resolves the user behind a bearer token with a TokenReview.
+func (s *Server) authenticate(ctx context.Context, authHeader string) (authenticationv1.UserInfo, int, error) {
+	tokenParts := strings.Split(authHeader, " ")
+	if len(tokenParts) != 2 || !strings.EqualFold(tokenParts[0], "bearer") {
+		return authenticationv1.UserInfo{}, http.StatusUnauthorized, fmt.Errorf("invalid Authorization header")
+	}
+
+	review, err := s.Clientset.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: tokenParts[1]},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "TokenReview failed")
+		return authenticationv1.UserInfo{}, http.StatusInternalServerError, fmt.Errorf("internal server error")
+	}
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, http.StatusUnauthorized, fmt.Errorf("unauthorized")
+	}
+	return review.Status.User, http.StatusOK, nil
+}
+
+// checkAccess asks the API server with a SubjectAccessReview whether user may perform attrs.
+func (s *Server) checkAccess(ctx context.Context, user authenticationv1.UserInfo, attrs *authorizationv1.ResourceAttributes) (int, error) {
+	if attrs.Namespace != "" && !s.inScope(ctx, attrs.Namespace) {
+		return http.StatusForbidden, fmt.Errorf("forbidden: namespace %s is not served by this proxy", attrs.Namespace)
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	sar, err := s.Clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               user.Username,
+			UID:                user.UID,
+			Groups:             user.Groups,
+			Extra:              extra,
+			ResourceAttributes: attrs,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "SubjectAccessReview failed")
+		return http.StatusInternalServerError, fmt.Errorf("internal server error")
+	}
+	if !sar.Status.Allowed {
+		return http.StatusForbidden, fmt.Errorf("forbidden: %s cannot %s %s", user.Username, attrs.Verb, describeResource(attrs))
+	}
+	return http.StatusOK, nil
+}
+
+func describeResource(attrs *authorizationv1.ResourceAttributes) string {
+	resource := attrs.Resource
+	if attrs.Subresource != "" {
+		resource += "/" + attrs.Subresource
+	}
+	if attrs.Name != "" {
+		resource += " " + attrs.Name
+	}
+	if attrs.Namespace != "" {
+		resource += " in namespace " + attrs.Namespace
+	}
+	return resource
+}
@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// lockedMessage is returned to clients of a session locked under spec.authFailurePolicy.
+const lockedMessage = "Debug session is locked after repeated invalid tokens"
+
+// lockedStatus is the HTTP status returned for locked sessions.
+const lockedStatus = http.StatusLocked
+
+// recordAuthFailure counts an attempt with an invalid token in the session's status, where the
+// controller compares it with spec.authFailurePolicy. Sessions without a policy are counted too, so
+// that token guessing shows up in the status and metrics either way.
+func (s *Server) recordAuthFailure(ctx context.Context, session *debugv1alpha1.DebugSession) {
+	authFailures.WithLabelValues(session.Namespace).Inc()
+	key := types.NamespacedName{Namespace: session.Namespace, Name: session.Name}
+	err := s.updateSessionStatus(ctx, key, func(st *debugv1alpha1.DebugSessionStatus) {
+		now := metav1.Now()
+		st.FailedAuthAttempts++
+		st.LastFailedAuthTime = &now
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to record authentication failure")
+	}
+}
@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// BackpressurePolicy is what the proxy does with pod output while a client reads it slower than the
+// debugger produces it and the output buffer is full.
+type BackpressurePolicy string
+
+const (
+	// BackpressureBlock stalls the pod's output until the client catches up.
+	BackpressureBlock BackpressurePolicy = "Block"
+	// BackpressureDropOldest discards the oldest buffered output and tells the client in-band.
+	BackpressureDropOldest BackpressurePolicy = "DropOldest"
+	// BackpressureDisconnect closes the client's connection; the debugger keeps running.
+	BackpressureDisconnect BackpressurePolicy = "Disconnect"
+)
+
+// ParseBackpressurePolicy validates a policy name.
+func ParseBackpressurePolicy(v string) (BackpressurePolicy, error) {
+	switch p := BackpressurePolicy(v); p {
+	case BackpressureBlock, BackpressureDropOldest, BackpressureDisconnect:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown backpressure policy %q: must be Block, DropOldest or Disconnect", v)
+}
+
+// errSlowClient ends the attach of a client that fell behind under BackpressureDisconnect.
+var errSlowClient = errors.New("client is reading output too slowly")
+
+// outputBuffer queues pod output for a client, up to limit bytes, and writes it from its own
+// goroutine so that a slow client does not block the attach stream unless the policy says so.
+type outputBuffer struct {
+	dst    io.Writer
+	policy BackpressurePolicy
+	limit  int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	chunks  [][]byte
+	size    int
+	dropped int
+	closed  bool
+	err     error
+	done    chan struct{}
+}
+
+func newOutputBuffer(dst io.Writer, policy BackpressurePolicy, limit int) *outputBuffer {
+	b := &outputBuffer{dst: dst, policy: policy, limit: limit, done: make(chan struct{})}
+	b.cond = sync.NewCond(&b.mu)
+	go b.run()
+	return b
+}
+
+// Write queues p. A chunk larger than the whole buffer is still accepted into an empty buffer.
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.err == nil && len(b.chunks) > 0 && b.size+len(p) > b.limit {
+		switch b.policy {
+		case BackpressureDropOldest:
+			b.size -= len(b.chunks[0])
+			b.dropped += len(b.chunks[0])
+			b.chunks = b.chunks[1:]
+		case BackpressureDisconnect:
+			b.err = errSlowClient
+			b.cond.Broadcast()
+		default:
+			b.cond.Wait()
+		}
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	b.chunks = append(b.chunks, append([]byte(nil), p...))
+	b.size += len(p)
+	b.cond.Broadcast()
+	return len(p), nil
+}
+
+func (b *outputBuffer) run() {
+	defer close(b.done)
+	for {
+		b.mu.Lock()
+		for len(b.chunks) == 0 && !b.closed && b.err == nil {
+			b.cond.Wait()
+		}
+		if b.err != nil || len(b.chunks) == 0 {
+			b.mu.Unlock()
+			return
+		}
+		chunks, dropped := b.chunks, b.dropped
+		b.chunks, b.size, b.dropped = nil, 0, 0
+		b.cond.Broadcast()
+		b.mu.Unlock()
+
+		if dropped > 0 {
+			droppedOutputBytes.Add(float64(dropped))
+			chunks = append([][]byte{[]byte(fmt.Sprintf(
+				"\r\n[kubedebugsess: %d bytes of output dropped, the connection is too slow]\r\n", dropped))}, chunks...)
+		}
+		for _, chunk := range chunks {
+			if _, err := b.dst.Write(chunk); err != nil {
+				b.mu.Lock()
+				b.err = err
+				b.cond.Broadcast()
+				b.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// close flushes what is buffered, waiting at most timeout, and stops the writer goroutine.
+func (b *outputBuffer) close(timeout time.Duration) {
+	b.mu.Lock()
+	b.closed = true
+	b.cond.Broadcast()
+	b.mu.Unlock()
+	select {
+	case <-b.done:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"golang.org/x/time/rate"
+)
+
+// Bandwidth caps the terminal and port-forward data rate of the proxy's connections, to contain an
+// accidental `cat` of a huge file as well as bulk data pulls through a debug shell. A nil Bandwidth
+// is unlimited.
+type Bandwidth struct {
+	// perConnection is the bytes per second of one connection, both directions together; 0 is unlimited.
+	perConnection int
+	// total is shared by all connections of the proxy; nil is unlimited.
+	total *rate.Limiter
+}
+
+// NewBandwidth returns the limits for perConnection and total bytes per second, where 0 is unlimited.
+// It returns nil when neither is limited.
+func NewBandwidth(perConnection, total int) *Bandwidth {
+	if perConnection <= 0 && total <= 0 {
+		return nil
+	}
+	b := &Bandwidth{perConnection: max(perConnection, 0)}
+	if total > 0 {
+		b.total = rate.NewLimiter(rate.Limit(total), total)
+	}
+	return b
+}
+
+// BandwidthFromEnv reads the limits, in bytes per second, from PROXY_BANDWIDTH_PER_CONNECTION and
+// PROXY_BANDWIDTH_TOTAL. Unset or 0 is unlimited.
+func BandwidthFromEnv() (*Bandwidth, error) {
+	limits := map[string]int{"PROXY_BANDWIDTH_PER_CONNECTION": 0, "PROXY_BANDWIDTH_TOTAL": 0}
+	for name := range limits {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a number of bytes per second, 0 for unlimited", name, v)
+		}
+		limits[name] = limit
+	}
+	return NewBandwidth(limits["PROXY_BANDWIDTH_PER_CONNECTION"], limits["PROXY_BANDWIDTH_TOTAL"]), nil
+}
+
+// throttle is the rate limit of one connection until ctx is done.
+type throttle struct {
+	ctx      context.Context
+	limiters []*rate.Limiter
+}
+
+// throttle returns the rate limit of a new connection, or nil when it is unlimited.
+func (b *Bandwidth) throttle(ctx context.Context) *throttle {
+	if b == nil {
+		return nil
+	}
+	t := &throttle{ctx: ctx}
+	if b.perConnection > 0 {
+		t.limiters = append(t.limiters, rate.NewLimiter(rate.Limit(b.perConnection), b.perConnection))
+	}
+	if b.total != nil {
+		t.limiters = append(t.limiters, b.total)
+	}
+	return t
+}
+
+// wait blocks until n more bytes may pass. It fails once the connection's context is done.
+func (t *throttle) wait(n int) error {
+	if t == nil {
+		return nil
+	}
+	for _, limiter := range t.limiters {
+		// WaitN rejects more than a burst at once.
+		for remaining := n; remaining > 0; {
+			chunk := min(remaining, limiter.Burst())
+			if err := limiter.WaitN(t.ctx, chunk); err != nil {
+				return err
+			}
+			remaining -= chunk
+		}
+	}
+	return nil
+}
+
+// throttledWriter delays writes to stay within a throttle.
+type throttledWriter struct {
+	io.Writer
+	t *throttle
+}
+
+func (w throttledWriter) Write(p []byte) (int, error) {
+	if err := w.t.wait(len(p)); err != nil {
+		return 0, err
+	}
+	return w.Writer.Write(p)
+}
+
+// throttledConn delays reads and writes of a port-forward connection to stay within a throttle.
+type throttledConn struct {
+	io.ReadWriter
+	t *throttle
+}
+
+func (c throttledConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriter.Read(p)
+	if n > 0 {
+		if werr := c.t.wait(n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+func (c throttledConn) Write(p []byte) (int, error) {
+	return throttledWriter{Writer: c.ReadWriter, t: c.t}.Write(p)
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ConnectionLimit caps the simultaneous attach and port-forward connections of the proxy and of each
+// DebugSession, so that the proxy turns excess clients away instead of degrading for everyone. A nil
+// ConnectionLimit is unlimited.
+type ConnectionLimit struct {
+	// total and perSession are the caps; 0 is unlimited.
+	total      int
+	perSession int
+
+	mu        sync.Mutex
+	open      int
+	bySession map[types.UID]int
+}
+
+// NewConnectionLimit returns the caps for total and perSession connections, where 0 is unlimited. It
+// returns nil when neither is capped.
+func NewConnectionLimit(total, perSession int) *ConnectionLimit {
+	if total <= 0 && perSession <= 0 {
+		return nil
+	}
+	return &ConnectionLimit{total: max(total, 0), perSession: max(perSession, 0), bySession: map[types.UID]int{}}
+}
+
+// ConnectionLimitFromEnv reads the caps from PROXY_MAX_CONNECTIONS and PROXY_MAX_CONNECTIONS_PER_SESSION.
+// Unset or 0 is unlimited.
+func ConnectionLimitFromEnv() (*ConnectionLimit, error) {
+	limits := map[string]int{"PROXY_MAX_CONNECTIONS": 0, "PROXY_MAX_CONNECTIONS_PER_SESSION": 0}
+	for name := range limits {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a number of connections, 0 for unlimited", name, v)
+		}
+		limits[name] = limit
+	}
+	return NewConnectionLimit(limits["PROXY_MAX_CONNECTIONS"], limits["PROXY_MAX_CONNECTIONS_PER_SESSION"]), nil
+}
+
+// acquire admits a connection to session, or returns why it is turned away. The returned func
+// releases the connection and must be called once it closes.
+func (l *ConnectionLimit) acquire(session types.UID) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.total > 0 && l.open >= l.total {
+		rejectedConnections.WithLabelValues(rejectedProxyFull).Inc()
+		return nil, fmt.Errorf("the debug proxy is at its limit of %d connections; try again later", l.total)
+	}
+	if l.perSession > 0 && l.bySession[session] >= l.perSession {
+		rejectedConnections.WithLabelValues(rejectedSessionFull).Inc()
+		return nil, fmt.Errorf("the debug session already has the maximum of %d connections", l.perSession)
+	}
+	l.open++
+	l.bySession[session]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.open--
+			if l.bySession[session]--; l.bySession[session] <= 0 {
+				delete(l.bySession, session)
+			}
+		})
+	}, nil
+}
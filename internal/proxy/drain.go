@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// drainCloseReason tells clients still attached when a drain times out to reconnect, which reaches a
+// replica that is not shutting down. Their one-time token stays valid for as long as the session does.
+const drainCloseReason = "debug proxy is restarting, reconnect"
+
+// drainPollInterval is how often Drain checks whether the last connection has ended.
+const drainPollInterval = time.Second
+
+// openWebSocket registers a client WebSocket so that Drain can wait for it.
+func (s *Server) openWebSocket(ws *websocket.Conn) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	if s.conns == nil {
+		s.conns = map[*websocket.Conn]struct{}{}
+	}
+	s.conns[ws] = struct{}{}
+}
+
+// closeWebSocket closes and unregisters a client WebSocket opened by upgrade.
+func (s *Server) closeWebSocket(ws *websocket.Conn) {
+	s.connsMu.Lock()
+	delete(s.conns, ws)
+	s.connsMu.Unlock()
+	_ = ws.Close()
+}
+
+// Draining reports whether Drain has been called.
+func (s *Server) Draining() bool {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	return s.draining
+}
+
+// ServeReady handles /readyz. It fails once the proxy is draining, so that the Service stops routing new
+// clients to it while its attached clients carry on.
+func (s *Server) ServeReady(w http.ResponseWriter, r *http.Request) {
+	if s.Draining() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("OK"))
+}
+
+// Drain prepares the proxy for shutdown without cutting off debugging sessions: it fails readiness and
+// waits for the open attach and port-forward WebSockets to end by themselves. WebSockets still open when
+// ctx is done are closed with a service-restart close frame asking the client to reconnect.
+//
+// The proxy does not hand its connections over to its replacement; with a rolling update that starts the
+// new replica first and a termination grace period longer than the drain, routine upgrades only end the
+// sessions still attached after the drain timeout, and those can reconnect.
+func (s *Server) Drain(ctx context.Context) {
+	logger := log.FromContext(ctx)
+	s.connsMu.Lock()
+	s.draining = true
+	open := len(s.conns)
+	s.connsMu.Unlock()
+	logger.Info("Draining attach connections", "open", open)
+
+	t := time.NewTicker(drainPollInterval)
+	defer t.Stop()
+	for {
+		s.connsMu.Lock()
+		open = len(s.conns)
+		s.connsMu.Unlock()
+		if open == 0 {
+			logger.Info("Drained all attach connections")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			s.closeAll()
+			logger.Info("Drain timed out, asked the remaining clients to reconnect", "open", open)
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// closeAll closes every open client WebSocket with a service-restart close frame.
+func (s *Server) closeAll() {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	message := websocket.FormatCloseMessage(websocket.CloseServiceRestart, drainCloseReason)
+	for ws := range s.conns {
+		// WriteControl may be called concurrently with the connection's other writes.
+		_ = ws.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+		_ = ws.Close()
+	}
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Handler returns the proxy's HTTP routes: /attach, /replay, /portforward, /readyz and the REST API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/attach", s)
+	mux.HandleFunc("/readyz", s.ServeReady)
+	mux.HandleFunc("/replay", s.ServeReplay)
+	mux.HandleFunc("/portforward", s.ServePortForward)
+	s.RegisterAPI(mux)
+	return withRequestLogger(mux)
+}
+
+// HTTPServer returns an http.Server serving Handler on addr. Clients that are slow to send their
+// request headers are cut off after the WebSocket handshake timeout.
+func (s *Server) HTTPServer(addr string) *http.Server {
+	return &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: s.WebSocket.HandshakeTimeout}
+}
+
+// Embedded runs the proxy inside another process, such as the controller manager in single-binary
+// mode. It implements the controller-runtime Runnable interface.
+type Embedded struct {
+	Server *Server
+	// Addr is the HTTP listen address.
+	Addr string
+	// GRPCAddr is the gRPC listen address; empty disables gRPC.
+	GRPCAddr string
+	// DrainTimeout is how long attached clients are given to finish on shutdown; 0 uses
+	// defaultEmbeddedDrainTimeout.
+	DrainTimeout time.Duration
+}
+
+// defaultEmbeddedDrainTimeout fits in the manager's default graceful shutdown timeout.
+const defaultEmbeddedDrainTimeout = 20 * time.Second
+
+// NeedLeaderElection returns false so that standby manager replicas keep serving attach connections.
+func (e *Embedded) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves until ctx is cancelled.
+func (e *Embedded) Start(ctx context.Context) error {
+	errc := make(chan error, 2)
+
+	httpServer := e.Server.HTTPServer(e.Addr)
+	go func() {
+		log.FromContext(ctx).Info("Starting embedded debug proxy server", "addr", e.Addr)
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			errc <- err
+		}
+	}()
+
+	var grpcServer *grpc.Server
+	if e.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", e.GRPCAddr)
+		if err != nil {
+			_ = httpServer.Close()
+			return err
+		}
+		grpcServer = NewGRPCServer(e.Server)
+		go func() {
+			log.FromContext(ctx).Info("Starting embedded debug proxy gRPC server", "addr", e.GRPCAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				errc <- err
+			}
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errc:
+	}
+	drainTimeout := e.DrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = defaultEmbeddedDrainTimeout
+	}
+	drainCtx, cancel := context.WithTimeout(log.IntoContext(context.Background(), log.FromContext(ctx)), drainTimeout)
+	defer cancel()
+	Shutdown(drainCtx, e.Server, httpServer, grpcServer)
+	return err
+}
+
+// Shutdown drains the proxy until ctx is done, then stops its servers. gRPC attaches, like WebSockets,
+// may finish during the drain; those left are cut off. grpcServer may be nil.
+func Shutdown(ctx context.Context, s *Server, httpServer *http.Server, grpcServer *grpc.Server) {
+	grpcStopped := make(chan struct{})
+	if grpcServer != nil {
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+	}
+	s.Drain(ctx)
+	if grpcServer != nil {
+		select {
+		case <-grpcStopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = httpServer.Shutdown(shutdownCtx)
+}
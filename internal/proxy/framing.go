@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket subprotocols a client may offer to choose how /attach and /portforward frame the stream.
+// Clients that offer neither get binary frames, as before negotiation existed.
+const (
+	// SubprotocolBinary carries the raw stream in binary messages both ways.
+	SubprotocolBinary = "binary.kubedebugsess.oxan0n.me"
+	// SubprotocolBase64 carries the stream base64-encoded in text messages both ways, for clients such as
+	// browsers that handle text messages more easily than binary ones.
+	SubprotocolBase64 = "base64.kubedebugsess.oxan0n.me"
+)
+
+// subprotocols lists the supported subprotocols in the proxy's order of preference.
+var subprotocols = []string{SubprotocolBinary, SubprotocolBase64}
+
+// framing is how the stream of one connection is carried in WebSocket messages.
+type framing int
+
+const (
+	framingBinary framing = iota
+	framingBase64
+)
+
+// framingOf returns the framing negotiated for ws.
+func framingOf(ws *websocket.Conn) framing {
+	if ws.Subprotocol() == SubprotocolBase64 {
+		return framingBase64
+	}
+	return framingBinary
+}
+
+// encode returns the message type and payload that carry p to the client.
+func (f framing) encode(p []byte) (int, []byte) {
+	if f == framingBase64 {
+		return websocket.TextMessage, []byte(base64.StdEncoding.EncodeToString(p))
+	}
+	return websocket.BinaryMessage, p
+}
+
+// decode returns the stream data carried by a client message. Binary messages are always raw, so that
+// a base64 client may still send them; text messages are raw unless the framing is base64.
+func (f framing) decode(messageType int, message []byte) ([]byte, error) {
+	if f != framingBase64 || messageType != websocket.TextMessage {
+		return message, nil
+	}
+	data := make([]byte, base64.StdEncoding.DecodedLen(len(message)))
+	n, err := base64.StdEncoding.Decode(data, message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 message: %w", err)
+	}
+	return data[:n], nil
+}
@@ -0,0 +1,410 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
+	webhookv1alpha1 "github.com/OxAN0N/KubeDebugSess/internal/webhook/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/pkg/sessionpb"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// grpcService serves sessionpb.DebugSessionService on top of the proxy Server.
+type grpcService struct {
+	sessionpb.UnimplementedDebugSessionServiceServer
+	*Server
+}
+
+// NewGRPCServer returns a gRPC server exposing the session API and terminal attach.
+func NewGRPCServer(s *Server, opts ...grpc.ServerOption) *grpc.Server {
+	srv := grpc.NewServer(opts...)
+	sessionpb.RegisterDebugSessionServiceServer(srv, &grpcService{Server: s})
+	return srv
+}
+
+// authorizeRPC authorizes the bearer token in the "authorization" metadata and returns its user.
+func (g *grpcService) authorizeRPC(ctx context.Context, verb, namespace, name string) (authenticationv1.UserInfo, error) {
+	var authHeader string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authHeader = values[0]
+		}
+	}
+	user, code, err := g.authenticate(ctx, authHeader)
+	if err == nil {
+		code, err = g.checkAccess(ctx, user, sessionAttributes(verb, namespace, name))
+	}
+	if err != nil {
+		return authenticationv1.UserInfo{}, status.Error(grpcCode(code), err.Error())
+	}
+	return user, nil
+}
+
+func (g *grpcService) CreateSession(ctx context.Context, req *sessionpb.CreateSessionRequest) (*sessionpb.Session, error) {
+	if req.GetNamespace() == "" {
+		return nil, status.Error(codes.InvalidArgument, "namespace is required")
+	}
+	user, err := g.authorizeRPC(ctx, "create", req.GetNamespace(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	session := &debugv1alpha1.DebugSession{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   req.GetNamespace(),
+			Name:        req.GetName(),
+			Annotations: map[string]string{debugv1alpha1.RequestedByAnnotation: user.Username},
+		},
+		Spec: specFromProto(req.GetSpec()),
+	}
+	session.Spec.RequestedBy = webhookv1alpha1.RequesterFromUserInfo(user)
+	if session.Name == "" {
+		session.GenerateName = "debug-"
+	}
+	if err := g.K8sClient.Create(ctx, session); err != nil {
+		return nil, grpcAPIError(err)
+	}
+	return sessionToProto(session), nil
+}
+
+func (g *grpcService) GetSession(ctx context.Context, req *sessionpb.GetSessionRequest) (*sessionpb.Session, error) {
+	if _, err := g.authorizeRPC(ctx, "get", req.GetNamespace(), req.GetName()); err != nil {
+		return nil, err
+	}
+	var session debugv1alpha1.DebugSession
+	if err := g.K8sClient.Get(ctx, types.NamespacedName{Namespace: req.GetNamespace(), Name: req.GetName()}, &session); err != nil {
+		return nil, grpcAPIError(err)
+	}
+	return sessionToProto(&session), nil
+}
+
+func (g *grpcService) ListSessions(ctx context.Context, req *sessionpb.ListSessionsRequest) (*sessionpb.ListSessionsResponse, error) {
+	if _, err := g.authorizeRPC(ctx, "list", req.GetNamespace(), ""); err != nil {
+		return nil, err
+	}
+	sessions, err := g.scopedSessions(ctx, req.GetNamespace())
+	if err != nil {
+		return nil, grpcAPIError(err)
+	}
+	resp := &sessionpb.ListSessionsResponse{Sessions: make([]*sessionpb.Session, 0, len(sessions))}
+	for i := range sessions {
+		resp.Sessions = append(resp.Sessions, sessionToProto(&sessions[i]))
+	}
+	return resp, nil
+}
+
+func (g *grpcService) DeleteSession(ctx context.Context, req *sessionpb.DeleteSessionRequest) (*sessionpb.DeleteSessionResponse, error) {
+	if _, err := g.authorizeRPC(ctx, "delete", req.GetNamespace(), req.GetName()); err != nil {
+		return nil, err
+	}
+	session := &debugv1alpha1.DebugSession{ObjectMeta: metav1.ObjectMeta{Namespace: req.GetNamespace(), Name: req.GetName()}}
+	if err := g.K8sClient.Delete(ctx, session); err != nil {
+		return nil, grpcAPIError(err)
+	}
+	return &sessionpb.DeleteSessionResponse{}, nil
+}
+
+func (g *grpcService) WatchSessions(req *sessionpb.WatchSessionsRequest, stream grpc.ServerStreamingServer[sessionpb.SessionEvent]) error {
+	ctx := stream.Context()
+	if _, err := g.authorizeRPC(ctx, "watch", req.GetNamespace(), ""); err != nil {
+		return err
+	}
+	if req.GetNamespace() == "" && g.Scope != nil && len(g.Scope.Names) > 0 {
+		return status.Error(codes.InvalidArgument, "namespace is required when the proxy serves a namespace allowlist")
+	}
+	watcher, ok := g.K8sClient.(client.WithWatch)
+	if !ok {
+		return status.Error(codes.Unimplemented, "the proxy client does not support watches")
+	}
+
+	w, err := watcher.Watch(ctx, &debugv1alpha1.DebugSessionList{}, client.InNamespace(req.GetNamespace()))
+	if err != nil {
+		return grpcAPIError(err)
+	}
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return status.Error(codes.Unavailable, "watch closed by the API server")
+			}
+			session, isSession := ev.Object.(*debugv1alpha1.DebugSession)
+			if !isSession || !g.inScope(ctx, session.Namespace) {
+				continue
+			}
+			var eventType sessionpb.SessionEvent_Type
+			switch ev.Type {
+			case watch.Added:
+				eventType = sessionpb.SessionEvent_ADDED
+			case watch.Modified:
+				eventType = sessionpb.SessionEvent_MODIFIED
+			case watch.Deleted:
+				eventType = sessionpb.SessionEvent_DELETED
+			default:
+				continue
+			}
+			if err := stream.Send(&sessionpb.SessionEvent{Type: eventType, Session: sessionToProto(session)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Attach bridges a gRPC stream to the debugger terminal, with the same token check, status updates
+// and recording as the WebSocket endpoint.
+func (g *grpcService) Attach(stream grpc.BidiStreamingServer[sessionpb.AttachRequest, sessionpb.AttachResponse]) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	start := first.GetStart()
+	if start == nil {
+		return status.Error(codes.InvalidArgument, "the first attach request must carry start")
+	}
+
+	if !g.inScope(stream.Context(), start.GetNamespace()) {
+		return status.Errorf(codes.PermissionDenied, "namespace %s is not served by this proxy", start.GetNamespace())
+	}
+	var session debugv1alpha1.DebugSession
+	if err := g.K8sClient.Get(stream.Context(), types.NamespacedName{Namespace: start.GetNamespace(), Name: start.GetName()}, &session); err != nil {
+		return grpcAPIError(err)
+	}
+
+	remoteAddr := "unknown"
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		remoteAddr = p.Addr.String()
+	}
+	var userAgent string
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+
+	ctx := withSessionLogger(connectionLogger(stream.Context(), remoteAddr), &session)
+	ctx, span := tracing.Tracer().Start(tracing.ContextWithSessionTrace(ctx, &session), "Attach",
+		trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(tracing.SessionAttributes(&session)...))
+	defer span.End()
+
+	if debugv1alpha1.IsLocked(&session) {
+		return status.Error(codes.PermissionDenied, lockedMessage)
+	}
+	if !session.Status.ReadyForAttach || session.Status.OneTimeToken != start.GetToken() {
+		g.Recorder.Eventf(&session, corev1.EventTypeWarning, eventReasonAuthFailed,
+			"Rejected attach attempt from %s: invalid or expired token", remoteAddr)
+		if session.Status.OneTimeToken != start.GetToken() {
+			g.recordAuthFailure(ctx, &session)
+		}
+		tracing.RecordError(span, fmt.Errorf("invalid or expired token"))
+		return status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	ns := session.Spec.TargetNamespace
+	if ns == "" {
+		ns = session.Namespace
+	}
+	containerName := session.Status.DebuggingContainerName
+	if containerName == "" {
+		containerName = debugv1alpha1.DebuggerContainerName(&session)
+	}
+	observer := session.Status.ReusedFrom != ""
+	if err := g.checkNodeLocal(ctx, ns, debugv1alpha1.CurrentTargetPod(&session)); err != nil {
+		tracing.RecordError(span, err)
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	release, err := g.Connections.acquire(session.UID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	defer release()
+
+	if err := g.rotateToken(ctx, &session, start.GetToken()); err != nil {
+		tracing.RecordError(span, err)
+		if errors.Is(err, errTokenUsed) {
+			// A client that lost the race for a valid token is not guessing, so it is not counted.
+			g.Recorder.Eventf(&session, corev1.EventTypeWarning, eventReasonAuthFailed,
+				"Rejected attach attempt from %s: %v", remoteAddr, err)
+			return status.Error(codes.Unauthenticated, "invalid or expired token")
+		}
+		log.FromContext(ctx).Error(err, "Failed to rotate the session token")
+		return status.Error(codes.Internal, "failed to rotate the session token")
+	}
+
+	rec, act, detach := g.openAttach(ctx, &session, ns, debugv1alpha1.CurrentTargetPod(&session), containerName, attachInfo{
+		RemoteAddr: remoteAddr,
+		UserAgent:  userAgent,
+		Protocol:   protocolGRPC,
+	})
+	defer detach()
+
+	width, height := uint32(initialTerminalWidth), uint32(initialTerminalHeight)
+	if size := start.GetSize(); size != nil {
+		width, height = size.GetWidth(), size.GetHeight()
+	}
+	resizeChan := make(chan remotecommand.TerminalSize, 1)
+	resizeChan <- remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}
+
+	throttle := g.Bandwidth.throttle(ctx)
+	stdinReader, stdinWriter := io.Pipe()
+	go func() {
+		defer stdinWriter.Close()
+		defer close(resizeChan)
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			switch {
+			case req.GetResize() != nil && !observer:
+				resizeChan <- remotecommand.TerminalSize{
+					Width:  uint16(req.GetResize().GetWidth()),
+					Height: uint16(req.GetResize().GetHeight()),
+				}
+			case req.GetStdin() != nil && !observer:
+				if err := throttle.wait(len(req.GetStdin())); err != nil {
+					return
+				}
+				rec.event(castInput, req.GetStdin())
+				act.received(len(req.GetStdin()))
+				if _, err := stdinWriter.Write(req.GetStdin()); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	var stdout io.Writer = activityWriter{Writer: &grpcStdout{stream: stream}, act: act}
+	if throttle != nil {
+		stdout = throttledWriter{Writer: stdout, t: throttle}
+	}
+	if rec != nil {
+		stdout = recordingWriter{Writer: stdout, rec: rec}
+	}
+	err = g.attach(ctx, ns, debugv1alpha1.CurrentTargetPod(&session), containerName, stdinReader, stdout,
+		&terminalSizeQueue{ch: resizeChan})
+	switch {
+	case g.followingWorkload(ctx, &session, ns, debugv1alpha1.CurrentTargetPod(&session)):
+		return status.Error(codes.Unavailable, podReplacedReason)
+	case err != nil:
+		tracing.RecordError(span, err)
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// grpcStdout sends terminal output as AttachResponses. Send must not be called concurrently.
+type grpcStdout struct {
+	mu     sync.Mutex
+	stream grpc.BidiStreamingServer[sessionpb.AttachRequest, sessionpb.AttachResponse]
+}
+
+func (w *grpcStdout) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// The stream may keep the message after Send returns, so p must not be reused.
+	if err := w.stream.Send(&sessionpb.AttachResponse{Stdout: append([]byte(nil), p...)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func specFromProto(spec *sessionpb.SessionSpec) debugv1alpha1.DebugSessionSpec {
+	return debugv1alpha1.DebugSessionSpec{
+		TargetPodName:       spec.GetTargetPodName(),
+		TargetContainerName: spec.GetTargetContainerName(),
+		TargetNamespace:     spec.GetTargetNamespace(),
+		DebuggerImage:       spec.GetDebuggerImage(),
+		TTL:                 spec.GetTtlSeconds(),
+		ArchiveFormat:       debugv1alpha1.ArchiveFormat(spec.GetArchiveFormat()),
+		ArchivePolicy:       debugv1alpha1.ArchivePolicy(spec.GetArchivePolicy()),
+	}
+}
+
+func sessionToProto(session *debugv1alpha1.DebugSession) *sessionpb.Session {
+	timestamp := func(t *metav1.Time) *timestamppb.Timestamp {
+		if t == nil {
+			return nil
+		}
+		return timestamppb.New(t.Time)
+	}
+	return &sessionpb.Session{
+		Namespace: session.Namespace,
+		Name:      session.Name,
+		Uid:       string(session.UID),
+		Spec: &sessionpb.SessionSpec{
+			TargetPodName:       session.Spec.TargetPodName,
+			TargetContainerName: session.Spec.TargetContainerName,
+			TargetNamespace:     session.Spec.TargetNamespace,
+			DebuggerImage:       session.Spec.DebuggerImage,
+			TtlSeconds:          session.Spec.TTL,
+			ArchiveFormat:       string(session.Spec.ArchiveFormat),
+			ArchivePolicy:       string(session.Spec.ArchivePolicy),
+		},
+		Status: &sessionpb.SessionStatus{
+			Phase:                  string(session.Status.Phase),
+			Message:                session.Status.Message,
+			StartTime:              timestamp(session.Status.StartTime),
+			ExpiryTime:             timestamp(session.Status.ExpiryTime),
+			ReadyForAttach:         session.Status.ReadyForAttach,
+			OneTimeToken:           session.Status.OneTimeToken,
+			DebuggingContainerName: session.Status.DebuggingContainerName,
+			LogKey:                 session.Status.LogKey,
+			LogUrl:                 session.Status.LogURL,
+		},
+	}
+}
+
+// grpcCode maps the HTTP status returned by authorize to a gRPC code.
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	default:
+		return codes.Internal
+	}
+}
+
+// grpcAPIError maps Kubernetes API errors to gRPC status errors.
+func grpcAPIError(err error) error {
+	switch {
+	case apierrors.IsNotFound(err):
+		return status.Error(codes.NotFound, err.Error())
+	case apierrors.IsAlreadyExists(err):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case apierrors.IsForbidden(err):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case apierrors.IsConflict(err):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Limits on the number of records returned by /api/v1/history.
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// ServeHistory handles GET /api/v1/history, searching the DebugSessionRecords of past sessions.
+// All filters are optional:
+//
+//	namespace  namespace of the DebugSession
+//	pod        target pod name
+//	user       requestedBy
+//	since      RFC 3339 timestamp; sessions that ended at or after it
+//	until      RFC 3339 timestamp; sessions that ended before it
+//	limit      maximum number of records, newest first (default 100)
+//
+// Callers authenticate with a Kubernetes bearer token and need `list` on debugsessionrecords.
+func (s *Server) ServeHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	since, until, err := parseTimeRange(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := defaultHistoryLimit
+	if v := q.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > maxHistoryLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxHistoryLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	if status, err := s.authorizeRecords(r); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	matches, err := s.findRecords(r.Context(), q, since, until)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "Failed to list debug session records")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(debugv1alpha1.DebugSessionRecordList{Items: matches})
+}
+
+// parseTimeRange reads the optional since and until RFC 3339 timestamps of q.
+func parseTimeRange(q url.Values) (since, until time.Time, err error) {
+	for name, t := range map[string]*time.Time{"since": &since, "until": &until} {
+		if v := q.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+			*t = parsed
+		}
+	}
+	return since, until, nil
+}
+
+// authorizeRecords checks that the caller may list debugsessionrecords.
+func (s *Server) authorizeRecords(r *http.Request) (int, error) {
+	return s.authorize(r.Context(), r.Header.Get("Authorization"), &authorizationv1.ResourceAttributes{
+		Verb:     "list",
+		Group:    debugv1alpha1.GroupVersion.Group,
+		Resource: "debugsessionrecords",
+	})
+}
+
+// findRecords returns the in-scope records matching the namespace, pod and user filters of q that
+// ended in [since, until), newest first. Zero times leave the range open.
+func (s *Server) findRecords(ctx context.Context, q url.Values, since, until time.Time) ([]debugv1alpha1.DebugSessionRecord, error) {
+	var opts []client.ListOption
+	if ns := q.Get("namespace"); ns != "" {
+		opts = append(opts, client.MatchingLabels{debugv1alpha1.SessionNamespaceLabel: ns})
+	}
+	records := &debugv1alpha1.DebugSessionRecordList{}
+	if err := s.K8sClient.List(ctx, records, opts...); err != nil {
+		return nil, err
+	}
+
+	pod, user := q.Get("pod"), q.Get("user")
+	matches := make([]debugv1alpha1.DebugSessionRecord, 0, len(records.Items))
+	for _, rec := range records.Items {
+		ended := recordTime(&rec)
+		switch {
+		case !s.inScope(ctx, rec.Labels[debugv1alpha1.SessionNamespaceLabel]),
+			pod != "" && rec.Spec.TargetPodName != pod,
+			user != "" && rec.Spec.RequestedBy != user,
+			!since.IsZero() && ended.Before(since),
+			!until.IsZero() && !ended.Before(until):
+			continue
+		}
+		rec.ManagedFields = nil
+		matches = append(matches, rec)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return recordTime(&matches[i]).After(recordTime(&matches[j]))
+	})
+	return matches, nil
+}
+
+// recordTime is when the recorded session ended, falling back to when the record was written.
+func recordTime(rec *debugv1alpha1.DebugSessionRecord) time.Time {
+	if rec.Spec.TerminationTime != nil {
+		return rec.Spec.TerminationTime.Time
+	}
+	return rec.CreationTimestamp.Time
+}
@@ -0,0 +1,29 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// connectionLogger returns the proxy's logger for one client connection.
+func connectionLogger(ctx context.Context, remoteAddr string) context.Context {
+	return log.IntoContext(ctx, log.Log.WithName("proxy").WithValues("remoteAddr", remoteAddr))
+}
+
+// withRequestLogger puts a connection logger into every request's context, so that handlers log with
+// the client's address; they add the session once they have resolved it.
+func withRequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(connectionLogger(r.Context(), r.RemoteAddr)))
+	})
+}
+
+// withSessionLogger adds the session to the logger in ctx.
+func withSessionLogger(ctx context.Context, session *debugv1alpha1.DebugSession) context.Context {
+	logger := log.FromContext(ctx).WithValues("session", client.ObjectKeyFromObject(session), "sessionUID", session.UID)
+	return log.IntoContext(ctx, logger)
+}
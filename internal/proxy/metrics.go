@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Directions of the bytes metrics, seen from the client: "in" is terminal input the client sent,
+// "out" is terminal output the proxy sent back.
+const (
+	directionIn  = "in"
+	directionOut = "out"
+)
+
+// sessionSeriesRetention is how long a session's bytes series outlives its last connection, so that
+// its final value is scraped before the series is dropped.
+const sessionSeriesRetention = 10 * time.Minute
+
+var (
+	sessionBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubedebugsess_proxy_session_bytes_total",
+		Help: "Terminal bytes transferred through the debug proxy per DebugSession and direction.",
+	}, []string{"namespace", "session", "team", "cost_center", "direction"})
+
+	connectionBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kubedebugsess_proxy_connection_bytes",
+		Help:    "Terminal bytes transferred per attach connection and direction.",
+		Buckets: prometheus.ExponentialBuckets(1024, 4, 10), // 1KiB .. 256MiB
+	}, []string{"direction"})
+
+	droppedOutputBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kubedebugsess_proxy_dropped_output_bytes_total",
+		Help: "Terminal output discarded for clients that read too slowly under the DropOldest backpressure policy.",
+	})
+
+	timeToAttach = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kubedebugsess_proxy_time_to_attach_seconds",
+		Help:    "Seconds from a DebugSession becoming ready for attach until its first client attached, per target namespace and debugger image.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 13), // 1s .. 68m
+	}, []string{"namespace", "image"})
+
+	rejectedConnections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubedebugsess_proxy_rejected_connections_total",
+		Help: "Attach and port-forward connections turned away by the connection limits, by the limit reached.",
+	}, []string{"limit"})
+
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubedebugsess_proxy_auth_failures_total",
+		Help: "Attach and port-forward attempts rejected for an invalid or expired session token, by session namespace.",
+	}, []string{"namespace"})
+)
+
+// Limits of the rejected connections metric.
+const (
+	rejectedProxyFull   = "proxy"
+	rejectedSessionFull = "session"
+)
+
+func init() {
+	// The controller-runtime registry is served by the manager in single-binary mode and by the
+	// proxy's own metrics endpoint otherwise.
+	ctrlmetrics.Registry.MustRegister(sessionBytes, connectionBytes, droppedOutputBytes, timeToAttach, rejectedConnections, authFailures)
+}
+
+// openConnections counts the attach connections of each session, so that its bytes series is only
+// dropped once none are left.
+var (
+	openConnectionsMu sync.Mutex
+	openConnections   = map[string]int{}
+)
+
+// holdSessionBytes keeps a session's bytes series while one of its connections is open.
+func holdSessionBytes(namespace, session string) {
+	openConnectionsMu.Lock()
+	defer openConnectionsMu.Unlock()
+	openConnections[namespace+"/"+session]++
+}
+
+// releaseSessionBytes drops a session's bytes series once its last connection has closed and the
+// final value has had time to be scraped.
+func releaseSessionBytes(namespace, session string) {
+	key := namespace + "/" + session
+	openConnectionsMu.Lock()
+	openConnections[key]--
+	openConnectionsMu.Unlock()
+
+	time.AfterFunc(sessionSeriesRetention, func() {
+		openConnectionsMu.Lock()
+		defer openConnectionsMu.Unlock()
+		if openConnections[key] > 0 {
+			return
+		}
+		delete(openConnections, key)
+		sessionBytes.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "session": session})
+	})
+}
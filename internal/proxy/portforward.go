@@ -0,0 +1,190 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const eventReasonPortForwarded = "PortForwarded"
+
+// ServePortForward handles /portforward: it tunnels a WebSocket client to the runtime debugger port of
+// a session with spec.runtimeAttach. Binary messages carry the raw TCP stream in both directions.
+func (s *Server) ServePortForward(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ns := q.Get("ns")
+	podName := q.Get("pod")
+	containerName := q.Get("container")
+	port, err := strconv.Atoi(q.Get("port"))
+	if ns == "" || podName == "" || containerName == "" || err != nil {
+		http.Error(w, "Missing required query parameters", http.StatusBadRequest)
+		return
+	}
+
+	token, ok := bearerToken(r)
+	if !ok {
+		http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
+		return
+	}
+	session, found, err := s.findSession(r.Context(), containerName, token)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "Failed to list debug sessions")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Debug session not found", http.StatusNotFound)
+		return
+	}
+
+	ctx, span := tracing.Tracer().Start(tracing.ContextWithSessionTrace(withSessionLogger(r.Context(), &session), &session), "PortForward",
+		trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(tracing.SessionAttributes(&session)...))
+	defer span.End()
+
+	if debugv1alpha1.IsLocked(&session) {
+		http.Error(w, lockedMessage, lockedStatus)
+		return
+	}
+	if !session.Status.ReadyForAttach || session.Status.OneTimeToken != token {
+		s.Recorder.Eventf(&session, corev1.EventTypeWarning, eventReasonAuthFailed,
+			"Rejected port-forward attempt from %s: invalid or expired token", r.RemoteAddr)
+		if session.Status.OneTimeToken != token {
+			s.recordAuthFailure(ctx, &session)
+		}
+		tracing.RecordError(span, fmt.Errorf("invalid or expired token"))
+		http.Error(w, "Unauthorized: Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+	// Only the runtime debugger's port is reachable, never the target's own ports.
+	if session.Spec.RuntimeAttach == nil || int32(port) != session.Spec.RuntimeAttach.Port {
+		http.Error(w, "Port is not the session's runtime debugger port", http.StatusForbidden)
+		return
+	}
+	if err := checkTarget(&session, ns, podName, containerName); err != nil {
+		s.Recorder.Eventf(&session, corev1.EventTypeWarning, eventReasonTargetMismatch,
+			"Rejected port-forward attempt from %s: %v", r.RemoteAddr, err)
+		tracing.RecordError(span, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err := s.checkNodeLocal(ctx, ns, podName); err != nil {
+		tracing.RecordError(span, err)
+		http.Error(w, err.Error(), http.StatusMisdirectedRequest)
+		return
+	}
+	release, err := s.Connections.acquire(session.UID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+
+	ws, err := s.upgrade(w, r)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to upgrade connection", "pod", podName)
+		return
+	}
+	defer s.closeWebSocket(ws)
+	if !s.rotateUpgradedToken(ctx, ws, &session, token, r.RemoteAddr, "port-forward") {
+		return
+	}
+
+	s.Recorder.Eventf(&session, corev1.EventTypeNormal, eventReasonPortForwarded,
+		"Client %s forwarded port %d of %s/%s", r.RemoteAddr, port, ns, podName)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.WebSocket.startKeepAlive(ctx, ws, cancel)
+	var conn io.ReadWriter = &wsconn{conn: ws, opts: s.WebSocket}
+	if throttle := s.Bandwidth.throttle(ctx); throttle != nil {
+		conn = throttledConn{ReadWriter: conn, t: throttle}
+	}
+	if err := s.portForward(ctx, ns, podName, port, conn); err != nil {
+		tracing.RecordError(span, err)
+		log.FromContext(ctx).Error(err, "Port-forward failed", "namespace", ns, "pod", podName)
+		_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()))
+	}
+}
+
+// portForward copies between conn and port of the pod through the pod portforward subresource until
+// either side closes.
+func (s *Server) portForward(ctx context.Context, ns, podName string, port int, conn io.ReadWriter) error {
+	transport, upgrader, err := spdy.RoundTripperFor(s.RESTCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create SPDY round tripper: %w", err)
+	}
+	req := s.Clientset.CoreV1().RESTClient().
+		Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(ns).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+	streamConn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return fmt.Errorf("failed to dial port-forward: %w", err)
+	}
+	defer streamConn.Close()
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, "0")
+	errorStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("failed to create error stream: %w", err)
+	}
+	// The error stream is only read from.
+	_ = errorStream.Close()
+	errc := make(chan error, 3)
+	go func() {
+		message, err := io.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errc <- fmt.Errorf("failed to read error stream: %w", err)
+		case len(message) > 0:
+			errc <- fmt.Errorf("port-forward failed: %s", message)
+		}
+	}()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("failed to create data stream: %w", err)
+	}
+	defer streamConn.RemoveStreams(dataStream, errorStream)
+
+	go copyStream(errc, dataStream, conn)
+	go copyStream(errc, conn, dataStream)
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-streamConn.CloseChan():
+		return nil
+	case err := <-errc:
+		return err
+	}
+}
+
+// copyStream copies src to dst and reports the outcome; a clean end of stream is reported as nil.
+func copyStream(errc chan<- error, dst io.Writer, src io.Reader) {
+	_, err := io.Copy(dst, src)
+	if s, ok := dst.(httpstream.Stream); ok {
+		_ = s.Close()
+	}
+	errc <- err
+}
@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/storage"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Asciicast v2 event codes. Terminal output and client input are kept apart so a reviewer can tell
+// what was typed from what was printed.
+const (
+	castOutput = "o"
+	castInput  = "i"
+)
+
+// recording tees an attach stream into log storage as an asciicast v2 file while the session runs,
+// so the transcript does not depend on the kubelet still holding the container logs afterwards.
+type recording struct {
+	Key string
+	log logr.Logger
+
+	mu      sync.Mutex
+	pw      *io.PipeWriter
+	start   time.Time
+	stopped bool
+	done    chan error
+}
+
+// startRecording begins uploading a recording for one attach connection. It returns nil when log
+// storage is not configured.
+func (s *Server) startRecording(ctx context.Context, session *debugv1alpha1.DebugSession, containerName string, width, height uint16) *recording {
+	if s.Storage == nil {
+		return nil
+	}
+
+	start := time.Now()
+	info := storage.ObjectInfo{
+		Namespace:        session.Spec.TargetNamespace,
+		SessionNamespace: session.Namespace,
+		Session:          session.Name,
+		UID:              string(session.UID),
+		User:             sessionRequester(session),
+		Pod:              debugv1alpha1.CurrentTargetPod(session),
+		Container:        containerName,
+		Time:             start,
+		Ticket:           session.Spec.TicketRef,
+		Timestamp:        start.UnixNano(),
+	}
+	if attribution := session.Status.CostAttribution; attribution != nil {
+		info.Team, info.CostCenter = attribution.Team, attribution.CostCenter
+	}
+	key, err := s.KeyLayout.Key(info)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Recording disabled")
+		return nil
+	}
+	rec := &recording{
+		Key:   key + ".cast",
+		log:   log.FromContext(ctx),
+		start: start,
+		done:  make(chan error, 1),
+	}
+	pr, pw := io.Pipe()
+	rec.pw = pw
+
+	// Keep uploading after the client goes away; the recording is finished by close.
+	uploadCtx := context.WithoutCancel(ctx)
+	go func() {
+		err := s.Storage.Put(uploadCtx, rec.Key, pr, info.Tags())
+		_ = pr.CloseWithError(err)
+		rec.done <- err
+	}()
+
+	title := fmt.Sprintf("%s/%s", session.Namespace, session.Name)
+	if session.Spec.TicketRef != "" {
+		title += " for " + session.Spec.TicketRef
+	}
+	header, _ := json.Marshal(map[string]interface{}{
+		"version":   2,
+		"width":     width,
+		"height":    height,
+		"timestamp": start.Unix(),
+		"title":     title,
+	})
+	rec.writeLine(header)
+	return rec
+}
+
+// event appends one asciicast event. Upload errors disable the recording without affecting the
+// attach stream.
+func (r *recording) event(code string, data []byte) {
+	if r == nil {
+		return
+	}
+	line, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), code, string(data)})
+	if err != nil {
+		return
+	}
+	r.writeLine(line)
+}
+
+func (r *recording) writeLine(line []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	if _, err := r.pw.Write(append(line, '\n')); err != nil {
+		r.log.Error(err, "Recording stopped", "key", r.Key)
+		r.stopped = true
+	}
+}
+
+// close finishes the upload and waits for storage to acknowledge it.
+func (r *recording) close() error {
+	r.mu.Lock()
+	r.stopped = true
+	_ = r.pw.Close()
+	r.mu.Unlock()
+	return <-r.done
+}
+
+// recordingWriter forwards terminal output to the client and the recording.
+type recordingWriter struct {
+	io.Writer
+	rec *recording
+}
+
+func (w recordingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.rec.event(castOutput, p[:n])
+	return n, err
+}
+
+// recordRecording appends the finished recording's key to the session status.
+func (s *Server) recordRecording(ctx context.Context, session *debugv1alpha1.DebugSession, key string) error {
+	return s.updateSessionStatus(ctx, types.NamespacedName{Namespace: session.Namespace, Name: session.Name},
+		func(st *debugv1alpha1.DebugSessionStatus) {
+			st.Recordings = append(st.Recordings, key)
+		})
+}
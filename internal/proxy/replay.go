@@ -0,0 +1,190 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Replay modes accepted by /replay.
+const (
+	// replayTimed streams terminal output with the original timing, scaled by speed.
+	replayTimed = "timed"
+	// replayText streams all terminal output at once.
+	replayText = "text"
+	// replayCast returns the raw asciicast v2 recording.
+	replayCast = "cast"
+)
+
+// maxReplayIdle caps pauses during timed replay, so an idle terminal does not stall the reviewer.
+const maxReplayIdle = 2 * time.Second
+
+// ServeReplay handles /replay?ns=<namespace>&session=<name>, streaming a stored recording back to
+// the caller. Query parameters:
+//
+//	recording  index into status.recordings (default: the latest)
+//	mode       timed (default), text or cast
+//	speed      playback speed multiplier for timed mode (default 1)
+//
+// Callers authenticate with a Kubernetes bearer token and need `get` on the debugsessions/replay
+// subresource of the session.
+func (s *Server) ServeReplay(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ns := q.Get("ns")
+	name := q.Get("session")
+	if ns == "" || name == "" {
+		http.Error(w, "Missing required query parameters", http.StatusBadRequest)
+		return
+	}
+
+	mode := q.Get("mode")
+	if mode == "" {
+		mode = replayTimed
+	}
+	if mode != replayTimed && mode != replayText && mode != replayCast {
+		http.Error(w, fmt.Sprintf("Unsupported mode %q", mode), http.StatusBadRequest)
+		return
+	}
+	speed := 1.0
+	if v := q.Get("speed"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "speed must be a positive number", http.StatusBadRequest)
+			return
+		}
+		speed = parsed
+	}
+
+	if s.Storage == nil {
+		http.Error(w, "Log storage is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	if status, err := s.authorize(r.Context(), r.Header.Get("Authorization"), &authorizationv1.ResourceAttributes{
+		Namespace:   ns,
+		Verb:        "get",
+		Group:       debugv1alpha1.GroupVersion.Group,
+		Resource:    "debugsessions",
+		Subresource: "replay",
+		Name:        name,
+	}); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	var session debugv1alpha1.DebugSession
+	if err := s.K8sClient.Get(r.Context(), types.NamespacedName{Namespace: ns, Name: name}, &session); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, "Debug session not found", http.StatusNotFound)
+			return
+		}
+		log.FromContext(r.Context()).Error(err, "Failed to get debug session", "session", types.NamespacedName{Namespace: ns, Name: name})
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	key, isCast, err := replayKey(&session, q.Get("recording"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	body, err := s.Storage.Get(r.Context(), key)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "Failed to open recording", "key", key)
+		http.Error(w, "Recording could not be read", http.StatusBadGateway)
+		return
+	}
+	defer body.Close()
+
+	if !isCast || mode == replayCast {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = io.Copy(w, body)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if mode == replayText {
+		speed = 0
+	}
+	if err := replayCastStream(r.Context(), w, body, speed); err != nil {
+		log.FromContext(r.Context()).Info("Replay stopped", "key", key, "reason", err.Error())
+	}
+}
+
+// replayKey picks the recording to replay. Sessions recorded before live capture was enabled fall
+// back to the archived transcript, which carries no timing.
+func replayKey(session *debugv1alpha1.DebugSession, index string) (string, bool, error) {
+	recordings := session.Status.Recordings
+	if len(recordings) == 0 {
+		if session.Status.LogKey == "" {
+			return "", false, fmt.Errorf("session has no stored transcript")
+		}
+		return session.Status.LogKey, false, nil
+	}
+	if index == "" {
+		return recordings[len(recordings)-1], true, nil
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(recordings) {
+		return "", false, fmt.Errorf("recording must be between 0 and %d", len(recordings)-1)
+	}
+	return recordings[i], true, nil
+}
+
+// replayCastStream writes the output events of an asciicast v2 stream to w. A speed of zero writes
+// everything without delay.
+func replayCastStream(ctx context.Context, w io.Writer, cast io.Reader, speed float64) error {
+	flusher, _ := w.(http.Flusher)
+	scanner := bufio.NewScanner(cast)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	// The first line is the recording header.
+	if !scanner.Scan() {
+		return scanner.Err()
+	}
+
+	var last float64
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || len(event) != 3 {
+			continue
+		}
+		at, _ := event[0].(float64)
+		code, _ := event[1].(string)
+		data, _ := event[2].(string)
+		if code != castOutput {
+			continue
+		}
+
+		if speed > 0 {
+			delay := min(time.Duration((at-last)/speed*float64(time.Second)), maxReplayIdle)
+			last = at
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		if _, err := io.WriteString(w, data); err != nil {
+			return err
+		}
+		if flusher != nil && speed > 0 {
+			flusher.Flush()
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/storage"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ReportRow is one session of a compliance report.
+type ReportRow struct {
+	Namespace       string   `json:"namespace"`
+	Session         string   `json:"session"`
+	UID             string   `json:"uid"`
+	RequestedBy     string   `json:"requestedBy"`
+	ApprovedBy      string   `json:"approvedBy,omitempty"`
+	DeniedBy        string   `json:"deniedBy,omitempty"`
+	TicketRef       string   `json:"ticketRef,omitempty"`
+	TargetNamespace string   `json:"targetNamespace"`
+	TargetPod       string   `json:"targetPod"`
+	TargetContainer string   `json:"targetContainer,omitempty"`
+	DebuggerImage   string   `json:"debuggerImage"`
+	Outcome         string   `json:"outcome"`
+	Message         string   `json:"message,omitempty"`
+	StartTime       string   `json:"startTime,omitempty"`
+	EndTime         string   `json:"endTime"`
+	DurationSeconds int64    `json:"durationSeconds"`
+	Team            string   `json:"team,omitempty"`
+	CostCenter      string   `json:"costCenter,omitempty"`
+	AttachedClients []string `json:"attachedClients,omitempty"`
+	// Transcript and Recordings are presigned links when the storage backend issues them, and
+	// storage keys otherwise.
+	Transcript string   `json:"transcript,omitempty"`
+	Recordings []string `json:"recordings,omitempty"`
+}
+
+// reportColumns is the CSV header; list values are joined with reportListSeparator.
+var reportColumns = []string{
+	"namespace", "session", "uid", "requested_by", "approved_by", "denied_by", "ticket_ref",
+	"target_namespace", "target_pod", "target_container", "debugger_image", "outcome", "message",
+	"start_time", "end_time", "duration_seconds", "team", "cost_center", "attached_clients",
+	"transcript", "recordings",
+}
+
+const reportListSeparator = " "
+
+// ServeReport handles GET /api/v1/report, the compliance report of the sessions that ended in a time
+// range, assembled from their DebugSessionRecords for periodic access reviews:
+//
+//	since      RFC 3339 timestamp; sessions that ended at or after it (required)
+//	until      RFC 3339 timestamp; sessions that ended before it (default now)
+//	format     csv or json (default json)
+//	namespace, pod and user filter like /api/v1/history
+//
+// Sessions are listed newest first, without a limit. Callers need `list` on debugsessionrecords.
+func (s *Server) ServeReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	since, until, err := parseTimeRange(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if since.IsZero() {
+		http.Error(w, "since is required", http.StatusBadRequest)
+		return
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	if status, err := s.authorizeRecords(r); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	records, err := s.findRecords(r.Context(), q, since, until)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "Failed to list debug session records")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	rows := make([]ReportRow, 0, len(records))
+	for i := range records {
+		rows = append(rows, s.reportRow(r.Context(), &records[i]))
+	}
+
+	filename := "kubedebugsess-report-" + since.UTC().Format("20060102") + "-" + until.UTC().Format("20060102") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(rows)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+	_ = cw.Write(reportColumns)
+	for _, row := range rows {
+		_ = cw.Write([]string{
+			row.Namespace, row.Session, row.UID, row.RequestedBy, row.ApprovedBy, row.DeniedBy, row.TicketRef,
+			row.TargetNamespace, row.TargetPod, row.TargetContainer, row.DebuggerImage, row.Outcome, row.Message,
+			row.StartTime, row.EndTime, strconv.FormatInt(row.DurationSeconds, 10), row.Team, row.CostCenter,
+			strings.Join(row.AttachedClients, reportListSeparator),
+			row.Transcript, strings.Join(row.Recordings, reportListSeparator),
+		})
+	}
+	cw.Flush()
+}
+
+// reportRow flattens a record into a report row, linking its transcript and recordings.
+func (s *Server) reportRow(ctx context.Context, rec *debugv1alpha1.DebugSessionRecord) ReportRow {
+	spec := &rec.Spec
+	row := ReportRow{
+		Namespace:       spec.Session.Namespace,
+		Session:         spec.Session.Name,
+		UID:             spec.Session.UID,
+		RequestedBy:     spec.RequestedBy,
+		ApprovedBy:      spec.ApprovedBy,
+		DeniedBy:        spec.DeniedBy,
+		TicketRef:       spec.TicketRef,
+		TargetNamespace: spec.TargetNamespace,
+		TargetPod:       spec.TargetPodName,
+		TargetContainer: spec.TargetContainerName,
+		DebuggerImage:   spec.DebuggerImage,
+		Outcome:         string(spec.Outcome),
+		Message:         spec.Message,
+		EndTime:         recordTime(rec).UTC().Format(time.RFC3339),
+		DurationSeconds: spec.DurationSeconds,
+		AttachedClients: spec.AttachedClients,
+		Transcript:      s.reportLink(ctx, spec.TranscriptKey),
+	}
+	if spec.StartTime != nil {
+		row.StartTime = spec.StartTime.UTC().Format(time.RFC3339)
+	}
+	if a := spec.CostAttribution; a != nil {
+		row.Team, row.CostCenter = a.Team, a.CostCenter
+	}
+	for _, key := range spec.Recordings {
+		row.Recordings = append(row.Recordings, s.reportLink(ctx, key))
+	}
+	return row
+}
+
+// reportLink presigns key for LinkExpiry, falling back to the key itself.
+func (s *Server) reportLink(ctx context.Context, key string) string {
+	if key == "" || s.Storage == nil {
+		return key
+	}
+	expiry := s.LinkExpiry
+	if expiry <= 0 {
+		expiry = storage.DefaultPresignExpiry
+	}
+	link, err := s.Storage.Presign(ctx, key, expiry)
+	if err != nil {
+		return key
+	}
+	return link
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"context"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// inScope reports whether the proxy serves DebugSessions in namespace ns.
+func (s *Server) inScope(ctx context.Context, ns string) bool {
+	allowed, err := s.Scope.Allows(ctx, s.K8sClient, ns)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to check whether namespace is in scope", "namespace", ns)
+		return false
+	}
+	return allowed
+}
+
+// scopedSessions lists the DebugSessions in ns, or in every served namespace when ns is empty,
+// narrowed by opts.
+func (s *Server) scopedSessions(ctx context.Context, ns string, opts ...client.ListOption) ([]debugv1alpha1.DebugSession, error) {
+	namespaces := []string{ns}
+	if ns == "" {
+		namespaces = s.Scope.ListNamespaces()
+	}
+
+	var sessions []debugv1alpha1.DebugSession
+	allowed := map[string]bool{}
+	for _, listNamespace := range namespaces {
+		list := &debugv1alpha1.DebugSessionList{}
+		if err := s.K8sClient.List(ctx, list, append(opts, client.InNamespace(listNamespace))...); err != nil {
+			return nil, err
+		}
+		for _, session := range list.Items {
+			in, seen := allowed[session.Namespace]
+			if !seen {
+				in = s.inScope(ctx, session.Namespace)
+				allowed[session.Namespace] = in
+			}
+			if in {
+				sessions = append(sessions, session)
+			}
+		}
+	}
+	return sessions, nil
+}
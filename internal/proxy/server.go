@@ -4,23 +4,37 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"net"
 	"net/http"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/audit"
+	"github.com/OxAN0N/KubeDebugSess/internal/scope"
+	"github.com/OxAN0N/KubeDebugSess/internal/storage"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/tools/remotecommand"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 // wsconn implements io.ReadWriter for websocket
 type wsconn struct {
 	conn       *websocket.Conn
+	opts       WebSocketOptions
 	readBuffer []byte
 }
 
@@ -30,7 +44,7 @@ func (w *wsconn) Read(p []byte) (n int, err error) {
 		w.readBuffer = w.readBuffer[n:]
 		return n, nil
 	}
-	_, message, err := w.conn.ReadMessage()
+	message, err := w.opts.readMessage(w.conn)
 	if err != nil {
 		return 0, io.EOF
 	}
@@ -42,7 +56,11 @@ func (w *wsconn) Read(p []byte) (n int, err error) {
 }
 
 func (w *wsconn) Write(p []byte) (n int, err error) {
-	err = w.conn.WriteMessage(websocket.BinaryMessage, p)
+	// A client that stops reading must not block the pod's output forever.
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.opts.WriteTimeout)); err != nil {
+		return 0, err
+	}
+	err = w.conn.WriteMessage(framingOf(w.conn).encode(p))
 	if err != nil {
 		return 0, err
 	}
@@ -62,28 +80,87 @@ func (q *terminalSizeQueue) Next() *remotecommand.TerminalSize {
 	return &size
 }
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin:       func(r *http.Request) bool { return true },
-	EnableCompression: false,
-}
+// Event reasons emitted by the proxy on DebugSessions.
+const (
+	eventReasonAttached   = "ClientAttached"
+	eventReasonDetached   = "ClientDetached"
+	eventReasonAuthFailed = "AuthenticationFailed"
+	// eventReasonTargetMismatch is recorded when a valid token is presented for a pod or container
+	// other than the session's own.
+	eventReasonTargetMismatch = "TargetMismatch"
+)
 
 // Server provides WebSocket <-> SPDY attach streaming
 type Server struct {
 	Clientset *kubernetes.Clientset
 	RESTCfg   *rest.Config
 	K8sClient client.Client
+	Recorder  record.EventRecorder
+	// Storage, when set, receives a live recording of every attach connection.
+	Storage storage.Storage
+	// LinkExpiry is the lifetime of the presigned links in compliance reports; zero uses
+	// storage.DefaultPresignExpiry.
+	LinkExpiry time.Duration
+	// KeyLayout renders the keys of recordings; nil uses storage.DefaultKeyTemplate.
+	KeyLayout *storage.KeyLayout
+	// Scope limits the namespaces whose DebugSessions are served; nil serves all of them.
+	Scope *scope.Namespaces
+	// NodeName, when set, makes the proxy node-local: it only attaches to pods on this node.
+	NodeName string
+	// WebSocket tunes the client WebSockets.
+	WebSocket WebSocketOptions
+	// Bandwidth caps the data rate of attach and port-forward connections; nil is unlimited.
+	Bandwidth *Bandwidth
+	// Connections caps the simultaneous attach and port-forward connections; nil is unlimited.
+	Connections *ConnectionLimit
+	// Auditor, when set, exports an audit record for every attach and detach.
+	Auditor audit.Exporter
+	// Name identifies this proxy replica in status.attachments; ConfigureFromEnv sets it to the host
+	// name, which is the pod name.
+	Name string
+
+	// connsMu guards the open client WebSockets and whether the proxy is draining.
+	connsMu  sync.Mutex
+	conns    map[*websocket.Conn]struct{}
+	draining bool
 }
 
 // NewServer constructs a Server
-func NewServer(clientset *kubernetes.Clientset, restCfg *rest.Config, k8sClient client.Client) *Server {
-	log.Println("[KubeDebugSess Proxy] Server started (v1)") // ✅ Version banner
+func NewServer(clientset *kubernetes.Clientset, restCfg *rest.Config, k8sClient client.Client, recorder record.EventRecorder,
+	logStorage storage.Storage) *Server {
 	return &Server{
 		Clientset: clientset,
 		RESTCfg:   restCfg,
 		K8sClient: k8sClient,
+		Recorder:  recorder,
+		Storage:   logStorage,
+		WebSocket: DefaultWebSocketOptions(),
 	}
 }
 
+// ConfigureFromEnv applies the settings both the standalone and the embedded proxy read from the
+// environment: the replica name, the lifetime of storage links, the WebSocket options, and the
+// bandwidth and connection limits.
+func (s *Server) ConfigureFromEnv() error {
+	var err error
+	if s.Name, err = os.Hostname(); err != nil {
+		return fmt.Errorf("failed to determine the proxy name: %w", err)
+	}
+	if s.LinkExpiry, err = storage.PresignExpiryFromEnv(); err != nil {
+		return fmt.Errorf("failed to set up log storage: %w", err)
+	}
+	if s.WebSocket, err = WebSocketOptionsFromEnv(); err != nil {
+		return fmt.Errorf("failed to set up WebSocket options: %w", err)
+	}
+	if s.Bandwidth, err = BandwidthFromEnv(); err != nil {
+		return fmt.Errorf("failed to set up bandwidth limits: %w", err)
+	}
+	if s.Connections, err = ConnectionLimitFromEnv(); err != nil {
+		return fmt.Errorf("failed to set up connection limits: %w", err)
+	}
+	return nil
+}
+
 // ServeHTTP handles /attach (and responds OK for others)
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// ✅ Allow health probes or port-forward checks
@@ -104,115 +181,339 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authHeader := r.Header.Get("Authorization")
-	tokenParts := strings.Split(authHeader, " ")
-	if len(tokenParts) != 2 || !strings.EqualFold(tokenParts[0], "bearer") {
+	receivedToken, ok := bearerToken(r)
+	if !ok {
 		http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
 		return
 	}
-	receivedToken := tokenParts[1]
-	sessionUID := strings.TrimPrefix(containerName, "debugger-")
 
-	var debugSession debugv1alpha1.DebugSession
-	sessionList := &debugv1alpha1.DebugSessionList{}
-	if err := s.K8sClient.List(r.Context(), sessionList); err != nil {
-		log.Printf("Error listing debug sessions: %v", err)
+	debugSession, found, err := s.findSession(r.Context(), containerName, receivedToken)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "Failed to list debug sessions")
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	found := false
-	for _, sess := range sessionList.Items {
-		if string(sess.UID) == sessionUID {
-			debugSession = sess
-			found = true
-			break
-		}
-	}
 	if !found {
 		http.Error(w, "Debug session not found", http.StatusNotFound)
 		return
 	}
+
+	ctx, span := tracing.Tracer().Start(tracing.ContextWithSessionTrace(withSessionLogger(r.Context(), &debugSession), &debugSession), "Attach",
+		trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(tracing.SessionAttributes(&debugSession)...))
+	defer span.End()
+
+	if debugv1alpha1.IsLocked(&debugSession) {
+		http.Error(w, lockedMessage, lockedStatus)
+		return
+	}
 	if !debugSession.Status.ReadyForAttach || debugSession.Status.OneTimeToken != receivedToken {
+		s.Recorder.Eventf(&debugSession, corev1.EventTypeWarning, eventReasonAuthFailed,
+			"Rejected attach attempt from %s: invalid or expired token", r.RemoteAddr)
+		if debugSession.Status.OneTimeToken != receivedToken {
+			s.recordAuthFailure(ctx, &debugSession)
+		}
+		tracing.RecordError(span, fmt.Errorf("invalid or expired token"))
 		http.Error(w, "Unauthorized: Invalid or expired token", http.StatusUnauthorized)
 		return
 	}
 
-	ws, err := upgrader.Upgrade(w, r, nil)
+	if err := checkTarget(&debugSession, ns, podName, containerName); err != nil {
+		s.Recorder.Eventf(&debugSession, corev1.EventTypeWarning, eventReasonTargetMismatch,
+			"Rejected attach attempt from %s: %v", r.RemoteAddr, err)
+		tracing.RecordError(span, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err := s.checkNodeLocal(ctx, ns, podName); err != nil {
+		tracing.RecordError(span, err)
+		http.Error(w, err.Error(), http.StatusMisdirectedRequest)
+		return
+	}
+	release, err := s.Connections.acquire(debugSession.UID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+
+	ws, err := s.upgrade(w, r)
 	if err != nil {
-		log.Printf("Failed to upgrade connection for pod %s: %v", podName, err)
+		log.FromContext(ctx).Error(err, "Failed to upgrade connection", "pod", podName)
+		return
+	}
+	defer s.closeWebSocket(ws)
+	if !s.rotateUpgradedToken(ctx, ws, &debugSession, receivedToken, r.RemoteAddr, "attach") {
 		return
 	}
-	defer ws.Close()
 
-	if err := s.stream(r.Context(), ns, podName, containerName, ws); err != nil {
-		log.Printf("Stream error for pod %s/%s: %v", ns, podName, err)
+	rec, act, detach := s.openAttach(ctx, &debugSession, ns, podName, containerName, attachInfo{
+		RemoteAddr:  r.RemoteAddr,
+		UserAgent:   r.UserAgent(),
+		Protocol:    protocolWebSocket,
+		OverlayUser: tailnetUser(r),
+	})
+	defer detach()
+
+	err = s.stream(ctx, ns, podName, containerName, ws, rec, act, debugSession.Status.ReusedFrom != "")
+	switch {
+	case s.followingWorkload(ctx, &debugSession, ns, podName):
+		_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseServiceRestart, podReplacedReason))
+	case err != nil:
+		tracing.RecordError(span, err)
+		log.FromContext(ctx).Error(err, "Attach stream failed", "namespace", ns, "pod", podName)
 		_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()))
 	}
 }
 
-func (s *Server) stream(ctx context.Context, ns, podName, containerName string, ws *websocket.Conn) error {
-	req := s.Clientset.CoreV1().RESTClient().
-		Post().
-		Resource("pods").
-		Name(podName).
-		Namespace(ns).
-		SubResource("attach").
-		Param("container", containerName).
-		Param("stdin", "true").
-		Param("stdout", "true").
-		Param("stderr", "true").
-		Param("tty", "true")
+// podReplacedReason tells a client why its attach ended when the session follows its workload. Close
+// reasons are limited to 123 bytes.
+const podReplacedReason = "target pod replaced; reconnect to the new pod once the session is ready"
 
-	executor, err := remotecommand.NewSPDYExecutor(s.RESTCfg, "POST", req.URL())
+// followingWorkload reports whether an attach ended because the target pod of a session with
+// spec.followWorkload went away, so that the controller moves the session to a replacement pod.
+func (s *Server) followingWorkload(ctx context.Context, session *debugv1alpha1.DebugSession, ns, podName string) bool {
+	if !session.Spec.FollowWorkload || session.Status.ReusedFrom != "" {
+		return false
+	}
+	// The request context is already cancelled once the client goes away.
+	pod, err := s.Clientset.CoreV1().Pods(ns).Get(context.WithoutCancel(ctx), podName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return true
+	}
+	return err == nil && pod.DeletionTimestamp != nil
+}
+
+// bearerToken returns the token of the request's Bearer Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	tokenParts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(tokenParts) != 2 || !strings.EqualFold(tokenParts[0], "bearer") {
+		return "", false
+	}
+	return tokenParts[1], true
+}
+
+// tailnetUser returns the Tailscale login that a tailscale serve sidecar vouches for. The header is
+// only trusted on connections from the loopback address, i.e. from the sidecar in the proxy's pod.
+func tailnetUser(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		return fmt.Errorf("failed to create SPDY executor: %w", err)
+		return ""
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return ""
+	}
+	return r.Header.Get("Tailscale-User-Login")
+}
+
+// checkTarget verifies that the namespace, pod and container a client asked for are the session's
+// current target and debugger container, so that a token cannot be pointed at another pod.
+func checkTarget(session *debugv1alpha1.DebugSession, ns, podName, containerName string) error {
+	targetNamespace := session.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = session.Namespace
+	}
+	switch {
+	case ns != targetNamespace:
+		return fmt.Errorf("namespace %q is not the session's target namespace", ns)
+	case podName != debugv1alpha1.CurrentTargetPod(session):
+		return fmt.Errorf("pod %q is not the session's target pod", podName)
+	case containerName != session.Status.DebuggingContainerName:
+		return fmt.Errorf("container %q is not the session's debugger container", containerName)
+	}
+	return nil
+}
+
+// findSession returns the session whose debugger container is containerName. Several sessions share a
+// debugger container when observers reuse it; the token picks the session. If no session holds the
+// token, the owning session, the one that is not reusing another's container, is returned, so that
+// invalid tokens are always counted against the same session and never lock an observer's by chance.
+func (s *Server) findSession(ctx context.Context, containerName, token string) (debugv1alpha1.DebugSession, bool, error) {
+	var debugSession debugv1alpha1.DebugSession
+	if errs := validation.IsValidLabelValue(containerName); len(errs) > 0 {
+		return debugSession, false, nil
 	}
+	sessions, err := s.scopedSessions(ctx, "", client.MatchingLabels{debugv1alpha1.DebuggerContainerLabel: containerName})
+	if err != nil {
+		return debugSession, false, err
+	}
+	found := false
+	for _, sess := range sessions {
+		if sess.Status.DebuggingContainerName != containerName {
+			continue
+		}
+		if sess.Status.OneTimeToken == token {
+			return sess, true, nil
+		}
+		if !found || preferredSession(&sess, &debugSession) {
+			debugSession = sess
+			found = true
+		}
+	}
+	return debugSession, found, nil
+}
+
+// preferredSession reports whether a should be blamed for an invalid token rather than b: the owner of
+// the debugger container first, then the session that sorts first by name.
+func preferredSession(a, b *debugv1alpha1.DebugSession) bool {
+	aOwner, bOwner := a.Status.ReusedFrom == "", b.Status.ReusedFrom == ""
+	if aOwner != bOwner {
+		return aOwner
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// Initial terminal size of an attach connection.
+const (
+	initialTerminalWidth  = 120
+	initialTerminalHeight = 40
+)
+
+// checkNodeLocal rejects attaches to pods on other nodes when the proxy runs node-local, so that a
+// misrouted client is told where to go instead of silently crossing nodes.
+func (s *Server) checkNodeLocal(ctx context.Context, ns, podName string) error {
+	if s.NodeName == "" {
+		return nil
+	}
+	pod, err := s.Clientset.CoreV1().Pods(ns).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get target pod: %w", err)
+	}
+	if pod.Spec.NodeName != s.NodeName {
+		return fmt.Errorf("target pod runs on node %s, not %s; connect through the proxy on that node",
+			pod.Spec.NodeName, s.NodeName)
+	}
+	return nil
+}
+
+// openAttach records the attach on the session and starts its recording and activity tracking. The
+// returned func records the detach and finishes the recording.
+func (s *Server) openAttach(ctx context.Context, session *debugv1alpha1.DebugSession, ns, podName, containerName string,
+	info attachInfo) (*recording, *activity, func()) {
+	remoteAddr := info.RemoteAddr
+	s.Recorder.Eventf(session, corev1.EventTypeNormal, eventReasonAttached,
+		"Client %s attached to %s/%s container %s", remoteAddr, ns, podName, containerName)
+	attachmentID, err := s.recordAttach(ctx, session, info)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to record attach")
+	}
+	stopHeartbeat := s.heartbeat(ctx, session, attachmentID)
+
+	rec := s.startRecording(ctx, session, containerName, initialTerminalWidth, initialTerminalHeight)
+	act := s.newActivity(ctx, session)
+	logger := log.FromContext(ctx)
+	logger.Info("Client attached", "pod", podName, "container", containerName, "protocol", info.Protocol)
+	return rec, act, func() {
+		stopHeartbeat()
+		bytesIn, bytesOut := act.close()
+		s.Recorder.Eventf(session, corev1.EventTypeNormal, eventReasonDetached,
+			"Client %s detached after sending %d and receiving %d bytes", remoteAddr, bytesIn, bytesOut)
+		// The request context is already cancelled once the client goes away.
+		if err := s.recordDetach(context.Background(), session, remoteAddr, attachmentID, bytesIn, bytesOut); err != nil {
+			logger.Error(err, "Failed to record detach")
+		}
+		logger.Info("Client detached", "bytesIn", bytesIn, "bytesOut", bytesOut)
+
+		if rec == nil {
+			return
+		}
+		if err := rec.close(); err != nil {
+			logger.Error(err, "Failed to upload recording", "key", rec.Key)
+			return
+		}
+		if err := s.recordRecording(context.Background(), session, rec.Key); err != nil {
+			logger.Error(err, "Failed to record recording", "key", rec.Key)
+		}
+	}
+}
 
+// stream bridges a WebSocket client to the debugger container. The attach stream is torn down as soon
+// as the client closes the connection or stops answering pings.
+func (s *Server) stream(ctx context.Context, ns, podName, containerName string, ws *websocket.Conn, rec *recording,
+	act *activity, observer bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.WebSocket.startKeepAlive(ctx, ws, cancel)
+	throttle := s.Bandwidth.throttle(ctx)
 	stdinReader, stdinWriter := io.Pipe()
 
 	// Goroutine to handle WebSocket → stdin
 	go func() {
 		defer stdinWriter.Close()
+		defer cancel()
 		for {
-			_, payload, err := ws.ReadMessage()
+			payload, err := s.WebSocket.readMessage(ws)
 			if err != nil {
 				return
 			}
 			// payload = append(payload, '\n')
+			// Observers of a reused debugger watch without typing into the shared shell.
+			if observer {
+				continue
+			}
+			if err := throttle.wait(len(payload)); err != nil {
+				return
+			}
+			rec.event(castInput, payload)
+			act.received(len(payload))
 			if _, err := stdinWriter.Write(payload); err != nil {
 				return
 			}
 		}
 	}()
 
-	streamer := &wsconn{conn: ws}
+	// The recording sees all output, including what a slow client has dropped; activity only what was sent.
+	output := newOutputBuffer(activityWriter{Writer: &wsconn{conn: ws, opts: s.WebSocket}, act: act},
+		s.WebSocket.Backpressure, s.WebSocket.OutputBufferSize)
+	defer output.close(s.WebSocket.WriteTimeout)
+	// Throttling stalls the debugger's output rather than filling the buffer.
+	var streamer io.Writer = output
+	if throttle != nil {
+		streamer = throttledWriter{Writer: streamer, t: throttle}
+	}
+	if rec != nil {
+		streamer = recordingWriter{Writer: streamer, rec: rec}
+	}
 	resizeChan := make(chan remotecommand.TerminalSize, 1)
 	resizeQueue := &terminalSizeQueue{ch: resizeChan}
-	resizeChan <- remotecommand.TerminalSize{Width: 120, Height: 40}
+	resizeChan <- remotecommand.TerminalSize{Width: initialTerminalWidth, Height: initialTerminalHeight}
 
-	// Optional: ping keepalive
-	done := make(chan struct{})
-	go func() {
-		t := time.NewTicker(30 * time.Second)
-		defer t.Stop()
-		for {
-			select {
-			case <-done:
-				return
-			case <-t.C:
-				_ = ws.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(5*time.Second))
-			}
-		}
-	}()
-	defer close(done)
+	err := s.attach(ctx, ns, podName, containerName, stdinReader, streamer, resizeQueue)
+	if ctx.Err() != nil {
+		// The client went away, which ends the attach normally.
+		return nil
+	}
+	return err
+}
+
+// attach runs the pod attach subresource with a TTY for the given client streams.
+func (s *Server) attach(ctx context.Context, ns, podName, containerName string, stdin io.Reader, stdout io.Writer,
+	sizes remotecommand.TerminalSizeQueue) error {
+	req := s.Clientset.CoreV1().RESTClient().
+		Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(ns).
+		SubResource("attach").
+		Param("container", containerName).
+		Param("stdin", "true").
+		Param("stdout", "true").
+		Param("stderr", "true").
+		Param("tty", "true")
+
+	executor, err := remotecommand.NewSPDYExecutor(s.RESTCfg, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create SPDY executor: %w", err)
+	}
 
-	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
-		Stdin:             stdinReader,
-		Stdout:            streamer,
-		Stderr:            streamer,
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:             stdin,
+		Stdout:            stdout,
+		Stderr:            stdout,
 		Tty:               true,
-		TerminalSizeQueue: resizeQueue,
+		TerminalSizeQueue: sizes,
 	})
-
-	return err
 }
@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	webhookv1alpha1 "github.com/OxAN0N/KubeDebugSess/internal/webhook/v1alpha1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// maxSessionRequestBytes bounds the body of a create request.
+const maxSessionRequestBytes = 64 << 10
+
+// CreateSessionRequest is the body of POST /api/v1/sessions.
+type CreateSessionRequest struct {
+	Namespace string `json:"namespace"`
+	// Name is optional; a name is generated when it is empty.
+	Name string                         `json:"name,omitempty"`
+	Spec debugv1alpha1.DebugSessionSpec `json:"spec"`
+}
+
+// RegisterAPI registers the REST API on mux. Every call authenticates with a Kubernetes bearer
+// token and is authorized against the caller's own RBAC permissions on DebugSessions; the proxy
+// then acts with its service account.
+func (s *Server) RegisterAPI(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/history", s.ServeHistory)
+	mux.HandleFunc("GET /api/v1/report", s.ServeReport)
+	mux.HandleFunc("POST /api/v1/sessions", s.createSession)
+	mux.HandleFunc("GET /api/v1/sessions", s.listSessions)
+	mux.HandleFunc("GET /api/v1/sessions/{namespace}/{name}", s.getSession)
+	mux.HandleFunc("DELETE /api/v1/sessions/{namespace}/{name}", s.deleteSession)
+}
+
+func sessionAttributes(verb, namespace, name string) *authorizationv1.ResourceAttributes {
+	return &authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Verb:      verb,
+		Group:     debugv1alpha1.GroupVersion.Group,
+		Resource:  "debugsessions",
+		Name:      name,
+	}
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	var req CreateSessionRequest
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxSessionRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Namespace == "" {
+		http.Error(w, "namespace is required", http.StatusBadRequest)
+		return
+	}
+
+	user, status, err := s.authenticate(r.Context(), r.Header.Get("Authorization"))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if status, err := s.checkAccess(r.Context(), user, sessionAttributes("create", req.Namespace, "")); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	session := &debugv1alpha1.DebugSession{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: req.Namespace,
+			Name:      req.Name,
+			// Record the authenticated caller rather than anything the request claims.
+			Annotations: map[string]string{debugv1alpha1.RequestedByAnnotation: user.Username},
+		},
+		Spec: req.Spec,
+	}
+	// The proxy's service account is a trusted requester, so the webhook keeps this as is.
+	session.Spec.RequestedBy = webhookv1alpha1.RequesterFromUserInfo(user)
+	if session.Name == "" {
+		session.GenerateName = "debug-"
+	}
+	if err := s.K8sClient.Create(r.Context(), session); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, session)
+}
+
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	ns := r.URL.Query().Get("namespace")
+	if status, err := s.authorize(r.Context(), r.Header.Get("Authorization"), sessionAttributes("list", ns, "")); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	items, err := s.scopedSessions(r.Context(), ns)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	sessions := &debugv1alpha1.DebugSessionList{Items: items}
+	for i := range sessions.Items {
+		sessions.Items[i].ManagedFields = nil
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request) {
+	key := types.NamespacedName{Namespace: r.PathValue("namespace"), Name: r.PathValue("name")}
+	if status, err := s.authorize(r.Context(), r.Header.Get("Authorization"), sessionAttributes("get", key.Namespace, key.Name)); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	var session debugv1alpha1.DebugSession
+	if err := s.K8sClient.Get(r.Context(), key, &session); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	session.ManagedFields = nil
+	writeJSON(w, http.StatusOK, &session)
+}
+
+// deleteSession terminates a session by deleting it; the controller archives the transcript first.
+func (s *Server) deleteSession(w http.ResponseWriter, r *http.Request) {
+	key := types.NamespacedName{Namespace: r.PathValue("namespace"), Name: r.PathValue("name")}
+	if status, err := s.authorize(r.Context(), r.Header.Get("Authorization"), sessionAttributes("delete", key.Namespace, key.Name)); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	session := &debugv1alpha1.DebugSession{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	if err := s.K8sClient.Delete(r.Context(), session); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError maps Kubernetes API errors to HTTP responses.
+func writeAPIError(w http.ResponseWriter, r *http.Request, err error) {
+	if status, ok := err.(apierrors.APIStatus); ok {
+		st := status.Status()
+		if st.Code >= 400 && st.Code < 500 {
+			http.Error(w, st.Message, int(st.Code))
+			return
+		}
+	}
+	log.FromContext(r.Context()).Error(err, "DebugSession API request failed")
+	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+}
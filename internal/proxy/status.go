@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/audit"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// maxAttachedClients bounds status.attachedClients.
+const maxAttachedClients = 20
+
+// maxAttachments bounds status.attachments; the oldest closed entries are dropped first.
+const maxAttachments = 50
+
+// Protocols recorded in status.attachments.
+const (
+	protocolWebSocket = "WebSocket"
+	protocolGRPC      = "gRPC"
+)
+
+// attachInfo describes an incoming attach connection for the audit trail.
+type attachInfo struct {
+	RemoteAddr  string
+	UserAgent   string
+	Protocol    string
+	OverlayUser string
+}
+
+// updateSessionStatus re-reads the session and applies mutate to its status, retrying on conflicts
+// with concurrent controller writes.
+func (s *Server) updateSessionStatus(ctx context.Context, key types.NamespacedName, mutate func(*debugv1alpha1.DebugSessionStatus)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var session debugv1alpha1.DebugSession
+		if err := s.K8sClient.Get(ctx, key, &session); err != nil {
+			return err
+		}
+		mutate(&session.Status)
+		return s.K8sClient.Status().Update(ctx, &session)
+	})
+}
+
+// recordAttach marks the session as attached, stamps the attach timestamps and appends an audit
+// entry for the connection. It returns the entry's ID for recordDetach.
+func (s *Server) recordAttach(ctx context.Context, session *debugv1alpha1.DebugSession, info attachInfo) (string, error) {
+	key := types.NamespacedName{Namespace: session.Namespace, Name: session.Name}
+	id := string(uuid.NewUUID())
+	remoteAddr := info.RemoteAddr
+	var attachment debugv1alpha1.Attachment
+	var readyFor time.Duration
+	var image string
+	err := s.updateSessionStatus(ctx, key, func(st *debugv1alpha1.DebugSessionStatus) {
+		now := metav1.Now()
+		readyFor = -1
+		if st.FirstAttachTime == nil {
+			st.FirstAttachTime = &now
+			if st.ReadyTime != nil {
+				readyFor, image = now.Sub(st.ReadyTime.Time), st.DebuggerImage
+			}
+		}
+		st.LastAttachTime = &now
+		st.LastActivityTime = &now
+		st.AttachCount++
+		if !slices.Contains(st.AttachedClients, remoteAddr) && len(st.AttachedClients) < maxAttachedClients {
+			st.AttachedClients = append(st.AttachedClients, remoteAddr)
+		}
+		attachment = debugv1alpha1.Attachment{
+			ID:            id,
+			User:          sessionRequester(session),
+			SourceIP:      sourceIP(remoteAddr),
+			UserAgent:     info.UserAgent,
+			Protocol:      info.Protocol,
+			OverlayUser:   info.OverlayUser,
+			Proxy:         s.Name,
+			AttachTime:    now,
+			HeartbeatTime: &now,
+		}
+		st.Attachments = trimAttachments(append(st.Attachments, attachment))
+		st.ConnectedClients = debugv1alpha1.OpenAttachments(st)
+		setAttachedCondition(st, session.Generation, metav1.ConditionTrue, "ClientConnected",
+			fmt.Sprintf("Client %s attached", remoteAddr))
+	})
+	if err == nil {
+		if readyFor >= 0 {
+			namespace := session.Spec.TargetNamespace
+			if namespace == "" {
+				namespace = session.Namespace
+			}
+			timeToAttach.WithLabelValues(namespace, image).Observe(readyFor.Seconds())
+		}
+		s.auditAttachment(ctx, audit.EventSessionAttached, session, attachment)
+	}
+	return id, err
+}
+
+// recordDetach closes the connection's audit entry and adds the connection's byte counts to the
+// session totals. When the last client leaves, it marks the session as no longer attached. An entry
+// the controller already closed as lost keeps its detach time.
+func (s *Server) recordDetach(ctx context.Context, session *debugv1alpha1.DebugSession, remoteAddr, id string,
+	bytesIn, bytesOut int64) error {
+	key := types.NamespacedName{Namespace: session.Namespace, Name: session.Name}
+	attachment := debugv1alpha1.Attachment{ID: id}
+	err := s.updateSessionStatus(ctx, key, func(st *debugv1alpha1.DebugSessionStatus) {
+		now := metav1.Now()
+		closed := false
+		for i := range st.Attachments {
+			if st.Attachments[i].ID == id {
+				if st.Attachments[i].DetachTime == nil {
+					st.Attachments[i].DetachTime = &now
+					closed = true
+				}
+				st.Attachments[i].BytesIn = bytesIn
+				st.Attachments[i].BytesOut = bytesOut
+				attachment = st.Attachments[i]
+			}
+		}
+		st.BytesIn += bytesIn
+		st.BytesOut += bytesOut
+		st.ConnectedClients = debugv1alpha1.OpenAttachments(st)
+		if !closed {
+			return
+		}
+		if st.ConnectedClients > 0 {
+			setAttachedCondition(st, session.Generation, metav1.ConditionTrue, "ClientDisconnected",
+				fmt.Sprintf("Client %s detached; %d client(s) still attached", remoteAddr, st.ConnectedClients))
+			return
+		}
+		// The controller times spec.detachGracePeriodSeconds from here.
+		st.LastDetachTime = &now
+		setAttachedCondition(st, session.Generation, metav1.ConditionFalse, "AllClientsDetached",
+			fmt.Sprintf("Client %s detached; no clients attached", remoteAddr))
+	})
+	if err == nil {
+		s.auditAttachment(ctx, audit.EventSessionDetached, session, attachment)
+	}
+	return err
+}
+
+// heartbeat refreshes the heartbeat of the connection's entry in status.attachments every
+// AttachmentHeartbeatInterval until the returned func is called, so that the controller can tell the
+// connection from one lost with a restarted proxy.
+func (s *Server) heartbeat(ctx context.Context, session *debugv1alpha1.DebugSession, id string) func() {
+	key := types.NamespacedName{Namespace: session.Namespace, Name: session.Name}
+	logger := log.FromContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(debugv1alpha1.AttachmentHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			err := s.updateSessionStatus(context.Background(), key, func(st *debugv1alpha1.DebugSessionStatus) {
+				now := metav1.Now()
+				for i := range st.Attachments {
+					if st.Attachments[i].ID == id && st.Attachments[i].DetachTime == nil {
+						st.Attachments[i].HeartbeatTime = &now
+					}
+				}
+			})
+			if err != nil {
+				logger.Error(err, "Failed to record attach heartbeat")
+			}
+		}
+	}()
+	return sync.OnceFunc(func() { close(done) })
+}
+
+// trimAttachments drops the oldest closed entries beyond maxAttachments. Open entries are kept, since
+// status.connectedClients counts them.
+func trimAttachments(attachments []debugv1alpha1.Attachment) []debugv1alpha1.Attachment {
+	excess := len(attachments) - maxAttachments
+	return slices.DeleteFunc(attachments, func(attachment debugv1alpha1.Attachment) bool {
+		if excess > 0 && attachment.DetachTime != nil {
+			excess--
+			return true
+		}
+		return false
+	})
+}
+
+// auditAttachment exports the audit record of an attach or detach. Export failures are logged and
+// never affect the connection.
+func (s *Server) auditAttachment(ctx context.Context, event string, session *debugv1alpha1.DebugSession,
+	attachment debugv1alpha1.Attachment) {
+	if s.Auditor == nil {
+		return
+	}
+	record := audit.NewRecord(event, session)
+	record.Attachment = &attachment
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := s.Auditor.Export(ctx, record); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to export attach audit record", "event", event)
+	}
+}
+
+// sessionRequester is the user the session's one-time token was issued to.
+func sessionRequester(session *debugv1alpha1.DebugSession) string {
+	if session.Spec.RequestedBy != nil {
+		return session.Spec.RequestedBy.Username
+	}
+	return session.Annotations[debugv1alpha1.RequestedByAnnotation]
+}
+
+// sourceIP strips the port from a host:port client address.
+func sourceIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+func setAttachedCondition(st *debugv1alpha1.DebugSessionStatus, generation int64, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&st.Conditions, metav1.Condition{
+		Type:               debugv1alpha1.ConditionAttached,
+		Status:             status,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"fmt"
+	"testing"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTrimAttachments(t *testing.T) {
+	entries := func(n int, open func(i int) bool) []debugv1alpha1.Attachment {
+		attachments := make([]debugv1alpha1.Attachment, n)
+		for i := range attachments {
+			attachments[i].ID = fmt.Sprint(i)
+			if !open(i) {
+				attachments[i].DetachTime = &metav1.Time{}
+			}
+		}
+		return attachments
+	}
+	none := func(int) bool { return false }
+
+	tests := []struct {
+		name        string
+		attachments []debugv1alpha1.Attachment
+		wantLen     int
+		wantFirst   string
+		wantOpen    int32
+	}{
+		{name: "under the limit", attachments: entries(3, none), wantLen: 3, wantFirst: "0"},
+		{name: "oldest closed dropped", attachments: entries(maxAttachments+2, none), wantLen: maxAttachments, wantFirst: "2"},
+		{
+			name:        "open entries kept",
+			attachments: entries(maxAttachments+2, func(i int) bool { return i < 2 }),
+			wantLen:     maxAttachments,
+			wantFirst:   "0",
+			wantOpen:    2,
+		},
+		{
+			name:        "all open",
+			attachments: entries(maxAttachments+2, func(int) bool { return true }),
+			wantLen:     maxAttachments + 2,
+			wantFirst:   "0",
+			wantOpen:    maxAttachments + 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trimAttachments(tt.attachments)
+			if len(got) != tt.wantLen || got[0].ID != tt.wantFirst {
+				t.Errorf("trimAttachments() kept %d entries from %q, want %d from %q", len(got), got[0].ID, tt.wantLen, tt.wantFirst)
+			}
+			if open := debugv1alpha1.OpenAttachments(&debugv1alpha1.DebugSessionStatus{Attachments: got}); open != tt.wantOpen {
+				t.Errorf("open entries = %d, want %d", open, tt.wantOpen)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/OxAN0N/KubeDebugSess/internal/tracing"
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// eventReasonTokenRotated is recorded when an attach replaced the session's token.
+const eventReasonTokenRotated = "TokenRotated"
+
+// errTokenUsed is returned by rotateToken when another attach rotated the presented token first.
+var errTokenUsed = errors.New("token was already used")
+
+// rotateUpgradedToken rotates the token of a WebSocket client once its connection was upgraded, so that
+// a failed upgrade does not use up the token. If the token cannot be rotated, ws is closed with a reason
+// for the client and false is returned. action names the request in events, e.g. "attach".
+func (s *Server) rotateUpgradedToken(ctx context.Context, ws *websocket.Conn, session *debugv1alpha1.DebugSession, presented, remoteAddr, action string) bool {
+	err := s.rotateToken(ctx, session, presented)
+	if err == nil {
+		return true
+	}
+	tracing.RecordError(trace.SpanFromContext(ctx), err)
+	if errors.Is(err, errTokenUsed) {
+		// A client that lost the race for a valid token is not guessing, so it is not counted.
+		s.Recorder.Eventf(session, corev1.EventTypeWarning, eventReasonAuthFailed,
+			"Rejected %s attempt from %s: %v", action, remoteAddr, err)
+		_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "invalid or expired token"))
+		return false
+	}
+	log.FromContext(ctx).Error(err, "Failed to rotate the session token")
+	_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "failed to rotate the session token"))
+	return false
+}
+
+// rotateToken replaces the token a client attached with, for sessions with spec.rotateTokenOnAttach,
+// so that a token captured from an earlier connection cannot be replayed. The new token replaces the
+// old one in status.oneTimeToken and in the connection instructions of status.message. Exactly one of
+// several concurrent attaches with the same token succeeds; the others get errTokenUsed.
+func (s *Server) rotateToken(ctx context.Context, session *debugv1alpha1.DebugSession, presented string) error {
+	if !session.Spec.RotateTokenOnAttach {
+		return nil
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(raw)
+
+	key := types.NamespacedName{Namespace: session.Namespace, Name: session.Name}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var latest debugv1alpha1.DebugSession
+		if err := s.K8sClient.Get(ctx, key, &latest); err != nil {
+			return err
+		}
+		if latest.Status.OneTimeToken != presented {
+			return errTokenUsed
+		}
+		latest.Status.OneTimeToken = token
+		latest.Status.Message = strings.ReplaceAll(latest.Status.Message, presented, token)
+		return s.K8sClient.Status().Update(ctx, &latest)
+	})
+	if err != nil {
+		return err
+	}
+	session.Status.OneTimeToken = token
+	s.Recorder.Event(session, corev1.EventTypeNormal, eventReasonTokenRotated,
+		"Rotated the session token after a client attached")
+	return nil
+}
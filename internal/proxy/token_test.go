@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTokenTestServer(t *testing.T, rotate bool) (*Server, *debugv1alpha1.DebugSession) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	_ = debugv1alpha1.AddToScheme(scheme)
+	session := &debugv1alpha1.DebugSession{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "s1"},
+		Spec:       debugv1alpha1.DebugSessionSpec{RotateTokenOnAttach: rotate},
+		Status:     debugv1alpha1.DebugSessionStatus{OneTimeToken: "old", Message: "Attach with --token old"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(session).WithStatusSubresource(session).Build()
+	return &Server{K8sClient: c, Recorder: record.NewFakeRecorder(100)}, session
+}
+
+func TestRotateToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		rotate    bool
+		presented string
+		wantErr   error
+		rotated   bool
+	}{
+		{name: "rotation disabled", presented: "old"},
+		{name: "current token", rotate: true, presented: "old", rotated: true},
+		{name: "token already rotated", rotate: true, presented: "stale", wantErr: errTokenUsed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, session := newTokenTestServer(t, tt.rotate)
+			err := s.rotateToken(context.Background(), session.DeepCopy(), tt.presented)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("rotateToken() error = %v, want %v", err, tt.wantErr)
+			}
+			var got debugv1alpha1.DebugSession
+			if err := s.K8sClient.Get(context.Background(), client.ObjectKeyFromObject(session), &got); err != nil {
+				t.Fatal(err)
+			}
+			if rotated := got.Status.OneTimeToken != "old"; rotated != tt.rotated {
+				t.Errorf("token rotated = %v, want %v", rotated, tt.rotated)
+			}
+			if want := "Attach with --token " + got.Status.OneTimeToken; got.Status.Message != want {
+				t.Errorf("message = %q, want %q", got.Status.Message, want)
+			}
+		})
+	}
+}
+
+func TestRotateTokenConcurrentAttaches(t *testing.T) {
+	s, session := newTokenTestServer(t, true)
+	const attaches = 8
+	errs := make([]error, attaches)
+	var wg sync.WaitGroup
+	for i := range attaches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.rotateToken(context.Background(), session.DeepCopy(), "old")
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, errTokenUsed):
+			t.Errorf("rotateToken() error = %v, want nil or %v", err, errTokenUsed)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d of %d concurrent attaches rotated the token, want exactly 1", succeeded, attaches)
+	}
+	var got debugv1alpha1.DebugSession
+	if err := s.K8sClient.Get(context.Background(), client.ObjectKeyFromObject(session), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.OneTimeToken == "old" || strings.Contains(got.Status.Message, "old") {
+		t.Errorf("token was not rotated: %+v", got.Status)
+	}
+}
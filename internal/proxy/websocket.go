@@ -0,0 +1,178 @@
+package proxy
+
+import (
+	"compress/flate"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocketOptions tunes the client WebSockets of /attach and /portforward.
+type WebSocketOptions struct {
+	// Compression negotiates permessage-deflate with clients that offer it, trading proxy CPU for
+	// bandwidth on slow links. Off by default.
+	Compression bool
+	// CompressionLevel is the flate level of compressed messages, from 1 (fastest) to 9 (smallest).
+	CompressionLevel int
+	// MaxMessageSize is the largest message a client may send; larger ones close the connection.
+	MaxMessageSize int64
+	// HandshakeTimeout bounds the request headers and the WebSocket upgrade.
+	HandshakeTimeout time.Duration
+	// PingInterval is how often the client is pinged.
+	PingInterval time.Duration
+	// ReadTimeout is how long a client may send neither a message nor a pong before it is
+	// considered gone. It must exceed PingInterval.
+	ReadTimeout time.Duration
+	// WriteTimeout is how long a write to a client that stops reading may block.
+	WriteTimeout time.Duration
+	// OutputBufferSize is how many bytes of pod output are queued for a client that reads slowly.
+	OutputBufferSize int
+	// Backpressure is what happens to further output once the buffer is full.
+	Backpressure BackpressurePolicy
+}
+
+// DefaultWebSocketOptions returns the options used unless the environment overrides them.
+func DefaultWebSocketOptions() WebSocketOptions {
+	return WebSocketOptions{
+		CompressionLevel: flate.BestSpeed,
+		MaxMessageSize:   64 << 10,
+		HandshakeTimeout: 10 * time.Second,
+		PingInterval:     30 * time.Second,
+		ReadTimeout:      60 * time.Second,
+		WriteTimeout:     10 * time.Second,
+		OutputBufferSize: 1 << 20,
+		Backpressure:     BackpressureBlock,
+	}
+}
+
+// WebSocketOptionsFromEnv reads, over DefaultWebSocketOptions, PROXY_WS_COMPRESSION ("true" enables
+// it), PROXY_WS_COMPRESSION_LEVEL, PROXY_WS_MAX_MESSAGE_SIZE (bytes), and the durations
+// PROXY_WS_HANDSHAKE_TIMEOUT, PROXY_WS_PING_INTERVAL, PROXY_WS_READ_TIMEOUT and PROXY_WS_WRITE_TIMEOUT,
+// PROXY_WS_OUTPUT_BUFFER_SIZE (bytes) and PROXY_WS_BACKPRESSURE (Block, DropOldest or Disconnect).
+func WebSocketOptionsFromEnv() (WebSocketOptions, error) {
+	opts := DefaultWebSocketOptions()
+	if v := os.Getenv("PROXY_WS_COMPRESSION"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid PROXY_WS_COMPRESSION %q: %w", v, err)
+		}
+		opts.Compression = enabled
+	}
+	if v := os.Getenv("PROXY_WS_COMPRESSION_LEVEL"); v != "" {
+		level, err := strconv.Atoi(v)
+		if err != nil || level < flate.BestSpeed || level > flate.BestCompression {
+			return opts, fmt.Errorf("invalid PROXY_WS_COMPRESSION_LEVEL %q: must be 1 to 9", v)
+		}
+		opts.CompressionLevel = level
+	}
+	if v := os.Getenv("PROXY_WS_MAX_MESSAGE_SIZE"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size <= 0 {
+			return opts, fmt.Errorf("invalid PROXY_WS_MAX_MESSAGE_SIZE %q: must be a positive number of bytes", v)
+		}
+		opts.MaxMessageSize = size
+	}
+	if v := os.Getenv("PROXY_WS_OUTPUT_BUFFER_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 {
+			return opts, fmt.Errorf("invalid PROXY_WS_OUTPUT_BUFFER_SIZE %q: must be a positive number of bytes", v)
+		}
+		opts.OutputBufferSize = size
+	}
+	if v := os.Getenv("PROXY_WS_BACKPRESSURE"); v != "" {
+		policy, err := ParseBackpressurePolicy(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid PROXY_WS_BACKPRESSURE: %w", err)
+		}
+		opts.Backpressure = policy
+	}
+	for name, d := range map[string]*time.Duration{
+		"PROXY_WS_HANDSHAKE_TIMEOUT": &opts.HandshakeTimeout,
+		"PROXY_WS_PING_INTERVAL":     &opts.PingInterval,
+		"PROXY_WS_READ_TIMEOUT":      &opts.ReadTimeout,
+		"PROXY_WS_WRITE_TIMEOUT":     &opts.WriteTimeout,
+	} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= 0 {
+			return opts, fmt.Errorf("invalid %s %q: must be a positive duration", name, v)
+		}
+		*d = parsed
+	}
+	if opts.ReadTimeout <= opts.PingInterval {
+		return opts, fmt.Errorf("PROXY_WS_READ_TIMEOUT (%s) must exceed PROXY_WS_PING_INTERVAL (%s)",
+			opts.ReadTimeout, opts.PingInterval)
+	}
+	return opts, nil
+}
+
+// upgrade upgrades an attach or port-forward request to a WebSocket configured by s.WebSocket. The
+// caller must close it with closeWebSocket.
+func (s *Server) upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	upgrader := websocket.Upgrader{
+		HandshakeTimeout:  s.WebSocket.HandshakeTimeout,
+		CheckOrigin:       func(r *http.Request) bool { return true },
+		EnableCompression: s.WebSocket.Compression,
+		Subprotocols:      subprotocols,
+	}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	ws.SetReadLimit(s.WebSocket.MaxMessageSize)
+	if s.WebSocket.Compression {
+		// Only takes effect when the client accepted permessage-deflate.
+		if err := ws.SetCompressionLevel(s.WebSocket.CompressionLevel); err != nil {
+			_ = ws.Close()
+			return nil, err
+		}
+	}
+	s.openWebSocket(ws)
+	return ws, nil
+}
+
+// startKeepAlive pings the client every PingInterval until ctx is done. Pongs and data messages push
+// the read deadline ReadTimeout ahead, so that reads from a vanished client fail instead of blocking
+// forever; a ping that cannot be sent calls cancel. It must be called before the connection is read.
+func (o WebSocketOptions) startKeepAlive(ctx context.Context, ws *websocket.Conn, cancel context.CancelFunc) {
+	_ = ws.SetReadDeadline(time.Now().Add(o.ReadTimeout))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(o.ReadTimeout))
+	})
+	go func() {
+		t := time.NewTicker(o.PingInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(o.WriteTimeout)); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+}
+
+// readMessage reads the next data message, decoded according to the connection's framing, and pushes
+// the read deadline ReadTimeout ahead.
+func (o WebSocketOptions) readMessage(ws *websocket.Conn) ([]byte, error) {
+	messageType, message, err := ws.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	if err := ws.SetReadDeadline(time.Now().Add(o.ReadTimeout)); err != nil {
+		return nil, err
+	}
+	return framingOf(ws).decode(messageType, message)
+}
@@ -0,0 +1,113 @@
+// Package scope limits the controller and the debug proxy to a subset of namespaces, for clusters
+// where KubeDebugSess cannot be granted cluster-wide rights.
+package scope
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Namespaces is the set of namespaces DebugSessions are served in. A nil *Namespaces allows every
+// namespace.
+type Namespaces struct {
+	// Names allowlists namespaces by name; empty allows any name.
+	Names []string
+	// Selector, when set, must match a namespace's labels.
+	Selector labels.Selector
+}
+
+// NewFromEnv reads WATCH_NAMESPACES (comma-separated names) and WATCH_NAMESPACE_SELECTOR (a label
+// selector). It returns nil, meaning cluster-wide, when neither is set.
+func NewFromEnv() (*Namespaces, error) {
+	var n Namespaces
+	for _, name := range strings.Split(os.Getenv("WATCH_NAMESPACES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			n.Names = append(n.Names, name)
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv("WATCH_NAMESPACE_SELECTOR")); raw != "" {
+		selector, err := labels.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WATCH_NAMESPACE_SELECTOR: %w", err)
+		}
+		n.Selector = selector
+	}
+	if len(n.Names) == 0 && n.Selector == nil {
+		return nil, nil
+	}
+	return &n, nil
+}
+
+// String describes the scope for logs.
+func (n *Namespaces) String() string {
+	if n == nil {
+		return "all namespaces"
+	}
+	var parts []string
+	if len(n.Names) > 0 {
+		parts = append(parts, "namespaces "+strings.Join(n.Names, ","))
+	}
+	if n.Selector != nil {
+		parts = append(parts, "namespaces matching "+n.Selector.String())
+	}
+	return strings.Join(parts, " and ")
+}
+
+// CacheNamespaces returns the manager cache configuration restricting watches to the allowlisted
+// namespaces, or nil to watch cluster-wide. A selector alone cannot narrow the cache, since the
+// matching namespaces are only known at runtime.
+func (n *Namespaces) CacheNamespaces() map[string]cache.Config {
+	if n == nil || len(n.Names) == 0 {
+		return nil
+	}
+	namespaces := make(map[string]cache.Config, len(n.Names))
+	for _, name := range n.Names {
+		namespaces[name] = cache.Config{}
+	}
+	return namespaces
+}
+
+// ListNamespaces returns the namespaces to issue List calls in: the allowlist, or "" (all namespaces)
+// when only a selector is set. Results must still be filtered with Allows.
+func (n *Namespaces) ListNamespaces() []string {
+	if n == nil || len(n.Names) == 0 {
+		return []string{""}
+	}
+	return n.Names
+}
+
+// Contains reports whether ns is in scope.
+func (n *Namespaces) Contains(ns *corev1.Namespace) bool {
+	if n == nil {
+		return true
+	}
+	if len(n.Names) > 0 && !slices.Contains(n.Names, ns.Name) {
+		return false
+	}
+	return n.Selector == nil || n.Selector.Matches(labels.Set(ns.Labels))
+}
+
+// Allows reports whether the namespace called name is in scope, reading its labels only when a
+// selector is set.
+func (n *Namespaces) Allows(ctx context.Context, reader client.Reader, name string) (bool, error) {
+	if n == nil {
+		return true, nil
+	}
+	if n.Selector == nil {
+		return slices.Contains(n.Names, name), nil
+	}
+	ns := &corev1.Namespace{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return n.Contains(ns), nil
+}
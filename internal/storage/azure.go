@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+	"k8s.io/utils/ptr"
+)
+
+// azureUploadBlockSize is the size of each staged block.
+const azureUploadBlockSize = 4 << 20
+
+// AzureBlob stores transcripts in an Azure Blob Storage container. Keys are written below Prefix
+// when set.
+type AzureBlob struct {
+	Client    *azblob.Client
+	Container string
+	Prefix    string
+	// SharedKey is set when the client was built from a connection string; presigned URLs are then
+	// signed locally instead of through a user delegation key.
+	SharedKey bool
+}
+
+// NewAzureBlobFromEnv configures the Azure Blob backend from AZURE_STORAGE_CONTAINER, the optional
+// AZURE_STORAGE_PREFIX and either AZURE_STORAGE_CONNECTION_STRING or AZURE_STORAGE_ACCOUNT_URL.
+// With an account URL, DefaultAzureCredential is used, so AKS Workload Identity and managed
+// identities work without any key material.
+func NewAzureBlobFromEnv(_ context.Context) (*AzureBlob, error) {
+	container := os.Getenv("AZURE_STORAGE_CONTAINER")
+	if container == "" {
+		return nil, fmt.Errorf("AZURE_STORAGE_CONTAINER must be set for the azure storage backend")
+	}
+	prefix := strings.Trim(os.Getenv("AZURE_STORAGE_PREFIX"), "/")
+
+	if conn := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); conn != "" {
+		client, err := azblob.NewClientFromConnectionString(conn, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure Blob client: %w", err)
+		}
+		return &AzureBlob{Client: client, Container: container, Prefix: prefix, SharedKey: true}, nil
+	}
+
+	accountURL := os.Getenv("AZURE_STORAGE_ACCOUNT_URL")
+	if accountURL == "" {
+		return nil, fmt.Errorf("AZURE_STORAGE_CONNECTION_STRING or AZURE_STORAGE_ACCOUNT_URL must be set for the azure storage backend")
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default Azure credential: %w", err)
+	}
+	client, err := azblob.NewClient(accountURL, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure Blob client: %w", err)
+	}
+	return &AzureBlob{Client: client, Container: container, Prefix: prefix}, nil
+}
+
+func (a *AzureBlob) blob(key string) string {
+	if a.Prefix == "" {
+		return key
+	}
+	return path.Join(a.Prefix, key)
+}
+
+// Put stores tags as blob index tags, which needs the Storage Blob Data Owner role or a SAS with
+// tag permission.
+func (a *AzureBlob) Put(ctx context.Context, key string, body io.Reader, tags map[string]string) error {
+	// Stage blocks one at a time so a transcript costs a single block buffer.
+	opts := &azblob.UploadStreamOptions{
+		BlockSize:   azureUploadBlockSize,
+		Concurrency: 1,
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: ptr.To(contentType(key))},
+	}
+	if len(tags) > 0 {
+		opts.Tags = make(map[string]string, len(tags))
+		for k, v := range tags {
+			opts.Tags[k] = tagValue(v, "+-=._:/")
+		}
+	}
+	if _, err := a.Client.UploadStream(ctx, a.Container, a.blob(key), body, opts); err != nil {
+		return fmt.Errorf("Azure Blob upload failed: %w", err)
+	}
+	return nil
+}
+
+func (a *AzureBlob) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := a.Client.DownloadStream(ctx, a.Container, a.blob(key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Azure Blob download failed: %w", err)
+	}
+	return resp.Body, nil
+}
+
+// Presign issues a read-only SAS URL. Identity-based clients sign it with a user delegation key,
+// which requires the Storage Blob Delegator role on the account.
+func (a *AzureBlob) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	blobClient := a.Client.ServiceClient().NewContainerClient(a.Container).NewBlobClient(a.blob(key))
+	expiresAt := time.Now().UTC().Add(expiry)
+
+	if a.SharedKey {
+		url, err := blobClient.GetSASURL(sas.BlobPermissions{Read: true}, expiresAt, nil)
+		if err != nil {
+			return "", fmt.Errorf("Azure Blob presign failed: %w", err)
+		}
+		return url, nil
+	}
+
+	start := time.Now().UTC().Add(-time.Minute)
+	udc, err := a.Client.ServiceClient().GetUserDelegationCredential(ctx, service.KeyInfo{
+		Start:  ptr.To(start.Format(sas.TimeFormat)),
+		Expiry: ptr.To(expiresAt.Format(sas.TimeFormat)),
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("Azure Blob presign failed: %w", err)
+	}
+	params, err := sas.BlobSignatureValues{
+		Protocol:      sas.ProtocolHTTPS,
+		StartTime:     start,
+		ExpiryTime:    expiresAt,
+		Permissions:   (&sas.BlobPermissions{Read: true}).String(),
+		ContainerName: a.Container,
+		BlobName:      a.blob(key),
+	}.SignWithUserDelegation(udc)
+	if err != nil {
+		return "", fmt.Errorf("Azure Blob presign failed: %w", err)
+	}
+	return blobClient.URL() + "?" + params.Encode(), nil
+}
+
+func (a *AzureBlob) Delete(ctx context.Context, key string) error {
+	_, err := a.Client.DeleteBlob(ctx, a.Container, a.blob(key), nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("Azure Blob delete failed: %w", err)
+	}
+	return nil
+}
+
+func toPtr[T any](v T) *T {
+	return &v
+}
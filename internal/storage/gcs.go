@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// gcsUploadChunkSize is the resumable upload chunk buffered per transcript.
+const gcsUploadChunkSize = 4 << 20
+
+// GCS stores transcripts in a Google Cloud Storage bucket. Keys are written below Prefix when set.
+type GCS struct {
+	Client *storage.Client
+	Bucket string
+	Prefix string
+}
+
+// NewGCSFromEnv configures the GCS backend from GCS_BUCKET_NAME and the optional GCS_PREFIX.
+// Credentials come from Application Default Credentials, so GKE Workload Identity works without
+// any key material; GOOGLE_APPLICATION_CREDENTIALS may point at a service account key instead.
+func NewGCSFromEnv(ctx context.Context) (*GCS, error) {
+	bucket := os.Getenv("GCS_BUCKET_NAME")
+	if bucket == "" {
+		return nil, fmt.Errorf("GCS_BUCKET_NAME must be set for the gcs storage backend")
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	return &GCS{Client: client, Bucket: bucket, Prefix: strings.Trim(os.Getenv("GCS_PREFIX"), "/")}, nil
+}
+
+func (g *GCS) object(key string) string {
+	if g.Prefix == "" {
+		return key
+	}
+	return path.Join(g.Prefix, key)
+}
+
+// Put stores tags as custom object metadata, GCS having no object tags.
+func (g *GCS) Put(ctx context.Context, key string, body io.Reader, tags map[string]string) error {
+	w := g.Client.Bucket(g.Bucket).Object(g.object(key)).NewWriter(ctx)
+	w.ContentType = contentType(key)
+	w.Metadata = tags
+	// Resumable upload in bounded chunks instead of the 16 MiB default buffer.
+	w.ChunkSize = gcsUploadChunkSize
+	if _, err := io.Copy(w, body); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("GCS upload failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("GCS upload failed: %w", err)
+	}
+	return nil
+}
+
+func (g *GCS) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	r, err := g.Client.Bucket(g.Bucket).Object(g.object(key)).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GCS download failed: %w", err)
+	}
+	return r, nil
+}
+
+// Presign issues a V4 signed URL. With Workload Identity the URL is signed through the IAM
+// Credentials API, which requires roles/iam.serviceAccountTokenCreator on the bound service account.
+func (g *GCS) Presign(_ context.Context, key string, expiry time.Duration) (string, error) {
+	url, err := g.Client.Bucket(g.Bucket).SignedURL(g.object(key), &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(expiry),
+	})
+	if err != nil {
+		return "", fmt.Errorf("GCS presign failed: %w", err)
+	}
+	return url, nil
+}
+
+func (g *GCS) Delete(ctx context.Context, key string) error {
+	err := g.Client.Bucket(g.Bucket).Object(g.object(key)).Delete(ctx)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("GCS delete failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultKeyTemplate is the key layout used unless LOG_KEY_TEMPLATE is set.
+const DefaultKeyTemplate = "debug-sessions/{{.Namespace}}/{{.Container}}-{{.Timestamp}}"
+
+// Tag keys set on every archived object.
+const (
+	TagSessionUID  = "kubedebugsess/session-uid"
+	TagNamespace   = "kubedebugsess/namespace"
+	TagRequestedBy = "kubedebugsess/requested-by"
+	TagTargetPod   = "kubedebugsess/target-pod"
+	TagTeam        = "kubedebugsess/team"
+	TagCostCenter  = "kubedebugsess/cost-center"
+	TagTicket      = "kubedebugsess/ticket"
+)
+
+// ObjectInfo describes the session an archived object belongs to. It fills the key template and
+// the object's tags.
+type ObjectInfo struct {
+	// Namespace is the namespace of the target pod.
+	Namespace string
+	// SessionNamespace and Session name the DebugSession.
+	SessionNamespace string
+	Session          string
+	UID              string
+	// User is the requester of the session.
+	User      string
+	Pod       string
+	Container string
+	// Team and CostCenter are the session's cost attribution, if any.
+	Team       string
+	CostCenter string
+	// Ticket is the session's spec.ticketRef, if any.
+	Ticket string
+	// Time is when the object was started; Timestamp is the same instant in Unix seconds, or
+	// nanoseconds for live recordings, of which one session may have several per second.
+	Time      time.Time
+	Timestamp int64
+}
+
+// Tags returns the tags identifying the session on the object.
+func (o ObjectInfo) Tags() map[string]string {
+	tags := map[string]string{
+		TagSessionUID: o.UID,
+		TagNamespace:  o.Namespace,
+		TagTargetPod:  o.Pod,
+	}
+	if o.User != "" {
+		tags[TagRequestedBy] = o.User
+	}
+	if o.Team != "" {
+		tags[TagTeam] = o.Team
+	}
+	if o.CostCenter != "" {
+		tags[TagCostCenter] = o.CostCenter
+	}
+	if o.Ticket != "" {
+		tags[TagTicket] = o.Ticket
+	}
+	return tags
+}
+
+// KeyLayout renders object keys from a text/template over ObjectInfo. A nil KeyLayout uses
+// DefaultKeyTemplate.
+type KeyLayout struct {
+	tmpl *template.Template
+}
+
+// NewKeyLayout parses a key template such as
+// "sessions/{{.Namespace}}/{{.Time.Format \"2006/01/02\"}}/{{.UID}}/{{.Container}}".
+func NewKeyLayout(text string) (*KeyLayout, error) {
+	tmpl, err := template.New("key").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	layout := &KeyLayout{tmpl: tmpl}
+	// Catch references to unknown fields at startup rather than at the first upload.
+	if _, err := layout.Key(ObjectInfo{Time: time.Now()}); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+// KeyLayoutFromEnv returns the key layout set by LOG_KEY_TEMPLATE, or nil for the default.
+func KeyLayoutFromEnv() (*KeyLayout, error) {
+	v := os.Getenv("LOG_KEY_TEMPLATE")
+	if v == "" {
+		return nil, nil
+	}
+	layout, err := NewKeyLayout(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOG_KEY_TEMPLATE %q: %w", v, err)
+	}
+	return layout, nil
+}
+
+var defaultKeyLayout = template.Must(template.New("key").Parse(DefaultKeyTemplate))
+
+// Key renders the key stem of an object; callers append its extension or artifact name.
+func (l *KeyLayout) Key(info ObjectInfo) (string, error) {
+	tmpl := defaultKeyLayout
+	if l != nil {
+		tmpl = l.tmpl
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, info); err != nil {
+		return "", fmt.Errorf("failed to render object key: %w", err)
+	}
+	return strings.TrimLeft(b.String(), "/"), nil
+}
+
+// maxTagValueLength is the longest tag value S3 and Azure Blob Storage accept.
+const maxTagValueLength = 256
+
+// tagValue replaces the characters object stores reject in tag values with '_' and truncates it to
+// maxTagValueLength. allowed lists the punctuation the backend accepts besides letters, digits and spaces.
+func tagValue(v, allowed string) string {
+	if len(v) > maxTagValueLength {
+		v = v[:maxTagValueLength]
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == ' ':
+			return r
+		case strings.ContainsRune(allowed, r):
+			return r
+		}
+		return '_'
+	}, v)
+}
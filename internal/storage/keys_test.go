@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyLayout(t *testing.T) {
+	info := ObjectInfo{
+		Namespace: "apps",
+		UID:       "uid-1",
+		Container: "debugger-abc",
+		Time:      time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC),
+		Timestamp: 1741064767,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		invalid  bool
+	}{
+		{name: "default", want: "debug-sessions/apps/debugger-abc-1741064767"},
+		{
+			name:     "dated layout",
+			template: `sessions/{{.Namespace}}/{{.Time.Format "2006/01/02"}}/{{.UID}}/{{.Container}}`,
+			want:     "sessions/apps/2025/03/04/uid-1/debugger-abc",
+		},
+		{name: "leading slash trimmed", template: "/{{.Namespace}}/{{.Container}}", want: "apps/debugger-abc"},
+		{name: "unknown field", template: "{{.Cluster}}/{{.Container}}", invalid: true},
+		{name: "unparsable", template: "{{.Namespace", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var layout *KeyLayout
+			if tt.template != "" {
+				var err error
+				layout, err = NewKeyLayout(tt.template)
+				if (err != nil) != tt.invalid {
+					t.Fatalf("NewKeyLayout() error = %v, want invalid %v", err, tt.invalid)
+				}
+				if tt.invalid {
+					return
+				}
+			}
+			got, err := layout.Key(info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		allowed string
+		want    string
+	}{
+		{name: "plain", value: "alice", want: "alice"},
+		{name: "allowed punctuation", value: "oidc:alice@example.com", allowed: ":@.", want: "oidc:alice@example.com"},
+		{name: "rejected punctuation", value: "oidc:alice@example.com", allowed: ":.", want: "oidc:alice_example.com"},
+		{name: "spaces kept", value: "Platform Team", want: "Platform Team"},
+		{name: "non-ASCII replaced", value: "café", want: "caf_"},
+		{name: "truncated", value: strings.Repeat("a", maxTagValueLength+10), want: strings.Repeat("a", maxTagValueLength)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tagValue(tt.value, tt.allowed); got != tt.want {
+				t.Errorf("tagValue(%q, %q) = %q, want %q", tt.value, tt.allowed, got, tt.want)
+			}
+		})
+	}
+}
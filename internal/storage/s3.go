@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3UploadPartSize is the multipart chunk size, the minimum S3 accepts.
+const s3UploadPartSize = manager.MinUploadPartSize
+
+// defaultS3CompatibleRegion is used for signing when S3_ENDPOINT is set without AWS_REGION.
+const defaultS3CompatibleRegion = "us-east-1"
+
+// S3 stores transcripts in an Amazon S3 bucket.
+type S3 struct {
+	Client *s3.Client
+	Bucket string
+	// Encryption configures server-side encryption of uploaded objects.
+	Encryption S3Encryption
+}
+
+// S3Encryption selects how uploaded objects are encrypted at rest. The zero value leaves the
+// bucket's default encryption in effect.
+type S3Encryption struct {
+	// Algorithm is the SSE-S3 / SSE-KMS algorithm (AES256, aws:kms or aws:kms:dsse).
+	Algorithm types.ServerSideEncryption
+	// KMSKeyID is the KMS key ARN or alias used with aws:kms.
+	KMSKeyID string
+	// CustomerKey is the raw 256-bit SSE-C key. It is mutually exclusive with Algorithm.
+	CustomerKey []byte
+}
+
+// s3EncryptionFromEnv reads S3_SSE, S3_SSE_KMS_KEY_ID and S3_SSE_CUSTOMER_KEY (base64). Setting a
+// KMS key without S3_SSE implies aws:kms.
+func s3EncryptionFromEnv() (S3Encryption, error) {
+	enc := S3Encryption{
+		Algorithm: types.ServerSideEncryption(os.Getenv("S3_SSE")),
+		KMSKeyID:  os.Getenv("S3_SSE_KMS_KEY_ID"),
+	}
+	if enc.KMSKeyID != "" && enc.Algorithm == "" {
+		enc.Algorithm = types.ServerSideEncryptionAwsKms
+	}
+
+	switch enc.Algorithm {
+	case "", types.ServerSideEncryptionAes256:
+		if enc.KMSKeyID != "" {
+			return S3Encryption{}, fmt.Errorf("S3_SSE_KMS_KEY_ID requires S3_SSE=aws:kms or aws:kms:dsse")
+		}
+	case types.ServerSideEncryptionAwsKms, types.ServerSideEncryptionAwsKmsDsse:
+	default:
+		return S3Encryption{}, fmt.Errorf("unsupported S3_SSE %q", enc.Algorithm)
+	}
+
+	if v := os.Getenv("S3_SSE_CUSTOMER_KEY"); v != "" {
+		if enc.Algorithm != "" {
+			return S3Encryption{}, fmt.Errorf("S3_SSE_CUSTOMER_KEY cannot be combined with S3_SSE or S3_SSE_KMS_KEY_ID")
+		}
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return S3Encryption{}, fmt.Errorf("invalid S3_SSE_CUSTOMER_KEY: %w", err)
+		}
+		if len(key) != 32 {
+			return S3Encryption{}, fmt.Errorf("S3_SSE_CUSTOMER_KEY must decode to 32 bytes, got %d", len(key))
+		}
+		enc.CustomerKey = key
+	}
+	return enc, nil
+}
+
+// apply sets the encryption parameters on a PutObject request.
+func (e S3Encryption) apply(in *s3.PutObjectInput) {
+	if e.Algorithm != "" {
+		in.ServerSideEncryption = e.Algorithm
+		if e.KMSKeyID != "" {
+			in.SSEKMSKeyId = aws.String(e.KMSKeyID)
+		}
+	}
+	if len(e.CustomerKey) > 0 {
+		in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = e.customerKeyHeaders()
+	}
+}
+
+// customerKeyHeaders returns the SSE-C algorithm, key and key MD5 parameters, which are required
+// on every write and read of an SSE-C object.
+func (e S3Encryption) customerKeyHeaders() (algorithm, key, keyMD5 *string) {
+	sum := md5.Sum(e.CustomerKey)
+	return aws.String(string(types.ServerSideEncryptionAes256)),
+		aws.String(base64.StdEncoding.EncodeToString(e.CustomerKey)),
+		aws.String(base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+// NewS3FromEnv configures the S3 backend from AWS_REGION, S3_BUCKET_NAME and, optionally, static
+// AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY credentials. Without static credentials the default AWS
+// credential chain (IRSA, instance profile, ...) is used.
+//
+// S3-compatible stores such as MinIO or Ceph RGW are supported through S3_ENDPOINT,
+// S3_FORCE_PATH_STYLE and S3_CA_BUNDLE (a PEM file used to verify a self-signed endpoint).
+// Server-side encryption is configured as described in s3EncryptionFromEnv.
+func NewS3FromEnv(ctx context.Context) (*S3, error) {
+	bucket := os.Getenv("S3_BUCKET_NAME")
+	if bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET_NAME must be set for the s3 storage backend")
+	}
+
+	endpoint := os.Getenv("S3_ENDPOINT")
+	region := os.Getenv("AWS_REGION")
+	if region == "" && endpoint != "" {
+		// Most S3-compatible stores ignore the region, but request signing still needs one.
+		region = defaultS3CompatibleRegion
+	}
+
+	opts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if caBundle := os.Getenv("S3_CA_BUNDLE"); caBundle != "" {
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read S3_CA_BUNDLE: %w", err)
+		}
+		opts = append(opts, config.WithCustomCABundle(bytes.NewReader(pem)))
+	}
+
+	forcePathStyle := false
+	if v := os.Getenv("S3_FORCE_PATH_STYLE"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3_FORCE_PATH_STYLE %q: %w", v, err)
+		}
+		forcePathStyle = parsed
+	}
+
+	encryption, err := s3EncryptionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default AWS config: %w", err)
+	}
+
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey != "" && secretKey != "" {
+		cfg.Credentials = aws.NewCredentialsCache(
+			credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""),
+		)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+		o.UsePathStyle = forcePathStyle
+	})
+	return &S3{Client: client, Bucket: bucket, Encryption: encryption}, nil
+}
+
+// uploader streams bodies as multipart uploads, so at most s3UploadPartSize bytes of a transcript
+// are buffered at a time.
+func (s *S3) uploader() *manager.Uploader {
+	return manager.NewUploader(s.Client, func(u *manager.Uploader) {
+		u.PartSize = s3UploadPartSize
+		u.Concurrency = 1
+	})
+}
+
+// Put tags the object through the x-amz-tagging header, which needs s3:PutObjectTagging.
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, tags map[string]string) error {
+	in := &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType(key)),
+	}
+	if len(tags) > 0 {
+		values := url.Values{}
+		for k, v := range tags {
+			values.Set(k, tagValue(v, "+-=._:/@"))
+		}
+		in.Tagging = aws.String(values.Encode())
+	}
+	s.Encryption.apply(in)
+	if _, err := s.uploader().Upload(ctx, in); err != nil {
+		return fmt.Errorf("S3 upload failed: %w", err)
+	}
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	in := &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	}
+	if len(s.Encryption.CustomerKey) > 0 {
+		in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = s.Encryption.customerKeyHeaders()
+	}
+	out, err := s.Client.GetObject(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("S3 download failed: %w", err)
+	}
+	return out.Body, nil
+}
+
+// Presign returns ErrPresignUnsupported for SSE-C objects: reading them requires the customer key
+// as request headers, which a plain link cannot carry.
+func (s *S3) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if len(s.Encryption.CustomerKey) > 0 {
+		return "", ErrPresignUnsupported
+	}
+	req, err := s3.NewPresignClient(s.Client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fmt.Errorf("S3 presign failed: %w", err)
+	}
+	return req.URL, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if _, err := s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		return fmt.Errorf("S3 delete failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Package storage abstracts where debug session transcripts are archived.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"time"
+)
+
+// Storage is an object store for session transcripts.
+type Storage interface {
+	// Put uploads body under key and tags the object with tags, stored as object tags where the
+	// backend has them and as metadata otherwise. Implementations must stream body rather than buffer it whole.
+	Put(ctx context.Context, key string, body io.Reader, tags map[string]string) error
+	// Get opens key for reading. The caller closes the returned reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Presign returns a URL granting time-limited read access to key.
+	Presign(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// Delete removes key.
+	Delete(ctx context.Context, key string) error
+}
+
+// ErrPresignUnsupported is returned by backends that cannot issue presigned URLs.
+var ErrPresignUnsupported = errors.New("presigned URLs are not supported by this storage backend")
+
+// Supported values of LOG_STORAGE_BACKEND.
+const (
+	BackendNone  = "none"
+	BackendS3    = "s3"
+	BackendGCS   = "gcs"
+	BackendAzure = "azure"
+)
+
+// contentTypes covers the capture artifact types the system MIME tables usually lack.
+var contentTypes = map[string]string{
+	".pcap":  "application/vnd.tcpdump.pcap",
+	".data":  "application/octet-stream",
+	".hprof": "application/octet-stream",
+}
+
+// contentType guesses the MIME type of an object from its key.
+func contentType(key string) string {
+	if t, ok := contentTypes[path.Ext(key)]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// DefaultPresignExpiry is how long presigned transcript URLs stay valid unless LOG_URL_EXPIRY is set.
+const DefaultPresignExpiry = 24 * time.Hour
+
+// PresignExpiryFromEnv returns the lifetime of presigned transcript URLs from LOG_URL_EXPIRY.
+func PresignExpiryFromEnv() (time.Duration, error) {
+	v := os.Getenv("LOG_URL_EXPIRY")
+	if v == "" {
+		return DefaultPresignExpiry, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LOG_URL_EXPIRY %q: %w", v, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("LOG_URL_EXPIRY must be positive, got %s", d)
+	}
+	return d, nil
+}
+
+// NewFromEnv returns the backend selected by LOG_STORAGE_BACKEND, or nil if transcript archival is
+// disabled. When the variable is unset, the backend is inferred from S3_BUCKET_NAME,
+// GCS_BUCKET_NAME or AZURE_STORAGE_CONTAINER, in that order.
+func NewFromEnv(ctx context.Context) (Storage, error) {
+	backend := os.Getenv("LOG_STORAGE_BACKEND")
+	if backend == "" {
+		backend = BackendNone
+		if os.Getenv("S3_BUCKET_NAME") != "" {
+			backend = BackendS3
+		} else if os.Getenv("GCS_BUCKET_NAME") != "" {
+			backend = BackendGCS
+		} else if os.Getenv("AZURE_STORAGE_CONTAINER") != "" {
+			backend = BackendAzure
+		}
+	}
+
+	switch backend {
+	case BackendNone:
+		return nil, nil
+	case BackendS3:
+		return NewS3FromEnv(ctx)
+	case BackendGCS:
+		return NewGCSFromEnv(ctx)
+	case BackendAzure:
+		return NewAzureBlobFromEnv(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported LOG_STORAGE_BACKEND %q", backend)
+	}
+}
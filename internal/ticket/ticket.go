@@ -0,0 +1,245 @@
+// Package ticket checks that the change or incident tickets sessions name in spec.ticketRef exist and
+// are open, in Jira, GitHub or ServiceNow. It speaks their REST APIs directly.
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for tickets the ticket system does not know.
+	ErrNotFound = errors.New("ticket not found")
+	// ErrClosed is returned for tickets that are resolved or closed.
+	ErrClosed = errors.New("ticket is closed")
+	// ErrUnsupported is returned for references that name no configured ticket system.
+	ErrUnsupported = errors.New("ticket reference does not name a configured ticket system")
+)
+
+// References accepted in spec.ticketRef, besides the URLs of the ticket systems.
+var (
+	jiraKeyPattern       = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-[0-9]+$`)
+	githubRefPattern     = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)#([0-9]+)$`)
+	githubPathPattern    = regexp.MustCompile(`^/([\w.-]+)/([\w.-]+)/(?:issues|pull)/([0-9]+)`)
+	serviceNowNumPattern = regexp.MustCompile(`\b([A-Z]{2,6}[0-9]{7,})\b`)
+)
+
+// Checker checks tickets against the ticket systems whose URL or token is set.
+type Checker struct {
+	// JiraURL is the base URL of Jira, e.g. https://example.atlassian.net. JiraUser and JiraToken
+	// authenticate with basic auth, or JiraToken alone as a bearer personal access token.
+	JiraURL   string
+	JiraUser  string
+	JiraToken string
+	// GitHubAPIURL is the GitHub REST API base URL and GitHubToken the token reading issues.
+	GitHubAPIURL string
+	GitHubToken  string
+	// ServiceNowURL is the instance URL, e.g. https://example.service-now.com, read with basic auth.
+	ServiceNowURL      string
+	ServiceNowUser     string
+	ServiceNowPassword string
+	HTTPClient         *http.Client
+}
+
+// NewCheckerFromEnv returns a Checker for the ticket systems configured in the environment, or nil if
+// none is.
+//
+//	TICKET_JIRA_URL         Jira base URL, with TICKET_JIRA_USER and TICKET_JIRA_TOKEN
+//	TICKET_GITHUB_TOKEN     GitHub token, with the optional TICKET_GITHUB_API_URL for GitHub Enterprise
+//	TICKET_SERVICENOW_URL   ServiceNow instance URL, with TICKET_SERVICENOW_USER and
+//	                        TICKET_SERVICENOW_PASSWORD
+func NewCheckerFromEnv() (*Checker, error) {
+	c := &Checker{
+		JiraURL:            strings.TrimSuffix(os.Getenv("TICKET_JIRA_URL"), "/"),
+		JiraUser:           os.Getenv("TICKET_JIRA_USER"),
+		JiraToken:          os.Getenv("TICKET_JIRA_TOKEN"),
+		GitHubToken:        os.Getenv("TICKET_GITHUB_TOKEN"),
+		GitHubAPIURL:       strings.TrimSuffix(os.Getenv("TICKET_GITHUB_API_URL"), "/"),
+		ServiceNowURL:      strings.TrimSuffix(os.Getenv("TICKET_SERVICENOW_URL"), "/"),
+		ServiceNowUser:     os.Getenv("TICKET_SERVICENOW_USER"),
+		ServiceNowPassword: os.Getenv("TICKET_SERVICENOW_PASSWORD"),
+		HTTPClient:         &http.Client{Timeout: 10 * time.Second},
+	}
+	if c.JiraURL == "" && c.GitHubToken == "" && c.ServiceNowURL == "" {
+		return nil, nil
+	}
+	if c.JiraURL != "" && c.JiraToken == "" {
+		return nil, fmt.Errorf("TICKET_JIRA_URL is set but TICKET_JIRA_TOKEN is not")
+	}
+	if c.ServiceNowURL != "" && (c.ServiceNowUser == "" || c.ServiceNowPassword == "") {
+		return nil, fmt.Errorf("TICKET_SERVICENOW_URL is set but TICKET_SERVICENOW_USER or TICKET_SERVICENOW_PASSWORD is not")
+	}
+	if c.GitHubToken != "" && c.GitHubAPIURL == "" {
+		c.GitHubAPIURL = "https://api.github.com"
+	}
+	return c, nil
+}
+
+// Check returns nil if the ticket ref names exists and is open. It wraps ErrNotFound, ErrClosed or
+// ErrUnsupported when the ticket must be rejected; any other error is transient.
+func (c *Checker) Check(ctx context.Context, ref string) error {
+	ref = strings.TrimSpace(ref)
+	if u, err := url.Parse(ref); err == nil && u.Host != "" {
+		return c.checkURL(ctx, ref, u)
+	}
+	switch {
+	case githubRefPattern.MatchString(ref) && c.GitHubToken != "":
+		m := githubRefPattern.FindStringSubmatch(ref)
+		return c.checkGitHub(ctx, m[1], m[2], m[3])
+	case jiraKeyPattern.MatchString(ref) && c.JiraURL != "":
+		return c.checkJira(ctx, ref)
+	case serviceNowNumPattern.MatchString(ref) && c.ServiceNowURL != "":
+		return c.checkServiceNow(ctx, serviceNowNumPattern.FindString(ref))
+	}
+	return fmt.Errorf("%w: %q", ErrUnsupported, ref)
+}
+
+// checkURL checks a ticket given by its URL, which must be on one of the configured ticket systems.
+func (c *Checker) checkURL(ctx context.Context, ref string, u *url.URL) error {
+	switch {
+	case c.JiraURL != "" && strings.HasPrefix(ref, c.JiraURL+"/browse/"):
+		return c.checkJira(ctx, strings.Trim(strings.TrimPrefix(u.Path, "/browse/"), "/"))
+	case c.ServiceNowURL != "" && strings.HasPrefix(ref, c.ServiceNowURL+"/"):
+		if number := serviceNowNumPattern.FindString(ref); number != "" {
+			return c.checkServiceNow(ctx, number)
+		}
+	case c.GitHubToken != "" && u.Host == c.githubHost() && githubPathPattern.MatchString(u.Path):
+		m := githubPathPattern.FindStringSubmatch(u.Path)
+		return c.checkGitHub(ctx, m[1], m[2], m[3])
+	}
+	return fmt.Errorf("%w: %q", ErrUnsupported, ref)
+}
+
+// githubHost is the host of the web URLs of GitHubAPIURL's issues: github.com, or the GitHub
+// Enterprise host serving the API under /api/v3.
+func (c *Checker) githubHost() string {
+	u, err := url.Parse(c.GitHubAPIURL)
+	if err != nil {
+		return ""
+	}
+	if u.Host == "api.github.com" {
+		return "github.com"
+	}
+	return u.Host
+}
+
+// checkJira treats issues whose status is in the Done category as closed.
+func (c *Checker) checkJira(ctx context.Context, key string) error {
+	if !jiraKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: %q is not a Jira issue key", ErrNotFound, key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.JiraURL+"/rest/api/2/issue/"+key+"?fields=status", nil)
+	if err != nil {
+		return err
+	}
+	if c.JiraUser != "" {
+		req.SetBasicAuth(c.JiraUser, c.JiraToken)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.JiraToken)
+	}
+	var issue struct {
+		Fields struct {
+			Status struct {
+				Name           string `json:"name"`
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	if err := c.get(req, "Jira issue "+key, &issue); err != nil {
+		return err
+	}
+	if issue.Fields.Status.StatusCategory.Key == "done" {
+		return fmt.Errorf("%w: Jira issue %s is %s", ErrClosed, key, issue.Fields.Status.Name)
+	}
+	return nil
+}
+
+// checkGitHub requires the issue or pull request to be open.
+func (c *Checker) checkGitHub(ctx context.Context, owner, repo, number string) error {
+	name := fmt.Sprintf("GitHub issue %s/%s#%s", owner, repo, number)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/repos/%s/%s/issues/%s", c.GitHubAPIURL, owner, repo, number), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.GitHubToken)
+	var issue struct {
+		State string `json:"state"`
+	}
+	if err := c.get(req, name, &issue); err != nil {
+		return err
+	}
+	if issue.State != "open" {
+		return fmt.Errorf("%w: %s is %s", ErrClosed, name, issue.State)
+	}
+	return nil
+}
+
+// checkServiceNow looks the number up in the task table, which incidents, changes and requests all
+// extend, and requires the record to be active.
+func (c *Checker) checkServiceNow(ctx context.Context, number string) error {
+	query := url.Values{
+		"sysparm_query":  {"number=" + number},
+		"sysparm_fields": {"number,active,state"},
+		"sysparm_limit":  {"1"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.ServiceNowURL+"/api/now/table/task?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.ServiceNowUser, c.ServiceNowPassword)
+	req.Header.Set("Accept", "application/json")
+	var resp struct {
+		Result []struct {
+			Active string `json:"active"`
+		} `json:"result"`
+	}
+	name := "ServiceNow ticket " + number
+	if err := c.get(req, name, &resp); err != nil {
+		return err
+	}
+	if len(resp.Result) == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if resp.Result[0].Active != "true" {
+		return fmt.Errorf("%w: %s is not active", ErrClosed, name)
+	}
+	return nil
+}
+
+// get sends req and decodes its JSON response into out. A 404 wraps ErrNotFound.
+func (c *Checker) get(req *http.Request, name string, out any) error {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("looking up %s returned %s: %s", name, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// Rejected reports whether err means the ticket must be rejected rather than checked again.
+func Rejected(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrClosed) || errors.Is(err, ErrUnsupported)
+}
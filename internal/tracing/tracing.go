@@ -0,0 +1,105 @@
+// Package tracing wires OpenTelemetry into the controller and the debug proxy.
+//
+// A DebugSession's lifecycle spans many short reconciles and, later, proxy connections. To show it as a
+// single trace, the first reconcile starts a root span and stores its context on the session in the
+// TraceParentAnnotation; every later span (phase reconciles, attach streams) is parented to it.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TraceParentAnnotation stores the W3C traceparent of a session's root span.
+const TraceParentAnnotation = "ajou.oxan0n.me/traceparent"
+
+const (
+	tracerName = "github.com/OxAN0N/KubeDebugSess"
+
+	// AttrSessionUID identifies the DebugSession a span belongs to.
+	AttrSessionUID = attribute.Key("debugsession.uid")
+	// AttrSessionName is the namespaced name of the DebugSession.
+	AttrSessionName = attribute.Key("debugsession.name")
+	// AttrSessionPhase is the phase the session was in when the span started.
+	AttrSessionPhase = attribute.Key("debugsession.phase")
+)
+
+var propagator = propagation.TraceContext{}
+
+// Setup installs the global TracerProvider. Spans are exported over OTLP/gRPC only when
+// OTEL_EXPORTER_OTLP_ENDPOINT (or the traces-specific variant) is set; otherwise tracing stays a no-op.
+// The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer shared by all KubeDebugSess components.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// SessionAttributes returns the attributes identifying obj on a span.
+func SessionAttributes(obj client.Object) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AttrSessionUID.String(string(obj.GetUID())),
+		AttrSessionName.String(obj.GetNamespace() + "/" + obj.GetName()),
+	}
+}
+
+// ContextWithSessionTrace returns ctx parented to the session's root span, if one was recorded.
+func ContextWithSessionTrace(ctx context.Context, obj client.Object) context.Context {
+	traceParent, ok := obj.GetAnnotations()[TraceParentAnnotation]
+	if !ok {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
+
+// TraceParent serializes the span context carried by ctx into a W3C traceparent value.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// RecordError marks the span as failed when err is non-nil.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,176 @@
+// Package vault issues and revokes the short-lived HashiCorp Vault credentials handed to debuggers.
+// It speaks Vault's HTTP API directly.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTokenFile is the controller's projected service account token, used for Kubernetes auth.
+const defaultTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Client reads dynamic secrets and revokes their leases.
+type Client struct {
+	// Addr is Vault's base URL, e.g. https://vault.vault.svc:8200.
+	Addr string
+	// Namespace is the Vault Enterprise namespace, if any.
+	Namespace string
+	// Token authenticates directly when set; otherwise Role logs in with Kubernetes auth.
+	Token string
+	// Role is the Kubernetes auth role the controller logs in as.
+	Role string
+	// AuthMount is the mount path of the Kubernetes auth method.
+	AuthMount string
+	// JWTFile holds the service account token presented to Kubernetes auth.
+	JWTFile    string
+	HTTPClient *http.Client
+
+	mu          sync.Mutex
+	loginToken  string
+	loginExpiry time.Time
+}
+
+// Secret is a dynamic secret read from Vault.
+type Secret struct {
+	LeaseID       string
+	LeaseDuration time.Duration
+	Data          map[string]string
+}
+
+// NewFromEnv returns a Client configured by VAULT_ADDR, VAULT_NAMESPACE, and either VAULT_TOKEN or
+// VAULT_ROLE (with VAULT_AUTH_MOUNT, default "kubernetes"). It returns nil when VAULT_ADDR is unset.
+func NewFromEnv() (*Client, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+	c := &Client{
+		Addr:       strings.TrimSuffix(addr, "/"),
+		Namespace:  os.Getenv("VAULT_NAMESPACE"),
+		Token:      os.Getenv("VAULT_TOKEN"),
+		Role:       os.Getenv("VAULT_ROLE"),
+		AuthMount:  os.Getenv("VAULT_AUTH_MOUNT"),
+		JWTFile:    defaultTokenFile,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	if c.AuthMount == "" {
+		c.AuthMount = "kubernetes"
+	}
+	if c.Token == "" && c.Role == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is set but neither VAULT_TOKEN nor VAULT_ROLE is")
+	}
+	return c, nil
+}
+
+// Read reads the secret at path, e.g. database/creds/readonly. Only string values of its data are kept.
+func (c *Client) Read(ctx context.Context, path string) (*Secret, error) {
+	var resp struct {
+		LeaseID       string         `json:"lease_id"`
+		LeaseDuration int            `json:"lease_duration"`
+		Data          map[string]any `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, strings.TrimPrefix(path, "/"), nil, &resp); err != nil {
+		return nil, err
+	}
+	secret := &Secret{
+		LeaseID:       resp.LeaseID,
+		LeaseDuration: time.Duration(resp.LeaseDuration) * time.Second,
+		Data:          map[string]string{},
+	}
+	for k, v := range resp.Data {
+		if s, ok := v.(string); ok {
+			secret.Data[k] = s
+		}
+	}
+	return secret, nil
+}
+
+// Revoke revokes the lease immediately, invalidating the credentials it issued.
+func (c *Client) Revoke(ctx context.Context, leaseID string) error {
+	return c.do(ctx, http.MethodPut, "sys/leases/revoke", map[string]string{"lease_id": leaseID}, nil)
+}
+
+// token returns the configured token, or logs in with Kubernetes auth and caches the result until
+// shortly before it expires.
+func (c *Client) token(ctx context.Context) (string, error) {
+	if c.Token != "" {
+		return c.Token, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loginToken != "" && time.Now().Before(c.loginExpiry) {
+		return c.loginToken, nil
+	}
+
+	jwt, err := os.ReadFile(c.JWTFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	body := map[string]string{"role": c.Role, "jwt": strings.TrimSpace(string(jwt))}
+	if err := c.request(ctx, http.MethodPost, "auth/"+c.AuthMount+"/login", "", body, &resp); err != nil {
+		return "", fmt.Errorf("vault login failed: %w", err)
+	}
+	c.loginToken = resp.Auth.ClientToken
+	c.loginExpiry = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second * 9 / 10)
+	return c.loginToken, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	token, err := c.token(ctx)
+	if err != nil {
+		return err
+	}
+	return c.request(ctx, method, path, token, body, out)
+}
+
+func (c *Client) request(ctx context.Context, method, path, token string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.Addr+"/v1/"+path, reader)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2025.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var debugsessionlog = logf.Log.WithName("debugsession-resource")
+
+// SetupDebugSessionWebhookWithManager registers the webhook for DebugSession in the manager.
+// trustedRequesters are the users, typically the debug proxy's service account, that may create
+// sessions on behalf of someone else by setting spec.requestedBy themselves. trustedApprovers are the
+// users, typically the controller's service account answering Slack, that may record approvals and
+// denials given by someone else.
+func SetupDebugSessionWebhookWithManager(mgr ctrl.Manager, trustedRequesters, trustedApprovers []string) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&debugv1alpha1.DebugSession{}).
+		WithDefaulter(&DebugSessionCustomDefaulter{TrustedRequesters: trustedRequesters}).
+		// The API reader avoids caching every pod in the cluster just to read annotations.
+		WithValidator(&DebugSessionCustomValidator{Reader: mgr.GetAPIReader(), TrustedApprovers: trustedApprovers}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-ajou-oxan0n-me-v1alpha1-debugsession,mutating=true,failurePolicy=fail,sideEffects=None,groups=ajou.oxan0n.me,resources=debugsessions,verbs=create,versions=v1alpha1,name=mdebugsession-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// DebugSessionCustomDefaulter records the creating user in spec.requestedBy.
+type DebugSessionCustomDefaulter struct {
+	TrustedRequesters []string
+}
+
+var _ admission.CustomDefaulter = &DebugSessionCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind DebugSession.
+func (d *DebugSessionCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	debugsession, ok := obj.(*debugv1alpha1.DebugSession)
+	if !ok {
+		return fmt.Errorf("expected a DebugSession object but got %T", obj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read admission request: %w", err)
+	}
+
+	if debugsession.Spec.RequestedBy != nil && slices.Contains(d.TrustedRequesters, req.UserInfo.Username) {
+		debugsessionlog.Info("Keeping requestedBy set by trusted requester", "name", sessionName(debugsession),
+			"requester", req.UserInfo.Username, "requestedBy", debugsession.Spec.RequestedBy.Username)
+		return nil
+	}
+	debugsession.Spec.RequestedBy = RequesterFromUserInfo(req.UserInfo)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-ajou-oxan0n-me-v1alpha1-debugsession,mutating=false,failurePolicy=fail,sideEffects=None,groups=ajou.oxan0n.me,resources=debugsessions,verbs=create;update,versions=v1alpha1,name=vdebugsession-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// DebugSessionCustomValidator rejects sessions that target pods or namespaces opted out of debugging
+// with debugv1alpha1.DenyDebugKey, approvals not given by the approving user themselves, and
+// skip-archive annotations not set by a trusted approver.
+type DebugSessionCustomValidator struct {
+	Reader           client.Reader
+	TrustedApprovers []string
+}
+
+var _ admission.CustomValidator = &DebugSessionCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type DebugSession.
+func (v *DebugSessionCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	debugsession, ok := obj.(*debugv1alpha1.DebugSession)
+	if !ok {
+		return nil, fmt.Errorf("expected a DebugSession object but got %T", obj)
+	}
+	for _, key := range []string{debugv1alpha1.ApprovedByKey, debugv1alpha1.DeniedByKey, debugv1alpha1.SkipArchiveKey} {
+		if _, ok := debugsession.Annotations[key]; ok {
+			return nil, fmt.Errorf("%s cannot be set when the session is created", key)
+		}
+	}
+	return nil, v.validateTarget(ctx, debugsession)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type DebugSession.
+// The target is not checked again, so that sessions whose target became protected can still be cleaned
+// up; the Pending reconciler checks the target again before injecting. Only approvals, the skip-archive
+// annotation and the immutable fields are validated.
+func (v *DebugSessionCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldSession, ok := oldObj.(*debugv1alpha1.DebugSession)
+	if !ok {
+		return nil, fmt.Errorf("expected a DebugSession object but got %T", oldObj)
+	}
+	newSession, ok := newObj.(*debugv1alpha1.DebugSession)
+	if !ok {
+		return nil, fmt.Errorf("expected a DebugSession object but got %T", newObj)
+	}
+	if err := validateImmutable(oldSession, newSession); err != nil {
+		return nil, err
+	}
+	if err := v.validateApproval(ctx, oldSession, newSession); err != nil {
+		return nil, err
+	}
+	if err := v.validateSkipArchive(ctx, oldSession, newSession); err != nil {
+		return nil, err
+	}
+	return nil, v.validateDenial(ctx, oldSession, newSession)
+}
+
+// validateImmutable rejects changes to the target, debugger image, requester, capture, security context
+// and archive policy, which the phase reconcilers and approvals assume stay fixed for the life of a
+// session. The CRD rejects changes of a set value; this also catches fields being set or cleared after
+// creation, which CEL transition rules skip, so that requestedBy cannot be cleared and then set to
+// another identity. Setting an empty targetNamespace to the session's own namespace keeps the same
+// target and is allowed.
+func validateImmutable(oldSession, newSession *debugv1alpha1.DebugSession) error {
+	targetNamespace := func(session *debugv1alpha1.DebugSession) string {
+		if session.Spec.TargetNamespace == "" {
+			return session.Namespace
+		}
+		return session.Spec.TargetNamespace
+	}
+	switch {
+	case newSession.Spec.TargetPodName != oldSession.Spec.TargetPodName:
+		return fmt.Errorf("spec.targetPodName is immutable")
+	case targetNamespace(newSession) != targetNamespace(oldSession):
+		return fmt.Errorf("spec.targetNamespace is immutable")
+	case newSession.Spec.DebuggerImage != oldSession.Spec.DebuggerImage:
+		return fmt.Errorf("spec.debuggerImage is immutable")
+	case !equality.Semantic.DeepEqual(newSession.Spec.RequestedBy, oldSession.Spec.RequestedBy):
+		return fmt.Errorf("spec.requestedBy is immutable")
+	case !equality.Semantic.DeepEqual(newSession.Spec.Capture, oldSession.Spec.Capture):
+		return fmt.Errorf("spec.capture is immutable")
+	case !equality.Semantic.DeepEqual(newSession.Spec.DebugSecurity, oldSession.Spec.DebugSecurity):
+		return fmt.Errorf("spec.debugSecurity is immutable")
+	case newSession.Spec.ArchivePolicy != oldSession.Spec.ArchivePolicy:
+		return fmt.Errorf("spec.archivePolicy is immutable")
+	}
+	return nil
+}
+
+// validateApproval accepts a new approved-by annotation only if it names the user setting it, or that
+// user is a trusted approver, and the approver is not the session's requester.
+func (v *DebugSessionCustomValidator) validateApproval(ctx context.Context, oldSession, newSession *debugv1alpha1.DebugSession) error {
+	approver := newSession.Annotations[debugv1alpha1.ApprovedByKey]
+	if approver == "" || approver == oldSession.Annotations[debugv1alpha1.ApprovedByKey] {
+		return nil
+	}
+	if err := v.validateDecider(ctx, debugv1alpha1.ApprovedByKey, approver); err != nil {
+		return err
+	}
+	if requester := newSession.Spec.RequestedBy; requester != nil && requester.Username == approver {
+		return fmt.Errorf("session %s cannot be approved by its requester", newSession.Name)
+	}
+	debugsessionlog.Info("Session approved", "name", newSession.GetName(), "approver", approver)
+	return nil
+}
+
+// validateDenial accepts a new denied-by annotation under the same rule as approvals, except that
+// requesters may deny their own sessions.
+func (v *DebugSessionCustomValidator) validateDenial(ctx context.Context, oldSession, newSession *debugv1alpha1.DebugSession) error {
+	denier := newSession.Annotations[debugv1alpha1.DeniedByKey]
+	if denier == "" || denier == oldSession.Annotations[debugv1alpha1.DeniedByKey] {
+		return nil
+	}
+	if err := v.validateDecider(ctx, debugv1alpha1.DeniedByKey, denier); err != nil {
+		return err
+	}
+	debugsessionlog.Info("Session denied", "name", newSession.GetName(), "denier", denier)
+	return nil
+}
+
+// validateSkipArchive accepts a new skip-archive annotation only from a trusted approver, since it
+// releases a deleted session without the archive its ArchivePolicy requires.
+func (v *DebugSessionCustomValidator) validateSkipArchive(ctx context.Context, oldSession, newSession *debugv1alpha1.DebugSession) error {
+	value, ok := newSession.Annotations[debugv1alpha1.SkipArchiveKey]
+	if old, had := oldSession.Annotations[debugv1alpha1.SkipArchiveKey]; !ok || (had && old == value) {
+		return nil
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read admission request: %w", err)
+	}
+	if !slices.Contains(v.TrustedApprovers, req.UserInfo.Username) {
+		return fmt.Errorf("%s can only be set by a trusted approver", debugv1alpha1.SkipArchiveKey)
+	}
+	debugsessionlog.Info("Session archive skipped", "name", newSession.GetName(), "user", req.UserInfo.Username)
+	return nil
+}
+
+// validateDecider checks that the user setting key to decider is decider, or a trusted approver.
+func (v *DebugSessionCustomValidator) validateDecider(ctx context.Context, key, decider string) error {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read admission request: %w", err)
+	}
+	if decider != req.UserInfo.Username && !slices.Contains(v.TrustedApprovers, req.UserInfo.Username) {
+		return fmt.Errorf("%s must be your own username %q", key, req.UserInfo.Username)
+	}
+	return nil
+}
+
+// sessionName names a session in logs. Sessions created with metadata.generateName have no name yet
+// when they are admitted, so their prefix is logged instead.
+func sessionName(session *debugv1alpha1.DebugSession) string {
+	if session.Name == "" && session.GenerateName != "" {
+		return session.GenerateName + "*"
+	}
+	return session.Name
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type DebugSession.
+func (v *DebugSessionCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateTarget denies sessions whose target namespace or pod is protected, or whose target namespace
+// does not allow the session's eBPF tracing or requires a ticket it lacks. A target that does not
+// exist yet is left to the Pending reconciler.
+func (v *DebugSessionCustomValidator) validateTarget(ctx context.Context, debugsession *debugv1alpha1.DebugSession) error {
+	targetNamespace := debugsession.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = debugsession.Namespace
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := v.Reader.Get(ctx, types.NamespacedName{Name: targetNamespace}, namespace); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get target namespace: %w", err)
+		}
+		namespace = nil
+	}
+	pod := &corev1.Pod{}
+	if err := v.Reader.Get(ctx, types.NamespacedName{Namespace: targetNamespace, Name: debugsession.Spec.TargetPodName}, pod); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get target pod: %w", err)
+		}
+		pod = nil
+	}
+
+	if reason := debugv1alpha1.ProtectedReason(namespace, pod); reason != "" {
+		debugsessionlog.Info("Rejecting session for protected target", "name", sessionName(debugsession), "reason", reason)
+		return fmt.Errorf("%s", reason)
+	}
+	if namespace != nil {
+		if reason := debugv1alpha1.EBPFDeniedReason(debugsession, namespace); reason != "" {
+			return fmt.Errorf("%s", reason)
+		}
+		if reason := debugv1alpha1.ConfigRefDeniedReason(debugsession, namespace); reason != "" {
+			return fmt.Errorf("%s", reason)
+		}
+		if reason := debugv1alpha1.TicketDeniedReason(debugsession, namespace); reason != "" {
+			return fmt.Errorf("%s", reason)
+		}
+	}
+	return nil
+}
+
+// RequesterFromUserInfo converts authenticated user info into a Requester.
+func RequesterFromUserInfo(user authenticationv1.UserInfo) *debugv1alpha1.Requester {
+	requester := &debugv1alpha1.Requester{
+		Username: user.Username,
+		UID:      user.UID,
+		Groups:   user.Groups,
+	}
+	if len(user.Extra) > 0 {
+		requester.Extra = make(map[string][]string, len(user.Extra))
+		for k, v := range user.Extra {
+			requester.Extra[k] = v
+		}
+	}
+	return requester
+}
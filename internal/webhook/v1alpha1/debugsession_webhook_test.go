@@ -0,0 +1,174 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// asUser returns a context carrying an admission request made by username.
+func asUser(username string) context.Context {
+	return admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: username}},
+	})
+}
+
+func annotated(annotations map[string]string) *debugv1alpha1.DebugSession {
+	return &debugv1alpha1.DebugSession{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "s1", Annotations: annotations},
+		Spec:       debugv1alpha1.DebugSessionSpec{RequestedBy: &debugv1alpha1.Requester{Username: "alice"}},
+	}
+}
+
+func TestValidateSkipArchive(t *testing.T) {
+	v := &DebugSessionCustomValidator{TrustedApprovers: []string{"ops"}}
+	skip := map[string]string{debugv1alpha1.SkipArchiveKey: "storage is down"}
+
+	tests := []struct {
+		name     string
+		user     string
+		old, new map[string]string
+		valid    bool
+	}{
+		{name: "not set", user: "alice", valid: true},
+		{name: "trusted approver", user: "ops", new: skip, valid: true},
+		{name: "requester", user: "alice", new: skip},
+		{name: "empty value", user: "alice", new: map[string]string{debugv1alpha1.SkipArchiveKey: ""}},
+		{name: "unchanged", user: "alice", old: skip, new: skip, valid: true},
+		{name: "changed", user: "alice", old: skip, new: map[string]string{debugv1alpha1.SkipArchiveKey: "other"}},
+		{name: "removed", user: "alice", old: skip, valid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.validateSkipArchive(asUser(tt.user), annotated(tt.old), annotated(tt.new))
+			if (err == nil) != tt.valid {
+				t.Errorf("validateSkipArchive() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateImmutable(t *testing.T) {
+	base := func() *debugv1alpha1.DebugSession {
+		return &debugv1alpha1.DebugSession{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "s1"},
+			Spec: debugv1alpha1.DebugSessionSpec{
+				TargetPodName: "web-0",
+				DebuggerImage: "busybox",
+				RequestedBy:   &debugv1alpha1.Requester{Username: "alice"},
+				ArchivePolicy: debugv1alpha1.ArchivePolicyRequired,
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*debugv1alpha1.DebugSession)
+		valid  bool
+	}{
+		{name: "unchanged", mutate: func(*debugv1alpha1.DebugSession) {}, valid: true},
+		{name: "mutable field", mutate: func(s *debugv1alpha1.DebugSession) { s.Spec.TerminateOnDetach = true }, valid: true},
+		{name: "own namespace made explicit", mutate: func(s *debugv1alpha1.DebugSession) { s.Spec.TargetNamespace = "apps" }, valid: true},
+		{name: "target pod", mutate: func(s *debugv1alpha1.DebugSession) { s.Spec.TargetPodName = "web-1" }},
+		{name: "target namespace", mutate: func(s *debugv1alpha1.DebugSession) { s.Spec.TargetNamespace = "other" }},
+		{name: "debugger image", mutate: func(s *debugv1alpha1.DebugSession) { s.Spec.DebuggerImage = "nicolaka/netshoot" }},
+		{name: "requester changed", mutate: func(s *debugv1alpha1.DebugSession) { s.Spec.RequestedBy.Username = "bob" }},
+		{name: "requester cleared", mutate: func(s *debugv1alpha1.DebugSession) { s.Spec.RequestedBy = nil }},
+		{
+			name: "capture added",
+			mutate: func(s *debugv1alpha1.DebugSession) {
+				s.Spec.Capture = &debugv1alpha1.Capture{Type: debugv1alpha1.CaptureTcpdump}
+			},
+		},
+		{
+			name: "privilege added",
+			mutate: func(s *debugv1alpha1.DebugSession) {
+				s.Spec.DebugSecurity = &debugv1alpha1.DebugSecurityContext{Privileged: ptr.To(true)}
+			},
+		},
+		{
+			name: "capability added",
+			mutate: func(s *debugv1alpha1.DebugSession) {
+				s.Spec.DebugSecurity = &debugv1alpha1.DebugSecurityContext{
+					Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"SYS_ADMIN"}},
+				}
+			},
+		},
+		{name: "archive policy", mutate: func(s *debugv1alpha1.DebugSession) { s.Spec.ArchivePolicy = debugv1alpha1.ArchivePolicyBestEffort }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newSession := base()
+			tt.mutate(newSession)
+			err := validateImmutable(base(), newSession)
+			if (err == nil) != tt.valid {
+				t.Errorf("validateImmutable() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateApproval(t *testing.T) {
+	v := &DebugSessionCustomValidator{TrustedApprovers: []string{"slack-app"}}
+	approved := func(approver string) map[string]string {
+		return map[string]string{debugv1alpha1.ApprovedByKey: approver}
+	}
+
+	tests := []struct {
+		name     string
+		user     string
+		old, new map[string]string
+		valid    bool
+	}{
+		{name: "no approval", user: "bob", valid: true},
+		{name: "own approval", user: "bob", new: approved("bob"), valid: true},
+		{name: "approval for someone else", user: "bob", new: approved("carol")},
+		{name: "trusted approver on behalf of someone", user: "slack-app", new: approved("carol"), valid: true},
+		{name: "requester approves own session", user: "alice", new: approved("alice")},
+		{name: "trusted approver on behalf of the requester", user: "slack-app", new: approved("alice")},
+		{name: "unchanged approval", user: "alice", old: approved("carol"), new: approved("carol"), valid: true},
+		{name: "approval replaced", user: "bob", old: approved("carol"), new: approved("dave")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.validateApproval(asUser(tt.user), annotated(tt.old), annotated(tt.new))
+			if (err == nil) != tt.valid {
+				t.Errorf("validateApproval() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateDenial(t *testing.T) {
+	v := &DebugSessionCustomValidator{TrustedApprovers: []string{"slack-app"}}
+	denied := func(denier string) map[string]string {
+		return map[string]string{debugv1alpha1.DeniedByKey: denier}
+	}
+
+	tests := []struct {
+		name  string
+		user  string
+		new   map[string]string
+		valid bool
+	}{
+		{name: "own denial", user: "bob", new: denied("bob"), valid: true},
+		{name: "requester denies own session", user: "alice", new: denied("alice"), valid: true},
+		{name: "denial for someone else", user: "bob", new: denied("carol")},
+		{name: "trusted approver on behalf of someone", user: "slack-app", new: denied("carol"), valid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.validateDenial(asUser(tt.user), annotated(nil), annotated(tt.new))
+			if (err == nil) != tt.valid {
+				t.Errorf("validateDenial() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+// Package client is a Go SDK for KubeDebugSess. It creates DebugSessions, waits for the debugger to
+// become ready, attaches to it through the debug proxy and fetches the archived transcript.
+//
+//	c, _ := client.New(restConfig, "https://debug-proxy.example.com")
+//	session, _ := c.CreateSession(ctx, "default", v1alpha1.DebugSessionSpec{TargetPodName: "web-0", DebuggerImage: "busybox"})
+//	session, _ = c.WaitForReady(ctx, session)
+//	term, _ := c.Attach(ctx, session)
+//	defer term.Close()
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	debugv1alpha1 "github.com/OxAN0N/KubeDebugSess/api/v1alpha1"
+	"github.com/gorilla/websocket"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultPollInterval is how often WaitForReady checks the session status.
+const DefaultPollInterval = time.Second
+
+// ErrSessionEnded is returned by WaitForReady when the session completed or failed before it became ready.
+var ErrSessionEnded = errors.New("debug session ended before it became ready")
+
+// ErrNoTranscript is returned by FetchTranscript when the session has no downloadable transcript.
+var ErrNoTranscript = errors.New("debug session has no downloadable transcript")
+
+// Client talks to the Kubernetes API for DebugSessions and to the debug proxy for attach.
+type Client struct {
+	// Kube reads and writes DebugSessions.
+	Kube ctrlclient.Client
+	// ProxyURL is the base URL of the debug proxy, e.g. http://debug-proxy:80.
+	ProxyURL string
+	// HTTPClient downloads transcripts. http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+	// Dialer opens attach WebSockets. websocket.DefaultDialer is used when nil.
+	Dialer *websocket.Dialer
+	// PollInterval overrides DefaultPollInterval.
+	PollInterval time.Duration
+}
+
+// New returns a Client using the given REST config and debug proxy URL.
+func New(cfg *rest.Config, proxyURL string) (*Client, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := debugv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	kube, err := ctrlclient.New(cfg, ctrlclient.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	return &Client{Kube: kube, ProxyURL: proxyURL}, nil
+}
+
+// CreateSession creates a DebugSession in namespace with a generated "debug-" name.
+func (c *Client) CreateSession(ctx context.Context, namespace string, spec debugv1alpha1.DebugSessionSpec) (*debugv1alpha1.DebugSession, error) {
+	session := &debugv1alpha1.DebugSession{Spec: spec}
+	session.Namespace = namespace
+	session.GenerateName = "debug-"
+	if err := c.Kube.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// WaitForReady polls the session until the debugger accepts attach connections and returns the
+// refreshed session. It returns ErrSessionEnded if the session completes or fails first.
+func (c *Client) WaitForReady(ctx context.Context, session *debugv1alpha1.DebugSession) (*debugv1alpha1.DebugSession, error) {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	key := types.NamespacedName{Namespace: session.Namespace, Name: session.Name}
+	latest := &debugv1alpha1.DebugSession{}
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		if err := c.Kube.Get(ctx, key, latest); err != nil {
+			return false, err
+		}
+		switch latest.Status.Phase {
+		case debugv1alpha1.Completed, debugv1alpha1.Failed:
+			return false, fmt.Errorf("%w: %s: %s", ErrSessionEnded, latest.Status.Phase, latest.Status.Message)
+		}
+		return latest.Status.ReadyForAttach && latest.Status.OneTimeToken != "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// WaitForCompletion polls the session until it completed or failed and returns the refreshed session,
+// e.g. to read the artifacts of a capture action.
+func (c *Client) WaitForCompletion(ctx context.Context, session *debugv1alpha1.DebugSession) (*debugv1alpha1.DebugSession, error) {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	key := types.NamespacedName{Namespace: session.Namespace, Name: session.Name}
+	latest := &debugv1alpha1.DebugSession{}
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		if err := c.Kube.Get(ctx, key, latest); err != nil {
+			return false, err
+		}
+		return latest.Status.Phase == debugv1alpha1.Completed || latest.Status.Phase == debugv1alpha1.Failed, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// Attach connects to the session's debugger terminal through the debug proxy. Reads return terminal
+// output and writes are sent as terminal input. The session must be ready for attach. Sessions with
+// spec.rotateTokenOnAttach get a new token on every attach, so their current token is read first.
+func (c *Client) Attach(ctx context.Context, session *debugv1alpha1.DebugSession) (io.ReadWriteCloser, error) {
+	if session.Spec.RotateTokenOnAttach {
+		latest := &debugv1alpha1.DebugSession{}
+		if err := c.Kube.Get(ctx, types.NamespacedName{Namespace: session.Namespace, Name: session.Name}, latest); err != nil {
+			return nil, err
+		}
+		session = latest
+	}
+	conn, err := c.dial(ctx, session, "attach", nil)
+	if err != nil {
+		return nil, fmt.Errorf("attach failed: %w", err)
+	}
+	return &terminal{conn: conn}, nil
+}
+
+// PortForward connects to the runtime debugger port of a session with spec.runtimeAttach through the
+// debug proxy. The returned stream carries the raw TCP connection, e.g. for a dlv or debugpy client.
+func (c *Client) PortForward(ctx context.Context, session *debugv1alpha1.DebugSession) (io.ReadWriteCloser, error) {
+	if session.Spec.RuntimeAttach == nil {
+		return nil, fmt.Errorf("debug session %s/%s does not attach a runtime debugger", session.Namespace, session.Name)
+	}
+	port := strconv.Itoa(int(session.Spec.RuntimeAttach.Port))
+	conn, err := c.dial(ctx, session, "portforward", url.Values{"port": {port}})
+	if err != nil {
+		return nil, fmt.Errorf("port-forward failed: %w", err)
+	}
+	return &terminal{conn: conn}, nil
+}
+
+// dial opens a WebSocket to the given debug proxy endpoint for the session's debugger.
+func (c *Client) dial(ctx context.Context, session *debugv1alpha1.DebugSession, path string,
+	query url.Values) (*websocket.Conn, error) {
+	if !session.Status.ReadyForAttach || session.Status.OneTimeToken == "" {
+		return nil, fmt.Errorf("debug session %s/%s is not ready for attach", session.Namespace, session.Name)
+	}
+	base, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch base.Scheme {
+	case "https":
+		base.Scheme = "wss"
+	case "http":
+		base.Scheme = "ws"
+	}
+
+	ns := session.Spec.TargetNamespace
+	if ns == "" {
+		ns = session.Namespace
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("ns", ns)
+	query.Set("pod", debugv1alpha1.CurrentTargetPod(session))
+	query.Set("container", debugv1alpha1.DebuggerContainerName(session))
+	endpoint := base.JoinPath(path)
+	endpoint.RawQuery = query.Encode()
+
+	dialer := c.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+	header := http.Header{"Authorization": {"Bearer " + session.Status.OneTimeToken}}
+	conn, resp, err := dialer.DialContext(ctx, endpoint.String(), header)
+	if err != nil {
+		if resp != nil {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Terminate asks the controller to end the session now instead of at its TTL. The transcript is
+// archived as for an expired session. Callers need update permission on debugsessions/status.
+func (c *Client) Terminate(ctx context.Context, session *debugv1alpha1.DebugSession) error {
+	switch session.Status.Phase {
+	case debugv1alpha1.Terminating, debugv1alpha1.Completed, debugv1alpha1.Failed:
+		return nil
+	}
+	patch := ctrlclient.MergeFrom(session.DeepCopy())
+	session.Status.Phase = debugv1alpha1.Terminating
+	session.Status.Message = "Session terminated by client."
+	session.Status.ReadyForAttach = false
+	return c.Kube.Status().Patch(ctx, session, patch)
+}
+
+// FetchTranscript downloads the archived transcript (or diagnostic bundle) of a finished session
+// through its presigned log URL. It returns ErrNoTranscript if the session has none, e.g. because
+// it has not finished yet or the storage backend cannot presign.
+func (c *Client) FetchTranscript(ctx context.Context, session *debugv1alpha1.DebugSession) (io.ReadCloser, error) {
+	latest := &debugv1alpha1.DebugSession{}
+	if err := c.Kube.Get(ctx, types.NamespacedName{Namespace: session.Namespace, Name: session.Name}, latest); err != nil {
+		return nil, err
+	}
+	if latest.Status.LogURL == "" {
+		return nil, ErrNoTranscript
+	}
+	if expiry := latest.Status.LogURLExpiryTime; expiry != nil && time.Now().After(expiry.Time) {
+		return nil, fmt.Errorf("%w: log URL expired at %s", ErrNoTranscript, expiry.Time.Format(time.RFC3339))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latest.Status.LogURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("transcript download failed: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// terminal adapts an attach WebSocket to io.ReadWriteCloser.
+type terminal struct {
+	conn *websocket.Conn
+	// buf holds the unread remainder of the last message.
+	buf []byte
+	// writeMu serializes writes; gorilla/websocket allows one concurrent writer.
+	writeMu sync.Mutex
+}
+
+func (t *terminal) Read(p []byte) (int, error) {
+	for len(t.buf) == 0 {
+		_, message, err := t.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+		t.buf = message
+	}
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+	return n, nil
+}
+
+func (t *terminal) Write(p []byte) (int, error) {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if err := t.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (t *terminal) Close() error {
+	t.writeMu.Lock()
+	_ = t.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	t.writeMu.Unlock()
+	return t.conn.Close()
+}